@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -14,13 +15,24 @@ import (
 	"github.com/mmmakskl/HeritageKeeper/service/internal/service"
 	handler "github.com/mmmakskl/HeritageKeeper/service/internal/transport/http-server/handlers/http"
 	mw "github.com/mmmakskl/HeritageKeeper/service/internal/transport/http-server/middleware"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/imaging"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/signedurl"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob/local"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob/s3"
 	"github.com/mmmakskl/HeritageKeeper/service/storage/postgresql"
 )
 
 type App struct {
-	server *http.Server
-	log    *slog.Logger
-	client *ssoGRPC.Client
+	server   *http.Server
+	log      *slog.Logger
+	client   *ssoGRPC.Client
+	service  *service.Service
+	auctions config.Auctions
+	trash    config.Trash
+	// jobs is the context of the background jobs, cancelled on shutdown.
+	jobs     context.Context
+	stopJobs context.CancelFunc
 }
 
 func New(
@@ -45,13 +57,23 @@ func New(
 		os.Exit(1)
 	}
 
-	serv := service.New(log, storage, storage, *client)
+	blobs, err := newBlobStore(cfg.Images)
+	if err != nil {
+		log.Error("failed to create blob store", slog.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	signer := signedurl.New(cfg.Images.URLSecret, cfg.Images.URLPrefix, cfg.Images.URLTTL)
+
+	serv := service.New(log, storage, storage, *client, blobs, signer, imaging.New(cfg.Images.MaxPixels), cfg.Images.MaxUploadSize, cfg.Trash.Retention)
 
 	router := chi.NewRouter()
 
 	secret := cfg.AppSecret
 
 	authMiddleware := mw.JWTAuthMiddleware(secret)
+	upload := mw.UploadMiddleware(cfg.Images.MaxUploadSize, 1, cfg.Images.UploadTimeout)
+	itemUpload := mw.UploadMiddleware(cfg.Images.MaxUploadSize, handler.MaxItemImages, cfg.Images.UploadTimeout)
 
 	router.Use(middleware.Logger)
 	router.Use(mw.New(log))
@@ -66,6 +88,34 @@ func New(
 		r.Post("/auth/register", handlers.Register(log))
 		r.Post("/auth/login", handlers.Login(log))
 		r.Get("/keeper/users", handlers.Users(log))
+		r.Get("/keeper/categories", handlers.Categories(log))
+		r.Get("/keeper/categories/{id}/schema", handlers.CategorySchema(log))
+		r.Get("/keeper/images/*", handlers.Image(log))
+		r.Get("/keeper/public/collections/recent", handlers.RecentPublicCollections(log))
+		r.Get("/keeper/public/users/{username}", handlers.PublicProfile(log))
+		r.Get("/keeper/public/users/{username}/collections/{id}", handlers.PublicCollection(log))
+		r.Get("/keeper/public/users/{username}/items/{item_id}", handlers.PublicItem(log))
+		r.Get("/keeper/public/lots", handlers.PublicLots(log))
+		r.Get("/keeper/public/lots/{lot_id}", handlers.PublicLot(log))
+		r.Get("/keeper/public/lots/{lot_id}/bids", handlers.Bids(log))
+		r.Get("/keeper/public/users/{username}/followers", handlers.PublicFollowers(log))
+		r.Get("/keeper/public/users/{username}/following", handlers.PublicFollowing(log))
+		r.Get("/keeper/public/users/{username}/items/{item_id}/comments", handlers.PublicItemComments(log))
+		r.Get("/keeper/public/users/{username}/collections/{id}/comments", handlers.PublicCollectionComments(log))
+		r.Get("/keeper/public/links/{token}", handlers.PublicShareLink(log))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(mw.StreamAuthMiddleware(secret))
+		r.Get("/api/keeper/notifications/stream", handlers.NotificationStream(log))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(authMiddleware)
+		r.Use(mw.AdminMiddleware(client.IsAdmin))
+		r.Post("/api/keeper/category", handlers.CreateCategory(log))
+		r.Put("/api/keeper/category/{id}", handlers.UpdateCategory(log))
+		r.Delete("/api/keeper/category/{id}", handlers.DeleteCategory(log))
 	})
 
 	router.Group(func(r chi.Router) {
@@ -79,13 +129,98 @@ func New(
 		r.Put("/api/keeper/collection", handlers.UpdateCollection(log))
 		r.Delete("/api/keeper/collection", handlers.DeleteCollection(log))
 		r.Delete("/api/keeper/collection/{id}", handlers.DeleteCollection(log))
-		r.Post("/api/keeper/item", handlers.CreateItem(log))
+		r.With(itemUpload).Post("/api/keeper/item", handlers.CreateItem(log))
 		r.Put("/api/keeper/item", handlers.UpdateItem(log))
-		r.Put("/api/keeper/item/{id}", handlers.UpdateItem(log))
-		//TODO: r.Post("/api/keeper/collection/{id}/lot", handlers.CreateLot(log))
-		// r.Put("/api/keeper/collection/{id}/lot", handlers.UpdateLot(log))
-		// r.Delete("/api/keeper/collection/{id}/lot", handlers.DeleteLot(log))
-		// r.Get("/api/keeper/collection/{id}/lot", handlers.GetLot(log))
+		r.Put("/api/keeper/item/{item_id}", handlers.UpdateItem(log))
+		r.Get("/api/keeper/collection/{id}/items", handlers.Items(log))
+		r.Get("/api/keeper/collection/{id}/history", handlers.CollectionHistory(log))
+		r.With(itemUpload).Post("/api/keeper/collection/item", handlers.CreateItem(log))
+		r.Get("/api/keeper/collection/item/{item_id}", handlers.Item(log))
+		r.Put("/api/keeper/collection/item/{item_id}", handlers.UpdateItem(log))
+		r.Delete("/api/keeper/collection/item/{item_id}", handlers.DeleteItem(log))
+		r.Get("/api/keeper/collection/item/{item_id}/history", handlers.ItemHistory(log))
+		r.Post("/api/keeper/collection/item/{item_id}/revert", handlers.RevertItem(log))
+		r.Get("/api/keeper/trash", handlers.Trash(log))
+		r.Post("/api/keeper/trash/collections/{id}/restore", handlers.RestoreCollection(log))
+		r.Post("/api/keeper/trash/items/{item_id}/restore", handlers.RestoreItem(log))
+		r.Post("/api/keeper/items/move", handlers.MoveItems(log))
+		r.Post("/api/keeper/items/copy", handlers.CopyItems(log))
+		r.Post("/api/keeper/items/bulk", handlers.UpdateItems(log))
+		r.Post("/api/keeper/collection/item/{item_id}/tags", handlers.AddItemTags(log))
+		r.Delete("/api/keeper/collection/item/{item_id}/tags/{tag}", handlers.RemoveItemTag(log))
+		r.Get("/api/keeper/tags", handlers.Tags(log))
+		r.Get("/api/keeper/tags/autocomplete", handlers.AutocompleteTags(log))
+		r.Get("/api/keeper/tags/items", handlers.ItemsByTags(log))
+		r.Post("/api/keeper/tags/merge", handlers.MergeTags(log))
+		r.Put("/api/keeper/tags/{tag}", handlers.RenameTag(log))
+		r.Get("/api/keeper/collection/item/{item_id}/images", handlers.ItemImages(log))
+		r.With(upload).Post("/api/keeper/collection/item/{item_id}/images", handlers.UploadItemImage(log))
+		r.Put("/api/keeper/collection/item/{item_id}/images/{image_id}/primary", handlers.SetPrimaryItemImage(log))
+		r.Delete("/api/keeper/collection/item/{item_id}/images/{image_id}", handlers.DeleteItemImage(log))
+		r.Get("/api/keeper/collection/{id}/images", handlers.CollectionImages(log))
+		r.With(upload).Post("/api/keeper/collection/{id}/images", handlers.UploadCollectionImage(log))
+		r.Delete("/api/keeper/collection/{id}/images/{image_id}", handlers.DeleteCollectionImage(log))
+		r.Get("/api/keeper/duplicates", handlers.Duplicates(log))
+		r.Get("/api/keeper/search", handlers.Search(log))
+		r.Get("/api/keeper/facets", handlers.Facets(log))
+		r.With(upload).Post("/api/keeper/profile/avatar", handlers.UploadAvatar(log))
+		r.Post("/api/keeper/collection/{id}/lot", handlers.CreateLot(log))
+		r.Get("/api/keeper/collection/{id}/lot", handlers.Lots(log))
+		r.Get("/api/keeper/collection/{id}/lot/{lot_id}", handlers.Lot(log))
+		r.Put("/api/keeper/collection/{id}/lot/{lot_id}", handlers.UpdateLot(log))
+		r.Put("/api/keeper/collection/{id}/lot/{lot_id}/status", handlers.SetLotStatus(log))
+		r.Delete("/api/keeper/collection/{id}/lot/{lot_id}", handlers.DeleteLot(log))
+		r.Post("/api/keeper/lots/{lot_id}/offers", handlers.MakeOffer(log))
+		r.Post("/api/keeper/lots/{lot_id}/bids", handlers.PlaceBid(log))
+		r.Get("/api/keeper/lots/{lot_id}/offers", handlers.Offers(log))
+		r.Get("/api/keeper/offers", handlers.Offers(log))
+		r.Get("/api/keeper/offers/{offer_id}", handlers.Offer(log))
+		r.Post("/api/keeper/offers/{offer_id}/counter", handlers.CounterOffer(log))
+		r.Post("/api/keeper/offers/{offer_id}/accept", handlers.AcceptOffer(log))
+		r.Post("/api/keeper/offers/{offer_id}/reject", handlers.RejectOffer(log))
+		r.Post("/api/keeper/trades", handlers.ProposeTrade(log))
+		r.Get("/api/keeper/trades", handlers.Trades(log))
+		r.Get("/api/keeper/trades/{trade_id}", handlers.Trade(log))
+		r.Post("/api/keeper/trades/{trade_id}/counter", handlers.CounterTrade(log))
+		r.Post("/api/keeper/trades/{trade_id}/accept", handlers.AcceptTrade(log))
+		r.Post("/api/keeper/trades/{trade_id}/decline", handlers.DeclineTrade(log))
+		r.Get("/api/keeper/collection/item/{item_id}/transfers", handlers.ItemTransfers(log))
+		r.Post("/api/keeper/wants", handlers.CreateWant(log))
+		r.Get("/api/keeper/wants", handlers.Wants(log))
+		r.Get("/api/keeper/wants/matches", handlers.WantMatches(log))
+		r.Get("/api/keeper/wants/{want_id}", handlers.Want(log))
+		r.Put("/api/keeper/wants/{want_id}", handlers.UpdateWant(log))
+		r.Delete("/api/keeper/wants/{want_id}", handlers.DeleteWant(log))
+		r.Get("/api/keeper/favorites", handlers.Favorites(log))
+		r.Put("/api/keeper/favorites/items/{item_id}", handlers.FavoriteItem(log))
+		r.Delete("/api/keeper/favorites/items/{item_id}", handlers.UnfavoriteItem(log))
+		r.Put("/api/keeper/favorites/collections/{id}", handlers.FavoriteCollection(log))
+		r.Delete("/api/keeper/favorites/collections/{id}", handlers.UnfavoriteCollection(log))
+		r.Put("/api/keeper/following/{username}", handlers.Follow(log))
+		r.Delete("/api/keeper/following/{username}", handlers.Unfollow(log))
+		r.Get("/api/keeper/following", handlers.Following(log))
+		r.Get("/api/keeper/followers", handlers.Followers(log))
+		r.Put("/api/keeper/profile/privacy", handlers.SetPrivacy(log))
+		r.Post("/api/keeper/items/{item_id}/comments", handlers.PostItemComment(log))
+		r.Post("/api/keeper/collections/{id}/comments", handlers.PostCollectionComment(log))
+		r.Put("/api/keeper/comments/{comment_id}", handlers.EditComment(log))
+		r.Delete("/api/keeper/comments/{comment_id}", handlers.DeleteComment(log))
+		r.Get("/api/keeper/notifications", handlers.Notifications(log))
+		r.Get("/api/keeper/notifications/unread", handlers.UnreadNotifications(log))
+		r.Post("/api/keeper/notifications/stream/token", handlers.NotificationStreamToken(log, mw.StreamTokenSigner(secret)))
+		r.Put("/api/keeper/notifications/read", handlers.MarkAllNotificationsRead(log))
+		r.Put("/api/keeper/notifications/{notification_id}/read", handlers.MarkNotificationRead(log))
+		r.Get("/api/keeper/notifications/preferences", handlers.NotificationPreferences(log))
+		r.Put("/api/keeper/notifications/preferences", handlers.SetNotificationPreferences(log))
+		r.Get("/api/keeper/feed", handlers.Feed(log))
+		r.Get("/api/keeper/collection/{id}/shares", handlers.CollectionShares(log))
+		r.Put("/api/keeper/collection/{id}/shares", handlers.ShareCollection(log))
+		r.Delete("/api/keeper/collection/{id}/shares/{share_id}", handlers.UnshareCollection(log))
+		r.Get("/api/keeper/shared", handlers.SharedCollections(log))
+		r.Delete("/api/keeper/shared/{id}", handlers.LeaveCollection(log))
+		r.Post("/api/keeper/collection/{id}/links", handlers.CreateShareLink(log))
+		r.Get("/api/keeper/links", handlers.ShareLinks(log))
+		r.Delete("/api/keeper/links/{link_id}", handlers.RevokeShareLink(log))
 	})
 
 	srv := &http.Server{
@@ -96,20 +231,49 @@ func New(
 		IdleTimeout:  cfg.HTTPServer.IdleTimeout,
 	}
 
+	jobs, stopJobs := context.WithCancel(context.Background())
+
 	return &App{
-		server: srv,
-		log:    log,
-		client: client,
+		jobs:     jobs,
+		stopJobs: stopJobs,
+		server:   srv,
+		log:      log,
+		client:   client,
+		service:  serv,
+		auctions: cfg.Auctions,
+		trash:    cfg.Trash,
+	}
+}
+
+func newBlobStore(cfg config.Images) (blob.Store, error) {
+	switch cfg.Driver {
+	case "s3":
+		return s3.New(s3.Config{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+		}, nil)
+	case "local", "":
+		return local.New(cfg.LocalDir)
+	default:
+		return nil, fmt.Errorf("unknown images driver %q", cfg.Driver)
 	}
 }
 
 func (a *App) Run() error {
+	go a.service.RunAuctionCloser(a.jobs, a.auctions.CloseInterval, a.auctions.CloseBatch)
+	go a.service.RunTrashPurger(a.jobs, a.trash.PurgeInterval, a.trash.PurgeBatch)
+	go a.service.RunNotificationListener(a.jobs)
+
 	a.log.Info("Starting server", slog.String("address", a.server.Addr))
 	return a.server.ListenAndServe()
 }
 
 // TODO: если grpc выключен (под вопросом)
 func (a *App) Shutdown(ctx context.Context) error {
+	a.stopJobs()
 	if err := a.server.Shutdown(ctx); err != nil {
 		return err
 	}
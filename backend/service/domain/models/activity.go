@@ -0,0 +1,37 @@
+package models
+
+// Activity types.
+const (
+	// ActivityItemsAdded: a collector added items to a public collection.
+	ActivityItemsAdded = "items_added"
+	// ActivityCollectionPublished: a collection was made public, or created
+	// public. A collection is published once.
+	ActivityCollectionPublished = "collection_published"
+	// ActivityLotListed: a lot went on sale. A lot is listed once.
+	ActivityLotListed = "lot_listed"
+)
+
+// Activity is an entry of the activity feed. Items added one after another
+// to the same collection make one entry: Count says how many, Items holds
+// the newest of them.
+//
+// Lots are public whatever their collection, so the collection of a lot
+// has no Collection title when the collection is private.
+type Activity struct {
+	ActivityID   int64          `json:"id"`
+	UserID       int64          `json:"-"`
+	Type         string         `json:"type"`
+	Username     string         `json:"username"`
+	CollectionID int64          `json:"collection_id,omitempty"`
+	Collection   string         `json:"collection,omitempty"`
+	Count        int            `json:"count,omitempty"`
+	Items        []ActivityItem `json:"items,omitempty"`
+	LotID        int64          `json:"lot_id,omitempty"`
+	Lot          string         `json:"lot,omitempty"`
+	CreatedAt    string         `json:"created_at"`
+}
+
+type ActivityItem struct {
+	ItemID int64  `json:"id"`
+	Title  string `json:"title"`
+}
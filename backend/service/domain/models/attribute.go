@@ -0,0 +1,29 @@
+package models
+
+// Attribute types a category schema can declare.
+const (
+	AttributeString  = "string"
+	AttributeNumber  = "number"
+	AttributeInteger = "integer"
+	AttributeBoolean = "boolean"
+	AttributeEnum    = "enum"
+)
+
+// AttributeField describes one attribute items of a category carry, e.g. the
+// metal of a coin or the perforation of a stamp. Unit is only shown to users,
+// Min and Max bound numbers and integers, Enum lists the allowed values of an
+// enum.
+type AttributeField struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label,omitempty"`
+	Type     string   `json:"type"`
+	Unit     string   `json:"unit,omitempty"`
+	Enum     []string `json:"enum,omitempty"`
+	Min      *float64 `json:"min,omitempty"`
+	Max      *float64 `json:"max,omitempty"`
+	Required bool     `json:"required,omitempty"`
+}
+
+// Attributes are the typed key/value attributes of an item. Values are
+// strings, float64 numbers or booleans, as decoded from JSON.
+type Attributes map[string]any
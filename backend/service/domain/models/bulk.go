@@ -0,0 +1,41 @@
+package models
+
+// Bulk actions on items.
+const (
+	BulkDelete   = "delete"
+	BulkTag      = "tag"
+	BulkCategory = "category"
+	BulkCountry  = "country"
+)
+
+// ItemSelection picks the items of a bulk operation: the items ItemIDs, or
+// when it is empty the items of CollectionID that match Filter. Limit caps
+// how many items an operation may touch.
+type ItemSelection struct {
+	ItemIDs      []int64
+	CollectionID int64
+	Filter       ListOptions
+	Limit        int
+}
+
+// ItemChange is what a bulk action does to each item. AddTags and
+// RemoveTags apply to BulkTag, CategoryID to BulkCategory and Country to
+// BulkCountry.
+type ItemChange struct {
+	Action     string
+	AddTags    []string
+	RemoveTags []string
+	CategoryID int64
+	Country    string
+}
+
+// ItemResult reports what a bulk operation did to one item. NewItemID is
+// the copy made of the item by a copy, and Warning tells what of the item
+// did not make it into the copy.
+type ItemResult struct {
+	ItemID    int64  `json:"item_id"`
+	OK        bool   `json:"ok"`
+	NewItemID int64  `json:"new_item_id,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Warning   string `json:"warning,omitempty"`
+}
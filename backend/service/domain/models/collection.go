@@ -10,10 +10,17 @@ type Collection struct {
 	IsPublic           bool   `json:"is_public"`
 	CreatedAt          string `json:"created_at"`
 	UpdatedAt          string `json:"updated_at"`
+	// Role is what the user who asked may do with the collection.
+	Role string `json:"role,omitempty"`
 }
 
 type Category struct {
 	CategoryID   int64  `json:"id"`
+	ParentID     int64  `json:"parent_id,omitempty"`
 	CategoryName string `json:"name"`
 	Description  string `json:"description"`
+	// AttributeSchema holds the fields the category declares itself. A
+	// subcategory also inherits the fields of its ancestors.
+	AttributeSchema []AttributeField `json:"attribute_schema,omitempty"`
+	Children        []Category       `json:"children,omitempty"`
 }
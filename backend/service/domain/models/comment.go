@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Comment is a comment on a public item or collection. Exactly one of
+// ItemID and CollectionID is set. Replies hang off their parent, so a
+// thread is a tree of comments.
+//
+// A deleted comment stays in its thread as a placeholder: it keeps its
+// place and replies but loses author and text.
+type Comment struct {
+	CommentID    int64  `json:"id"`
+	ItemID       int64  `json:"item_id,omitempty"`
+	CollectionID int64  `json:"collection_id,omitempty"`
+	ParentID     int64  `json:"parent_id,omitempty"`
+	AuthorID     int64  `json:"-"`
+	Author       string `json:"author,omitempty"`
+	// Body is the markdown source, HTML its sanitized rendering.
+	Body      string    `json:"body,omitempty"`
+	HTML      string    `json:"html,omitempty"`
+	Deleted   bool      `json:"deleted,omitempty"`
+	Edited    bool      `json:"edited,omitempty"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+	Replies   []Comment `json:"replies,omitempty"`
+}
+
+// RateLimit allows Count actions per Window.
+type RateLimit struct {
+	Count  int
+	Window time.Duration
+}
@@ -0,0 +1,20 @@
+package models
+
+// DuplicateImage is one side of a likely duplicate: an image and its item.
+type DuplicateImage struct {
+	ItemID       int64  `json:"item_id"`
+	CollectionID int64  `json:"collection_id"`
+	Title        string `json:"title"`
+	ImageID      int64  `json:"image_id"`
+	ThumbKey     string `json:"-"`
+	ThumbURL     string `json:"thumb_url"`
+}
+
+// Duplicate pairs images of two different items that look alike. Distance is
+// the number of differing bits of their perceptual hashes.
+type Duplicate struct {
+	Image      DuplicateImage `json:"image"`
+	Match      DuplicateImage `json:"match"`
+	Distance   int            `json:"-"`
+	Similarity float64        `json:"similarity"`
+}
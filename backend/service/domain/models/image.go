@@ -0,0 +1,32 @@
+package models
+
+// Image is an uploaded photo. Key is the full size variant; images uploaded
+// before variants were introduced have no thumb or medium keys and fall back
+// to the full size URL.
+type Image struct {
+	ImageID      int64  `json:"id"`
+	ItemID       int64  `json:"item_id,omitempty"`
+	CollectionID int64  `json:"collection_id,omitempty"`
+	Key          string `json:"-"`
+	ThumbKey     string `json:"-"`
+	MediumKey    string `json:"-"`
+	URL          string `json:"url"`
+	ThumbURL     string `json:"thumb_url"`
+	MediumURL    string `json:"medium_url"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	Hash         uint64 `json:"-"`
+	IsPrimary    bool   `json:"is_primary"`
+	UploadedAt   string `json:"upload_date"`
+}
+
+// Keys returns the blob keys of every stored variant.
+func (i Image) Keys() []string {
+	var keys []string
+	for _, key := range []string{i.Key, i.MediumKey, i.ThumbKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
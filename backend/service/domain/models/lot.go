@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Lot statuses. Draft lots are only visible to the seller, active and
+// reserved ones are listed in the catalogue, sold and withdrawn are final.
+const (
+	LotDraft     = "draft"
+	LotActive    = "active"
+	LotReserved  = "reserved"
+	LotSold      = "sold"
+	LotWithdrawn = "withdrawn"
+)
+
+// Lot offers one or more items of a collection for sale or exchange. Price is
+// in minor units of Currency (kopecks, cents) and is zero for exchange-only
+// lots.
+type Lot struct {
+	LotID        int64    `json:"id"`
+	UserID       int64    `json:"user_id"`
+	Seller       string   `json:"seller,omitempty"`
+	CollectionID int64    `json:"collection_id"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Price        int64    `json:"price,omitempty"`
+	Currency     string   `json:"currency,omitempty"`
+	ExchangeOnly bool     `json:"exchange_only"`
+	Status       string   `json:"status"`
+	ItemIDs      []int64  `json:"item_ids"`
+	Items        []Item   `json:"items,omitempty"`
+	Auction      *Auction `json:"auction,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// Auction holds the terms and the bidding state of an auction lot. The price
+// of the lot is the opening bid and every later bid has to raise the current
+// one by at least MinIncrement. A bid in the last Extension seconds moves the
+// end to Extension seconds after the bid, so nobody can snipe the auction.
+// The reserve price is only shown to the seller; bidders see ReserveMet.
+type Auction struct {
+	StartsAt     time.Time  `json:"starts_at"`
+	EndsAt       time.Time  `json:"ends_at"`
+	ReservePrice int64      `json:"reserve_price,omitempty"`
+	MinIncrement int64      `json:"min_increment"`
+	Extension    int64      `json:"extension"`
+	CurrentBid   int64      `json:"current_bid,omitempty"`
+	BidderID     int64      `json:"bidder_id,omitempty"`
+	BidCount     int64      `json:"bid_count"`
+	MinBid       int64      `json:"min_bid"`
+	ReserveMet   bool       `json:"reserve_met"`
+	WinnerID     int64      `json:"winner_id,omitempty"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+}
+
+// Bid is a bid placed on an auction lot, in minor units of the lot currency.
+type Bid struct {
+	BidID     int64  `json:"id"`
+	LotID     int64  `json:"lot_id"`
+	BidderID  int64  `json:"bidder_id"`
+	Bidder    string `json:"bidder"`
+	Amount    int64  `json:"amount"`
+	CreatedAt string `json:"created_at"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "encoding/json"
+
+// Notification types. A user may turn each of them off.
+const (
+	// NotificationComment: someone commented on a collection of the user or
+	// one of its items.
+	NotificationComment = "comment"
+	// NotificationReply: someone replied to a comment of the user.
+	NotificationReply = "reply"
+	// NotificationOffer: an offer was made to the user, or an offer of the
+	// user was countered, accepted or rejected.
+	NotificationOffer = "offer"
+	// NotificationTrade: the same for trade proposals.
+	NotificationTrade = "trade"
+	// NotificationWantMatch: a new item or lot matches the want list.
+	NotificationWantMatch = "want_match"
+	// NotificationFollower: someone started following the user.
+	NotificationFollower = "follower"
+	// NotificationShare: a collection was shared with the user.
+	NotificationShare = "share"
+)
+
+// NotificationTypes lists the notification types in the order preferences
+// are shown.
+var NotificationTypes = []string{
+	NotificationComment,
+	NotificationReply,
+	NotificationOffer,
+	NotificationTrade,
+	NotificationWantMatch,
+	NotificationFollower,
+	NotificationShare,
+}
+
+// Notification tells a user about something that happened. Payload depends
+// on Type and carries the ids needed to link to the subject, plus an
+// "event" for offers and trades.
+type Notification struct {
+	NotificationID int64           `json:"id"`
+	UserID         int64           `json:"-"`
+	Type           string          `json:"type"`
+	Payload        json.RawMessage `json:"payload"`
+	Read           bool            `json:"read"`
+	CreatedAt      string          `json:"created_at"`
+}
+
+// NotificationPreference tells whether the user gets notifications of Type.
+type NotificationPreference struct {
+	Type    string `json:"type"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Events in the payload of offer and trade notifications. Answers use the
+// status they gave the offer or trade: accepted, rejected or declined.
+const (
+	NotificationEventProposed  = "proposed"
+	NotificationEventCountered = "countered"
+	// NotificationEventClosed: a pending offer lapsed because the lot went
+	// to another buyer.
+	NotificationEventClosed = "closed"
+)
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Offer statuses. A pending offer waits for the other party; countering it
+// closes it with a new pending offer in the chain. A pending offer past its
+// expiry is reported as expired. An accepted offer is cancelled when the
+// seller releases the reserved lot without selling it.
+const (
+	OfferPending   = "pending"
+	OfferCountered = "countered"
+	OfferAccepted  = "accepted"
+	OfferRejected  = "rejected"
+	OfferExpired   = "expired"
+	OfferCancelled = "cancelled"
+)
+
+// Offer is a price proposed for a lot, either by a buyer or, as a counter
+// offer, by the seller. Offers answering each other form a chain through
+// ParentID; every offer of a chain has the same buyer. Amount is in minor
+// units of Currency, which is the currency of the lot.
+type Offer struct {
+	OfferID   int64     `json:"id"`
+	LotID     int64     `json:"lot_id"`
+	SellerID  int64     `json:"seller_id"`
+	BuyerID   int64     `json:"buyer_id"`
+	Buyer     string    `json:"buyer,omitempty"`
+	AuthorID  int64     `json:"author_id"`
+	ParentID  int64     `json:"parent_id,omitempty"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+	Message   string    `json:"message,omitempty"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt string    `json:"created_at"`
+	UpdatedAt string    `json:"updated_at"`
+}
@@ -0,0 +1,46 @@
+package models
+
+// Sort fields of the list endpoints. Not every list supports all of them.
+const (
+	SortTitle     = "title"
+	SortYear      = "year"
+	SortUsername  = "username"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortPrice     = "price"
+)
+
+// Cursor points at the last row of a page. Value is the sort column of that
+// row as text and ID breaks ties, so the next page starts right after it.
+// Sort and Desc bind the cursor to the order it was issued for.
+type Cursor struct {
+	Sort  string `json:"s,omitempty"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+// ListOptions selects a page of collections, items or users. Cursor is the
+// opaque next_cursor of the previous page, After is its decoded form.
+type ListOptions struct {
+	Sort   string
+	Desc   bool
+	Limit  int
+	Cursor string
+	After  *Cursor
+
+	// Query matches a substring of the title, collection name or username.
+	Query      string
+	CategoryID int64
+	Country    string
+	YearFrom   int
+	YearTo     int
+	Tags       []string
+	IsPublic   *bool
+
+	// Lot catalogue filters. Prices are in minor units of Currency.
+	Currency     string
+	PriceMin     int64
+	PriceMax     int64
+	ExchangeOnly *bool
+}
@@ -0,0 +1,27 @@
+package models
+
+// PublicUser is the part of a user profile that anybody may see. It is a
+// separate type so that email, phone and birth date cannot end up in public
+// responses by accident.
+type PublicUser struct {
+	Username    string `json:"username"`
+	ImageUrl    string `json:"profile_image_url"`
+	MemberSince string `json:"created_at"`
+	Followers   int64  `json:"followers"`
+	Following   int64  `json:"following"`
+	// FollowersPrivate hides the list of followers, not their count.
+	FollowersPrivate bool `json:"followers_private"`
+}
+
+// PublicCollection is a public collection as seen by other users.
+type PublicCollection struct {
+	CollectionID       int64  `json:"id"`
+	Owner              string `json:"owner"`
+	CollectionName     string `json:"name"`
+	Description        string `json:"description"`
+	CollectionImageUrl string `json:"cover_image_url"`
+	CategoryID         int64  `json:"category_id"`
+	ItemCount          int64  `json:"item_count"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+// SearchFilter narrows a full-text search. Zero values mean "no filter".
+type SearchFilter struct {
+	Query        string
+	CollectionID int64
+	CategoryID   int64
+	Country      string
+	YearFrom     int
+	YearTo       int
+	Tags         []string
+	Limit        int
+	Offset       int
+}
+
+// ItemHit is an item found by a search. The snippets are HTML escaped with
+// the matched words wrapped in <mark>.
+type ItemHit struct {
+	Item
+	Owner              string  `json:"owner"`
+	IsOwn              bool    `json:"is_own"`
+	Rank               float64 `json:"rank"`
+	TitleSnippet       string  `json:"title_snippet"`
+	DescriptionSnippet string  `json:"description_snippet"`
+}
+
+// CollectionHit is a collection whose name matched a search.
+type CollectionHit struct {
+	CollectionID   int64   `json:"id"`
+	Owner          string  `json:"owner"`
+	IsOwn          bool    `json:"is_own"`
+	CollectionName string  `json:"name"`
+	NameSnippet    string  `json:"name_snippet"`
+	Rank           float64 `json:"rank"`
+}
+
+type SearchResult struct {
+	Items       []ItemHit       `json:"items"`
+	Collections []CollectionHit `json:"collections"`
+}
+
+// FacetValue is one value of a facet with the number of matching items. Key
+// is only set for attributes, Label for values that are ids.
+type FacetValue struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+type Facets struct {
+	Countries  []FacetValue `json:"countries"`
+	Categories []FacetValue `json:"categories"`
+	Decades    []FacetValue `json:"decades"`
+	Tags       []FacetValue `json:"tags"`
+	Attributes []FacetValue `json:"attributes"`
+}
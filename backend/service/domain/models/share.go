@@ -0,0 +1,36 @@
+package models
+
+// Roles of a user on a collection. The owner does everything, an editor
+// changes the collection and its items but not who sees them, a viewer only
+// reads.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// ShareRoles are the roles a collection can be shared with.
+var ShareRoles = []string{RoleViewer, RoleEditor}
+
+// CollectionShare gives a user a role on a private collection. An
+// invitation by an email nobody has registered with is Pending until they
+// do; it has Email instead of Username.
+type CollectionShare struct {
+	ShareID      int64  `json:"id"`
+	CollectionID int64  `json:"collection_id"`
+	Username     string `json:"username,omitempty"`
+	Email        string `json:"email,omitempty"`
+	Role         string `json:"role"`
+	Pending      bool   `json:"pending,omitempty"`
+	InvitedBy    string `json:"invited_by"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// SharedCollection is a collection of Owner shared with the user as Role.
+type SharedCollection struct {
+	Owner      string     `json:"owner"`
+	Role       string     `json:"role"`
+	Collection Collection `json:"collection"`
+	SharedAt   string     `json:"shared_at"`
+}
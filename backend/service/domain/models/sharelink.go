@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ShareLink lets anybody holding its token read a collection without an
+// account, until the link expires, runs out of views or is revoked. Only a
+// hash of the token is stored, so Token is set once: when the link is
+// created.
+type ShareLink struct {
+	LinkID       int64     `json:"id"`
+	CollectionID int64     `json:"collection_id"`
+	Token        string    `json:"token,omitempty"`
+	TokenHash    []byte    `json:"-"`
+	PasswordHash string    `json:"-"`
+	HasPassword  bool      `json:"has_password"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// MaxViews is 0 for a link with no view limit.
+	MaxViews     int64  `json:"max_views,omitempty"`
+	Views        int64  `json:"views"`
+	CreatedAt    string `json:"created_at"`
+	LastViewedAt string `json:"last_viewed_at,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// FavoriteItem is a public item a user marked as favorite.
+type FavoriteItem struct {
+	Owner       string `json:"owner"`
+	Item        Item   `json:"item"`
+	FavoritedAt string `json:"favorited_at"`
+}
+
+// Favorites are the public items and collections a user marked, most
+// recent first. Those that went private are left out.
+type Favorites struct {
+	Items       []FavoriteItem     `json:"items"`
+	Collections []PublicCollection `json:"collections"`
+}
+
+// Follow is a collector on one side of a follow.
+type Follow struct {
+	Username   string `json:"username"`
+	ImageUrl   string `json:"profile_image_url"`
+	FollowedAt string `json:"followed_at"`
+}
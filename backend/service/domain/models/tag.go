@@ -0,0 +1,7 @@
+package models
+
+type Tag struct {
+	TagID int64  `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
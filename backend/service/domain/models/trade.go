@@ -0,0 +1,44 @@
+package models
+
+// Trade statuses. A pending trade waits for its recipient, who accepts,
+// declines or counters it. Countering closes the trade with a new pending
+// one in the other direction.
+const (
+	TradePending   = "pending"
+	TradeCountered = "countered"
+	TradeAccepted  = "accepted"
+	TradeDeclined  = "declined"
+)
+
+// Trade proposes to exchange items of the proposer for items of the
+// recipient. Each side names the collection its new items go to: the
+// proposer when proposing, the recipient when accepting. Trades answering
+// each other form a chain through ParentID.
+type Trade struct {
+	TradeID               int64   `json:"id"`
+	ProposerID            int64   `json:"proposer_id"`
+	Proposer              string  `json:"proposer"`
+	RecipientID           int64   `json:"recipient_id"`
+	Recipient             string  `json:"recipient"`
+	ParentID              int64   `json:"parent_id,omitempty"`
+	ProposerCollectionID  int64   `json:"proposer_collection_id"`
+	RecipientCollectionID int64   `json:"recipient_collection_id,omitempty"`
+	OfferedItemIDs        []int64 `json:"offered_item_ids"`
+	RequestedItemIDs      []int64 `json:"requested_item_ids"`
+	Message               string  `json:"message,omitempty"`
+	Status                string  `json:"status"`
+	CreatedAt             string  `json:"created_at"`
+	UpdatedAt             string  `json:"updated_at"`
+}
+
+// ItemTransfer records an item changing hands.
+type ItemTransfer struct {
+	TransferID       int64  `json:"id"`
+	ItemID           int64  `json:"item_id"`
+	TradeID          int64  `json:"trade_id,omitempty"`
+	FromUserID       int64  `json:"from_user_id"`
+	ToUserID         int64  `json:"to_user_id"`
+	FromCollectionID int64  `json:"from_collection_id,omitempty"`
+	ToCollectionID   int64  `json:"to_collection_id,omitempty"`
+	CreatedAt        string `json:"created_at"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// TrashedCollection is a collection in the trash. ItemCount is the number
+// of its items that come back with it when it is restored.
+type TrashedCollection struct {
+	Collection Collection `json:"collection"`
+	ItemCount  int64      `json:"item_count"`
+	DeletedBy  string     `json:"deleted_by"`
+	DeletedAt  time.Time  `json:"deleted_at"`
+	// PurgeAt is when the collection and its items are deleted for good.
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// TrashedItem is an item in the trash of a collection that is not.
+type TrashedItem struct {
+	Item           Item      `json:"item"`
+	CollectionName string    `json:"collection_name"`
+	DeletedBy      string    `json:"deleted_by"`
+	DeletedAt      time.Time `json:"deleted_at"`
+	PurgeAt        time.Time `json:"purge_at"`
+}
+
+// Trash is what a user may restore: the collections they deleted and the
+// items deleted from collections they may edit.
+type Trash struct {
+	Collections []TrashedCollection `json:"collections"`
+	Items       []TrashedItem       `json:"items"`
+}
@@ -7,7 +7,7 @@ import "time"
 type User struct {
 	UserID     int64         `json:"id"`
 	Username   string        `json:"username"`
-	Email      string        `json:"email"`
+	Email      string        `json:"email,omitempty"`
 	Phone      string        `json:"phone"`
 	Birth_date time.Time     `json:"birth_date"`
 	CreatedAt  time.Duration `json:"created_at"`
@@ -15,4 +15,6 @@ type User struct {
 	ImageUrl   string        `json:"profile_image_url"`
 	IsActive   bool          `json:"is_active"`
 	IsBlocked  bool          `json:"is_blocked"`
+	// FollowersPrivate hides the user's followers from other users.
+	FollowersPrivate bool `json:"followers_private"`
 }
@@ -0,0 +1,61 @@
+package models
+
+import "encoding/json"
+
+// What a version is of.
+const (
+	EntityItem       = "item"
+	EntityCollection = "collection"
+)
+
+// What made a version.
+const (
+	VersionCreate  = "create"
+	VersionUpdate  = "update"
+	VersionDelete  = "delete"
+	VersionRevert  = "revert"
+	VersionRestore = "restore"
+)
+
+// Version is a snapshot of an item or a collection as it was after a
+// change, numbered from 1 per item or collection. Changes holds the fields
+// that differ from the previous version.
+type Version struct {
+	VersionID int64                  `json:"id"`
+	Version   int                    `json:"version"`
+	Action    string                 `json:"action"`
+	UserID    int64                  `json:"-"`
+	Username  string                 `json:"username"`
+	Snapshot  json.RawMessage        `json:"snapshot"`
+	Changes   map[string]FieldChange `json:"changes"`
+	// RevertedFrom is the version a revert restored.
+	RevertedFrom int    `json:"reverted_from,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// FieldChange is the value of a field before and after a change.
+type FieldChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// ItemSnapshot is what a version of an item keeps.
+type ItemSnapshot struct {
+	CollectionID int64      `json:"collection_id"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	CategoryID   int64      `json:"category_id"`
+	Country      string     `json:"country"`
+	Year         string     `json:"year"`
+	Attributes   Attributes `json:"attributes"`
+	Images       []string   `json:"item_images_url"`
+	Tags         []string   `json:"tags"`
+}
+
+// CollectionSnapshot is what a version of a collection keeps.
+type CollectionSnapshot struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CategoryID  int64  `json:"category_id"`
+	IsPublic    bool   `json:"is_public"`
+}
@@ -0,0 +1,35 @@
+package models
+
+// Want is an entry of a collector's want list: a description of an item
+// they are missing. Empty fields match anything. Attributes must all be
+// present with the same values on a matching item. MaxPrice, in minor units
+// of Currency, only restricts lots; exchange-only lots always qualify.
+type Want struct {
+	WantID     int64      `json:"id"`
+	UserID     int64      `json:"user_id"`
+	Title      string     `json:"title"`
+	Keywords   string     `json:"keywords,omitempty"`
+	CategoryID int64      `json:"category_id,omitempty"`
+	Country    string     `json:"country,omitempty"`
+	YearFrom   int        `json:"year_from,omitempty"`
+	YearTo     int        `json:"year_to,omitempty"`
+	MaxPrice   int64      `json:"max_price,omitempty"`
+	Currency   string     `json:"currency,omitempty"`
+	Attributes Attributes `json:"attributes,omitempty"`
+	CreatedAt  string     `json:"created_at"`
+	UpdatedAt  string     `json:"updated_at"`
+}
+
+// WantMatch is a public item, or an active lot holding it, that matches a
+// want of the user.
+type WantMatch struct {
+	MatchID   int64  `json:"id"`
+	WantID    int64  `json:"want_id"`
+	WantTitle string `json:"want_title"`
+	Owner     string `json:"owner"`
+	Item      Item   `json:"item"`
+	LotID     int64  `json:"lot_id,omitempty"`
+	Price     int64  `json:"price,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
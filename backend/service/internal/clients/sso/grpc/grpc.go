@@ -110,3 +110,19 @@ func (c *Client) Login(ctx context.Context, email string, passwd string, appid i
 
 	return user.Token, nil
 }
+
+func (c *Client) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	const op = "grpc.client.isadmin"
+
+	c.log.DebugContext(ctx, op, "check admin", slog.Int64("user_id", userID))
+
+	resp, err := c.api.IsAdmin(ctx, &ssov1.IsAdminRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		c.log.ErrorContext(ctx, op, "failed to check admin", err)
+		return false, err
+	}
+
+	return resp.IsAdmin, nil
+}
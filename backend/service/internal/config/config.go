@@ -15,6 +15,49 @@ type Config struct {
 	Clients    ClientsConfig `yaml:"clients" env-required:"true"`
 	Frontend   Frontend      `yaml:"frontend" env-required:"true"`
 	AppSecret  string        `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
+	Images     Images        `yaml:"images"`
+	Auctions   Auctions      `yaml:"auctions"`
+	Trash      Trash         `yaml:"trash"`
+}
+
+type Auctions struct {
+	// CloseInterval is how often ended auctions are finalized.
+	CloseInterval time.Duration `yaml:"close_interval" env-default:"10s"`
+	CloseBatch    int           `yaml:"close_batch" env-default:"100"`
+}
+
+type Trash struct {
+	// Retention is how long deleted collections and items can be restored
+	// before they are purged, with their images.
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+	PurgeBatch    int           `yaml:"purge_batch" env-default:"100"`
+}
+
+type Images struct {
+	// Driver selects the blob store: "local" or "s3".
+	Driver        string `yaml:"driver" env-default:"local"`
+	LocalDir      string `yaml:"local_dir" env-default:"./data/images"`
+	S3            S3     `yaml:"s3"`
+	MaxUploadSize int64  `yaml:"max_upload_size" env-default:"20971520"`
+	// MaxPixels bounds width*height of uploads to reject decompression bombs.
+	MaxPixels int           `yaml:"max_pixels" env-default:"50000000"`
+	URLPrefix string        `yaml:"url_prefix" env-default:"/api/keeper/images"`
+	URLTTL    time.Duration `yaml:"url_ttl" env-default:"1h"`
+	// URLSecret signs image URLs. It is kept apart from AppSecret so that a
+	// leaked image URL says nothing about the key that signs sessions.
+	URLSecret string `yaml:"url_secret" env-required:"true" env:"IMAGES_URL_SECRET"`
+	// UploadTimeout is how long an upload request has to be read and
+	// answered; HTTPServer.Timeout is too short for large files.
+	UploadTimeout time.Duration `yaml:"upload_timeout" env-default:"2m"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region" env-default:"us-east-1"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key" env:"S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"S3_SECRET_KEY"`
 }
 
 type ClientsConfig struct {
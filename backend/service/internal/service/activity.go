@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// maxActivityItems caps the items shown on a grouped items_added entry.
+const maxActivityItems = 5
+
+// recordActivity records an event for the activity feed after the write it
+// tells about went through. The write stands if recording fails; the feed
+// just misses the event.
+func (s *Service) recordActivity(ctx context.Context, a models.Activity) {
+	if err := s.write_storage.RecordActivity(ctx, a); err != nil {
+		s.log.Error("failed to record activity", slog.String("type", a.Type), slog.String("err", err.Error()))
+	}
+}
+
+// groupActivities merges runs of items added by the same collector to the
+// same collection, newest first, into one entry.
+func groupActivities(activities []models.Activity) []models.Activity {
+	var feed []models.Activity
+	for _, a := range activities {
+		if n := len(feed); n > 0 && a.Type == models.ActivityItemsAdded {
+			last := &feed[n-1]
+			if last.Type == a.Type && last.UserID == a.UserID && last.CollectionID == a.CollectionID {
+				last.Count += a.Count
+				if len(last.Items) < maxActivityItems {
+					last.Items = append(last.Items, a.Items...)
+				}
+				continue
+			}
+		}
+		feed = append(feed, a)
+	}
+	return feed
+}
+
+// Feed returns what the collectors the user follows did lately, and what
+// happened in the collections the user favorited, newest first. before is
+// the next_before of the previous page; the one of the next page is
+// returned, 0 on the last page.
+func (s *Service) Feed(ctx context.Context, userID, before int64, limit int) ([]models.Activity, int64, error) {
+	s.log.Debug("Get feed", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	if limit <= 0 || limit > maxPageLimit {
+		limit = defaultPageLimit
+	}
+
+	activities, err := s.read_storage.Feed(ctx, userID, before, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var next int64
+	if len(activities) == limit {
+		next = activities[len(activities)-1].ActivityID
+	}
+
+	return groupActivities(activities), next, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func added(id, userID, collectionID, itemID int64) models.Activity {
+	return models.Activity{
+		ActivityID:   id,
+		UserID:       userID,
+		Type:         models.ActivityItemsAdded,
+		CollectionID: collectionID,
+		Count:        1,
+		Items:        []models.ActivityItem{{ItemID: itemID}},
+	}
+}
+
+func TestGroupActivities(t *testing.T) {
+	published := models.Activity{ActivityID: 4, UserID: sellerID, Type: models.ActivityCollectionPublished, CollectionID: 10}
+
+	feed := groupActivities([]models.Activity{
+		added(9, sellerID, 10, 109),
+		added(8, sellerID, 10, 108),
+		added(7, sellerID, 10, 107),
+		added(6, buyerID, 10, 106),
+		added(5, sellerID, 11, 105),
+		published,
+	})
+
+	assert.Equal(t, []models.Activity{
+		{
+			ActivityID:   9,
+			UserID:       sellerID,
+			Type:         models.ActivityItemsAdded,
+			CollectionID: 10,
+			Count:        3,
+			Items:        []models.ActivityItem{{ItemID: 109}, {ItemID: 108}, {ItemID: 107}},
+		},
+		added(6, buyerID, 10, 106),
+		added(5, sellerID, 11, 105),
+		published,
+	}, feed)
+}
+
+func TestGroupActivities_CapsItems(t *testing.T) {
+	var activities []models.Activity
+	for i := int64(0); i < maxActivityItems+3; i++ {
+		activities = append(activities, added(100-i, sellerID, 10, 200-i))
+	}
+
+	feed := groupActivities(activities)
+	assert.Len(t, feed, 1)
+	assert.Equal(t, maxActivityItems+3, feed[0].Count)
+	assert.Len(t, feed[0].Items, maxActivityItems)
+}
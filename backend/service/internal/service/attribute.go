@@ -0,0 +1,221 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	maxAttributeFields = 50
+	maxAttributeLength = 500
+)
+
+var attributeKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// ValidationErrors maps the fields of a request to what is wrong with them,
+// so clients can show the message next to the right input.
+type ValidationErrors map[string]string
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field, msg := range e {
+		fields = append(fields, field+": "+msg)
+	}
+	sort.Strings(fields)
+	return "validation failed: " + strings.Join(fields, "; ")
+}
+
+// CategorySchema returns the attribute fields items of a category carry,
+// including the ones inherited from its ancestors.
+func (s *Service) CategorySchema(ctx context.Context, categoryID int64) ([]models.AttributeField, error) {
+	s.log.Debug("Get category schema", slog.String("category_id", strconv.Itoa(int(categoryID))))
+
+	return s.read_storage.CategorySchema(ctx, categoryID)
+}
+
+// validateSchema checks the attribute schema of a category. Errors are keyed
+// by the position of the field, e.g. "attribute_schema[2].enum".
+func validateSchema(schema []models.AttributeField) error {
+	errs := ValidationErrors{}
+	if len(schema) > maxAttributeFields {
+		errs["attribute_schema"] = fmt.Sprintf("must have at most %d fields", maxAttributeFields)
+		return errs
+	}
+
+	seen := make(map[string]bool, len(schema))
+	for i, field := range schema {
+		name := fmt.Sprintf("attribute_schema[%d]", i)
+
+		switch {
+		case !attributeKeyRe.MatchString(field.Key):
+			errs[name+".key"] = "must be lowercase letters, digits and underscores"
+		case seen[field.Key]:
+			errs[name+".key"] = "is declared twice"
+		}
+		seen[field.Key] = true
+
+		switch field.Type {
+		case models.AttributeString, models.AttributeBoolean:
+		case models.AttributeNumber, models.AttributeInteger:
+			if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+				errs[name+".min"] = "must not be greater than max"
+			}
+		case models.AttributeEnum:
+			if len(field.Enum) == 0 {
+				errs[name+".enum"] = "must list the allowed values"
+			}
+		default:
+			errs[name+".type"] = "must be one of string, number, integer, boolean, enum"
+		}
+
+		if field.Type != models.AttributeEnum && len(field.Enum) > 0 {
+			errs[name+".enum"] = "is only allowed for enum fields"
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateAttributes checks item attributes against the schema of the item's
+// category and returns them normalized: strings trimmed, empty values
+// dropped. Items without a category, or of a category without a schema, may
+// carry any scalar attributes.
+func (s *Service) validateAttributes(ctx context.Context, categoryID int64, attributes models.Attributes) (models.Attributes, error) {
+	var schema []models.AttributeField
+	if categoryID != 0 {
+		var err error
+		if schema, err = s.read_storage.CategorySchema(ctx, categoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	return checkAttributes(schema, attributes)
+}
+
+func checkAttributes(schema []models.AttributeField, attributes models.Attributes) (models.Attributes, error) {
+	errs := ValidationErrors{}
+	out := make(models.Attributes, len(attributes))
+
+	for key, value := range attributes {
+		if s, ok := value.(string); ok {
+			value = strings.TrimSpace(s)
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		out[key] = value
+	}
+
+	if len(schema) == 0 {
+		if len(out) > maxAttributeFields {
+			errs["attributes"] = fmt.Sprintf("must have at most %d attributes", maxAttributeFields)
+		}
+		for key, value := range out {
+			if !attributeKeyRe.MatchString(key) {
+				errs["attributes."+key] = "name must be lowercase letters, digits and underscores"
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				if utf8.RuneCountInString(v) > maxAttributeLength {
+					errs["attributes."+key] = fmt.Sprintf("must be at most %d characters", maxAttributeLength)
+				}
+			case float64, bool:
+			default:
+				errs["attributes."+key] = "must be a string, number or boolean"
+			}
+		}
+		if len(errs) > 0 {
+			return nil, errs
+		}
+		return out, nil
+	}
+
+	fields := make(map[string]models.AttributeField, len(schema))
+	for _, field := range schema {
+		fields[field.Key] = field
+
+		value, ok := out[field.Key]
+		if !ok {
+			if field.Required {
+				errs["attributes."+field.Key] = "is required"
+			}
+			continue
+		}
+		if msg := checkValue(field, value); msg != "" {
+			errs["attributes."+field.Key] = msg
+		}
+	}
+	for key := range out {
+		if _, ok := fields[key]; !ok {
+			errs["attributes."+key] = "is not defined for this category"
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+// checkValue returns what is wrong with the value of a field, or "".
+func checkValue(field models.AttributeField, value any) string {
+	switch field.Type {
+	case models.AttributeString:
+		v, ok := value.(string)
+		if !ok {
+			return "must be a string"
+		}
+		if utf8.RuneCountInString(v) > maxAttributeLength {
+			return fmt.Sprintf("must be at most %d characters", maxAttributeLength)
+		}
+	case models.AttributeBoolean:
+		if _, ok := value.(bool); !ok {
+			return "must be true or false"
+		}
+	case models.AttributeEnum:
+		v, ok := value.(string)
+		if !ok || !slices.Contains(field.Enum, v) {
+			return "must be one of " + strings.Join(field.Enum, ", ")
+		}
+	case models.AttributeNumber, models.AttributeInteger:
+		v, ok := value.(float64)
+		if !ok {
+			return "must be a number"
+		}
+		if field.Type == models.AttributeInteger && v != math.Trunc(v) {
+			return "must be a whole number"
+		}
+		if field.Min != nil && v < *field.Min {
+			return fmt.Sprintf("must be at least %s%s", formatNumber(*field.Min), unitSuffix(field))
+		}
+		if field.Max != nil && v > *field.Max {
+			return fmt.Sprintf("must be at most %s%s", formatNumber(*field.Max), unitSuffix(field))
+		}
+	}
+	return ""
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func unitSuffix(field models.AttributeField) string {
+	if field.Unit == "" {
+		return ""
+	}
+	return " " + field.Unit
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+var coinSchema = []models.AttributeField{
+	{Key: "denomination", Type: models.AttributeString, Required: true},
+	{Key: "metal", Type: models.AttributeEnum, Enum: []string{"gold", "silver", "copper"}},
+	{Key: "weight_g", Type: models.AttributeNumber, Unit: "g", Min: ptr(0)},
+	{Key: "mintage", Type: models.AttributeInteger},
+	{Key: "proof", Type: models.AttributeBoolean},
+}
+
+func TestCheckAttributes_Valid(t *testing.T) {
+	attributes, err := checkAttributes(coinSchema, models.Attributes{
+		"denomination": " 1 rouble ",
+		"metal":        "silver",
+		"weight_g":     19.99,
+		"mintage":      float64(1000),
+		"proof":        false,
+		"mint_mark":    nil,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.Attributes{
+		"denomination": "1 rouble",
+		"metal":        "silver",
+		"weight_g":     19.99,
+		"mintage":      float64(1000),
+		"proof":        false,
+	}, attributes)
+}
+
+func TestCheckAttributes_FieldErrors(t *testing.T) {
+	_, err := checkAttributes(coinSchema, models.Attributes{
+		"metal":     "platinum",
+		"weight_g":  -1.0,
+		"mintage":   2.5,
+		"proof":     "yes",
+		"mint_mark": "СПБ",
+	})
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		"attributes.denomination": "is required",
+		"attributes.metal":        "must be one of gold, silver, copper",
+		"attributes.weight_g":     "must be at least 0 g",
+		"attributes.mintage":      "must be a whole number",
+		"attributes.proof":        "must be true or false",
+		"attributes.mint_mark":    "is not defined for this category",
+	}, errs)
+}
+
+func TestCheckAttributes_NoSchema(t *testing.T) {
+	attributes, err := checkAttributes(nil, models.Attributes{"notes": "bought in 1998", "graded": true})
+	require.NoError(t, err)
+	assert.Len(t, attributes, 2)
+
+	_, err = checkAttributes(nil, models.Attributes{"Bad Key": "x", "nested": map[string]any{"a": 1.0}})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "attributes.Bad Key")
+	assert.Contains(t, errs, "attributes.nested")
+}
+
+func TestValidateSchema(t *testing.T) {
+	require.NoError(t, validateSchema(coinSchema))
+
+	err := validateSchema([]models.AttributeField{
+		{Key: "metal", Type: models.AttributeEnum},
+		{Key: "metal", Type: models.AttributeString},
+		{Key: "Weight", Type: "float"},
+		{Key: "size", Type: models.AttributeNumber, Min: ptr(10), Max: ptr(1)},
+	})
+
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, ValidationErrors{
+		"attribute_schema[0].enum": "must list the allowed values",
+		"attribute_schema[1].key":  "is declared twice",
+		"attribute_schema[2].key":  "must be lowercase letters, digits and underscores",
+		"attribute_schema[2].type": "must be one of string, number, integer, boolean, enum",
+		"attribute_schema[3].min":  "must not be greater than max",
+	}, errs)
+}
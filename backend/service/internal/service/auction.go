@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	maxAuctionDuration  = 30 * 24 * time.Hour
+	maxAuctionExtension = 3600
+)
+
+// normalizeAuction checks the terms of an auction with opening bid price and
+// records the problems in errs. An auction without a start starts now.
+func normalizeAuction(a *models.Auction, price int64, now time.Time, errs ValidationErrors) {
+	if a.StartsAt.IsZero() {
+		a.StartsAt = now
+	}
+
+	switch {
+	case a.StartsAt.After(now.Add(maxAuctionDuration)):
+		errs["starts_at"] = "must be within 30 days"
+	case !a.EndsAt.After(a.StartsAt) || !a.EndsAt.After(now):
+		errs["ends_at"] = "must be in the future and after the start"
+	case a.EndsAt.Sub(a.StartsAt) > maxAuctionDuration:
+		errs["ends_at"] = "must be at most 30 days after the start"
+	}
+
+	if a.MinIncrement <= 0 {
+		errs["min_increment"] = "must be positive, in minor units of the currency"
+	}
+	if a.ReservePrice < 0 || (a.ReservePrice > 0 && a.ReservePrice < price) {
+		errs["reserve_price"] = "must not be below the opening price"
+	}
+	if a.Extension < 0 || a.Extension > maxAuctionExtension {
+		errs["extension"] = "must be between 0 and 3600 seconds"
+	}
+
+	// Bidding state is kept by storage.
+	a.CurrentBid, a.BidderID, a.BidCount, a.WinnerID, a.ClosedAt = 0, 0, 0, 0, nil
+}
+
+// hideReserve keeps the reserve price of an auction lot from bidders.
+func hideReserve(lot *models.Lot) {
+	if lot.Auction != nil {
+		lot.Auction.ReservePrice = 0
+	}
+}
+
+// PlaceBid bids amount on an auction lot of the catalogue and returns the
+// auction after the bid.
+func (s *Service) PlaceBid(ctx context.Context, userID, lotID, amount int64) (models.Auction, error) {
+	s.log.Debug("Place bid", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	lot, err := s.read_storage.PublicLot(ctx, lotID)
+	if err != nil {
+		return models.Auction{}, err
+	}
+	switch {
+	case lot.Auction == nil:
+		return models.Auction{}, storage.ErrNotAuction
+	case lot.UserID == userID:
+		return models.Auction{}, ErrOwnLot
+	case amount <= 0:
+		return models.Auction{}, ValidationErrors{"amount": "must be positive, in minor units of the currency"}
+	}
+
+	auction, err := s.write_storage.PlaceBid(ctx, lotID, userID, amount)
+	if err != nil {
+		return models.Auction{}, err
+	}
+	auction.ReservePrice = 0
+
+	return auction, nil
+}
+
+// Bids returns the bid history of an auction lot of the catalogue, highest
+// bid first.
+func (s *Service) Bids(ctx context.Context, lotID int64) ([]models.Bid, error) {
+	s.log.Debug("Get bids", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	lot, err := s.read_storage.PublicLot(ctx, lotID)
+	if err != nil {
+		return nil, err
+	}
+	if lot.Auction == nil {
+		return nil, storage.ErrNotAuction
+	}
+
+	return s.read_storage.Bids(ctx, lotID)
+}
+
+// CloseAuctions finalizes the auctions that have ended, batch by batch, and
+// returns how many it closed.
+func (s *Service) CloseAuctions(ctx context.Context, batch int) (int, error) {
+	var total int
+	for {
+		closed, err := s.write_storage.CloseAuctions(ctx, batch)
+		if err != nil {
+			return total, err
+		}
+		total += len(closed)
+		for _, lotID := range closed {
+			s.log.Info("auction closed", slog.Int64("lot_id", lotID))
+		}
+		// Once a run closes nothing, every due auction that is not being
+		// bid on right now is closed.
+		if len(closed) == 0 {
+			return total, nil
+		}
+	}
+}
+
+// RunAuctionCloser closes ended auctions every interval until ctx is done.
+func (s *Service) RunAuctionCloser(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.CloseAuctions(ctx, batch); err != nil {
+				s.log.Error("failed to close auctions", slog.String("err", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeAuction(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	a := &models.Auction{EndsAt: now.Add(24 * time.Hour), MinIncrement: 100, BidCount: 3}
+	errs := ValidationErrors{}
+	normalizeAuction(a, 1000, now, errs)
+	assert.Empty(t, errs)
+	assert.Equal(t, now, a.StartsAt)
+	assert.Zero(t, a.BidCount)
+
+	a = &models.Auction{StartsAt: now, EndsAt: now.Add(31 * 24 * time.Hour), ReservePrice: 500, Extension: 7200}
+	errs = ValidationErrors{}
+	normalizeAuction(a, 1000, now, errs)
+	assert.Contains(t, errs, "ends_at")
+	assert.Contains(t, errs, "min_increment")
+	assert.Contains(t, errs, "reserve_price")
+	assert.Contains(t, errs, "extension")
+}
+
+func TestPlaceBid_OwnLot(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.PlaceBid(context.Background(), sellerID, 5, 1000)
+	assert.ErrorIs(t, err, storage.ErrNotAuction)
+
+	s.read.lot.Auction = &models.Auction{MinIncrement: 100}
+	_, err = s.PlaceBid(context.Background(), sellerID, 5, 1000)
+	assert.ErrorIs(t, err, ErrOwnLot)
+
+	_, err = s.MakeOffer(context.Background(), buyerID, 5, models.Offer{Amount: 1000})
+	assert.ErrorIs(t, err, ErrAuctionLot)
+}
+
+func TestCloseAuctions_GoesOnAfterShortBatch(t *testing.T) {
+	// The first batch came back short because a lot was locked by a bid.
+	s := newTestService(t)
+	s.write.closeBatches = [][]int64{{1}, {2, 3}}
+
+	closed, err := s.CloseAuctions(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, closed)
+	assert.Equal(t, 2, s.write.closeCalls)
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	// maxBulkItems caps how many items one bulk operation may touch.
+	maxBulkItems = 500
+	// maxCountry bounds the country of an item.
+	maxCountry = 100
+)
+
+// bulkActions lists the actions UpdateItems supports.
+var bulkActions = []string{models.BulkDelete, models.BulkTag, models.BulkCategory, models.BulkCountry}
+
+// itemSelection checks that sel names items, either by id or by a
+// collection and a filter, and drops repeated ids.
+func itemSelection(sel models.ItemSelection, errs ValidationErrors) models.ItemSelection {
+	sel.Limit = maxBulkItems
+	if len(sel.ItemIDs) == 0 {
+		if sel.CollectionID <= 0 {
+			errs["item_ids"] = "item_ids or collection_id is required"
+			return sel
+		}
+		filter, err := listOptions(sel.Filter, itemSorts)
+		if err != nil {
+			errs["filter"] = err.Error()
+		}
+		sel.Filter = filter
+		return sel
+	}
+
+	seen := make(map[int64]bool, len(sel.ItemIDs))
+	itemIDs := make([]int64, 0, len(sel.ItemIDs))
+	for _, id := range sel.ItemIDs {
+		if id <= 0 {
+			errs["item_ids"] = "must be positive ids"
+			return sel
+		}
+		if !seen[id] {
+			seen[id] = true
+			itemIDs = append(itemIDs, id)
+		}
+	}
+	if len(itemIDs) > maxBulkItems {
+		errs["item_ids"] = fmt.Sprintf("must have at most %d items", maxBulkItems)
+	}
+	sel.ItemIDs = itemIDs
+	return sel
+}
+
+// MoveItems moves the selected items of the user into another collection of
+// theirs and reports what happened to each item.
+func (s *Service) MoveItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	s.log.Debug("Move items", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	errs := ValidationErrors{}
+	sel = itemSelection(sel, errs)
+	if collectionID <= 0 {
+		errs["collection_id"] = "is required"
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	results, err := s.write_storage.MoveItems(ctx, userID, sel, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range results {
+		if res.OK {
+			s.itemsAdded(ctx, userID, collectionID, res.ItemID)
+		}
+	}
+
+	return results, nil
+}
+
+// CopyItems copies the selected items of the user, with their tags and
+// images, into a collection of theirs and reports the copy made of each
+// item.
+func (s *Service) CopyItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	s.log.Debug("Copy items", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	errs := ValidationErrors{}
+	sel = itemSelection(sel, errs)
+	if collectionID <= 0 {
+		errs["collection_id"] = "is required"
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	results, err := s.write_storage.CopyItems(ctx, userID, sel, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range results {
+		if res.OK {
+			missing, err := s.copyItemImages(ctx, userID, res.ItemID, res.NewItemID)
+			switch {
+			case err != nil:
+				results[i].Warning = "images could not be copied"
+			case missing > 0:
+				results[i].Warning = fmt.Sprintf("%d of the images could not be copied", missing)
+			}
+			s.itemsAdded(ctx, userID, collectionID, res.NewItemID)
+		}
+	}
+
+	return results, nil
+}
+
+// UpdateItems applies one change to the selected items the user may edit
+// and reports what happened to each item.
+func (s *Service) UpdateItems(ctx context.Context, userID int64, sel models.ItemSelection, change models.ItemChange) ([]models.ItemResult, error) {
+	s.log.Debug("Update items", slog.String("action", change.Action))
+
+	errs := ValidationErrors{}
+	sel = itemSelection(sel, errs)
+
+	switch change.Action {
+	case models.BulkDelete:
+	case models.BulkTag:
+		if len(change.AddTags) == 0 && len(change.RemoveTags) == 0 {
+			errs["add_tags"] = "add_tags or remove_tags is required"
+		}
+		var err error
+		if len(change.AddTags) > 0 {
+			if change.AddTags, err = normalizeTags(change.AddTags); err != nil {
+				errs["add_tags"] = err.Error()
+			}
+		}
+		if len(change.RemoveTags) > 0 {
+			if change.RemoveTags, err = normalizeTags(change.RemoveTags); err != nil {
+				errs["remove_tags"] = err.Error()
+			}
+		}
+	case models.BulkCategory:
+		if change.CategoryID < 0 {
+			errs["category_id"] = "must not be negative"
+		}
+	case models.BulkCountry:
+		change.Country = strings.TrimSpace(change.Country)
+		switch n := utf8.RuneCountInString(change.Country); {
+		case n == 0:
+			errs["country"] = "is required"
+		case n > maxCountry:
+			errs["country"] = fmt.Sprintf("must be at most %d characters", maxCountry)
+		}
+	default:
+		errs["action"] = "must be one of " + strings.Join(bulkActions, ", ")
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var check func(models.Item) (models.Attributes, error)
+	if change.Action == models.BulkCategory {
+		if err := s.validateCategory(ctx, change.CategoryID); err != nil {
+			return nil, err
+		}
+		var schema []models.AttributeField
+		if change.CategoryID != 0 {
+			var err error
+			if schema, err = s.read_storage.CategorySchema(ctx, change.CategoryID); err != nil {
+				return nil, err
+			}
+		}
+		check = func(item models.Item) (models.Attributes, error) {
+			return checkAttributes(schema, item.Attributes)
+		}
+	}
+
+	results, err := s.write_storage.UpdateItems(ctx, userID, sel, change, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.Contains([]string{models.BulkTag, models.BulkCategory, models.BulkCountry}, change.Action) {
+		for _, res := range results {
+			if res.OK {
+				s.matchWants(ctx, "item_id", res.ItemID, s.write_storage.MatchItem)
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// itemsAdded runs what follows an item landing in a collection: matching
+// it against wants and telling followers about it.
+func (s *Service) itemsAdded(ctx context.Context, userID, collectionID, itemID int64) {
+	s.matchWants(ctx, "item_id", itemID, s.write_storage.MatchItem)
+	s.recordActivity(ctx, models.Activity{
+		UserID:       userID,
+		Type:         models.ActivityItemsAdded,
+		CollectionID: collectionID,
+		Items:        []models.ActivityItem{{ItemID: itemID}},
+	})
+}
+
+// copyItemImages gives the copy of an item its own copy of every image file
+// of the original, so deleting one never breaks the other. It returns how
+// many images could not be copied; the copy is left without them.
+func (s *Service) copyItemImages(ctx context.Context, userID, itemID, copyID int64) (int, error) {
+	images, err := s.read_storage.ItemImages(ctx, userID, itemID)
+	if err != nil {
+		s.log.Error("failed to get item images", slog.String("item_id", strconv.Itoa(int(itemID))), slog.String("err", err.Error()))
+		return 0, err
+	}
+
+	missing := 0
+	for _, image := range images {
+		copied, err := s.copyImage(ctx, fmt.Sprintf("items/%d", copyID), image)
+		if err != nil {
+			s.log.Error("failed to copy image", slog.String("key", image.Key), slog.String("err", err.Error()))
+			missing++
+			continue
+		}
+		if _, err := s.write_storage.AddItemImage(ctx, userID, copyID, copied); err != nil {
+			s.log.Error("failed to add image", slog.String("item_id", strconv.Itoa(int(copyID))), slog.String("err", err.Error()))
+			s.deleteBlobs(ctx, copied.Keys())
+			missing++
+		}
+	}
+	return missing, nil
+}
+
+// copyImage copies the files of every variant of image under a new random
+// name under prefix. Nothing is left behind if one of them fails.
+func (s *Service) copyImage(ctx context.Context, prefix string, image models.Image) (models.Image, error) {
+	if isExternalURL(image.Key) {
+		return image, nil
+	}
+
+	key, err := blobKey(prefix)
+	if err != nil {
+		return models.Image{}, err
+	}
+
+	copied := image
+	copied.ImageID, copied.ItemID = 0, 0
+	copied.Key = key
+	if image.MediumKey != "" {
+		copied.MediumKey = key + strings.TrimPrefix(image.MediumKey, image.Key)
+	}
+	if image.ThumbKey != "" {
+		copied.ThumbKey = key + strings.TrimPrefix(image.ThumbKey, image.Key)
+	}
+
+	from, to := image.Keys(), copied.Keys()
+	for i := range from {
+		if err := s.copyBlob(ctx, from[i], to[i]); err != nil {
+			s.deleteBlobs(ctx, to[:i])
+			return models.Image{}, err
+		}
+	}
+
+	return copied, nil
+}
+
+func (s *Service) copyBlob(ctx context.Context, from, to string) error {
+	r, info, err := s.blobs.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return s.blobs.Put(ctx, to, r, info.Size, info.ContentType)
+}
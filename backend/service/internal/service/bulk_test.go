@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateItems_Validates(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.UpdateItems(context.Background(), sellerID, models.ItemSelection{}, models.ItemChange{Action: "rename"})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "item_ids")
+	assert.Contains(t, errs, "action")
+
+	ids := make([]int64, maxBulkItems+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+	_, err = s.UpdateItems(context.Background(), sellerID, models.ItemSelection{ItemIDs: ids}, models.ItemChange{Action: models.BulkCountry})
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "item_ids")
+	assert.Contains(t, errs, "country")
+	assert.Empty(t, s.write.change.Action)
+}
+
+func TestUpdateItems_Tags(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.UpdateItems(context.Background(), sellerID, models.ItemSelection{ItemIDs: []int64{3, 4, 3}}, models.ItemChange{
+		Action:     models.BulkTag,
+		AddTags:    []string{" Russian  Empire", "russian empire"},
+		RemoveTags: []string{"USSR"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4}, s.write.sel.ItemIDs)
+	assert.Equal(t, maxBulkItems, s.write.sel.Limit)
+	assert.Equal(t, []string{"russian empire"}, s.write.change.AddTags)
+	assert.Equal(t, []string{"ussr"}, s.write.change.RemoveTags)
+}
+
+func TestUpdateItems_CategoryChecksAttributes(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.UpdateItems(context.Background(), sellerID, models.ItemSelection{CollectionID: 3}, models.ItemChange{
+		Action:     models.BulkCategory,
+		CategoryID: 7,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), s.write.sel.CollectionID)
+	require.Len(t, s.write.checked, 2)
+	assert.NoError(t, s.write.checked[0])
+	assert.Error(t, s.write.checked[1], "an item missing a required attribute of the new category fails")
+}
+
+func TestCopyItems_CopiesImages(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"items/3/abc", "items/3/abc-thumb"} {
+		require.NoError(t, s.blobs.Put(ctx, key, strings.NewReader(key), int64(len(key)), "image/jpeg"))
+	}
+	s.read.images = []models.Image{{ImageID: 1, ItemID: 3, Key: "items/3/abc", ThumbKey: "items/3/abc-thumb", IsPrimary: true}}
+	s.write.results = []models.ItemResult{{ItemID: 3, OK: true, NewItemID: 9}, {ItemID: 4, Error: "item already exists"}}
+
+	results, err := s.CopyItems(ctx, sellerID, models.ItemSelection{ItemIDs: []int64{3, 4}}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, s.write.results, results)
+
+	require.Len(t, s.write.added, 1)
+	copied := s.write.added[0]
+	assert.Equal(t, int64(9), copied.ItemID)
+	assert.True(t, copied.IsPrimary)
+	assert.True(t, strings.HasPrefix(copied.Key, "items/9/"))
+	assert.Equal(t, copied.Key+"-thumb", copied.ThumbKey)
+
+	r, _, err := s.blobs.Get(ctx, copied.ThumbKey)
+	require.NoError(t, err)
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "items/3/abc-thumb", string(data))
+}
+
+func TestCopyItems_WarnsAboutImagesNotCopied(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	require.NoError(t, s.blobs.Put(ctx, "items/3/abc", strings.NewReader("abc"), 3, "image/jpeg"))
+	// The file of the second image is gone from the blob store.
+	s.read.images = []models.Image{{ImageID: 1, ItemID: 3, Key: "items/3/abc"}, {ImageID: 2, ItemID: 3, Key: "items/3/def"}}
+	s.write.results = []models.ItemResult{{ItemID: 3, OK: true, NewItemID: 9}}
+
+	results, err := s.CopyItems(ctx, sellerID, models.ItemSelection{ItemIDs: []int64{3}}, 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].OK)
+	assert.Equal(t, "1 of the images could not be copied", results[0].Warning)
+	assert.Len(t, s.write.added, 1)
+}
+
+func TestCopyItems_RequiresTarget(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.CopyItems(context.Background(), sellerID, models.ItemSelection{ItemIDs: []int64{3}}, 0)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "collection_id")
+}
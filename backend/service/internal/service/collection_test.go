@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCollection_CoverMustBeExternal(t *testing.T) {
+	s := newTestService(t)
+
+	// A blob key would be signed on every read of the collection.
+	_, err := s.SetCollection(context.Background(), sellerID, "Coins", "", "items/3/abc", 0, false)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "cover_image_url")
+	assert.Empty(t, s.write.cover)
+
+	_, err = s.SetCollection(context.Background(), sellerID, "Coins", "", "https://example.com/coins.jpg", 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/coins.jpg", s.write.cover)
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/markdown"
+)
+
+const maxCommentLength = 5000
+
+// commentLimits bound how fast one user may post comments: a burst of a
+// few, then a steady rate.
+var commentLimits = []models.RateLimit{
+	{Count: 5, Window: time.Minute},
+	{Count: 60, Window: time.Hour},
+}
+
+// commentBody trims a comment and renders it to HTML.
+func commentBody(body string) (string, string, error) {
+	body = strings.TrimSpace(body)
+	switch n := utf8.RuneCountInString(body); {
+	case n == 0:
+		return "", "", ValidationErrors{"body": "is required"}
+	case n > maxCommentLength:
+		return "", "", ValidationErrors{"body": "must be at most 5000 characters"}
+	}
+	return body, markdown.ToHTML(body), nil
+}
+
+// threadComments arranges comments, oldest first, into threads. Deleted
+// comments lose author and text; those without replies are left out.
+func threadComments(comments []models.Comment) []models.Comment {
+	children := make(map[int64][]models.Comment)
+	for _, c := range comments {
+		if c.Deleted {
+			c.Author, c.Body, c.HTML, c.Edited = "", "", "", false
+		}
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+
+	var thread func(parentID int64) []models.Comment
+	thread = func(parentID int64) []models.Comment {
+		var out []models.Comment
+		for _, c := range children[parentID] {
+			c.Replies = thread(c.CommentID)
+			if c.Deleted && len(c.Replies) == 0 {
+				continue
+			}
+			out = append(out, c)
+		}
+		return out
+	}
+
+	return thread(0)
+}
+
+// PostComment comments on a public item or collection, or replies to a
+// comment on it.
+func (s *Service) PostComment(ctx context.Context, userID int64, c models.Comment) (int64, error) {
+	s.log.Debug("Post comment", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	body, html, err := commentBody(c.Body)
+	if err != nil {
+		return 0, err
+	}
+	c.AuthorID, c.Body, c.HTML = userID, body, html
+
+	return s.write_storage.CreateComment(ctx, c, commentLimits)
+}
+
+// EditComment changes the text of a comment of the user.
+func (s *Service) EditComment(ctx context.Context, userID, commentID int64, body string) error {
+	s.log.Debug("Edit comment", slog.String("comment_id", strconv.Itoa(int(commentID))))
+
+	body, html, err := commentBody(body)
+	if err != nil {
+		return err
+	}
+
+	return s.write_storage.UpdateComment(ctx, userID, commentID, body, html)
+}
+
+// DeleteComment deletes a comment of the user, or any comment on one of the
+// user's collections and their items.
+func (s *Service) DeleteComment(ctx context.Context, userID, commentID int64) error {
+	s.log.Debug("Delete comment", slog.String("comment_id", strconv.Itoa(int(commentID))))
+
+	return s.write_storage.DeleteComment(ctx, userID, commentID)
+}
+
+// ItemComments returns the threads on a public item of the user.
+func (s *Service) ItemComments(ctx context.Context, username string, itemID int64) ([]models.Comment, error) {
+	s.log.Debug("Get item comments", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	if _, err := s.read_storage.PublicItem(ctx, username, itemID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.read_storage.Comments(ctx, itemID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return threadComments(comments), nil
+}
+
+// CollectionComments returns the threads on a public collection of the user.
+func (s *Service) CollectionComments(ctx context.Context, username string, collectionID int64) ([]models.Comment, error) {
+	s.log.Debug("Get collection comments", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	if _, err := s.read_storage.PublicCollection(ctx, username, collectionID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.read_storage.Comments(ctx, 0, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return threadComments(comments), nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostComment(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.PostComment(context.Background(), buyerID, models.Comment{ItemID: 10, Body: "  nice **coin**\n"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(buyerID), s.write.comment.AuthorID)
+	assert.Equal(t, "nice **coin**", s.write.comment.Body)
+	assert.Equal(t, "<p>nice <strong>coin</strong></p>", s.write.comment.HTML)
+	assert.Equal(t, commentLimits, s.write.limits)
+}
+
+func TestPostComment_Invalid(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.PostComment(context.Background(), buyerID, models.Comment{ItemID: 10, Body: " \n"})
+	assert.Equal(t, ValidationErrors{"body": "is required"}, err)
+
+	_, err = s.PostComment(context.Background(), buyerID, models.Comment{ItemID: 10, Body: strings.Repeat("a", maxCommentLength+1)})
+	assert.Equal(t, ValidationErrors{"body": "must be at most 5000 characters"}, err)
+}
+
+func TestItemComments_Threads(t *testing.T) {
+	s := newTestService(t)
+	s.read.comments = []models.Comment{
+		{CommentID: 1, Author: "a", Body: "first"},
+		{CommentID: 2, Author: "b", Body: "gone", Deleted: true},
+		{CommentID: 3, ParentID: 2, Author: "c", Body: "reply"},
+		{CommentID: 4, ParentID: 1, Author: "b", Body: "gone too", Deleted: true},
+		{CommentID: 5, ParentID: 3, Author: "a", Body: "nested"},
+	}
+
+	threads, err := s.ItemComments(context.Background(), "owner", 10)
+	require.NoError(t, err)
+
+	require.Len(t, threads, 2)
+	assert.Equal(t, int64(1), threads[0].CommentID)
+	assert.Empty(t, threads[0].Replies, "deleted comments without replies are left out")
+
+	placeholder := threads[1]
+	assert.True(t, placeholder.Deleted)
+	assert.Empty(t, placeholder.Author)
+	assert.Empty(t, placeholder.Body)
+	require.Len(t, placeholder.Replies, 1)
+	assert.Equal(t, "reply", placeholder.Replies[0].Body)
+	require.Len(t, placeholder.Replies[0].Replies, 1)
+	assert.Equal(t, int64(5), placeholder.Replies[0].Replies[0].CommentID)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// maxFacetValues is how many of the most frequent values each facet returns.
+const maxFacetValues = 50
+
+// Facets counts the items of a collection or of a search by country,
+// category, decade, tag and attribute. Either a query or a collection is
+// required, the other filters narrow the counts down like in Search.
+func (s *Service) Facets(ctx context.Context, userID int64, filter models.SearchFilter) (models.Facets, error) {
+	s.log.Debug("Facets", slog.String("user_id", strconv.Itoa(int(userID))), slog.String("collection_id", strconv.Itoa(int(filter.CollectionID))))
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.CollectionID < 0 || utf8.RuneCountInString(filter.Query) > maxQueryLength {
+		return models.Facets{}, ErrInvalidQuery
+	}
+	if filter.Query == "" && filter.CollectionID == 0 {
+		return models.Facets{}, ErrInvalidQuery
+	}
+
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return models.Facets{}, err
+	}
+
+	return s.read_storage.Facets(ctx, userID, filter, maxFacetValues)
+}
@@ -0,0 +1,362 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	ssogrpc "github.com/mmmakskl/HeritageKeeper/service/internal/clients/sso/grpc"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob/local"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sellerID = 1
+	buyerID  = 2
+
+	testRetention = 30 * 24 * time.Hour
+)
+
+var deletedAt = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeReadStorage embeds ReadStorage so it only implements what the tests
+// use. It serves a fixed fixture and records what it was asked for.
+type fakeReadStorage struct {
+	ReadStorage
+	// lot is the only public lot, offers are made on it.
+	lot    models.Lot
+	offers map[int64]models.Offer
+	trade  models.Trade
+	// links are keyed by token hash.
+	links    map[string]models.ShareLink
+	schema   []models.AttributeField
+	images   []models.Image
+	comments []models.Comment
+	prefs    []models.NotificationPreference
+	// next is the cursor Items hands out.
+	next   *models.Cursor
+	opts   models.ListOptions
+	filter models.SearchFilter
+}
+
+func (f *fakeReadStorage) Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, *models.Cursor, error) {
+	f.opts = opts
+	return nil, f.next, nil
+}
+
+func (f *fakeReadStorage) Item(ctx context.Context, userID, itemID int64) (models.Item, error) {
+	return models.Item{ItemID: itemID, Title: "Penny Black"}, nil
+}
+
+func (f *fakeReadStorage) ItemImages(ctx context.Context, userID, itemID int64) ([]models.Image, error) {
+	return f.images, nil
+}
+
+func (f *fakeReadStorage) Category(ctx context.Context, categoryID int64) (models.Category, error) {
+	return models.Category{CategoryID: categoryID}, nil
+}
+
+func (f *fakeReadStorage) CategorySchema(ctx context.Context, categoryID int64) ([]models.AttributeField, error) {
+	return f.schema, nil
+}
+
+func (f *fakeReadStorage) Search(ctx context.Context, userID int64, filter models.SearchFilter) (models.SearchResult, error) {
+	f.filter = filter
+	return models.SearchResult{}, nil
+}
+
+func (f *fakeReadStorage) Facets(ctx context.Context, userID int64, filter models.SearchFilter, limit int) (models.Facets, error) {
+	f.filter = filter
+	return models.Facets{}, nil
+}
+
+func (f *fakeReadStorage) PublicItem(ctx context.Context, username string, itemID int64) (models.Item, error) {
+	return models.Item{}, nil
+}
+
+func (f *fakeReadStorage) Comments(ctx context.Context, itemID, collectionID int64) ([]models.Comment, error) {
+	return f.comments, nil
+}
+
+func (f *fakeReadStorage) NotificationPreferences(ctx context.Context, userID int64) ([]models.NotificationPreference, error) {
+	return f.prefs, nil
+}
+
+func (f *fakeReadStorage) PublicLot(ctx context.Context, lotID int64) (models.Lot, error) {
+	if lotID != f.lot.LotID {
+		return models.Lot{}, storage.ErrLotNotFound
+	}
+	return f.lot, nil
+}
+
+func (f *fakeReadStorage) Offer(ctx context.Context, offerID int64) (models.Offer, error) {
+	offer, ok := f.offers[offerID]
+	if !ok {
+		return models.Offer{}, storage.ErrOfferNotFound
+	}
+	return offer, nil
+}
+
+func (f *fakeReadStorage) Trade(ctx context.Context, tradeID int64) (models.Trade, error) {
+	if tradeID != f.trade.TradeID {
+		return models.Trade{}, storage.ErrTradeNotFound
+	}
+	return f.trade, nil
+}
+
+func (f *fakeReadStorage) ShareLink(ctx context.Context, tokenHash []byte) (models.ShareLink, error) {
+	link, ok := f.links[string(tokenHash)]
+	if !ok {
+		return models.ShareLink{}, storage.ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+func (f *fakeReadStorage) Trash(ctx context.Context, userID int64) (models.Trash, error) {
+	return models.Trash{
+		Collections: []models.TrashedCollection{{Collection: models.Collection{CollectionID: 2}, DeletedAt: deletedAt}},
+		Items:       []models.TrashedItem{{Item: models.Item{ItemID: 3}, DeletedAt: deletedAt.Add(time.Hour)}},
+	}, nil
+}
+
+// fakeWriteStorage embeds WriteStorage so it only implements what the tests
+// use. It records the writes and answers from what the tests set on it.
+type fakeWriteStorage struct {
+	WriteStorage
+	read *fakeReadStorage
+
+	cover      string
+	lot        models.Lot
+	from       []string
+	offer      models.Offer
+	trade      models.Trade
+	want       models.Want
+	comment    models.Comment
+	limits     []models.RateLimit
+	prefs      []models.NotificationPreference
+	invitee    string
+	role       string
+	activities []models.Activity
+
+	// accepted are the offer and the collection of the last accept.
+	acceptedOffer      int64
+	acceptedCollection int64
+
+	matchedLots  []int64
+	matchedItems []int64
+	matchErr     error
+	viewed       []int64
+
+	// sel and change are of the last bulk write, whose results are results.
+	sel     models.ItemSelection
+	change  models.ItemChange
+	results []models.ItemResult
+	// checked are the errors of the check of a bulk category change.
+	checked []error
+	added   []models.Image
+
+	// snapshot is the version RevertItem goes back to.
+	snapshot models.Item
+	reverted int
+
+	// closeBatches and purgeBatches are handed out one per call.
+	closeBatches [][]int64
+	closeCalls   int
+	purgeBatches [][]models.Image
+	purgeCalls   int
+	purgeBefore  time.Time
+	purgeLimit   int
+}
+
+func (f *fakeWriteStorage) SetCollection(ctx context.Context, userID int64, collectionName string, description string, image_url string, categoryID int64, isPublic bool) (int64, error) {
+	f.cover = image_url
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) SetItem(ctx context.Context, userID, collectionID int64, title, description string, categoryID int64, country string, images []string, year string, attributes models.Attributes) (int64, error) {
+	return 7, nil
+}
+
+func (f *fakeWriteStorage) CopyItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	f.sel = sel
+	return f.results, nil
+}
+
+func (f *fakeWriteStorage) UpdateItems(
+	ctx context.Context,
+	userID int64,
+	sel models.ItemSelection,
+	change models.ItemChange,
+	check func(models.Item) (models.Attributes, error),
+) ([]models.ItemResult, error) {
+	f.sel, f.change = sel, change
+	if check != nil {
+		for _, attributes := range []models.Attributes{{"denomination": "1 rouble"}, {"metal": "silver"}} {
+			_, err := check(models.Item{Attributes: attributes})
+			f.checked = append(f.checked, err)
+		}
+	}
+	return f.results, nil
+}
+
+func (f *fakeWriteStorage) AddItemImage(ctx context.Context, userID, itemID int64, image models.Image) (models.Image, error) {
+	image.ItemID = itemID
+	f.added = append(f.added, image)
+	return image, nil
+}
+
+func (f *fakeWriteStorage) RevertItem(ctx context.Context, userID, itemID int64, version int, check func(models.Item) (models.Attributes, error)) error {
+	if _, err := check(f.snapshot); err != nil {
+		return err
+	}
+	f.reverted = version
+	return nil
+}
+
+func (f *fakeWriteStorage) RestoreItem(ctx context.Context, userID, itemID int64) error {
+	return nil
+}
+
+func (f *fakeWriteStorage) DiscardItem(ctx context.Context, userID, itemID int64) ([]models.Image, error) {
+	return []models.Image{{ItemID: itemID, Key: "items/3/abc", ThumbKey: "items/3/abc-thumb"}}, nil
+}
+
+func (f *fakeWriteStorage) PurgeTrash(ctx context.Context, before time.Time, limit int) (int, []models.Image, error) {
+	f.purgeBefore, f.purgeLimit = before, limit
+	if f.purgeCalls == len(f.purgeBatches) {
+		return 0, nil, nil
+	}
+	images := f.purgeBatches[f.purgeCalls]
+	f.purgeCalls++
+	return len(images), images, nil
+}
+
+func (f *fakeWriteStorage) CreateLot(ctx context.Context, userID int64, lot models.Lot) (int64, error) {
+	f.lot = lot
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) SetLotStatus(ctx context.Context, userID, lotID int64, status string, from []string) error {
+	f.from = from
+	return nil
+}
+
+func (f *fakeWriteStorage) CloseAuctions(ctx context.Context, limit int) ([]int64, error) {
+	if f.closeCalls == len(f.closeBatches) {
+		return nil, nil
+	}
+	f.closeCalls++
+	return f.closeBatches[f.closeCalls-1], nil
+}
+
+func (f *fakeWriteStorage) CreateOffer(ctx context.Context, offer models.Offer) (int64, error) {
+	f.offer = offer
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) CounterOffer(ctx context.Context, parentID int64, offer models.Offer) (int64, error) {
+	f.offer = offer
+	return 2, nil
+}
+
+func (f *fakeWriteStorage) AcceptOffer(ctx context.Context, offerID int64) error {
+	f.acceptedOffer = offerID
+	return nil
+}
+
+func (f *fakeWriteStorage) CreateTrade(ctx context.Context, t models.Trade) (int64, error) {
+	f.trade = t
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) AcceptTrade(ctx context.Context, tradeID, collectionID int64) error {
+	f.acceptedCollection = collectionID
+	return nil
+}
+
+func (f *fakeWriteStorage) CreateWant(ctx context.Context, w models.Want) (int64, error) {
+	f.want = w
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) MatchLot(ctx context.Context, lotID int64) error {
+	f.matchedLots = append(f.matchedLots, lotID)
+	return f.matchErr
+}
+
+func (f *fakeWriteStorage) MatchItem(ctx context.Context, itemID int64) error {
+	f.matchedItems = append(f.matchedItems, itemID)
+	return f.matchErr
+}
+
+func (f *fakeWriteStorage) CreateComment(ctx context.Context, c models.Comment, limits []models.RateLimit) (int64, error) {
+	f.comment, f.limits = c, limits
+	return 1, nil
+}
+
+func (f *fakeWriteStorage) SetNotificationPreferences(ctx context.Context, userID int64, prefs []models.NotificationPreference) error {
+	f.prefs = prefs
+	return nil
+}
+
+func (f *fakeWriteStorage) RecordActivity(ctx context.Context, a models.Activity) error {
+	f.activities = append(f.activities, a)
+	return nil
+}
+
+func (f *fakeWriteStorage) ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error) {
+	f.invitee, f.role = invitee, role
+	return models.CollectionShare{ShareID: 1, CollectionID: collectionID, Username: invitee, Role: role}, nil
+}
+
+func (f *fakeWriteStorage) CreateShareLink(ctx context.Context, ownerID int64, link models.ShareLink) (models.ShareLink, error) {
+	link.LinkID = int64(len(f.read.links) + 1)
+	link.HasPassword = link.PasswordHash != ""
+	f.read.links[string(link.TokenHash)] = link
+	return link, nil
+}
+
+func (f *fakeWriteStorage) ViewShareLink(ctx context.Context, linkID int64) (models.PublicCollection, []models.Item, error) {
+	f.viewed = append(f.viewed, linkID)
+	return models.PublicCollection{CollectionID: 3}, []models.Item{{ItemID: 7}}, nil
+}
+
+// testService is a Service over the fake storage and a blob store in a
+// temporary directory.
+type testService struct {
+	*Service
+	read  *fakeReadStorage
+	write *fakeWriteStorage
+	blobs *local.Store
+}
+
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+
+	blobs, err := local.New(t.TempDir())
+	require.NoError(t, err)
+
+	read := &fakeReadStorage{
+		lot: models.Lot{LotID: 5, UserID: sellerID, Currency: "EUR", Status: models.LotActive},
+		offers: map[int64]models.Offer{
+			10: {OfferID: 10, LotID: 5, SellerID: sellerID, BuyerID: buyerID, AuthorID: buyerID, Currency: "EUR", Status: models.OfferPending},
+			11: {OfferID: 11, LotID: 5, SellerID: sellerID, BuyerID: buyerID, AuthorID: buyerID, Currency: "EUR", Status: models.OfferExpired},
+		},
+		trade:  models.Trade{TradeID: 3, ProposerID: buyerID, RecipientID: sellerID, Status: models.TradePending},
+		links:  map[string]models.ShareLink{},
+		schema: coinSchema,
+	}
+	write := &fakeWriteStorage{read: read}
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return &testService{
+		Service: New(log, read, write, ssogrpc.Client{}, blobs, nil, nil, 0, testRetention),
+		read:    read,
+		write:   write,
+		blobs:   blobs,
+	}
+}
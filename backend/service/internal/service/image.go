@@ -0,0 +1,378 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/imaging"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
+)
+
+// duplicateDistance is the largest perceptual hash distance at which two
+// images are reported as likely duplicates.
+const duplicateDistance = 10
+
+var (
+	ErrImageTooLarge    = errors.New("image is too large")
+	ErrUnsupportedImage = errors.New("unsupported image type")
+)
+
+// readUpload reads the whole upload, enforcing the size limit, and turns it
+// into sanitized variants. Only JPEG, PNG and WebP are accepted.
+func (s *Service) readUpload(r io.Reader) ([]imaging.Variant, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxUploadSize {
+		return nil, ErrImageTooLarge
+	}
+
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/webp":
+	default:
+		return nil, ErrUnsupportedImage
+	}
+
+	variants, err := s.images.Process(data)
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return nil, ErrUnsupportedImage
+	case errors.Is(err, imaging.ErrTooManyPixels):
+		return nil, ErrImageTooLarge
+	case err != nil:
+		return nil, err
+	}
+
+	return variants, nil
+}
+
+// putBlob stores data under key.
+func (s *Service) putBlob(ctx context.Context, key string, data []byte, contentType string) error {
+	return s.blobs.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// blobKey returns a new random key under prefix.
+func blobKey(prefix string) (string, error) {
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	return prefix + "/" + hex.EncodeToString(name), nil
+}
+
+// putVariants stores every variant under a shared random name and returns an
+// image holding their keys. Nothing is left behind if one of them fails.
+func (s *Service) putVariants(ctx context.Context, prefix string, variants []imaging.Variant) (models.Image, error) {
+	key, err := blobKey(prefix)
+	if err != nil {
+		return models.Image{}, err
+	}
+
+	var image models.Image
+	for _, v := range variants {
+		switch v.Size {
+		case imaging.Full:
+			image.Key = key
+			image.Width, image.Height = v.Width, v.Height
+		case imaging.Medium:
+			image.MediumKey = key + "-" + string(v.Size)
+		case imaging.Thumb:
+			image.ThumbKey = key + "-" + string(v.Size)
+		}
+	}
+
+	for _, v := range variants {
+		variantKey := image.Key
+		switch v.Size {
+		case imaging.Medium:
+			variantKey = image.MediumKey
+		case imaging.Thumb:
+			variantKey = image.ThumbKey
+		}
+		if err := s.putBlob(ctx, variantKey, v.Data, v.ContentType); err != nil {
+			s.deleteBlobs(ctx, image.Keys())
+			return models.Image{}, err
+		}
+	}
+
+	return image, nil
+}
+
+// deleteBlob removes a blob that is no longer referenced. Failures only leave
+// an orphaned file behind, so they are logged rather than returned.
+func (s *Service) deleteBlob(ctx context.Context, key string) {
+	if key == "" || isExternalURL(key) {
+		return
+	}
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		s.log.Error("failed to delete blob", slog.String("key", key), slog.String("err", err.Error()))
+	}
+}
+
+func (s *Service) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		s.deleteBlob(ctx, key)
+	}
+}
+
+func isExternalURL(raw string) bool {
+	return strings.Contains(raw, "://") || strings.HasPrefix(raw, "/")
+}
+
+// imageURL turns a stored blob key into a signed URL. Values that already are
+// URLs, like the ones in item_images_url, are returned unchanged.
+func (s *Service) imageURL(key string) string {
+	if key == "" || isExternalURL(key) || s.signer == nil {
+		return key
+	}
+	return s.signer.URL(key)
+}
+
+// withURL signs the URL of every variant. Images without variants serve the
+// full size for every size.
+func (s *Service) withURL(image models.Image) models.Image {
+	image.URL = s.imageURL(image.Key)
+	image.MediumURL, image.ThumbURL = image.URL, image.URL
+	if image.MediumKey != "" {
+		image.MediumURL = s.imageURL(image.MediumKey)
+	}
+	if image.ThumbKey != "" {
+		image.ThumbURL = s.imageURL(image.ThumbKey)
+	}
+	return image
+}
+
+func (s *Service) withURLs(images []models.Image) []models.Image {
+	for i := range images {
+		images[i] = s.withURL(images[i])
+	}
+	return images
+}
+
+// UploadItemImage stores a new image of the user's item. It also returns the
+// user's other items with a similar looking image, so the client can warn
+// about a likely duplicate.
+func (s *Service) UploadItemImage(ctx context.Context, userID, itemID int64, r io.Reader, isPrimary bool) (models.Image, []models.Duplicate, error) {
+	s.log.Debug("Upload item image", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	variants, err := s.readUpload(r)
+	if err != nil {
+		return models.Image{}, nil, err
+	}
+
+	// Check ownership before writing the blob to avoid orphaned uploads.
+	item, err := s.read_storage.Item(ctx, userID, itemID)
+	if err != nil {
+		return models.Image{}, nil, err
+	}
+
+	image, err := s.putVariants(ctx, fmt.Sprintf("items/%d", itemID), variants)
+	if err != nil {
+		return models.Image{}, nil, err
+	}
+	image.IsPrimary = isPrimary
+	image.Hash, err = variantHash(variants)
+	if err != nil {
+		s.deleteBlobs(ctx, image.Keys())
+		return models.Image{}, nil, err
+	}
+
+	stored, err := s.write_storage.AddItemImage(ctx, userID, itemID, image)
+	if err != nil {
+		s.deleteBlobs(ctx, image.Keys())
+		return models.Image{}, nil, err
+	}
+	stored = s.withURL(stored)
+
+	// The image is already saved, so a failed lookup only loses the warning.
+	duplicates, err := s.read_storage.SimilarImages(ctx, userID, itemID, stored.Hash, duplicateDistance)
+	if err != nil {
+		s.log.Error("failed to find similar images", slog.String("err", err.Error()))
+		return stored, nil, nil
+	}
+	for i := range duplicates {
+		duplicates[i].Image = models.DuplicateImage{
+			ItemID:       itemID,
+			CollectionID: item.CollectionID,
+			Title:        item.Title,
+			ImageID:      stored.ImageID,
+			ThumbURL:     stored.ThumbURL,
+		}
+	}
+
+	return stored, s.withDuplicateURLs(duplicates), nil
+}
+
+func (s *Service) ItemImages(ctx context.Context, userID, itemID int64) ([]models.Image, error) {
+	s.log.Debug("Get item images", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	images, err := s.read_storage.ItemImages(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withURLs(images), nil
+}
+
+func (s *Service) SetPrimaryItemImage(ctx context.Context, userID, itemID, imageID int64) error {
+	s.log.Debug("Set primary item image", slog.String("image_id", strconv.Itoa(int(imageID))))
+
+	return s.write_storage.SetPrimaryItemImage(ctx, userID, itemID, imageID)
+}
+
+func (s *Service) DeleteItemImage(ctx context.Context, userID, itemID, imageID int64) error {
+	s.log.Debug("Delete item image", slog.String("image_id", strconv.Itoa(int(imageID))))
+
+	image, err := s.write_storage.DeleteItemImage(ctx, userID, itemID, imageID)
+	if err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, image.Keys())
+
+	return nil
+}
+
+func (s *Service) UploadCollectionImage(ctx context.Context, userID, collectionID int64, r io.Reader, isPrimary bool) (models.Image, error) {
+	s.log.Debug("Upload collection image", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	variants, err := s.readUpload(r)
+	if err != nil {
+		return models.Image{}, err
+	}
+
+	if _, err := s.read_storage.Collection(ctx, userID, collectionID); err != nil {
+		return models.Image{}, err
+	}
+
+	image, err := s.putVariants(ctx, fmt.Sprintf("collections/%d", collectionID), variants)
+	if err != nil {
+		return models.Image{}, err
+	}
+	image.IsPrimary = isPrimary
+
+	stored, err := s.write_storage.AddCollectionImage(ctx, userID, collectionID, image)
+	if err != nil {
+		s.deleteBlobs(ctx, image.Keys())
+		return models.Image{}, err
+	}
+
+	return s.withURL(stored), nil
+}
+
+func (s *Service) CollectionImages(ctx context.Context, userID, collectionID int64) ([]models.Image, error) {
+	s.log.Debug("Get collection images", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	images, err := s.read_storage.CollectionImages(ctx, userID, collectionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withURLs(images), nil
+}
+
+func (s *Service) DeleteCollectionImage(ctx context.Context, userID, collectionID, imageID int64) error {
+	s.log.Debug("Delete collection image", slog.String("image_id", strconv.Itoa(int(imageID))))
+
+	image, err := s.write_storage.DeleteCollectionImage(ctx, userID, collectionID, imageID)
+	if err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, image.Keys())
+
+	return nil
+}
+
+// UploadAvatar replaces the user's profile image and returns its URL.
+func (s *Service) UploadAvatar(ctx context.Context, userID int64, r io.Reader) (string, error) {
+	s.log.Debug("Upload avatar", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	variants, err := s.readUpload(r)
+	if err != nil {
+		return "", err
+	}
+
+	// Avatars are only ever shown small, so the medium variant is enough.
+	key, err := blobKey(fmt.Sprintf("avatars/%d", userID))
+	if err != nil {
+		return "", err
+	}
+	for _, v := range variants {
+		if v.Size != imaging.Medium {
+			continue
+		}
+		if err := s.putBlob(ctx, key, v.Data, v.ContentType); err != nil {
+			return "", err
+		}
+	}
+
+	old, err := s.write_storage.SetUserAvatar(ctx, userID, key)
+	if err != nil {
+		s.deleteBlob(ctx, key)
+		return "", err
+	}
+	s.deleteBlob(ctx, old)
+
+	return s.imageURL(key), nil
+}
+
+// OpenImage returns the blob behind a signed image URL.
+func (s *Service) OpenImage(ctx context.Context, key, expires, sig string) (io.ReadCloser, blob.Info, error) {
+	// Without a signer no URL was ever signed, so no image is served.
+	if s.signer == nil || s.blobs == nil {
+		return nil, blob.Info{}, storage.ErrImageNotFound
+	}
+	if err := s.signer.Verify(key, expires, sig); err != nil {
+		return nil, blob.Info{}, err
+	}
+
+	return s.blobs.Get(ctx, key)
+}
+
+// variantHash returns the perceptual hash of the thumbnail, the cheapest
+// variant to decode.
+func variantHash(variants []imaging.Variant) (uint64, error) {
+	for _, v := range variants {
+		if v.Size == imaging.Thumb {
+			return imaging.Hash(v.Data)
+		}
+	}
+	return 0, ErrUnsupportedImage
+}
+
+func (s *Service) withDuplicateURLs(duplicates []models.Duplicate) []models.Duplicate {
+	for i := range duplicates {
+		d := &duplicates[i]
+		d.Similarity = math.Round(imaging.Similarity(d.Distance)*100) / 100
+		if d.Image.ThumbKey != "" {
+			d.Image.ThumbURL = s.imageURL(d.Image.ThumbKey)
+		}
+		d.Match.ThumbURL = s.imageURL(d.Match.ThumbKey)
+	}
+	return duplicates
+}
+
+// Duplicates lists pairs of the user's items that have similar looking images,
+// most similar first.
+func (s *Service) Duplicates(ctx context.Context, userID int64) ([]models.Duplicate, error) {
+	s.log.Debug("Get duplicates", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	duplicates, err := s.read_storage.Duplicates(ctx, userID, duplicateDistance)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withDuplicateURLs(duplicates), nil
+}
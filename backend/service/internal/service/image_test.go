@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenImage_WithoutSigner(t *testing.T) {
+	s := newTestService(t)
+
+	_, _, err := s.OpenImage(context.Background(), "items/1/full.jpg", "0", "sig")
+
+	assert.ErrorIs(t, err, storage.ErrImageNotFound)
+}
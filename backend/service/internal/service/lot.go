@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	maxLotItems       = 50
+	maxLotTitle       = 200
+	maxLotDescription = 5000
+)
+
+var (
+	ErrInvalidLotStatus  = errors.New("invalid lot status")
+	ErrInvalidPriceRange = errors.New("invalid price range")
+
+	currencyRe = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	lotSorts = []string{models.SortTitle, models.SortPrice, models.SortCreatedAt, models.SortUpdatedAt}
+
+	// lotTransitions lists for every status the statuses a lot may move to
+	// it from. Sold and withdrawn lots are final.
+	lotTransitions = map[string][]string{
+		models.LotDraft:     {models.LotActive},
+		models.LotActive:    {models.LotDraft, models.LotReserved},
+		models.LotReserved:  {models.LotActive},
+		models.LotSold:      {models.LotActive, models.LotReserved},
+		models.LotWithdrawn: {models.LotDraft, models.LotActive, models.LotReserved},
+	}
+)
+
+// normalizeLot checks the terms of a lot and returns them cleaned up: item
+// ids deduplicated, currency upper-cased, auction start defaulted.
+func normalizeLot(lot models.Lot) (models.Lot, error) {
+	errs := ValidationErrors{}
+
+	lot.Title = strings.TrimSpace(lot.Title)
+	switch n := utf8.RuneCountInString(lot.Title); {
+	case n == 0:
+		errs["title"] = "is required"
+	case n > maxLotTitle:
+		errs["title"] = "must be at most 200 characters"
+	}
+	if utf8.RuneCountInString(lot.Description) > maxLotDescription {
+		errs["description"] = "must be at most 5000 characters"
+	}
+
+	ids := make([]int64, 0, len(lot.ItemIDs))
+	for _, id := range lot.ItemIDs {
+		if id <= 0 {
+			errs["item_ids"] = "must be item ids"
+			break
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	lot.ItemIDs = ids
+	switch {
+	case len(ids) == 0 && errs["item_ids"] == "":
+		errs["item_ids"] = "must list at least one item"
+	case len(ids) > maxLotItems:
+		errs["item_ids"] = "must list at most 50 items"
+	}
+
+	lot.Currency = strings.ToUpper(strings.TrimSpace(lot.Currency))
+	if lot.ExchangeOnly {
+		if lot.Price != 0 {
+			errs["price"] = "must be empty for exchange-only lots"
+		}
+		if lot.Currency != "" {
+			errs["currency"] = "must be empty for exchange-only lots"
+		}
+	} else {
+		if lot.Price <= 0 {
+			errs["price"] = "must be positive, in minor units of the currency"
+		}
+		if !currencyRe.MatchString(lot.Currency) {
+			errs["currency"] = "must be a three-letter ISO 4217 code"
+		}
+	}
+
+	if lot.Auction != nil {
+		if lot.ExchangeOnly {
+			errs["auction"] = "cannot be exchange-only"
+		}
+		normalizeAuction(lot.Auction, lot.Price, time.Now(), errs)
+	}
+
+	if len(errs) > 0 {
+		return models.Lot{}, errs
+	}
+	return lot, nil
+}
+
+// CreateLot lists items of one of the user's collections. A lot starts as a
+// draft unless it is published right away.
+func (s *Service) CreateLot(ctx context.Context, userID int64, lot models.Lot) (int64, error) {
+	s.log.Debug("Create lot", slog.String("collection_id", strconv.Itoa(int(lot.CollectionID))))
+
+	switch lot.Status {
+	case "":
+		lot.Status = models.LotDraft
+	case models.LotDraft, models.LotActive:
+	default:
+		return 0, ErrInvalidLotStatus
+	}
+
+	lot, err := normalizeLot(lot)
+	if err != nil {
+		return 0, err
+	}
+
+	lotID, err := s.write_storage.CreateLot(ctx, userID, lot)
+	if err != nil {
+		return 0, err
+	}
+
+	if lot.Status == models.LotActive {
+		s.matchWants(ctx, "lot_id", lotID, s.write_storage.MatchLot)
+		s.recordActivity(ctx, models.Activity{UserID: userID, Type: models.ActivityLotListed, CollectionID: lot.CollectionID, LotID: lotID})
+	}
+
+	return lotID, nil
+}
+
+// UpdateLot changes the terms and items of a draft or active lot.
+func (s *Service) UpdateLot(ctx context.Context, userID int64, lot models.Lot) error {
+	s.log.Debug("Update lot", slog.String("lot_id", strconv.Itoa(int(lot.LotID))))
+
+	lot, err := normalizeLot(lot)
+	if err != nil {
+		return err
+	}
+
+	if err := s.write_storage.UpdateLot(ctx, userID, lot); err != nil {
+		return err
+	}
+
+	s.matchWants(ctx, "lot_id", lot.LotID, s.write_storage.MatchLot)
+
+	return nil
+}
+
+// SetLotStatus moves a lot along draft -> active <-> reserved -> sold, or
+// withdraws it.
+func (s *Service) SetLotStatus(ctx context.Context, userID, lotID int64, status string) error {
+	s.log.Debug("Set lot status", slog.String("lot_id", strconv.Itoa(int(lotID))), slog.String("status", status))
+
+	from, ok := lotTransitions[status]
+	if !ok {
+		return ErrInvalidLotStatus
+	}
+
+	if err := s.write_storage.SetLotStatus(ctx, userID, lotID, status, from); err != nil {
+		return err
+	}
+
+	if status == models.LotActive {
+		s.matchWants(ctx, "lot_id", lotID, s.write_storage.MatchLot)
+		s.recordActivity(ctx, models.Activity{UserID: userID, Type: models.ActivityLotListed, LotID: lotID})
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteLot(ctx context.Context, userID, lotID int64) error {
+	s.log.Debug("Delete lot", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	return s.write_storage.DeleteLot(ctx, userID, lotID)
+}
+
+// Lot returns a lot of the user with its items.
+func (s *Service) Lot(ctx context.Context, userID, lotID int64) (models.Lot, error) {
+	s.log.Debug("Get lot", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	lot, err := s.read_storage.Lot(ctx, userID, lotID)
+	if err != nil {
+		return models.Lot{}, err
+	}
+
+	return s.withLotItems(ctx, lot)
+}
+
+func (s *Service) Lots(ctx context.Context, userID, collectionID int64) ([]models.Lot, error) {
+	s.log.Debug("Get lots", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.read_storage.Lots(ctx, userID, collectionID)
+}
+
+// PublicLots returns a page of the lot catalogue, newest first unless
+// another order is asked for.
+func (s *Service) PublicLots(ctx context.Context, opts models.ListOptions) ([]models.Lot, string, error) {
+	s.log.Debug("Get public lots")
+
+	if opts.Sort == "" {
+		opts.Sort, opts.Desc = models.SortCreatedAt, true
+	}
+	if opts.PriceMin < 0 || opts.PriceMax < 0 || (opts.PriceMax != 0 && opts.PriceMin > opts.PriceMax) {
+		return nil, "", ErrInvalidPriceRange
+	}
+	opts.Currency = strings.ToUpper(strings.TrimSpace(opts.Currency))
+
+	opts, err := listOptions(opts, lotSorts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	lots, next, err := s.read_storage.PublicLots(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range lots {
+		hideReserve(&lots[i])
+	}
+
+	return lots, encodeCursor(next, opts), nil
+}
+
+// PublicLot returns a lot of the catalogue with its items.
+func (s *Service) PublicLot(ctx context.Context, lotID int64) (models.Lot, error) {
+	s.log.Debug("Get public lot", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	lot, err := s.read_storage.PublicLot(ctx, lotID)
+	if err != nil {
+		return models.Lot{}, err
+	}
+	hideReserve(&lot)
+
+	return s.withLotItems(ctx, lot)
+}
+
+func (s *Service) withLotItems(ctx context.Context, lot models.Lot) (models.Lot, error) {
+	items, err := s.read_storage.LotItems(ctx, lot.LotID)
+	if err != nil {
+		return models.Lot{}, err
+	}
+	lot.Items = items
+
+	return lot, nil
+}
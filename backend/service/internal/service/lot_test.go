@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateLot_Normalizes(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.CreateLot(context.Background(), 1, models.Lot{
+		Title:    "  Silver roubles ",
+		Price:    150000,
+		Currency: " eur",
+		ItemIDs:  []int64{3, 1, 3},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Silver roubles", s.write.lot.Title)
+	assert.Equal(t, "EUR", s.write.lot.Currency)
+	assert.Equal(t, []int64{3, 1}, s.write.lot.ItemIDs)
+	assert.Equal(t, models.LotDraft, s.write.lot.Status)
+	// Drafts are not on display, so there is nothing to match yet.
+	assert.Empty(t, s.write.matchedLots)
+}
+
+func TestCreateLot_Invalid(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.CreateLot(context.Background(), 1, models.Lot{ExchangeOnly: true, Price: 10, ItemIDs: []int64{-1}})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "title")
+	assert.Contains(t, errs, "price")
+	assert.Contains(t, errs, "item_ids")
+	assert.NotContains(t, errs, "currency")
+
+	_, err = s.CreateLot(context.Background(), 1, models.Lot{Title: "lot", Price: 1, Currency: "USD", ItemIDs: []int64{1}, Status: models.LotSold})
+	assert.ErrorIs(t, err, ErrInvalidLotStatus)
+}
+
+func TestSetLotStatus_Transitions(t *testing.T) {
+	s := newTestService(t)
+
+	require.NoError(t, s.SetLotStatus(context.Background(), 1, 1, models.LotSold))
+	assert.ElementsMatch(t, []string{models.LotActive, models.LotReserved}, s.write.from)
+	assert.Empty(t, s.write.matchedLots)
+
+	require.NoError(t, s.SetLotStatus(context.Background(), 1, 1, models.LotActive))
+	assert.Equal(t, []int64{1}, s.write.matchedLots)
+	assert.Equal(t, []models.Activity{{UserID: 1, Type: models.ActivityLotListed, LotID: 1}}, s.write.activities)
+
+	assert.ErrorIs(t, s.SetLotStatus(context.Background(), 1, 1, "auctioned"), ErrInvalidLotStatus)
+}
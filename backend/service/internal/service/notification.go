@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	// notificationBuffer is how far a stream may fall behind before it is
+	// dropped.
+	notificationBuffer = 16
+	// maxNotificationReplay caps what a reconnecting stream catches up on.
+	maxNotificationReplay = 100
+	// listenRetry is the pause before listening again after the listener
+	// failed.
+	listenRetry = 5 * time.Second
+)
+
+// notificationHub hands the notifications heard from the database to the
+// streams open on this replica.
+type notificationHub struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan models.Notification]struct{}
+}
+
+func newNotificationHub() *notificationHub {
+	return &notificationHub{subs: make(map[int64]map[chan models.Notification]struct{})}
+}
+
+// subscribe opens a stream of the notifications of the user. The returned
+// func closes it.
+func (h *notificationHub) subscribe(userID int64) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification, notificationBuffer)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[chan models.Notification]struct{})
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(userID, ch)
+	}
+}
+
+// remove closes a stream unless it is closed already. h.mu is held.
+func (h *notificationHub) remove(userID int64, ch chan models.Notification) {
+	if _, ok := h.subs[userID][ch]; !ok {
+		return
+	}
+	delete(h.subs[userID], ch)
+	if len(h.subs[userID]) == 0 {
+		delete(h.subs, userID)
+	}
+	close(ch)
+}
+
+// publish passes a notification to the streams of its user. A stream too
+// slow to take it is closed rather than waited for; its client reconnects
+// and catches up from the last notification it got.
+func (h *notificationHub) publish(n models.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[n.UserID] {
+		select {
+		case ch <- n:
+		default:
+			h.remove(n.UserID, ch)
+		}
+	}
+}
+
+// resync tells every stream that notifications may have been missed, with a
+// notification without an id. A stream too slow to take it is closed, as by
+// publish.
+func (h *notificationHub) resync() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for userID, subs := range h.subs {
+		for ch := range subs {
+			select {
+			case ch <- models.Notification{UserID: userID}:
+			default:
+				h.remove(userID, ch)
+			}
+		}
+	}
+}
+
+// Notifications returns notifications of the user, newest first, older
+// than before when it is not 0.
+func (s *Service) Notifications(ctx context.Context, userID, before int64, limit int, unreadOnly bool) ([]models.Notification, error) {
+	s.log.Debug("Get notifications", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	if limit <= 0 || limit > maxPageLimit {
+		limit = defaultPageLimit
+	}
+
+	return s.read_storage.Notifications(ctx, userID, before, limit, unreadOnly)
+}
+
+func (s *Service) UnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	s.log.Debug("Get unread notifications", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.read_storage.UnreadNotifications(ctx, userID)
+}
+
+func (s *Service) MarkNotificationRead(ctx context.Context, userID, notificationID int64) error {
+	s.log.Debug("Mark notification read", slog.String("notification_id", strconv.Itoa(int(notificationID))))
+
+	return s.write_storage.MarkNotificationRead(ctx, userID, notificationID)
+}
+
+func (s *Service) MarkAllNotificationsRead(ctx context.Context, userID int64) (int64, error) {
+	s.log.Debug("Mark all notifications read", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.write_storage.MarkAllNotificationsRead(ctx, userID)
+}
+
+// NotificationPreferences returns whether the user gets each type of
+// notification. Types are on until turned off.
+func (s *Service) NotificationPreferences(ctx context.Context, userID int64) ([]models.NotificationPreference, error) {
+	s.log.Debug("Get notification preferences", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	set, err := s.read_storage.NotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := make(map[string]bool, len(set))
+	for _, p := range set {
+		enabled[p.Type] = p.Enabled
+	}
+
+	prefs := make([]models.NotificationPreference, len(models.NotificationTypes))
+	for i, typ := range models.NotificationTypes {
+		on, ok := enabled[typ]
+		prefs[i] = models.NotificationPreference{Type: typ, Enabled: on || !ok}
+	}
+
+	return prefs, nil
+}
+
+// SetNotificationPreferences turns types of notifications on or off. Types
+// left out keep their setting.
+func (s *Service) SetNotificationPreferences(ctx context.Context, userID int64, prefs []models.NotificationPreference) error {
+	s.log.Debug("Set notification preferences", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	errs := ValidationErrors{}
+	for _, p := range prefs {
+		if !slices.Contains(models.NotificationTypes, p.Type) {
+			errs["preferences."+p.Type] = "is not a notification type"
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return s.write_storage.SetNotificationPreferences(ctx, userID, prefs)
+}
+
+// SubscribeNotifications streams the notifications of the user as they are
+// written, on any replica. A notification without an id means some may have
+// been missed; the reader catches up through NotificationsAfter. The stream
+// is closed when the returned func is called, or by the service when the
+// reader falls behind.
+func (s *Service) SubscribeNotifications(userID int64) (<-chan models.Notification, func()) {
+	return s.hub.subscribe(userID)
+}
+
+// NotificationsAfter returns what a stream missed since notification after,
+// oldest first.
+func (s *Service) NotificationsAfter(ctx context.Context, userID, after int64) ([]models.Notification, error) {
+	s.log.Debug("Get notifications after", slog.String("notification_id", strconv.Itoa(int(after))))
+
+	return s.read_storage.NotificationsAfter(ctx, userID, after, maxNotificationReplay)
+}
+
+// RunNotificationListener feeds the notification streams of this replica
+// until ctx is done, listening again whenever the listener fails. Streams
+// catch up on what the listener missed meanwhile.
+func (s *Service) RunNotificationListener(ctx context.Context) {
+	for {
+		err := s.read_storage.ListenNotifications(ctx, s.hub.publish, s.hub.resync)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			s.log.Error("failed to listen for notifications", slog.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+	}
+}
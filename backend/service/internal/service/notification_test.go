@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferences_DefaultOn(t *testing.T) {
+	s := newTestService(t)
+	s.read.prefs = []models.NotificationPreference{{Type: models.NotificationOffer, Enabled: false}}
+
+	prefs, err := s.NotificationPreferences(context.Background(), sellerID)
+	require.NoError(t, err)
+	require.Len(t, prefs, len(models.NotificationTypes))
+	for _, p := range prefs {
+		assert.Equal(t, p.Type != models.NotificationOffer, p.Enabled, p.Type)
+	}
+}
+
+func TestSetNotificationPreferences_UnknownType(t *testing.T) {
+	s := newTestService(t)
+
+	err := s.SetNotificationPreferences(context.Background(), sellerID, []models.NotificationPreference{
+		{Type: models.NotificationComment, Enabled: false},
+		{Type: "newsletter", Enabled: false},
+	})
+	assert.Equal(t, ValidationErrors{"preferences.newsletter": "is not a notification type"}, err)
+	assert.Nil(t, s.write.prefs)
+}
+
+func TestNotificationHub(t *testing.T) {
+	hub := newNotificationHub()
+
+	seller, stopSeller := hub.subscribe(sellerID)
+	buyer, stopBuyer := hub.subscribe(buyerID)
+	defer stopBuyer()
+
+	hub.publish(models.Notification{NotificationID: 1, UserID: sellerID})
+	assert.Equal(t, int64(1), (<-seller).NotificationID)
+	assert.Empty(t, buyer)
+
+	stopSeller()
+	stopSeller()
+	_, open := <-seller
+	assert.False(t, open)
+	hub.publish(models.Notification{NotificationID: 2, UserID: sellerID})
+}
+
+func TestNotificationHub_SlowStreamClosed(t *testing.T) {
+	hub := newNotificationHub()
+
+	events, stop := hub.subscribe(buyerID)
+	defer stop()
+
+	for i := 0; i <= notificationBuffer; i++ {
+		hub.publish(models.Notification{NotificationID: int64(i + 1), UserID: buyerID})
+	}
+
+	var got int
+	for range events {
+		got++
+	}
+	assert.Equal(t, notificationBuffer, got)
+}
+
+func TestNotificationHub_Resync(t *testing.T) {
+	hub := newNotificationHub()
+
+	seller, stopSeller := hub.subscribe(sellerID)
+	defer stopSeller()
+	buyer, stopBuyer := hub.subscribe(buyerID)
+	defer stopBuyer()
+
+	hub.resync()
+
+	assert.Equal(t, models.Notification{UserID: sellerID}, <-seller)
+	assert.Equal(t, models.Notification{UserID: buyerID}, <-buyer)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	defaultOfferTTL = 72 * time.Hour
+	maxOfferTTL     = 30 * 24 * time.Hour
+	maxOfferMessage = 1000
+)
+
+var (
+	ErrOwnLot        = errors.New("cannot make an offer on your own lot")
+	ErrLotNotForSale = errors.New("lot is offered for exchange only")
+	ErrNotYourTurn   = errors.New("offer is waiting for the other party")
+	ErrAuctionLot    = errors.New("lot is sold by auction")
+)
+
+// normalizeOffer checks the terms of an offer. An offer without an expiry
+// expires after defaultOfferTTL.
+func normalizeOffer(offer models.Offer, now time.Time) (models.Offer, error) {
+	errs := ValidationErrors{}
+
+	if offer.Amount <= 0 {
+		errs["amount"] = "must be positive, in minor units of the currency"
+	}
+
+	offer.Message = strings.TrimSpace(offer.Message)
+	if utf8.RuneCountInString(offer.Message) > maxOfferMessage {
+		errs["message"] = "must be at most 1000 characters"
+	}
+
+	switch {
+	case offer.ExpiresAt.IsZero():
+		offer.ExpiresAt = now.Add(defaultOfferTTL)
+	case !offer.ExpiresAt.After(now):
+		errs["expires_at"] = "must be in the future"
+	case offer.ExpiresAt.After(now.Add(maxOfferTTL)):
+		errs["expires_at"] = "must be within 30 days"
+	}
+
+	if len(errs) > 0 {
+		return models.Offer{}, errs
+	}
+	return offer, nil
+}
+
+// MakeOffer opens a negotiation on an active lot of the catalogue. The offer
+// is in the currency of the lot.
+func (s *Service) MakeOffer(ctx context.Context, userID, lotID int64, offer models.Offer) (int64, error) {
+	s.log.Debug("Make offer", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	lot, err := s.read_storage.PublicLot(ctx, lotID)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case lot.UserID == userID:
+		return 0, ErrOwnLot
+	case lot.ExchangeOnly:
+		return 0, ErrLotNotForSale
+	case lot.Auction != nil:
+		return 0, ErrAuctionLot
+	case lot.Status != models.LotActive:
+		return 0, storage.ErrLotLocked
+	}
+
+	offer, err = normalizeOffer(offer, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	offer.LotID = lot.LotID
+	offer.BuyerID = userID
+	offer.AuthorID = userID
+	offer.Currency = lot.Currency
+
+	return s.write_storage.CreateOffer(ctx, offer)
+}
+
+// pendingOffer returns the offer the user is about to answer. Only the
+// parties of the negotiation see an offer, and only the one who did not make
+// it may answer it while it is pending.
+func (s *Service) pendingOffer(ctx context.Context, userID, offerID int64) (models.Offer, error) {
+	offer, err := s.Offer(ctx, userID, offerID)
+	if err != nil {
+		return models.Offer{}, err
+	}
+	if offer.Status != models.OfferPending {
+		return models.Offer{}, storage.ErrOfferClosed
+	}
+	if offer.AuthorID == userID {
+		return models.Offer{}, ErrNotYourTurn
+	}
+
+	return offer, nil
+}
+
+// CounterOffer answers a pending offer with another amount. The seller
+// counters the buyer's offers and the buyer the seller's.
+func (s *Service) CounterOffer(ctx context.Context, userID, offerID int64, counter models.Offer) (int64, error) {
+	s.log.Debug("Counter offer", slog.String("offer_id", strconv.Itoa(int(offerID))))
+
+	offer, err := s.pendingOffer(ctx, userID, offerID)
+	if err != nil {
+		return 0, err
+	}
+
+	counter, err = normalizeOffer(counter, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	counter.LotID = offer.LotID
+	counter.BuyerID = offer.BuyerID
+	counter.AuthorID = userID
+	counter.Currency = offer.Currency
+
+	return s.write_storage.CounterOffer(ctx, offerID, counter)
+}
+
+// AcceptOffer closes the deal on a pending offer and reserves the lot for
+// the buyer.
+func (s *Service) AcceptOffer(ctx context.Context, userID, offerID int64) error {
+	s.log.Debug("Accept offer", slog.String("offer_id", strconv.Itoa(int(offerID))))
+
+	if _, err := s.pendingOffer(ctx, userID, offerID); err != nil {
+		return err
+	}
+
+	return s.write_storage.AcceptOffer(ctx, offerID)
+}
+
+func (s *Service) RejectOffer(ctx context.Context, userID, offerID int64) error {
+	s.log.Debug("Reject offer", slog.String("offer_id", strconv.Itoa(int(offerID))))
+
+	if _, err := s.pendingOffer(ctx, userID, offerID); err != nil {
+		return err
+	}
+
+	return s.write_storage.RejectOffer(ctx, offerID)
+}
+
+// Offer returns an offer the user made or received.
+func (s *Service) Offer(ctx context.Context, userID, offerID int64) (models.Offer, error) {
+	s.log.Debug("Get offer", slog.String("offer_id", strconv.Itoa(int(offerID))))
+
+	offer, err := s.read_storage.Offer(ctx, offerID)
+	if err != nil {
+		return models.Offer{}, err
+	}
+	if offer.BuyerID != userID && offer.SellerID != userID {
+		return models.Offer{}, storage.ErrOfferNotFound
+	}
+
+	return offer, nil
+}
+
+// Offers returns the offers the user made or received, on one lot if lotID
+// is set.
+func (s *Service) Offers(ctx context.Context, userID, lotID int64) ([]models.Offer, error) {
+	s.log.Debug("Get offers", slog.String("lot_id", strconv.Itoa(int(lotID))))
+
+	return s.read_storage.Offers(ctx, userID, lotID)
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeOffer(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.MakeOffer(context.Background(), buyerID, 5, models.Offer{Amount: 9000, Message: " fair price? "})
+	require.NoError(t, err)
+	assert.Equal(t, int64(buyerID), s.write.offer.BuyerID)
+	assert.Equal(t, int64(buyerID), s.write.offer.AuthorID)
+	assert.Equal(t, "EUR", s.write.offer.Currency)
+	assert.Equal(t, "fair price?", s.write.offer.Message)
+	assert.WithinDuration(t, time.Now().Add(defaultOfferTTL), s.write.offer.ExpiresAt, time.Minute)
+
+	_, err = s.MakeOffer(context.Background(), sellerID, 5, models.Offer{Amount: 9000})
+	assert.ErrorIs(t, err, ErrOwnLot)
+
+	_, err = s.MakeOffer(context.Background(), buyerID, 5, models.Offer{Amount: 0, ExpiresAt: time.Now().Add(-time.Hour)})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "amount")
+	assert.Contains(t, errs, "expires_at")
+}
+
+func TestOffer_Turns(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	// The buyer cannot answer their own offer, strangers do not see it.
+	assert.ErrorIs(t, s.AcceptOffer(ctx, buyerID, 10), ErrNotYourTurn)
+	assert.ErrorIs(t, s.AcceptOffer(ctx, 3, 10), storage.ErrOfferNotFound)
+	assert.ErrorIs(t, s.AcceptOffer(ctx, sellerID, 11), storage.ErrOfferClosed)
+
+	counterID, err := s.CounterOffer(ctx, sellerID, 10, models.Offer{Amount: 9500})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counterID)
+	assert.Equal(t, int64(sellerID), s.write.offer.AuthorID)
+	assert.Equal(t, int64(buyerID), s.write.offer.BuyerID)
+
+	require.NoError(t, s.AcceptOffer(ctx, sellerID, 10))
+	assert.Equal(t, int64(10), s.write.acceptedOffer)
+}
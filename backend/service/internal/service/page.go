@@ -0,0 +1,115 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Sort fields supported by each list.
+var (
+	itemSorts       = []string{models.SortTitle, models.SortYear, models.SortCreatedAt, models.SortUpdatedAt}
+	collectionSorts = []string{models.SortTitle, models.SortCreatedAt, models.SortUpdatedAt}
+	userSorts       = []string{models.SortUsername, models.SortCreatedAt}
+)
+
+// listOptions validates opts against the sorts a list supports, applies the
+// default limit and decodes the cursor of the previous page.
+func listOptions(opts models.ListOptions, sorts []string) (models.ListOptions, error) {
+	if opts.Sort != "" && !slices.Contains(sorts, opts.Sort) {
+		return models.ListOptions{}, ErrInvalidSort
+	}
+	if opts.Limit <= 0 || opts.Limit > maxPageLimit {
+		opts.Limit = defaultPageLimit
+	}
+	if opts.YearFrom < 0 || opts.YearTo < 0 || (opts.YearTo != 0 && opts.YearFrom > opts.YearTo) {
+		return models.ListOptions{}, ErrInvalidYearRange
+	}
+	opts.Query = strings.TrimSpace(opts.Query)
+	opts.Country = strings.TrimSpace(opts.Country)
+
+	if len(opts.Tags) > 0 {
+		tags, err := normalizeTags(opts.Tags)
+		if err != nil {
+			return models.ListOptions{}, err
+		}
+		opts.Tags = tags
+	}
+
+	if opts.Cursor != "" {
+		after, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return models.ListOptions{}, err
+		}
+		// A cursor only makes sense in the order it was issued for.
+		if after.Sort != opts.Sort || after.Desc != opts.Desc {
+			return models.ListOptions{}, ErrInvalidCursor
+		}
+		opts.After = &after
+	}
+
+	return opts, nil
+}
+
+// encodeCursor turns the cursor returned by the storage into the opaque
+// next_cursor of a response. It is empty on the last page.
+func encodeCursor(next *models.Cursor, opts models.ListOptions) string {
+	if next == nil {
+		return ""
+	}
+	next.Sort, next.Desc = opts.Sort, opts.Desc
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (models.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+
+	var c models.Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 || !validCursorValue(c) {
+		return models.Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// validCursorValue checks that the value of a cursor parses as the type of
+// its sort column, so a tampered cursor is rejected before it reaches the
+// database.
+func validCursorValue(c models.Cursor) bool {
+	switch c.Sort {
+	case "", models.SortYear, models.SortPrice:
+		_, err := strconv.ParseInt(c.Value, 10, 64)
+		return err == nil
+	case models.SortCreatedAt, models.SortUpdatedAt:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999-07", "2006-01-02 15:04:05.999999-07:00"} {
+			if _, err := time.Parse(layout, c.Value); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
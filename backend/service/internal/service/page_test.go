@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItems_CursorRoundTrip(t *testing.T) {
+	s := newTestService(t)
+	s.read.next = &models.Cursor{Value: "2024-05-01 10:00:00.123456+03", ID: 42}
+
+	opts := models.ListOptions{Sort: models.SortUpdatedAt, Desc: true}
+	_, next, err := s.Items(context.Background(), 1, 10, opts)
+	require.NoError(t, err)
+	require.NotEmpty(t, next)
+	assert.Equal(t, defaultPageLimit, s.read.opts.Limit)
+	assert.Nil(t, s.read.opts.After)
+
+	opts.Cursor = next
+	_, _, err = s.Items(context.Background(), 1, 10, opts)
+	require.NoError(t, err)
+	require.NotNil(t, s.read.opts.After)
+	assert.Equal(t, int64(42), s.read.opts.After.ID)
+	assert.Equal(t, "2024-05-01 10:00:00.123456+03", s.read.opts.After.Value)
+
+	s.read.next = nil
+	_, next, err = s.Items(context.Background(), 1, 10, opts)
+	require.NoError(t, err)
+	assert.Empty(t, next)
+}
+
+func TestItems_InvalidListOptions(t *testing.T) {
+	s := newTestService(t)
+
+	titleCursor := encodeCursor(&models.Cursor{Value: "a", ID: 1}, models.ListOptions{Sort: models.SortTitle})
+	tamperedYear := encodeCursor(&models.Cursor{Value: "1913; DROP", ID: 1}, models.ListOptions{Sort: models.SortYear})
+
+	tests := []struct {
+		name    string
+		opts    models.ListOptions
+		wantErr error
+	}{
+		{name: "unknown sort", opts: models.ListOptions{Sort: "price"}, wantErr: ErrInvalidSort},
+		{name: "garbage cursor", opts: models.ListOptions{Cursor: "%%%"}, wantErr: ErrInvalidCursor},
+		{name: "cursor of another sort", opts: models.ListOptions{Sort: models.SortYear, Cursor: titleCursor}, wantErr: ErrInvalidCursor},
+		{name: "cursor of another order", opts: models.ListOptions{Sort: models.SortTitle, Desc: true, Cursor: titleCursor}, wantErr: ErrInvalidCursor},
+		{name: "tampered value", opts: models.ListOptions{Sort: models.SortYear, Cursor: tamperedYear}, wantErr: ErrInvalidCursor},
+		{name: "reversed years", opts: models.ListOptions{YearFrom: 1915, YearTo: 1900}, wantErr: ErrInvalidYearRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.Items(context.Background(), 1, 10, tt.opts)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	defaultFeedLimit = 20
+	maxFeedLimit     = 100
+)
+
+func (s *Service) withPublicURLs(collections []models.PublicCollection) []models.PublicCollection {
+	for i := range collections {
+		collections[i].CollectionImageUrl = s.imageURL(collections[i].CollectionImageUrl)
+	}
+	return collections
+}
+
+// PublicProfile returns what anybody may see of a user: the public part of
+// the profile and the user's public collections.
+func (s *Service) PublicProfile(ctx context.Context, username string) (models.PublicUser, []models.PublicCollection, error) {
+	s.log.Debug("Get public profile", slog.String("username", username))
+
+	user, err := s.read_storage.PublicUser(ctx, username)
+	if err != nil {
+		return models.PublicUser{}, nil, err
+	}
+	user.ImageUrl = s.imageURL(user.ImageUrl)
+
+	collections, err := s.read_storage.PublicCollections(ctx, username)
+	if err != nil {
+		return models.PublicUser{}, nil, err
+	}
+
+	return user, s.withPublicURLs(collections), nil
+}
+
+// PublicCollection returns a public collection of the user with its items.
+func (s *Service) PublicCollection(ctx context.Context, username string, collectionID int64) (models.PublicCollection, []models.Item, error) {
+	s.log.Debug("Get public collection", slog.String("username", username))
+
+	collection, err := s.read_storage.PublicCollection(ctx, username, collectionID)
+	if err != nil {
+		return models.PublicCollection{}, nil, err
+	}
+	collection.CollectionImageUrl = s.imageURL(collection.CollectionImageUrl)
+
+	items, err := s.read_storage.PublicItems(ctx, username, collectionID)
+	if err != nil {
+		return models.PublicCollection{}, nil, err
+	}
+
+	return collection, items, nil
+}
+
+// PublicItem returns an item of one of the user's public collections with
+// its images.
+func (s *Service) PublicItem(ctx context.Context, username string, itemID int64) (models.Item, []models.Image, error) {
+	s.log.Debug("Get public item", slog.String("username", username))
+
+	item, err := s.read_storage.PublicItem(ctx, username, itemID)
+	if err != nil {
+		return models.Item{}, nil, err
+	}
+
+	images, err := s.read_storage.PublicItemImages(ctx, username, itemID)
+	if err != nil {
+		return models.Item{}, nil, err
+	}
+
+	return item, s.withURLs(images), nil
+}
+
+// RecentPublicCollections returns the most recently updated public
+// collections of all users.
+func (s *Service) RecentPublicCollections(ctx context.Context, limit int) ([]models.PublicCollection, error) {
+	s.log.Debug("Get recent public collections")
+
+	if limit <= 0 || limit > maxFeedLimit {
+		limit = defaultFeedLimit
+	}
+
+	collections, err := s.read_storage.RecentPublicCollections(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withPublicURLs(collections), nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	maxQueryLength     = 200
+)
+
+var (
+	ErrInvalidQuery     = errors.New("invalid search query")
+	ErrInvalidYearRange = errors.New("invalid year range")
+)
+
+// Search finds the items and collections matching filter.Query among the
+// user's own and all public ones, best matches first.
+func (s *Service) Search(ctx context.Context, userID int64, filter models.SearchFilter) (models.SearchResult, error) {
+	s.log.Debug("Search", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" || utf8.RuneCountInString(filter.Query) > maxQueryLength {
+		return models.SearchResult{}, ErrInvalidQuery
+	}
+	filter, err := normalizeFilter(filter)
+	if err != nil {
+		return models.SearchResult{}, err
+	}
+
+	return s.read_storage.Search(ctx, userID, filter)
+}
+
+// normalizeFilter validates the filters shared by search and facets and
+// brings them to the form the storage expects.
+func normalizeFilter(filter models.SearchFilter) (models.SearchFilter, error) {
+	if filter.YearFrom < 0 || filter.YearTo < 0 || (filter.YearTo != 0 && filter.YearFrom > filter.YearTo) {
+		return models.SearchFilter{}, ErrInvalidYearRange
+	}
+	if filter.Limit <= 0 || filter.Limit > maxSearchLimit {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	filter.Country = strings.TrimSpace(filter.Country)
+
+	if len(filter.Tags) > 0 {
+		tags, err := normalizeTags(filter.Tags)
+		if err != nil {
+			return models.SearchFilter{}, err
+		}
+		filter.Tags = tags
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch_NormalizesFilter(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.Search(context.Background(), 1, models.SearchFilter{
+		Query:   "  серебряный рубль ",
+		Country: " Russia ",
+		Tags:    []string{"Silver", "silver"},
+		Limit:   1000,
+		Offset:  -5,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "серебряный рубль", s.read.filter.Query)
+	assert.Equal(t, "Russia", s.read.filter.Country)
+	assert.Equal(t, []string{"silver"}, s.read.filter.Tags)
+	assert.Equal(t, defaultSearchLimit, s.read.filter.Limit)
+	assert.Equal(t, 0, s.read.filter.Offset)
+}
+
+func TestSearch_InvalidFilters(t *testing.T) {
+	s := newTestService(t)
+
+	tests := []struct {
+		name    string
+		filter  models.SearchFilter
+		wantErr error
+	}{
+		{name: "empty query", filter: models.SearchFilter{Query: "   "}, wantErr: ErrInvalidQuery},
+		{name: "reversed years", filter: models.SearchFilter{Query: "coin", YearFrom: 1915, YearTo: 1900}, wantErr: ErrInvalidYearRange},
+		{name: "negative year", filter: models.SearchFilter{Query: "coin", YearFrom: -1}, wantErr: ErrInvalidYearRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Search(context.Background(), 1, tt.filter)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestFacets_RequiresQueryOrCollection(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.Facets(context.Background(), 1, models.SearchFilter{Country: "Russia"})
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+
+	_, err = s.Facets(context.Background(), 1, models.SearchFilter{CollectionID: 7, Tags: []string{" Silver "}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), s.read.filter.CollectionID)
+	assert.Equal(t, []string{"silver"}, s.read.filter.Tags)
+}
@@ -8,6 +8,9 @@ import (
 
 	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
 	ssogrpc "github.com/mmmakskl/HeritageKeeper/service/internal/clients/sso/grpc"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/imaging"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/signedurl"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
 )
 
 type Service struct {
@@ -15,17 +18,75 @@ type Service struct {
 	read_storage  ReadStorage
 	write_storage WriteStorage
 	sso_client    ssogrpc.Client
+	blobs         blob.Store
+	signer        *signedurl.Signer
+	images        *imaging.Processor
+	maxUploadSize int64
+	// trashRetention is how long deleted collections and items can be
+	// restored before they are purged.
+	trashRetention time.Duration
+	hub            *notificationHub
 	// tokenTTL time.Duration
 }
 
 type ReadStorage interface {
 	User(ctx context.Context, userID int64) (models.User, error)
-	Users() ([]models.User, error)
-	Collections(ctx context.Context, userID int64) ([]models.Collection, error)
+	Users(ctx context.Context, opts models.ListOptions) ([]models.User, *models.Cursor, error)
+	Collections(ctx context.Context, userID int64, opts models.ListOptions) ([]models.Collection, *models.Cursor, error)
 	Collection(ctx context.Context, userID, collectionID int64) (models.Collection, error)
-	Category(ctx context.Context) ([]models.Category, error)
-	Item(ctx context.Context, collectionID, itemID int64) (models.Item, error)
-	Items(ctx context.Context, collectionID int64) ([]models.Item, error)
+	Category(ctx context.Context, categoryID int64) (models.Category, error)
+	CategorySchema(ctx context.Context, categoryID int64) ([]models.AttributeField, error)
+	Categories(ctx context.Context) ([]models.Category, error)
+	Tags(ctx context.Context, userID int64) ([]models.Tag, error)
+	AutocompleteTags(ctx context.Context, userID int64, prefix string, limit int) ([]models.Tag, error)
+	ItemsByTags(ctx context.Context, userID int64, tags []string) ([]models.Item, error)
+	ItemImages(ctx context.Context, userID, itemID int64) ([]models.Image, error)
+	Duplicates(ctx context.Context, userID int64, maxDistance int) ([]models.Duplicate, error)
+	SimilarImages(ctx context.Context, userID, itemID int64, hash uint64, maxDistance int) ([]models.Duplicate, error)
+	PublicUser(ctx context.Context, username string) (models.PublicUser, error)
+	PublicCollections(ctx context.Context, username string) ([]models.PublicCollection, error)
+	PublicCollection(ctx context.Context, username string, collectionID int64) (models.PublicCollection, error)
+	RecentPublicCollections(ctx context.Context, limit int) ([]models.PublicCollection, error)
+	PublicItems(ctx context.Context, username string, collectionID int64) ([]models.Item, error)
+	PublicItem(ctx context.Context, username string, itemID int64) (models.Item, error)
+	PublicItemImages(ctx context.Context, username string, itemID int64) ([]models.Image, error)
+	Search(ctx context.Context, userID int64, filter models.SearchFilter) (models.SearchResult, error)
+	Facets(ctx context.Context, userID int64, filter models.SearchFilter, limit int) (models.Facets, error)
+	CollectionImages(ctx context.Context, userID, collectionID int64) ([]models.Image, error)
+	Item(ctx context.Context, userID, itemID int64) (models.Item, error)
+	Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, *models.Cursor, error)
+	Lot(ctx context.Context, userID, lotID int64) (models.Lot, error)
+	Lots(ctx context.Context, userID, collectionID int64) ([]models.Lot, error)
+	LotItems(ctx context.Context, lotID int64) ([]models.Item, error)
+	PublicLots(ctx context.Context, opts models.ListOptions) ([]models.Lot, *models.Cursor, error)
+	PublicLot(ctx context.Context, lotID int64) (models.Lot, error)
+	Offer(ctx context.Context, offerID int64) (models.Offer, error)
+	Offers(ctx context.Context, userID, lotID int64) ([]models.Offer, error)
+	Bids(ctx context.Context, lotID int64) ([]models.Bid, error)
+	Trade(ctx context.Context, tradeID int64) (models.Trade, error)
+	Trades(ctx context.Context, userID int64) ([]models.Trade, error)
+	ItemTransfers(ctx context.Context, userID, itemID int64) ([]models.ItemTransfer, error)
+	Want(ctx context.Context, userID, wantID int64) (models.Want, error)
+	Wants(ctx context.Context, userID int64) ([]models.Want, error)
+	WantMatches(ctx context.Context, userID, wantID int64) ([]models.WantMatch, error)
+	Favorites(ctx context.Context, userID int64) (models.Favorites, error)
+	Followers(ctx context.Context, userID int64) ([]models.Follow, error)
+	Following(ctx context.Context, userID int64) ([]models.Follow, error)
+	PublicFollowers(ctx context.Context, username string) ([]models.Follow, error)
+	PublicFollowing(ctx context.Context, username string) ([]models.Follow, error)
+	Comments(ctx context.Context, itemID, collectionID int64) ([]models.Comment, error)
+	Notifications(ctx context.Context, userID, before int64, limit int, unreadOnly bool) ([]models.Notification, error)
+	NotificationsAfter(ctx context.Context, userID, after int64, limit int) ([]models.Notification, error)
+	UnreadNotifications(ctx context.Context, userID int64) (int64, error)
+	NotificationPreferences(ctx context.Context, userID int64) ([]models.NotificationPreference, error)
+	Feed(ctx context.Context, userID, before int64, limit int) ([]models.Activity, error)
+	CollectionShares(ctx context.Context, ownerID, collectionID int64) ([]models.CollectionShare, error)
+	SharedCollections(ctx context.Context, userID int64) ([]models.SharedCollection, error)
+	ShareLinks(ctx context.Context, ownerID, collectionID int64) ([]models.ShareLink, error)
+	ShareLink(ctx context.Context, tokenHash []byte) (models.ShareLink, error)
+	Versions(ctx context.Context, userID int64, entity string, entityID int64) ([]models.Version, error)
+	Trash(ctx context.Context, userID int64) (models.Trash, error)
+	ListenNotifications(ctx context.Context, handle func(models.Notification), missed func()) error
 }
 
 type WriteStorage interface {
@@ -62,7 +123,18 @@ type WriteStorage interface {
 		ctx context.Context,
 		categoryName string,
 		description string,
+		parentID int64,
+		schema []models.AttributeField,
 	) (int64, error)
+	UpdateCategory(
+		ctx context.Context,
+		categoryID int64,
+		categoryName string,
+		description string,
+		parentID int64,
+		schema []models.AttributeField,
+	) error
+	DeleteCategory(ctx context.Context, categoryID int64) error
 	SetItem(
 		ctx context.Context,
 		userID int64,
@@ -73,7 +145,7 @@ type WriteStorage interface {
 		country string,
 		images []string,
 		year string,
-		attributes []string,
+		attributes models.Attributes,
 	) (int64, error)
 	UpdateItem(
 		ctx context.Context,
@@ -86,9 +158,71 @@ type WriteStorage interface {
 		country string,
 		images []string,
 		year string,
-		attributes []string,
+		attributes models.Attributes,
 	) error
-	DeleteItem(ctx context.Context, collectionID, itemID int64) error
+	DeleteItem(ctx context.Context, userID, itemID int64) error
+	DiscardItem(ctx context.Context, userID, itemID int64) ([]models.Image, error)
+	MoveItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error)
+	CopyItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error)
+	UpdateItems(
+		ctx context.Context,
+		userID int64,
+		sel models.ItemSelection,
+		change models.ItemChange,
+		check func(models.Item) (models.Attributes, error),
+	) ([]models.ItemResult, error)
+	RevertItem(ctx context.Context, userID, itemID int64, version int, check func(models.Item) (models.Attributes, error)) error
+	CreateLot(ctx context.Context, userID int64, lot models.Lot) (int64, error)
+	UpdateLot(ctx context.Context, userID int64, lot models.Lot) error
+	SetLotStatus(ctx context.Context, userID, lotID int64, status string, from []string) error
+	DeleteLot(ctx context.Context, userID, lotID int64) error
+	CreateOffer(ctx context.Context, offer models.Offer) (int64, error)
+	CounterOffer(ctx context.Context, parentID int64, offer models.Offer) (int64, error)
+	AcceptOffer(ctx context.Context, offerID int64) error
+	RejectOffer(ctx context.Context, offerID int64) error
+	PlaceBid(ctx context.Context, lotID, bidderID, amount int64) (models.Auction, error)
+	CloseAuctions(ctx context.Context, limit int) ([]int64, error)
+	CreateTrade(ctx context.Context, t models.Trade) (int64, error)
+	CounterTrade(ctx context.Context, parentID int64, t models.Trade) (int64, error)
+	AcceptTrade(ctx context.Context, tradeID, collectionID int64) error
+	DeclineTrade(ctx context.Context, tradeID int64) error
+	CreateWant(ctx context.Context, w models.Want) (int64, error)
+	UpdateWant(ctx context.Context, w models.Want) error
+	DeleteWant(ctx context.Context, userID, wantID int64) error
+	MatchItem(ctx context.Context, itemID int64) error
+	MatchCollection(ctx context.Context, collectionID int64) error
+	MatchLot(ctx context.Context, lotID int64) error
+	FavoriteItem(ctx context.Context, userID, itemID int64) error
+	FavoriteCollection(ctx context.Context, userID, collectionID int64) error
+	Unfavorite(ctx context.Context, userID, itemID, collectionID int64) error
+	Follow(ctx context.Context, userID int64, username string) error
+	Unfollow(ctx context.Context, userID int64, username string) error
+	SetFollowersPrivate(ctx context.Context, userID int64, private bool) error
+	CreateComment(ctx context.Context, c models.Comment, limits []models.RateLimit) (int64, error)
+	UpdateComment(ctx context.Context, authorID, commentID int64, body, html string) error
+	DeleteComment(ctx context.Context, userID, commentID int64) error
+	MarkNotificationRead(ctx context.Context, userID, notificationID int64) error
+	MarkAllNotificationsRead(ctx context.Context, userID int64) (int64, error)
+	SetNotificationPreferences(ctx context.Context, userID int64, prefs []models.NotificationPreference) error
+	RecordActivity(ctx context.Context, a models.Activity) error
+	ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error)
+	UnshareCollection(ctx context.Context, ownerID, collectionID, shareID int64) error
+	LeaveCollection(ctx context.Context, userID, collectionID int64) error
+	CreateShareLink(ctx context.Context, ownerID int64, link models.ShareLink) (models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, ownerID, linkID int64) error
+	ViewShareLink(ctx context.Context, linkID int64) (models.PublicCollection, []models.Item, error)
+	AddItemTags(ctx context.Context, userID, itemID int64, tags []string) error
+	RemoveItemTag(ctx context.Context, userID, itemID int64, tag string) error
+	MergeTags(ctx context.Context, userID int64, from []string, into string) error
+	AddItemImage(ctx context.Context, userID, itemID int64, image models.Image) (models.Image, error)
+	SetPrimaryItemImage(ctx context.Context, userID, itemID, imageID int64) error
+	DeleteItemImage(ctx context.Context, userID, itemID, imageID int64) (models.Image, error)
+	AddCollectionImage(ctx context.Context, userID, collectionID int64, image models.Image) (models.Image, error)
+	DeleteCollectionImage(ctx context.Context, userID, collectionID, imageID int64) (models.Image, error)
+	SetUserAvatar(ctx context.Context, userID int64, key string) (string, error)
+	RestoreCollection(ctx context.Context, userID, collectionID int64) error
+	RestoreItem(ctx context.Context, userID, itemID int64) error
+	PurgeTrash(ctx context.Context, before time.Time, limit int) (int, []models.Image, error)
 }
 
 func New(
@@ -96,12 +230,23 @@ func New(
 	read_storage ReadStorage,
 	write_storage WriteStorage,
 	sso_client ssogrpc.Client,
+	blobs blob.Store,
+	signer *signedurl.Signer,
+	images *imaging.Processor,
+	maxUploadSize int64,
+	trashRetention time.Duration,
 ) *Service {
 	return &Service{
-		log:           log,
-		read_storage:  read_storage,
-		write_storage: write_storage,
-		sso_client:    sso_client,
+		log:            log,
+		read_storage:   read_storage,
+		write_storage:  write_storage,
+		sso_client:     sso_client,
+		blobs:          blobs,
+		signer:         signer,
+		images:         images,
+		maxUploadSize:  maxUploadSize,
+		trashRetention: trashRetention,
+		hub:            newNotificationHub(),
 	}
 }
 
@@ -118,6 +263,7 @@ func (s *Service) User(ctx context.Context, userID int64) (models.User, error) {
 	if err != nil {
 		return models.User{}, err
 	}
+	user.ImageUrl = s.imageURL(user.ImageUrl)
 
 	return user, nil
 }
@@ -128,15 +274,21 @@ func (s *Service) Login(ctx context.Context, email string) error {
 	return s.write_storage.Login(ctx, email)
 }
 
-func (s *Service) Users() ([]models.User, error) {
+// Users returns a page of users and the cursor of the next one.
+func (s *Service) Users(ctx context.Context, opts models.ListOptions) ([]models.User, string, error) {
 	s.log.Debug("Get users")
 
-	users, err := s.read_storage.Users()
+	opts, err := listOptions(opts, userSorts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	users, next, err := s.read_storage.Users(ctx, opts)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return users, nil
+	return users, encodeCursor(next, opts), nil
 }
 
 func (s *Service) UpdateUserInfo(
@@ -163,9 +315,26 @@ func (s *Service) SetCollection(
 ) (int64, error) {
 	s.log.Debug("Set collection", slog.String("user_id", strconv.Itoa(int(userID))))
 
+	// Anything else would be taken for a blob key and signed on every read,
+	// so covers of our own only come from UploadCollectionImage.
+	if image_url != "" && !isExternalURL(image_url) {
+		return 0, ValidationErrors{"cover_image_url": "must be an external URL; upload the cover as a collection image instead"}
+	}
+
+	if err := s.validateCategory(ctx, categoryID); err != nil {
+		return 0, err
+	}
+
 	collectionID, err := s.write_storage.SetCollection(ctx, userID, collectionName, description, image_url, categoryID, isPublic)
+	if err != nil {
+		return 0, err
+	}
+
+	if isPublic {
+		s.recordActivity(ctx, models.Activity{UserID: userID, Type: models.ActivityCollectionPublished, CollectionID: collectionID})
+	}
 
-	return collectionID, err
+	return collectionID, nil
 }
 
 func (s *Service) UpdateCollection(
@@ -179,7 +348,21 @@ func (s *Service) UpdateCollection(
 ) error {
 	s.log.Debug("Update collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
 
-	return s.write_storage.UpdateCollection(ctx, userID, collectionID, collectionName, description, categoryID, isPublic)
+	if err := s.validateCategory(ctx, categoryID); err != nil {
+		return err
+	}
+
+	err := s.write_storage.UpdateCollection(ctx, userID, collectionID, collectionName, description, categoryID, isPublic)
+	if err != nil {
+		return err
+	}
+
+	if isPublic {
+		s.matchWants(ctx, "collection_id", collectionID, s.write_storage.MatchCollection)
+		s.recordActivity(ctx, models.Activity{UserID: userID, Type: models.ActivityCollectionPublished, CollectionID: collectionID})
+	}
+
+	return nil
 }
 
 func (s *Service) DeleteCollection(ctx context.Context, userID, collectionID int64) error {
@@ -188,15 +371,25 @@ func (s *Service) DeleteCollection(ctx context.Context, userID, collectionID int
 	return s.write_storage.DeleteCollection(ctx, userID, collectionID)
 }
 
-func (s *Service) Collections(ctx context.Context, userID int64) ([]models.Collection, error) {
+// Collections returns a page of the user's collections and the cursor of the
+// next one.
+func (s *Service) Collections(ctx context.Context, userID int64, opts models.ListOptions) ([]models.Collection, string, error) {
 	s.log.Debug("Get collections", slog.String("user_id", strconv.Itoa(int(userID))))
 
-	collections, err := s.read_storage.Collections(ctx, userID)
+	opts, err := listOptions(opts, collectionSorts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	collections, next, err := s.read_storage.Collections(ctx, userID, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range collections {
+		collections[i].CollectionImageUrl = s.imageURL(collections[i].CollectionImageUrl)
 	}
 
-	return collections, nil
+	return collections, encodeCursor(next, opts), nil
 }
 
 func (s *Service) Collection(ctx context.Context, userID, collectionID int64) (models.Collection, error) {
@@ -206,6 +399,7 @@ func (s *Service) Collection(ctx context.Context, userID, collectionID int64) (m
 	if err != nil {
 		return models.Collection{}, err
 	}
+	collection.CollectionImageUrl = s.imageURL(collection.CollectionImageUrl)
 
 	return collection, nil
 }
@@ -220,15 +414,32 @@ func (s *Service) SetItem(
 	country string,
 	images []string,
 	year string,
-	attributes []string,
+	attributes models.Attributes,
 ) (int64, error) {
 	s.log.Debug("Set item", slog.String("collection_id", strconv.Itoa(int(collectionID))))
 
+	if err := s.validateCategory(ctx, category_id); err != nil {
+		return 0, err
+	}
+
+	attributes, err := s.validateAttributes(ctx, category_id, attributes)
+	if err != nil {
+		return 0, err
+	}
+
 	itemID, err := s.write_storage.SetItem(ctx, userID, collectionID, title, description, category_id, country, images, year, attributes)
 	if err != nil {
 		return 0, err
 	}
 
+	s.matchWants(ctx, "item_id", itemID, s.write_storage.MatchItem)
+	s.recordActivity(ctx, models.Activity{
+		UserID:       userID,
+		Type:         models.ActivityItemsAdded,
+		CollectionID: collectionID,
+		Items:        []models.ActivityItem{{ItemID: itemID}},
+	})
+
 	return itemID, nil
 }
 
@@ -243,22 +454,55 @@ func (s *Service) UpdateItem(
 	country string,
 	images []string,
 	year string,
-	attributes []string,
+	attributes models.Attributes,
 ) error {
 	s.log.Debug("Update item", slog.String("item_id", strconv.Itoa(int(itemID))))
-	return s.write_storage.UpdateItem(ctx, userID, collectionID, itemID, title, description, category_id, country, images, year, attributes)
+
+	if err := s.validateCategory(ctx, category_id); err != nil {
+		return err
+	}
+
+	attributes, err := s.validateAttributes(ctx, category_id, attributes)
+	if err != nil {
+		return err
+	}
+
+	err = s.write_storage.UpdateItem(ctx, userID, collectionID, itemID, title, description, category_id, country, images, year, attributes)
+	if err != nil {
+		return err
+	}
+
+	s.matchWants(ctx, "item_id", itemID, s.write_storage.MatchItem)
+
+	return nil
 }
 
-func (s *Service) DeleteItem(ctx context.Context, collectionID, itemID int64) error {
+func (s *Service) DeleteItem(ctx context.Context, userID, itemID int64) error {
 	s.log.Debug("Delete item", slog.String("item_id", strconv.Itoa(int(itemID))))
 
-	return s.write_storage.DeleteItem(ctx, collectionID, itemID)
+	return s.write_storage.DeleteItem(ctx, userID, itemID)
+}
+
+// DiscardItem deletes an item that was just created, with the blobs of its
+// images, without sending it to the trash.
+func (s *Service) DiscardItem(ctx context.Context, userID, itemID int64) error {
+	s.log.Debug("Discard item", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	images, err := s.write_storage.DiscardItem(ctx, userID, itemID)
+	if err != nil {
+		return err
+	}
+	for _, image := range images {
+		s.deleteBlobs(ctx, image.Keys())
+	}
+
+	return nil
 }
 
-func (s *Service) Item(ctx context.Context, collectionID, itemID int64) (models.Item, error) {
+func (s *Service) Item(ctx context.Context, userID, itemID int64) (models.Item, error) {
 	s.log.Debug("Get item", slog.String("item_id", strconv.Itoa(int(itemID))))
 
-	item, err := s.read_storage.Item(ctx, collectionID, itemID)
+	item, err := s.read_storage.Item(ctx, userID, itemID)
 	if err != nil {
 		return models.Item{}, err
 	}
@@ -266,39 +510,126 @@ func (s *Service) Item(ctx context.Context, collectionID, itemID int64) (models.
 	return item, nil
 }
 
-func (s *Service) Items(ctx context.Context, collectionID int64) ([]models.Item, error) {
+// Items returns a page of the items of a collection and the cursor of the
+// next one.
+func (s *Service) Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, string, error) {
 	s.log.Debug("Get items", slog.String("collection_id", strconv.Itoa(int(collectionID))))
 
-	items, err := s.read_storage.Items(ctx, collectionID)
+	opts, err := listOptions(opts, itemSorts)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	items, next, err := s.read_storage.Items(ctx, userID, collectionID, opts)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return items, nil
+	return items, encodeCursor(next, opts), nil
 }
 
-func (s *Service) Category(ctx context.Context) ([]models.Category, error) {
-	s.log.Debug("Get category")
+// Categories returns all categories arranged as a forest: root categories
+// with their subcategories nested in Children.
+func (s *Service) Categories(ctx context.Context) ([]models.Category, error) {
+	s.log.Debug("Get categories")
 
-	category, err := s.read_storage.Category(ctx)
+	categories, err := s.read_storage.Categories(ctx)
 	if err != nil {
-		return []models.Category{}, err
+		return nil, err
+	}
+
+	return buildCategoryTree(categories), nil
+}
+
+func buildCategoryTree(categories []models.Category) []models.Category {
+	children := make(map[int64][]models.Category, len(categories))
+	known := make(map[int64]bool, len(categories))
+	for _, c := range categories {
+		known[c.CategoryID] = true
+	}
+	for _, c := range categories {
+		parent := c.ParentID
+		if !known[parent] {
+			parent = 0
+		}
+		children[parent] = append(children[parent], c)
+	}
+
+	var attach func(parentID int64) []models.Category
+	attach = func(parentID int64) []models.Category {
+		nodes := children[parentID]
+		for i := range nodes {
+			nodes[i].Children = attach(nodes[i].CategoryID)
+		}
+		return nodes
 	}
 
-	return category, nil
+	return attach(0)
+}
+
+func (s *Service) Category(ctx context.Context, categoryID int64) (models.Category, error) {
+	s.log.Debug("Get category", slog.String("category_id", strconv.Itoa(int(categoryID))))
+
+	return s.read_storage.Category(ctx, categoryID)
 }
 
 func (s *Service) CreateCategory(
 	ctx context.Context,
 	categoryName string,
 	description string,
+	parentID int64,
+	schema []models.AttributeField,
 ) (int64, error) {
 	s.log.Debug("Create category", slog.String("category_name", categoryName))
 
-	categoryID, err := s.write_storage.CreateCategory(ctx, categoryName, description)
+	if err := s.validateCategory(ctx, parentID); err != nil {
+		return 0, err
+	}
+	if err := validateSchema(schema); err != nil {
+		return 0, err
+	}
+
+	categoryID, err := s.write_storage.CreateCategory(ctx, categoryName, description, parentID, schema)
 	if err != nil {
 		return 0, err
 	}
 
 	return categoryID, nil
 }
+
+func (s *Service) UpdateCategory(
+	ctx context.Context,
+	categoryID int64,
+	categoryName string,
+	description string,
+	parentID int64,
+	schema []models.AttributeField,
+) error {
+	s.log.Debug("Update category", slog.String("category_id", strconv.Itoa(int(categoryID))))
+
+	if err := s.validateCategory(ctx, parentID); err != nil {
+		return err
+	}
+	if err := validateSchema(schema); err != nil {
+		return err
+	}
+
+	return s.write_storage.UpdateCategory(ctx, categoryID, categoryName, description, parentID, schema)
+}
+
+func (s *Service) DeleteCategory(ctx context.Context, categoryID int64) error {
+	s.log.Debug("Delete category", slog.String("category_id", strconv.Itoa(int(categoryID))))
+
+	return s.write_storage.DeleteCategory(ctx, categoryID)
+}
+
+// validateCategory checks that a category referenced by a collection, item or
+// subcategory exists. Zero means "no category" and is always valid.
+func (s *Service) validateCategory(ctx context.Context, categoryID int64) error {
+	if categoryID == 0 {
+		return nil
+	}
+
+	_, err := s.read_storage.Category(ctx, categoryID)
+	return err
+}
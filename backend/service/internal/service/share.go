@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// maxInvitee bounds a username or email to share with.
+const maxInvitee = 255
+
+// ShareCollection shares a collection of the owner with invitee, a username
+// or an email, as role. Sharing again with the same invitee changes the role.
+func (s *Service) ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error) {
+	s.log.Debug("Share collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	errs := ValidationErrors{}
+	invitee = strings.TrimSpace(invitee)
+	switch n := utf8.RuneCountInString(invitee); {
+	case n == 0:
+		errs["user"] = "is required"
+	case n > maxInvitee:
+		errs["user"] = "must be at most 255 characters"
+	}
+	if !slices.Contains(models.ShareRoles, role) {
+		errs["role"] = "must be one of " + strings.Join(models.ShareRoles, ", ")
+	}
+	if len(errs) > 0 {
+		return models.CollectionShare{}, errs
+	}
+
+	return s.write_storage.ShareCollection(ctx, ownerID, collectionID, invitee, role)
+}
+
+func (s *Service) UnshareCollection(ctx context.Context, ownerID, collectionID, shareID int64) error {
+	s.log.Debug("Unshare collection", slog.String("share_id", strconv.Itoa(int(shareID))))
+
+	return s.write_storage.UnshareCollection(ctx, ownerID, collectionID, shareID)
+}
+
+// LeaveCollection drops a collection someone shared with the user.
+func (s *Service) LeaveCollection(ctx context.Context, userID, collectionID int64) error {
+	s.log.Debug("Leave collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.write_storage.LeaveCollection(ctx, userID, collectionID)
+}
+
+func (s *Service) CollectionShares(ctx context.Context, ownerID, collectionID int64) ([]models.CollectionShare, error) {
+	s.log.Debug("Get collection shares", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.read_storage.CollectionShares(ctx, ownerID, collectionID)
+}
+
+// SharedCollections returns the collections others shared with the user.
+func (s *Service) SharedCollections(ctx context.Context, userID int64) ([]models.SharedCollection, error) {
+	s.log.Debug("Get shared collections", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	shared, err := s.read_storage.SharedCollections(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		shared[i].Collection.CollectionImageUrl = s.imageURL(shared[i].Collection.CollectionImageUrl)
+	}
+
+	return shared, nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareCollection(t *testing.T) {
+	s := newTestService(t)
+
+	share, err := s.ShareCollection(context.Background(), sellerID, 3, "  collector  ", models.RoleEditor)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), share.ShareID)
+	assert.Equal(t, "collector", s.write.invitee)
+	assert.Equal(t, models.RoleEditor, s.write.role)
+}
+
+func TestShareCollection_Validates(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.ShareCollection(context.Background(), sellerID, 3, " ", models.RoleOwner)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "user")
+	assert.Contains(t, errs, "role")
+	assert.Empty(t, s.write.invitee)
+}
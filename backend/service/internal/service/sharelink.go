@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	defaultShareLinkTTL = 7 * 24 * time.Hour
+	maxShareLinkTTL     = 90 * 24 * time.Hour
+	// bcrypt ignores what follows the first 72 bytes of a password.
+	maxShareLinkPassword = 72
+)
+
+// shareLinkToken returns a new random token and the hash stored for it.
+func shareLinkToken() (string, []byte, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashShareLinkToken(token), nil
+}
+
+func hashShareLinkToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// CreateShareLink makes a link to a collection of the owner that expires
+// after ttl, a week when ttl is 0. A password, when given, is asked for on
+// every view; maxViews of 0 puts no limit on them.
+func (s *Service) CreateShareLink(ctx context.Context, ownerID, collectionID int64, ttl time.Duration, password string, maxViews int64) (models.ShareLink, error) {
+	s.log.Debug("Create share link", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	errs := ValidationErrors{}
+	if ttl == 0 {
+		ttl = defaultShareLinkTTL
+	}
+	if ttl < time.Minute || ttl > maxShareLinkTTL {
+		errs["expires_in"] = "must be between a minute and 90 days"
+	}
+	if len(password) > maxShareLinkPassword {
+		errs["password"] = "must be at most 72 bytes"
+	}
+	if maxViews < 0 {
+		errs["max_views"] = "must not be negative"
+	}
+	if len(errs) > 0 {
+		return models.ShareLink{}, errs
+	}
+
+	token, hash, err := shareLinkToken()
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	link := models.ShareLink{
+		CollectionID: collectionID,
+		TokenHash:    hash,
+		ExpiresAt:    time.Now().Add(ttl),
+		MaxViews:     maxViews,
+	}
+	if password != "" {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return models.ShareLink{}, err
+		}
+		link.PasswordHash = string(passwordHash)
+	}
+
+	link, err = s.write_storage.CreateShareLink(ctx, ownerID, link)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	link.Token = token
+
+	return link, nil
+}
+
+// ShareLinks returns the outstanding links of the owner, to collectionID
+// only unless it is 0.
+func (s *Service) ShareLinks(ctx context.Context, ownerID, collectionID int64) ([]models.ShareLink, error) {
+	s.log.Debug("Get share links", slog.String("user_id", strconv.Itoa(int(ownerID))))
+
+	return s.read_storage.ShareLinks(ctx, ownerID, collectionID)
+}
+
+func (s *Service) RevokeShareLink(ctx context.Context, ownerID, linkID int64) error {
+	s.log.Debug("Revoke share link", slog.String("link_id", strconv.Itoa(int(linkID))))
+
+	return s.write_storage.RevokeShareLink(ctx, ownerID, linkID)
+}
+
+// OpenShareLink resolves a token to the collection it shares and its items,
+// counting a view. A wrong password counts none.
+func (s *Service) OpenShareLink(ctx context.Context, token, password string) (models.PublicCollection, []models.Item, error) {
+	s.log.Debug("Open share link")
+
+	link, err := s.read_storage.ShareLink(ctx, hashShareLinkToken(token))
+	if err != nil {
+		return models.PublicCollection{}, nil, err
+	}
+	if link.HasPassword {
+		err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return models.PublicCollection{}, nil, storage.ErrShareLinkPassword
+		}
+		if err != nil {
+			return models.PublicCollection{}, nil, err
+		}
+	}
+
+	collection, items, err := s.write_storage.ViewShareLink(ctx, link.LinkID)
+	if err != nil {
+		return models.PublicCollection{}, nil, err
+	}
+	collection.CollectionImageUrl = s.imageURL(collection.CollectionImageUrl)
+
+	return collection, items, nil
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShareLink(t *testing.T) {
+	s := newTestService(t)
+
+	link, err := s.CreateShareLink(context.Background(), sellerID, 3, 0, "", 5)
+	require.NoError(t, err)
+	assert.Len(t, link.Token, 43)
+	assert.Equal(t, hashShareLinkToken(link.Token), link.TokenHash)
+	assert.False(t, link.HasPassword)
+	assert.WithinDuration(t, time.Now().Add(defaultShareLinkTTL), link.ExpiresAt, time.Minute)
+
+	other, err := s.CreateShareLink(context.Background(), sellerID, 3, time.Hour, "", 0)
+	require.NoError(t, err)
+	assert.NotEqual(t, link.Token, other.Token)
+}
+
+func TestCreateShareLink_Validates(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.CreateShareLink(context.Background(), sellerID, 3, maxShareLinkTTL+time.Hour, "", -1)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "expires_in")
+	assert.Contains(t, errs, "max_views")
+}
+
+func TestOpenShareLink_Password(t *testing.T) {
+	s := newTestService(t)
+
+	link, err := s.CreateShareLink(context.Background(), sellerID, 3, time.Hour, "appraise", 0)
+	require.NoError(t, err)
+	assert.True(t, link.HasPassword)
+	assert.NotContains(t, link.PasswordHash, "appraise")
+
+	_, _, err = s.OpenShareLink(context.Background(), link.Token, "guess")
+	assert.ErrorIs(t, err, storage.ErrShareLinkPassword)
+	assert.Empty(t, s.write.viewed, "a wrong password must not use up a view")
+
+	collection, items, err := s.OpenShareLink(context.Background(), link.Token, "appraise")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), collection.CollectionID)
+	assert.Len(t, items, 1)
+	assert.Equal(t, []int64{link.LinkID}, s.write.viewed)
+
+	_, _, err = s.OpenShareLink(context.Background(), "unknown", "")
+	assert.ErrorIs(t, err, storage.ErrShareLinkNotFound)
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+func (s *Service) FavoriteItem(ctx context.Context, userID, itemID int64) error {
+	s.log.Debug("Favorite item", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	return s.write_storage.FavoriteItem(ctx, userID, itemID)
+}
+
+func (s *Service) UnfavoriteItem(ctx context.Context, userID, itemID int64) error {
+	s.log.Debug("Unfavorite item", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	return s.write_storage.Unfavorite(ctx, userID, itemID, 0)
+}
+
+func (s *Service) FavoriteCollection(ctx context.Context, userID, collectionID int64) error {
+	s.log.Debug("Favorite collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.write_storage.FavoriteCollection(ctx, userID, collectionID)
+}
+
+func (s *Service) UnfavoriteCollection(ctx context.Context, userID, collectionID int64) error {
+	s.log.Debug("Unfavorite collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.write_storage.Unfavorite(ctx, userID, 0, collectionID)
+}
+
+// Favorites returns the public items and collections the user marked.
+func (s *Service) Favorites(ctx context.Context, userID int64) (models.Favorites, error) {
+	s.log.Debug("Get favorites", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	favorites, err := s.read_storage.Favorites(ctx, userID)
+	if err != nil {
+		return models.Favorites{}, err
+	}
+	favorites.Collections = s.withPublicURLs(favorites.Collections)
+
+	return favorites, nil
+}
+
+func (s *Service) Follow(ctx context.Context, userID int64, username string) error {
+	s.log.Debug("Follow", slog.String("username", username))
+
+	return s.write_storage.Follow(ctx, userID, strings.TrimSpace(username))
+}
+
+func (s *Service) Unfollow(ctx context.Context, userID int64, username string) error {
+	s.log.Debug("Unfollow", slog.String("username", username))
+
+	return s.write_storage.Unfollow(ctx, userID, strings.TrimSpace(username))
+}
+
+// Followers returns the user's own followers. They are visible to the user
+// even when hidden from others.
+func (s *Service) Followers(ctx context.Context, userID int64) ([]models.Follow, error) {
+	s.log.Debug("Get followers", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	follows, err := s.read_storage.Followers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withAvatars(follows), nil
+}
+
+func (s *Service) Following(ctx context.Context, userID int64) ([]models.Follow, error) {
+	s.log.Debug("Get following", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	follows, err := s.read_storage.Following(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withAvatars(follows), nil
+}
+
+func (s *Service) PublicFollowers(ctx context.Context, username string) ([]models.Follow, error) {
+	s.log.Debug("Get public followers", slog.String("username", username))
+
+	follows, err := s.read_storage.PublicFollowers(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withAvatars(follows), nil
+}
+
+func (s *Service) PublicFollowing(ctx context.Context, username string) ([]models.Follow, error) {
+	s.log.Debug("Get public following", slog.String("username", username))
+
+	follows, err := s.read_storage.PublicFollowing(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.withAvatars(follows), nil
+}
+
+// SetFollowersPrivate hides the user's followers from other users, or
+// shows them again. The counts stay public.
+func (s *Service) SetFollowersPrivate(ctx context.Context, userID int64, private bool) error {
+	s.log.Debug("Set followers privacy", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.write_storage.SetFollowersPrivate(ctx, userID, private)
+}
+
+func (s *Service) withAvatars(follows []models.Follow) []models.Follow {
+	for i := range follows {
+		follows[i].ImageUrl = s.imageURL(follows[i].ImageUrl)
+	}
+	return follows
+}
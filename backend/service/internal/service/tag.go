@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	maxTagLength             = 50
+	defaultAutocompleteLimit = 10
+)
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// normalizeTag lower-cases the tag and collapses runs of whitespace into a
+// single space, so that "Russian  Empire " and "russian empire" are the same
+// tag.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// normalizeTags normalizes every tag and drops duplicates, keeping order.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidTag
+	}
+	return normalized, nil
+}
+
+func (s *Service) AddItemTags(ctx context.Context, userID, itemID int64, tags []string) ([]string, error) {
+	s.log.Debug("Add item tags", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.write_storage.AddItemTags(ctx, userID, itemID, tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (s *Service) RemoveItemTag(ctx context.Context, userID, itemID int64, tag string) error {
+	s.log.Debug("Remove item tag", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return err
+	}
+
+	return s.write_storage.RemoveItemTag(ctx, userID, itemID, tag)
+}
+
+func (s *Service) Tags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	s.log.Debug("Get tags", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.read_storage.Tags(ctx, userID)
+}
+
+func (s *Service) AutocompleteTags(ctx context.Context, userID int64, prefix string, limit int) ([]models.Tag, error) {
+	s.log.Debug("Autocomplete tags", slog.String("prefix", prefix))
+
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	if prefix == "" {
+		return nil, ErrInvalidTag
+	}
+	if limit <= 0 || limit > 50 {
+		limit = defaultAutocompleteLimit
+	}
+
+	return s.read_storage.AutocompleteTags(ctx, userID, prefix, limit)
+}
+
+func (s *Service) ItemsByTags(ctx context.Context, userID int64, tags []string) ([]models.Item, error) {
+	s.log.Debug("Get items by tags", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.read_storage.ItemsByTags(ctx, userID, tags)
+}
+
+// MergeTags moves the user's items from the from tags to into. A rename is
+// a merge of one tag.
+func (s *Service) MergeTags(ctx context.Context, userID int64, from []string, into string) (string, error) {
+	s.log.Debug("Merge tags", slog.String("into", into))
+
+	from, err := normalizeTags(from)
+	if err != nil {
+		return "", err
+	}
+	into, err = normalizeTag(into)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.write_storage.MergeTags(ctx, userID, from, into); err != nil {
+		return "", err
+	}
+
+	return into, nil
+}
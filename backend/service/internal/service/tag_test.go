@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "lower case", tag: "Silver", expected: "silver"},
+		{name: "whitespace", tag: "  Russian \t Empire ", expected: "russian empire"},
+		{name: "cyrillic", tag: "Российская Империя", expected: "российская империя"},
+		{name: "empty", tag: "   ", wantErr: true},
+		{name: "too long", tag: string(make([]rune, maxTagLength+1)), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := normalizeTag(tt.tag)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidTag)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+}
+
+func TestNormalizeTags_Deduplicates(t *testing.T) {
+	tags, err := normalizeTags([]string{"Silver", "silver ", "Gold"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"silver", "gold"}, tags)
+
+	_, err = normalizeTags(nil)
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	maxTradeItems   = 50
+	maxTradeMessage = 1000
+)
+
+// tradeItemIDs deduplicates the item ids of one side of a trade and records
+// a problem with them under key.
+func tradeItemIDs(ids []int64, key string, errs ValidationErrors) []int64 {
+	out := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 {
+			errs[key] = "must be item ids"
+			return nil
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+
+	switch {
+	case len(out) == 0:
+		errs[key] = "must list at least one item"
+	case len(out) > maxTradeItems:
+		errs[key] = "must list at most 50 items"
+	}
+	return out
+}
+
+func normalizeTrade(t models.Trade) (models.Trade, error) {
+	errs := ValidationErrors{}
+
+	t.OfferedItemIDs = tradeItemIDs(t.OfferedItemIDs, "offered_item_ids", errs)
+	t.RequestedItemIDs = tradeItemIDs(t.RequestedItemIDs, "requested_item_ids", errs)
+	if t.ProposerCollectionID <= 0 {
+		errs["collection_id"] = "is required"
+	}
+
+	t.Message = strings.TrimSpace(t.Message)
+	if utf8.RuneCountInString(t.Message) > maxTradeMessage {
+		errs["message"] = "must be at most 1000 characters"
+	}
+
+	if len(errs) > 0 {
+		return models.Trade{}, errs
+	}
+	return t, nil
+}
+
+// ProposeTrade offers items of the user for public items of another
+// collector. The other collector is the owner of the requested items.
+func (s *Service) ProposeTrade(ctx context.Context, userID int64, t models.Trade) (int64, error) {
+	s.log.Debug("Propose trade", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	t.ProposerID = userID
+	t.ParentID = 0
+	t, err := normalizeTrade(t)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.write_storage.CreateTrade(ctx, t)
+}
+
+// pendingTrade returns the trade the user is about to answer. Only the
+// parties see a trade and only its recipient answers it while it is
+// pending.
+func (s *Service) pendingTrade(ctx context.Context, userID, tradeID int64) (models.Trade, error) {
+	t, err := s.Trade(ctx, userID, tradeID)
+	if err != nil {
+		return models.Trade{}, err
+	}
+	if t.Status != models.TradePending {
+		return models.Trade{}, storage.ErrTradeClosed
+	}
+	if t.RecipientID != userID {
+		return models.Trade{}, ErrNotYourTurn
+	}
+
+	return t, nil
+}
+
+// CounterTrade answers a pending trade with other items. The counter goes
+// back to the proposer, with the user's collection as the target of the
+// items they would get.
+func (s *Service) CounterTrade(ctx context.Context, userID, tradeID int64, counter models.Trade) (int64, error) {
+	s.log.Debug("Counter trade", slog.String("trade_id", strconv.Itoa(int(tradeID))))
+
+	if _, err := s.pendingTrade(ctx, userID, tradeID); err != nil {
+		return 0, err
+	}
+
+	counter.ProposerID = userID
+	counter, err := normalizeTrade(counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.write_storage.CounterTrade(ctx, tradeID, counter)
+}
+
+// AcceptTrade closes a pending trade: the offered items move into
+// collectionID of the user, the requested ones into the proposer's target
+// collection.
+func (s *Service) AcceptTrade(ctx context.Context, userID, tradeID, collectionID int64) error {
+	s.log.Debug("Accept trade", slog.String("trade_id", strconv.Itoa(int(tradeID))))
+
+	if collectionID <= 0 {
+		return ValidationErrors{"collection_id": "is required"}
+	}
+	if _, err := s.pendingTrade(ctx, userID, tradeID); err != nil {
+		return err
+	}
+
+	return s.write_storage.AcceptTrade(ctx, tradeID, collectionID)
+}
+
+func (s *Service) DeclineTrade(ctx context.Context, userID, tradeID int64) error {
+	s.log.Debug("Decline trade", slog.String("trade_id", strconv.Itoa(int(tradeID))))
+
+	if _, err := s.pendingTrade(ctx, userID, tradeID); err != nil {
+		return err
+	}
+
+	return s.write_storage.DeclineTrade(ctx, tradeID)
+}
+
+// Trade returns a trade the user proposed or received.
+func (s *Service) Trade(ctx context.Context, userID, tradeID int64) (models.Trade, error) {
+	s.log.Debug("Get trade", slog.String("trade_id", strconv.Itoa(int(tradeID))))
+
+	t, err := s.read_storage.Trade(ctx, tradeID)
+	if err != nil {
+		return models.Trade{}, err
+	}
+	if t.ProposerID != userID && t.RecipientID != userID {
+		return models.Trade{}, storage.ErrTradeNotFound
+	}
+
+	return t, nil
+}
+
+func (s *Service) Trades(ctx context.Context, userID int64) ([]models.Trade, error) {
+	s.log.Debug("Get trades", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.read_storage.Trades(ctx, userID)
+}
+
+// ItemTransfers returns the history of an item of the user changing hands.
+func (s *Service) ItemTransfers(ctx context.Context, userID, itemID int64) ([]models.ItemTransfer, error) {
+	s.log.Debug("Get item transfers", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	return s.read_storage.ItemTransfers(ctx, userID, itemID)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposeTrade(t *testing.T) {
+	s := newTestService(t)
+
+	_, err := s.ProposeTrade(context.Background(), buyerID, models.Trade{
+		OfferedItemIDs:       []int64{4, 4, 5},
+		RequestedItemIDs:     []int64{9},
+		ProposerCollectionID: 10,
+		Message:              " swap? ",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(buyerID), s.write.trade.ProposerID)
+	assert.Equal(t, []int64{4, 5}, s.write.trade.OfferedItemIDs)
+	assert.Equal(t, "swap?", s.write.trade.Message)
+
+	_, err = s.ProposeTrade(context.Background(), buyerID, models.Trade{OfferedItemIDs: []int64{0}})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "offered_item_ids")
+	assert.Contains(t, errs, "requested_item_ids")
+	assert.Contains(t, errs, "collection_id")
+}
+
+func TestAcceptTrade_Turns(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	assert.ErrorIs(t, s.AcceptTrade(ctx, buyerID, 3, 10), ErrNotYourTurn)
+	assert.ErrorIs(t, s.AcceptTrade(ctx, 99, 3, 10), storage.ErrTradeNotFound)
+
+	var errs ValidationErrors
+	assert.ErrorAs(t, s.AcceptTrade(ctx, sellerID, 3, 0), &errs)
+
+	require.NoError(t, s.AcceptTrade(ctx, sellerID, 3, 20))
+	assert.Equal(t, int64(20), s.write.acceptedCollection)
+}
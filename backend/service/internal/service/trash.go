@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// Trash lists the collections and items the user can restore, with when
+// each of them is purged.
+func (s *Service) Trash(ctx context.Context, userID int64) (models.Trash, error) {
+	s.log.Debug("Get trash", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	trash, err := s.read_storage.Trash(ctx, userID)
+	if err != nil {
+		return models.Trash{}, err
+	}
+	for i := range trash.Collections {
+		trash.Collections[i].PurgeAt = trash.Collections[i].DeletedAt.Add(s.trashRetention)
+	}
+	for i := range trash.Items {
+		trash.Items[i].PurgeAt = trash.Items[i].DeletedAt.Add(s.trashRetention)
+	}
+
+	return trash, nil
+}
+
+// RestoreCollection takes a collection of the user, and the items deleted
+// with it, out of the trash.
+func (s *Service) RestoreCollection(ctx context.Context, userID, collectionID int64) error {
+	s.log.Debug("Restore collection", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.write_storage.RestoreCollection(ctx, userID, collectionID)
+}
+
+// RestoreItem takes an item out of the trash and returns it.
+func (s *Service) RestoreItem(ctx context.Context, userID, itemID int64) (models.Item, error) {
+	s.log.Debug("Restore item", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	if err := s.write_storage.RestoreItem(ctx, userID, itemID); err != nil {
+		return models.Item{}, err
+	}
+
+	return s.read_storage.Item(ctx, userID, itemID)
+}
+
+// PurgeTrash deletes for good what has been in the trash for longer than
+// the retention period, batch by batch, along with the blobs of its images,
+// and returns how many collections and items it purged.
+func (s *Service) PurgeTrash(ctx context.Context, batch int) (int, error) {
+	before := time.Now().Add(-s.trashRetention)
+
+	var total int
+	for {
+		purged, images, err := s.write_storage.PurgeTrash(ctx, before, batch)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+		for _, image := range images {
+			s.deleteBlobs(ctx, image.Keys())
+		}
+		// A run purges up to batch collections and as many items; once it
+		// purges neither, nothing expired is left that is not being
+		// restored.
+		if purged == 0 {
+			return total, nil
+		}
+	}
+}
+
+// RunTrashPurger purges expired trash every interval until ctx is done.
+func (s *Service) RunTrashPurger(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeTrash(ctx, batch)
+			if err != nil {
+				s.log.Error("failed to purge trash", slog.String("err", err.Error()))
+			}
+			if purged > 0 {
+				s.log.Info("trash purged", slog.Int("purged", purged))
+			}
+		}
+	}
+}
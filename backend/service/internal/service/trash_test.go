@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash_PurgeAt(t *testing.T) {
+	s := newTestService(t)
+
+	trash, err := s.Trash(context.Background(), sellerID)
+	require.NoError(t, err)
+	require.Len(t, trash.Collections, 1)
+	require.Len(t, trash.Items, 1)
+	assert.Equal(t, deletedAt.Add(testRetention), trash.Collections[0].PurgeAt)
+	assert.Equal(t, deletedAt.Add(time.Hour+testRetention), trash.Items[0].PurgeAt)
+}
+
+func TestRestoreItem(t *testing.T) {
+	s := newTestService(t)
+
+	item, err := s.RestoreItem(context.Background(), sellerID, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "Penny Black", item.Title)
+}
+
+func TestPurgeTrash_DeletesBlobsBatchByBatch(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"items/3/abc", "items/3/abc-thumb", "items/4/def", "collections/2/ghi"} {
+		require.NoError(t, s.blobs.Put(ctx, key, strings.NewReader(key), int64(len(key)), "image/jpeg"))
+	}
+	s.write.purgeBatches = [][]models.Image{
+		{{ItemID: 3, Key: "items/3/abc", ThumbKey: "items/3/abc-thumb"}, {ItemID: 4, Key: "items/4/def"}},
+		{{CollectionID: 2, Key: "collections/2/ghi"}},
+	}
+
+	start := time.Now()
+	purged, err := s.PurgeTrash(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.Equal(t, 2, s.write.purgeCalls)
+	assert.Equal(t, 2, s.write.purgeLimit)
+	assert.WithinDuration(t, start.Add(-testRetention), s.write.purgeBefore, time.Minute)
+
+	for _, key := range []string{"items/3/abc", "items/3/abc-thumb", "items/4/def", "collections/2/ghi"} {
+		_, _, err := s.blobs.Get(ctx, key)
+		assert.Error(t, err, key)
+	}
+}
+
+func TestDiscardItem_DeletesBlobs(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"items/3/abc", "items/3/abc-thumb"} {
+		require.NoError(t, s.blobs.Put(ctx, key, strings.NewReader(key), int64(len(key)), "image/jpeg"))
+	}
+
+	require.NoError(t, s.DiscardItem(ctx, sellerID, 3))
+	for _, key := range []string{"items/3/abc", "items/3/abc-thumb"} {
+		_, _, err := s.blobs.Get(ctx, key)
+		assert.Error(t, err, key)
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// ItemHistory returns the versions of an item, newest first. The history
+// of a deleted item stays readable to whoever may view its collection.
+func (s *Service) ItemHistory(ctx context.Context, userID, itemID int64) ([]models.Version, error) {
+	s.log.Debug("Get item history", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	return s.read_storage.Versions(ctx, userID, models.EntityItem, itemID)
+}
+
+// CollectionHistory returns the versions of a collection, newest first.
+func (s *Service) CollectionHistory(ctx context.Context, userID, collectionID int64) ([]models.Version, error) {
+	s.log.Debug("Get collection history", slog.String("collection_id", strconv.Itoa(int(collectionID))))
+
+	return s.read_storage.Versions(ctx, userID, models.EntityCollection, collectionID)
+}
+
+// RevertItem puts an item back the way it was in version and returns it.
+// The attributes of the version are checked against the schema its
+// category has now, which may have changed since.
+func (s *Service) RevertItem(ctx context.Context, userID, itemID int64, version int) (models.Item, error) {
+	s.log.Debug("Revert item", slog.String("item_id", strconv.Itoa(int(itemID))))
+
+	if version <= 0 {
+		return models.Item{}, ValidationErrors{"version": "must be positive"}
+	}
+
+	check := func(item models.Item) (models.Attributes, error) {
+		return s.validateAttributes(ctx, item.CategoryID, item.Attributes)
+	}
+	if err := s.write_storage.RevertItem(ctx, userID, itemID, version, check); err != nil {
+		return models.Item{}, err
+	}
+
+	s.matchWants(ctx, "item_id", itemID, s.write_storage.MatchItem)
+
+	return s.read_storage.Item(ctx, userID, itemID)
+}
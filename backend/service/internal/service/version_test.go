@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevertItem(t *testing.T) {
+	s := newTestService(t)
+	s.write.snapshot = models.Item{CategoryID: 7, Attributes: models.Attributes{"denomination": "1 rouble"}}
+
+	item, err := s.RevertItem(context.Background(), sellerID, 3, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.write.reverted)
+	assert.Equal(t, int64(3), item.ItemID)
+}
+
+func TestRevertItem_ChecksAttributesAgainstCategoryNow(t *testing.T) {
+	s := newTestService(t)
+	s.write.snapshot = models.Item{CategoryID: 7, Attributes: models.Attributes{"metal": "silver"}}
+
+	_, err := s.RevertItem(context.Background(), sellerID, 3, 2)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "attributes.denomination")
+	assert.Zero(t, s.write.reverted)
+
+	_, err = s.RevertItem(context.Background(), sellerID, 3, 0)
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "version")
+}
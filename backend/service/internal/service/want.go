@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+const (
+	maxWantTitle    = 200
+	maxWantKeywords = 500
+	maxWantCountry  = 100
+)
+
+func normalizeWant(w models.Want) (models.Want, error) {
+	errs := ValidationErrors{}
+
+	w.Title = strings.TrimSpace(w.Title)
+	switch n := utf8.RuneCountInString(w.Title); {
+	case n == 0:
+		errs["title"] = "is required"
+	case n > maxWantTitle:
+		errs["title"] = "must be at most 200 characters"
+	}
+
+	w.Keywords = strings.TrimSpace(w.Keywords)
+	if utf8.RuneCountInString(w.Keywords) > maxWantKeywords {
+		errs["keywords"] = "must be at most 500 characters"
+	}
+
+	w.Country = strings.TrimSpace(w.Country)
+	if utf8.RuneCountInString(w.Country) > maxWantCountry {
+		errs["country"] = "must be at most 100 characters"
+	}
+
+	if w.CategoryID < 0 {
+		errs["category_id"] = "must be a category id"
+	}
+	if w.YearFrom < 0 {
+		errs["year_from"] = "must not be negative"
+	}
+	if w.YearTo < 0 || (w.YearTo != 0 && w.YearTo < w.YearFrom) {
+		errs["year_to"] = "must not be before year_from"
+	}
+
+	w.Currency = strings.ToUpper(strings.TrimSpace(w.Currency))
+	switch {
+	case w.MaxPrice < 0:
+		errs["max_price"] = "must be positive, in minor units of the currency"
+	case w.MaxPrice == 0:
+		if w.Currency != "" {
+			errs["currency"] = "must be empty without max_price"
+		}
+	case !currencyRe.MatchString(w.Currency):
+		errs["currency"] = "must be a three-letter ISO 4217 code"
+	}
+
+	if len(errs) > 0 {
+		return models.Want{}, errs
+	}
+	return w, nil
+}
+
+// wantAttributes checks the attribute constraints of a want. Unlike an
+// item, a want names only the attributes it cares about, so required fields
+// of the category schema may be left out.
+func (s *Service) wantAttributes(ctx context.Context, categoryID int64, attributes models.Attributes) (models.Attributes, error) {
+	var schema []models.AttributeField
+	if categoryID != 0 {
+		var err error
+		if schema, err = s.read_storage.CategorySchema(ctx, categoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(schema) == 0 {
+		return checkAttributes(nil, attributes)
+	}
+
+	fields := make(map[string]models.AttributeField, len(schema))
+	for _, field := range schema {
+		fields[field.Key] = field
+	}
+
+	errs := ValidationErrors{}
+	out := make(models.Attributes, len(attributes))
+	for key, value := range attributes {
+		if str, ok := value.(string); ok {
+			value = strings.TrimSpace(str)
+		}
+		if value == nil || value == "" {
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			errs["attributes."+key] = "is not defined for this category"
+			continue
+		}
+		if msg := checkValue(field, value); msg != "" {
+			errs["attributes."+key] = msg
+			continue
+		}
+		out[key] = value
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return out, nil
+}
+
+func (s *Service) validateWant(ctx context.Context, w models.Want) (models.Want, error) {
+	w, err := normalizeWant(w)
+	if err != nil {
+		return models.Want{}, err
+	}
+
+	if err := s.validateCategory(ctx, w.CategoryID); err != nil {
+		return models.Want{}, err
+	}
+
+	if w.Attributes, err = s.wantAttributes(ctx, w.CategoryID, w.Attributes); err != nil {
+		return models.Want{}, err
+	}
+
+	return w, nil
+}
+
+// CreateWant adds an entry to the user's want list. It is matched against
+// the public items and lots right away.
+func (s *Service) CreateWant(ctx context.Context, userID int64, w models.Want) (int64, error) {
+	s.log.Debug("Create want", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	w.UserID = userID
+	w, err := s.validateWant(ctx, w)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.write_storage.CreateWant(ctx, w)
+}
+
+func (s *Service) UpdateWant(ctx context.Context, userID int64, w models.Want) error {
+	s.log.Debug("Update want", slog.String("want_id", strconv.Itoa(int(w.WantID))))
+
+	w.UserID = userID
+	w, err := s.validateWant(ctx, w)
+	if err != nil {
+		return err
+	}
+
+	return s.write_storage.UpdateWant(ctx, w)
+}
+
+func (s *Service) DeleteWant(ctx context.Context, userID, wantID int64) error {
+	s.log.Debug("Delete want", slog.String("want_id", strconv.Itoa(int(wantID))))
+
+	return s.write_storage.DeleteWant(ctx, userID, wantID)
+}
+
+func (s *Service) Want(ctx context.Context, userID, wantID int64) (models.Want, error) {
+	s.log.Debug("Get want", slog.String("want_id", strconv.Itoa(int(wantID))))
+
+	return s.read_storage.Want(ctx, userID, wantID)
+}
+
+func (s *Service) Wants(ctx context.Context, userID int64) ([]models.Want, error) {
+	s.log.Debug("Get wants", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	return s.read_storage.Wants(ctx, userID)
+}
+
+// WantMatches returns what was found for the user's want list, or for one
+// want of it when wantID is not 0.
+func (s *Service) WantMatches(ctx context.Context, userID, wantID int64) ([]models.WantMatch, error) {
+	s.log.Debug("Get want matches", slog.String("user_id", strconv.Itoa(int(userID))))
+
+	if wantID != 0 {
+		if _, err := s.read_storage.Want(ctx, userID, wantID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.read_storage.WantMatches(ctx, userID, wantID)
+}
+
+// matchWants runs a matcher after a write went through. The write stands
+// even if matching fails: the item or lot is matched again on its next
+// change, and a want on its next edit.
+func (s *Service) matchWants(ctx context.Context, what string, id int64, match func(context.Context, int64) error) {
+	if err := match(ctx, id); err != nil {
+		s.log.Error("failed to match wants", slog.String(what, strconv.Itoa(int(id))), slog.String("err", err.Error()))
+	}
+}
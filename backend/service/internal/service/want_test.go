@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateWant_Normalizes(t *testing.T) {
+	s := newTestService(t)
+	s.read.schema = []models.AttributeField{
+		{Key: "metal", Type: models.AttributeEnum, Enum: []string{"silver", "gold"}, Required: true},
+		{Key: "mint", Type: models.AttributeString, Required: true},
+	}
+
+	_, err := s.CreateWant(context.Background(), buyerID, models.Want{
+		Title:      " 1 rouble 1913 ",
+		CategoryID: 3,
+		YearFrom:   1900,
+		YearTo:     1917,
+		MaxPrice:   50000,
+		Currency:   "rub",
+		Attributes: models.Attributes{"metal": "silver", "mint": " "},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(buyerID), s.write.want.UserID)
+	assert.Equal(t, "1 rouble 1913", s.write.want.Title)
+	assert.Equal(t, "RUB", s.write.want.Currency)
+	// Required fields of the schema may be left out of a want.
+	assert.Equal(t, models.Attributes{"metal": "silver"}, s.write.want.Attributes)
+}
+
+func TestCreateWant_Invalid(t *testing.T) {
+	s := newTestService(t)
+	s.read.schema = []models.AttributeField{
+		{Key: "metal", Type: models.AttributeEnum, Enum: []string{"silver", "gold"}},
+	}
+
+	_, err := s.CreateWant(context.Background(), buyerID, models.Want{
+		YearFrom: 1917,
+		YearTo:   1900,
+		Currency: "RUB",
+	})
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "title")
+	assert.Contains(t, errs, "year_to")
+	assert.Contains(t, errs, "currency")
+
+	_, err = s.CreateWant(context.Background(), buyerID, models.Want{
+		Title:      "rouble",
+		CategoryID: 3,
+		Attributes: models.Attributes{"metal": "copper", "weight": 20.0},
+	})
+	require.ErrorAs(t, err, &errs)
+	assert.Contains(t, errs, "attributes.metal")
+	assert.Contains(t, errs, "attributes.weight")
+}
+
+func TestSetItem_MatchesWants(t *testing.T) {
+	s := newTestService(t)
+
+	itemID, err := s.SetItem(context.Background(), sellerID, 10, "rouble", "", 0, "", nil, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{itemID}, s.write.matchedItems)
+
+	// A failing matcher does not fail the s.write.
+	s.write.matchErr = errors.New("boom")
+	_, err = s.SetItem(context.Background(), sellerID, 10, "rouble", "", 0, "", nil, "", nil)
+	assert.NoError(t, err)
+}
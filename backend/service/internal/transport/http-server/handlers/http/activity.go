@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+)
+
+type FeedResponse struct {
+	resp.Response
+	Activities []models.Activity `json:"activities"`
+	// NextBefore is the before of the next page, 0 on the last one.
+	NextBefore int64  `json:"next_before,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// Feed returns the activity feed of the user, newest first. It reads
+// ?before=&limit=; before is the next_before of the previous page.
+func (h *handler) Feed(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Feed"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		query := r.URL.Query()
+		before, err := queryID(query, "before")
+		if err != nil {
+			log.Error("failed to parse before", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse before %d", http.StatusBadRequest)))
+			return
+		}
+		limit, err := queryInt(query, "limit")
+		if err != nil {
+			log.Error("failed to parse limit", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse limit %d", http.StatusBadRequest)))
+			return
+		}
+
+		activities, next, err := h.service.Feed(r.Context(), userID, before, limit)
+		if err != nil {
+			log.Error("failed to get feed", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+		if activities == nil {
+			activities = []models.Activity{}
+		}
+
+		render.JSON(w, r, FeedResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Activities: activities,
+			NextBefore: next,
+			Message:    "feed",
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+type BidRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+type BidResponse struct {
+	resp.Response
+	LotID   int64                `json:"lot_id,omitempty"`
+	Auction *models.Auction      `json:"auction,omitempty"`
+	Bids    []models.Bid         `json:"bids,omitempty"`
+	Errors  svc.ValidationErrors `json:"errors,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// bidError renders the client-facing message for bidding errors and reports
+// whether err was one of them.
+func bidError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, BidResponse{
+			Response: response.Error(fmt.Sprintf("invalid bid %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrLotNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrNotAuction):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot is not an auction %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrOwnLot):
+		render.JSON(w, r, response.Error(fmt.Sprintf("cannot bid on your own lot %d", http.StatusBadRequest)))
+	case errors.Is(err, storage.ErrAuctionClosed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("auction is not open for bids %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrBidTooLow):
+		render.JSON(w, r, response.Error(fmt.Sprintf("bid is below the minimum bid %d", http.StatusConflict)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) PlaceBid(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.PlaceBid"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lotID, err := int64URLParam(r, "lot_id")
+		if err != nil {
+			log.Error("failed to parse lot id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse lot id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req BidRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		auction, err := h.service.PlaceBid(r.Context(), userID, lotID, req.Amount)
+		if err != nil {
+			log.Error("failed to place bid", slog.String("err", err.Error()))
+			if !bidError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("bid placed", slog.Int64("lot_id", lotID), slog.Int64("amount", req.Amount))
+		render.JSON(w, r, BidResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lotID,
+			Auction: &auction,
+			Message: "bid placed",
+		})
+	}
+}
+
+// Bids is the public bid history of an auction lot.
+func (h *handler) Bids(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicBids"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		lotID, err := int64URLParam(r, "lot_id")
+		if err != nil {
+			log.Error("failed to parse lot id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse lot id %d", http.StatusBadRequest)))
+			return
+		}
+
+		bids, err := h.service.Bids(r.Context(), lotID)
+		if err != nil {
+			log.Error("failed to get bids", slog.String("err", err.Error()))
+			if !bidError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, BidResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lotID,
+			Bids:    bids,
+			Message: "bids",
+		})
+	}
+}
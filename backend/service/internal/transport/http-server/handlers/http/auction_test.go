@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceBid(t *testing.T) {
+	runAPITests(t, []apiTest[BidResponse]{
+		{
+			name:   "minimum bid",
+			method: http.MethodPost,
+			path:   "/api/keeper/lots/5/bids",
+			userID: strangerID,
+			body:   `{"amount":1000}`,
+			check: func(t *testing.T, f *fakeService, res BidResponse) {
+				require.NotNil(t, res.Auction)
+				assert.Equal(t, int64(1100), res.Auction.MinBid)
+			},
+		},
+		{
+			name:   "below minimum",
+			method: http.MethodPost,
+			path:   "/api/keeper/lots/5/bids",
+			userID: strangerID,
+			body:   `{"amount":1050}`,
+			setup:  func(f *fakeService) { f.auction.MinBid = 1100 },
+			err:    "below the minimum bid",
+		},
+	})
+}
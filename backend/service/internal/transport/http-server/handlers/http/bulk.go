@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// ItemFilter selects the items of CollectionID that match the rest, like the
+// query parameters of the item list do.
+type ItemFilter struct {
+	CollectionID int64    `json:"collection_id"`
+	Query        string   `json:"q"`
+	CategoryID   int64    `json:"category_id"`
+	Country      string   `json:"country"`
+	YearFrom     int      `json:"year_from"`
+	YearTo       int      `json:"year_to"`
+	Tags         []string `json:"tags"`
+}
+
+// BulkItemsRequest selects items by ItemIDs, or when there are none by
+// Filter. CollectionID is the target of a move or copy; Action and the
+// fields after it are the change of a bulk update.
+type BulkItemsRequest struct {
+	ItemIDs      []int64    `json:"item_ids"`
+	Filter       ItemFilter `json:"filter"`
+	CollectionID int64      `json:"collection_id"`
+	Action       string     `json:"action"`
+	AddTags      []string   `json:"add_tags"`
+	RemoveTags   []string   `json:"remove_tags"`
+	CategoryID   int64      `json:"category_id"`
+	Country      string     `json:"country"`
+}
+
+func (req BulkItemsRequest) selection() models.ItemSelection {
+	return models.ItemSelection{
+		ItemIDs:      req.ItemIDs,
+		CollectionID: req.Filter.CollectionID,
+		Filter: models.ListOptions{
+			Query:      req.Filter.Query,
+			CategoryID: req.Filter.CategoryID,
+			Country:    req.Filter.Country,
+			YearFrom:   req.Filter.YearFrom,
+			YearTo:     req.Filter.YearTo,
+			Tags:       req.Filter.Tags,
+		},
+	}
+}
+
+type BulkItemsResponse struct {
+	resp.Response
+	Results   []models.ItemResult  `json:"results,omitempty"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Errors    svc.ValidationErrors `json:"errors,omitempty"`
+}
+
+// bulkError renders the client-facing message for bulk operation errors and
+// reports whether err was one of them.
+func bulkError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, BulkItemsResponse{
+			Response: response.Error(fmt.Sprintf("invalid request %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrTooManyItems):
+		render.JSON(w, r, response.Error(fmt.Sprintf("selection matches too many items %d", http.StatusBadRequest)))
+	default:
+		return categoryError(w, r, err)
+	}
+	return true
+}
+
+// MoveItems moves items of the user into another collection of theirs.
+func (h *handler) MoveItems(log *slog.Logger) http.HandlerFunc {
+	return h.bulkItems(log, "handlers.auth.MoveItems", func(ctx context.Context, userID int64, req BulkItemsRequest) ([]models.ItemResult, error) {
+		return h.service.MoveItems(ctx, userID, req.selection(), req.CollectionID)
+	})
+}
+
+// CopyItems copies items of the user into a collection of theirs.
+func (h *handler) CopyItems(log *slog.Logger) http.HandlerFunc {
+	return h.bulkItems(log, "handlers.auth.CopyItems", func(ctx context.Context, userID int64, req BulkItemsRequest) ([]models.ItemResult, error) {
+		return h.service.CopyItems(ctx, userID, req.selection(), req.CollectionID)
+	})
+}
+
+// UpdateItems deletes, re-tags, or changes the category or country of items
+// the user may edit.
+func (h *handler) UpdateItems(log *slog.Logger) http.HandlerFunc {
+	return h.bulkItems(log, "handlers.auth.UpdateItems", func(ctx context.Context, userID int64, req BulkItemsRequest) ([]models.ItemResult, error) {
+		return h.service.UpdateItems(ctx, userID, req.selection(), models.ItemChange{
+			Action:     req.Action,
+			AddTags:    req.AddTags,
+			RemoveTags: req.RemoveTags,
+			CategoryID: req.CategoryID,
+			Country:    req.Country,
+		})
+	})
+}
+
+// bulkItems decodes a bulk request, runs it and renders the result of every
+// item. A request that ran is a success even if some of its items failed.
+func (h *handler) bulkItems(log *slog.Logger, op string, run func(ctx context.Context, userID int64, req BulkItemsRequest) ([]models.ItemResult, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req BulkItemsRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		results, err := run(r.Context(), userID, req)
+		if err != nil {
+			log.Error("failed to run bulk operation", slog.String("err", err.Error()))
+			if !bulkError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		res := BulkItemsResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Results: results,
+		}
+		for _, result := range results {
+			if result.OK {
+				res.Succeeded++
+			} else {
+				res.Failed++
+			}
+		}
+
+		log.Info("bulk operation done", slog.Int("succeeded", res.Succeeded), slog.Int("failed", res.Failed))
+		render.JSON(w, r, res)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveItems(t *testing.T) {
+	runAPITests(t, []apiTest[BulkItemsResponse]{
+		{
+			name:   "one listed",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/move",
+			userID: ownerID,
+			body:   `{"item_ids":[100,101],"collection_id":12}`,
+			setup:  func(f *fakeService) { f.listed[101] = true },
+			check: func(t *testing.T, f *fakeService, res BulkItemsResponse) {
+				assert.Equal(t, []int64{100, 101}, f.sel.ItemIDs)
+				assert.Equal(t, 1, res.Succeeded)
+				assert.Equal(t, 1, res.Failed)
+				require.Len(t, res.Results, 2)
+				assert.Equal(t, "item is already listed in another lot", res.Results[1].Error)
+				assert.Equal(t, int64(12), f.items[100].CollectionID)
+				assert.Equal(t, int64(10), f.items[101].CollectionID)
+			},
+		},
+		{
+			name:   "foreign collection",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/move",
+			userID: strangerID,
+			body:   `{"item_ids":[100],"collection_id":12}`,
+			err:    "collection not found 404",
+		},
+	})
+}
+
+func TestUpdateItems(t *testing.T) {
+	runAPITests(t, []apiTest[BulkItemsResponse]{
+		{
+			name:   "filter",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/bulk",
+			userID: ownerID,
+			body:   `{"filter":{"collection_id":10,"tags":["coins"],"year_from":1900},"action":"country","country":"Russia"}`,
+			err:    "selection matches too many items 400",
+			check: func(t *testing.T, f *fakeService, res BulkItemsResponse) {
+				assert.Equal(t, int64(10), f.sel.CollectionID)
+				assert.Equal(t, []string{"coins"}, f.sel.Filter.Tags)
+				assert.Equal(t, 1900, f.sel.Filter.YearFrom)
+				assert.Equal(t, "Russia", f.change.Country)
+			},
+		},
+		{
+			name:   "unknown action",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/bulk",
+			userID: ownerID,
+			body:   `{"item_ids":[100],"action":"rename"}`,
+			err:    "invalid request 400",
+			check: func(t *testing.T, f *fakeService, res BulkItemsResponse) {
+				assert.Contains(t, res.Errors, "action")
+			},
+		},
+	})
+}
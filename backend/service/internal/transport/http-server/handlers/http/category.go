@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+type CategoryRequest struct {
+	CategoryName    string                  `json:"name" validate:"required,max=50"`
+	Description     string                  `json:"description"`
+	ParentID        int64                   `json:"parent_id"`
+	AttributeSchema []models.AttributeField `json:"attribute_schema"`
+}
+
+type CategoryResponse struct {
+	resp.Response
+	CategoryID   int64             `json:"id,omitempty"`
+	CategoryName string            `json:"name,omitempty"`
+	ParentID     int64             `json:"parent_id,omitempty"`
+	Categories   []models.Category `json:"categories,omitempty"`
+	// AttributeSchema is the full schema of a category, inherited fields
+	// included.
+	AttributeSchema []models.AttributeField `json:"attribute_schema,omitempty"`
+	Errors          svc.ValidationErrors    `json:"errors,omitempty"`
+	Message         string                  `json:"message,omitempty"`
+}
+
+// validationErrors reports whether err carries field-level messages.
+func validationErrors(err error) (svc.ValidationErrors, bool) {
+	var errs svc.ValidationErrors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	return nil, false
+}
+
+// categoryError renders the client-facing message for category storage errors
+// and reports whether err was one of them.
+func categoryError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrCategoryNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("category not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCategoryExists):
+		render.JSON(w, r, response.Error(fmt.Sprintf("category already exists %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrCategoryInUse):
+		render.JSON(w, r, response.Error(fmt.Sprintf("category is in use %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrCategoryCycle):
+		render.JSON(w, r, response.Error(fmt.Sprintf("category cannot be its own ancestor %d", http.StatusBadRequest)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) Categories(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Categories"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		categories, err := h.service.Categories(r.Context())
+		if err != nil {
+			log.Error("failed to get categories", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, CategoryResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Categories: categories,
+			Message:    "categories",
+		})
+	}
+}
+
+// CategorySchema returns the attribute fields items of a category carry, so
+// that clients can build the attribute form.
+func (h *handler) CategorySchema(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CategorySchema"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		categoryID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse category id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse category id %d", http.StatusBadRequest)))
+			return
+		}
+
+		schema, err := h.service.CategorySchema(r.Context(), categoryID)
+		if err != nil {
+			log.Error("failed to get category schema", slog.String("err", err.Error()))
+			if !categoryError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, CategoryResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CategoryID:      categoryID,
+			AttributeSchema: schema,
+			Message:         "category schema",
+		})
+	}
+}
+
+func (h *handler) CreateCategory(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CreateCategory"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req CategoryRequest
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Error("invalid request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to validate request"))
+			return
+		}
+
+		categoryID, err := h.service.CreateCategory(r.Context(), req.CategoryName, req.Description, req.ParentID, req.AttributeSchema)
+		if err != nil {
+			log.Error("failed to create category", slog.String("err", err.Error()))
+			if errs, ok := validationErrors(err); ok {
+				render.JSON(w, r, CategoryResponse{
+					Response: response.Error(fmt.Sprintf("invalid attribute schema %d", http.StatusBadRequest)),
+					Errors:   errs,
+				})
+				return
+			}
+			if !categoryError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("category created", slog.Int64("category_id", categoryID))
+		render.JSON(w, r, CategoryResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CategoryID:   categoryID,
+			CategoryName: req.CategoryName,
+			ParentID:     req.ParentID,
+			Message:      "category created",
+		})
+	}
+}
+
+func (h *handler) UpdateCategory(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UpdateCategory"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		categoryID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse category id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse category id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req CategoryRequest
+
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := validator.New().Struct(req); err != nil {
+			log.Error("invalid request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to validate request"))
+			return
+		}
+
+		err = h.service.UpdateCategory(r.Context(), categoryID, req.CategoryName, req.Description, req.ParentID, req.AttributeSchema)
+		if err != nil {
+			log.Error("failed to update category", slog.String("err", err.Error()))
+			if errs, ok := validationErrors(err); ok {
+				render.JSON(w, r, CategoryResponse{
+					Response: response.Error(fmt.Sprintf("invalid attribute schema %d", http.StatusBadRequest)),
+					Errors:   errs,
+				})
+				return
+			}
+			if !categoryError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("category updated", slog.Int64("category_id", categoryID))
+		render.JSON(w, r, CategoryResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CategoryID:   categoryID,
+			CategoryName: req.CategoryName,
+			ParentID:     req.ParentID,
+			Message:      "category updated",
+		})
+	}
+}
+
+func (h *handler) DeleteCategory(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteCategory"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		categoryID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse category id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse category id %d", http.StatusBadRequest)))
+			return
+		}
+
+		err = h.service.DeleteCategory(r.Context(), categoryID)
+		if err != nil {
+			log.Error("failed to delete category", slog.String("err", err.Error()))
+			if !categoryError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("category deleted", slog.Int64("category_id", categoryID))
+		render.JSON(w, r, CategoryResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CategoryID: categoryID,
+			Message:    "category deleted",
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateCategory(t *testing.T) {
+	runAPITests(t, []apiTest[CategoryResponse]{
+		{
+			name:   "admin",
+			method: http.MethodPost,
+			path:   "/api/keeper/category",
+			userID: adminID,
+			body:   `{"name":"Europe","parent_id":1}`,
+			check: func(t *testing.T, f *fakeService, res CategoryResponse) {
+				assert.Equal(t, int64(1), res.ParentID)
+				assert.Equal(t, "Europe", f.categories[res.CategoryID].CategoryName)
+			},
+		},
+		{
+			name:   "not admin",
+			method: http.MethodPost,
+			path:   "/api/keeper/category",
+			userID: ownerID,
+			body:   `{"name":"Europe"}`,
+			code:   http.StatusForbidden,
+			check: func(t *testing.T, f *fakeService, res CategoryResponse) {
+				assert.Len(t, f.categories, 1)
+			},
+		},
+		{
+			name:   "unknown parent",
+			method: http.MethodPost,
+			path:   "/api/keeper/category",
+			userID: adminID,
+			body:   `{"name":"Europe","parent_id":7}`,
+			err:    "category not found",
+		},
+		{
+			name:   "invalid schema",
+			method: http.MethodPost,
+			path:   "/api/keeper/category",
+			userID: adminID,
+			body:   `{"name":"Stamps","attribute_schema":[{"key":"perforation","type":"number"},{"key":"watermark"}]}`,
+			err:    "invalid attribute schema",
+			check: func(t *testing.T, f *fakeService, res CategoryResponse) {
+				assert.Equal(t, svc.ValidationErrors{"attribute_schema[1].type": "is required"}, res.Errors)
+				assert.Len(t, f.categories, 1)
+			},
+		},
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
 	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	"github.com/mmmakskl/HeritageKeeper/service/lib/jwt"
@@ -29,14 +30,16 @@ type CollectionRequest struct {
 
 type CollectionResponse struct {
 	resp.Response
-	UserID         int64               `json:"user_id,omitempty"`
-	Collections    []models.Collection `json:"collections,omitempty"`
-	CollectionID   int64               `json:"id,omitempty"`
-	CollectionName string              `json:"name,omitempty"`
-	CategoryID     int64               `json:"category_id,omitempty"`
-	Description    string              `json:"description,omitempty"`
-	IsPublic       bool                `json:"is_public,omitempty"`
-	Message        string              `json:"message,omitempty"`
+	UserID         int64                `json:"user_id,omitempty"`
+	Collections    []models.Collection  `json:"collections,omitempty"`
+	NextCursor     string               `json:"next_cursor,omitempty"`
+	CollectionID   int64                `json:"id,omitempty"`
+	CollectionName string               `json:"name,omitempty"`
+	CategoryID     int64                `json:"category_id,omitempty"`
+	Description    string               `json:"description,omitempty"`
+	IsPublic       bool                 `json:"is_public,omitempty"`
+	Errors         svc.ValidationErrors `json:"errors,omitempty"`
+	Message        string               `json:"message,omitempty"`
 }
 
 func (h *handler) CreateCollection(log *slog.Logger) http.HandlerFunc {
@@ -90,12 +93,19 @@ func (h *handler) CreateCollection(log *slog.Logger) http.HandlerFunc {
 			return
 		}
 
-		//! Заглушка:
-		ImageURL := "not found"
-
-		collectionID, err := h.service.SetCollection(r.Context(), userIDInt, req.CollectionName, req.Description, ImageURL, req.CategoryID, req.IsPublic)
+		collectionID, err := h.service.SetCollection(r.Context(), userIDInt, req.CollectionName, req.Description, req.ImageURL, req.CategoryID, req.IsPublic)
 		if err != nil {
 			log.Error("failed to set collection in storage", slog.String("err", err.Error()))
+			if errs, ok := validationErrors(err); ok {
+				render.JSON(w, r, CollectionResponse{
+					Response: response.Error(fmt.Sprintf("invalid collection %d", http.StatusBadRequest)),
+					Errors:   errs,
+				})
+				return
+			}
+			if categoryError(w, r, err) {
+				return
+			}
 			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
 			return
 		}
@@ -163,6 +173,9 @@ func (h *handler) UpdateCollection(log *slog.Logger) http.HandlerFunc {
 		err = h.service.UpdateCollection(r.Context(), userIDInt, req.CollectionID, req.CollectionName, req.Description, req.CategoryID, req.IsPublic)
 		if err != nil {
 			log.Error("failed to update collection in storage", slog.String("err", err.Error()))
+			if categoryError(w, r, err) || shareError(w, r, err) {
+				return
+			}
 			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
 			return
 		}
@@ -215,30 +228,20 @@ func (h *handler) Collections(log *slog.Logger) http.HandlerFunc {
 			return
 		}
 
-		var req Request
-
-		err = render.DecodeJSON(r.Body, &req)
+		opts, err := parseListOptions(r)
 		if err != nil {
-			log.Error("failed to decode request", slog.String("err", err.Error()))
-
-			render.JSON(w, r, resp.Error("failed to decode request"))
-
+			log.Error("failed to parse list options", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
 			return
 		}
 
-		if err := validator.New().Struct(req); err != nil {
-			log.Error("invalid request", slog.String("err", err.Error()))
-
-			render.JSON(w, r, resp.Error("failed to validate request"))
-
-			return
-		}
-
-		collections, err := h.service.Collections(r.Context(), userIDInt)
+		collections, next, err := h.service.Collections(r.Context(), userIDInt, opts)
 		if err != nil {
 			log.Error("failed to get collections", slog.String("err", err.Error()))
 
-			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			if !listError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
 			return
 		}
 		log.Info("collections", slog.Int64("user_id", userIDInt))
@@ -249,6 +252,7 @@ func (h *handler) Collections(log *slog.Logger) http.HandlerFunc {
 			},
 			UserID:      userIDInt,
 			Collections: collections,
+			NextCursor:  next,
 			Message:     "collections",
 		})
 	}
@@ -414,6 +418,9 @@ func (h *handler) DeleteCollection(log *slog.Logger) http.HandlerFunc {
 				return
 			}
 			log.Error("failed to delete collection in storage", slog.String("err", err.Error()))
+			if itemLockedError(w, r, err) || itemInLotError(w, r, err) {
+				return
+			}
 			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
 			return
 		}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// CommentRequest posts or edits a comment. Body is markdown; ParentID makes
+// the comment a reply and is ignored on edit.
+type CommentRequest struct {
+	Body     string `json:"body"`
+	ParentID int64  `json:"parent_id"`
+}
+
+type CommentResponse struct {
+	resp.Response
+	CommentID int64                `json:"id,omitempty"`
+	Comments  []models.Comment     `json:"comments,omitempty"`
+	Errors    svc.ValidationErrors `json:"errors,omitempty"`
+	Message   string               `json:"message,omitempty"`
+}
+
+// commentError renders the client-facing message for comment errors and
+// reports whether err was one of them.
+func commentError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, CommentResponse{
+			Response: response.Error(fmt.Sprintf("invalid comment %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrCommentNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("comment not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrRateLimited):
+		render.JSON(w, r, response.Error(fmt.Sprintf("too many comments, try again later %d", http.StatusTooManyRequests)))
+	default:
+		return false
+	}
+	return true
+}
+
+// postComment builds the handlers that comment on the item or collection
+// whose id is the URL parameter param.
+func (h *handler) postComment(log *slog.Logger, op, param, what string, target func(c *models.Comment, id int64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		id, err := int64URLParam(r, param)
+		if err != nil {
+			log.Error("failed to parse "+what+" id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse %s id %d", what, http.StatusBadRequest)))
+			return
+		}
+
+		var req CommentRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		c := models.Comment{Body: req.Body, ParentID: req.ParentID}
+		target(&c, id)
+
+		commentID, err := h.service.PostComment(r.Context(), userID, c)
+		if err != nil {
+			log.Error("failed to post comment", slog.String("err", err.Error()))
+			if !commentError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("comment posted", slog.Int64("comment_id", commentID))
+		render.JSON(w, r, CommentResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CommentID: commentID,
+			Message:   "comment posted",
+		})
+	}
+}
+
+func (h *handler) PostItemComment(log *slog.Logger) http.HandlerFunc {
+	return h.postComment(log, "handlers.auth.PostItemComment", "item_id", "item", func(c *models.Comment, id int64) { c.ItemID = id })
+}
+
+func (h *handler) PostCollectionComment(log *slog.Logger) http.HandlerFunc {
+	return h.postComment(log, "handlers.auth.PostCollectionComment", "id", "collection", func(c *models.Comment, id int64) { c.CollectionID = id })
+}
+
+// EditComment changes the text of a comment of the user.
+func (h *handler) EditComment(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.EditComment"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		commentID, err := int64URLParam(r, "comment_id")
+		if err != nil {
+			log.Error("failed to parse comment id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse comment id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req CommentRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := h.service.EditComment(r.Context(), userID, commentID, req.Body); err != nil {
+			log.Error("failed to edit comment", slog.String("err", err.Error()))
+			if !commentError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("comment edited", slog.Int64("comment_id", commentID))
+		render.JSON(w, r, CommentResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CommentID: commentID,
+			Message:   "comment edited",
+		})
+	}
+}
+
+// DeleteComment deletes a comment of the user, or a comment on one of the
+// user's collections or its items.
+func (h *handler) DeleteComment(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteComment"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		commentID, err := int64URLParam(r, "comment_id")
+		if err != nil {
+			log.Error("failed to parse comment id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse comment id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.DeleteComment(r.Context(), userID, commentID); err != nil {
+			log.Error("failed to delete comment", slog.String("err", err.Error()))
+			if !commentError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("comment deleted", slog.Int64("comment_id", commentID))
+		render.JSON(w, r, CommentResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "comment deleted",
+		})
+	}
+}
+
+// publicComments builds the handlers that list the threads on the public
+// item or collection of {username} whose id is the URL parameter param.
+func (h *handler) publicComments(log *slog.Logger, op, param, what string, list func(r *http.Request, username string, id int64) ([]models.Comment, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		id, err := int64URLParam(r, param)
+		if err != nil {
+			log.Error("failed to parse "+what+" id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse %s id %d", what, http.StatusBadRequest)))
+			return
+		}
+
+		comments, err := list(r, chi.URLParam(r, "username"), id)
+		if err != nil {
+			log.Error("failed to get comments", slog.String("err", err.Error()))
+			if !commentError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, CommentResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Comments: comments,
+			Message:  "comments",
+		})
+	}
+}
+
+func (h *handler) PublicItemComments(log *slog.Logger) http.HandlerFunc {
+	return h.publicComments(log, "handlers.PublicItemComments", "item_id", "item", func(r *http.Request, username string, id int64) ([]models.Comment, error) {
+		return h.service.ItemComments(r.Context(), username, id)
+	})
+}
+
+func (h *handler) PublicCollectionComments(log *slog.Logger) http.HandlerFunc {
+	return h.publicComments(log, "handlers.PublicCollectionComments", "id", "collection", func(r *http.Request, username string, id int64) ([]models.Comment, error) {
+		return h.service.CollectionComments(r.Context(), username, id)
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostItemComment(t *testing.T) {
+	runAPITests(t, []apiTest[CommentResponse]{
+		{
+			name:   "reply",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/100/comments",
+			userID: strangerID,
+			body:   `{"body":"nice","parent_id":3}`,
+			check: func(t *testing.T, f *fakeService, res CommentResponse) {
+				assert.Equal(t, int64(1), res.CommentID)
+				assert.Equal(t, []models.Comment{{ItemID: 100, ParentID: 3, AuthorID: strangerID, Body: "nice"}}, f.comments)
+			},
+		},
+		{
+			name:   "rate limited",
+			method: http.MethodPost,
+			path:   "/api/keeper/items/100/comments",
+			userID: strangerID,
+			body:   `{"body":"again"}`,
+			setup: func(f *fakeService) {
+				f.comments = []models.Comment{{ItemID: 100, AuthorID: strangerID, Body: "nice"}}
+			},
+			err: "too many comments",
+			check: func(t *testing.T, f *fakeService, res CommentResponse) {
+				assert.Len(t, f.comments, 1)
+			},
+		},
+	})
+}
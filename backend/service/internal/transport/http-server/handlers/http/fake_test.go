@@ -0,0 +1,589 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	ownerID    = 1
+	strangerID = 2
+	adminID    = 42
+)
+
+// usernames of the users of the fixture.
+var usernames = map[int64]string{ownerID: "owner", strangerID: "stranger", adminID: "admin"}
+
+// fakeService keeps the fixture of newFakeService in memory and mirrors the
+// checks the service and the postgres storage make on it. It embeds the
+// service interface, so a handler calling anything else panics.
+type fakeService struct {
+	service
+
+	collections map[int64]models.Collection
+	items       map[int64]models.Item
+	// listed items are held by a draft or active lot, locked ones by a
+	// reserved or sold lot.
+	listed map[int64]bool
+	locked map[int64]bool
+	// images maps image ids to the items they show.
+	images    map[int64]int64
+	discarded []int64
+
+	trashedCollections map[int64]models.TrashedCollection
+	trashedItems       map[int64]models.Item
+
+	categories map[int64]models.Category
+	lots       map[int64]models.Lot
+	auction    models.Auction
+	offers     map[int64]models.Offer
+	trades     map[int64]models.Trade
+	wants      map[int64]models.Want
+	shares     map[int64]models.CollectionShare
+
+	// notifications are streamed to every subscriber, which is then
+	// dropped. missed is what a stream that got 4 did not get.
+	notifications []models.Notification
+	missed        []models.Notification
+
+	id int64
+
+	// What the handlers passed on.
+	opts      models.ListOptions
+	sel       models.ItemSelection
+	change    models.ItemChange
+	offer     models.Offer
+	want      models.Want
+	wantID    int64
+	comments  []models.Comment
+	favorited []int64
+	followed  []string
+	ttl       time.Duration
+	after     []int64
+}
+
+// newFakeService returns the fixture every handler test starts from: the
+// public collection 10 and the private collection 11 of the owner, with
+// items 100 and 101 in 10 and 102 in 11, and the owner's lots, offers and
+// trades on them.
+func newFakeService() *fakeService {
+	deletedAt := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	return &fakeService{
+		collections: map[int64]models.Collection{
+			10: {CollectionID: 10, UserID: ownerID, CollectionName: "Coins", IsPublic: true},
+			11: {CollectionID: 11, UserID: ownerID, CollectionName: "Stamps"},
+			12: {CollectionID: 12, UserID: ownerID, CollectionName: "Medals"},
+		},
+		items: map[int64]models.Item{
+			100: {ItemID: 100, CollectionID: 10, Title: "1 rouble 1913"},
+			101: {ItemID: 101, CollectionID: 10, Title: "50 kopeks 1912"},
+			102: {ItemID: 102, CollectionID: 11, Title: "Penny Red"},
+		},
+		listed: map[int64]bool{},
+		locked: map[int64]bool{},
+		images: map[int64]int64{},
+		trashedCollections: map[int64]models.TrashedCollection{
+			2: {
+				Collection: models.Collection{CollectionID: 2, UserID: ownerID, CollectionName: "Old stamps"},
+				ItemCount:  400,
+				DeletedAt:  deletedAt,
+				PurgeAt:    deletedAt.Add(30 * 24 * time.Hour),
+			},
+		},
+		trashedItems: map[int64]models.Item{
+			103: {ItemID: 103, CollectionID: 11, Title: "Penny Black"},
+			// Restoring 104 would make a second "1 rouble 1913" in 10.
+			104: {ItemID: 104, CollectionID: 10, Title: "1 rouble 1913"},
+		},
+		categories: map[int64]models.Category{
+			1: {CategoryID: 1, CategoryName: "Coins"},
+		},
+		lots: map[int64]models.Lot{
+			5: {LotID: 5, UserID: ownerID, CollectionID: 10, Status: models.LotActive},
+			6: {LotID: 6, UserID: ownerID, CollectionID: 10, Status: models.LotSold},
+		},
+		auction: models.Auction{MinBid: 1000, MinIncrement: 100},
+		offers: map[int64]models.Offer{
+			7: {OfferID: 7, LotID: 5, SellerID: ownerID, BuyerID: strangerID, Amount: 9000, Status: models.OfferPending},
+		},
+		trades: map[int64]models.Trade{
+			3: {TradeID: 3, ProposerID: strangerID, RecipientID: ownerID, Status: models.TradePending},
+		},
+		wants: map[int64]models.Want{
+			5: {WantID: 5, UserID: ownerID, Title: "1 rouble 1914"},
+		},
+		shares: map[int64]models.CollectionShare{
+			1: {ShareID: 1, CollectionID: 10, Role: models.RoleViewer},
+		},
+		notifications: []models.Notification{
+			{NotificationID: 5, Type: models.NotificationReply},
+			{NotificationID: 6, Type: models.NotificationOffer},
+		},
+		missed: []models.Notification{{NotificationID: 5, Type: models.NotificationReply}},
+		id:     200,
+	}
+}
+
+// nextID returns the id of a new row.
+func (f *fakeService) nextID() int64 {
+	f.id++
+	return f.id
+}
+
+// collection returns a collection of the user.
+func (f *fakeService) collection(userID, collectionID int64) (models.Collection, error) {
+	c, ok := f.collections[collectionID]
+	if !ok || c.UserID != userID {
+		return models.Collection{}, storage.ErrCollectionNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeService) Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, string, error) {
+	if _, err := f.collection(userID, collectionID); err != nil {
+		return nil, "", err
+	}
+	f.opts = opts
+	var items []models.Item
+	for _, item := range f.items {
+		if item.CollectionID == collectionID {
+			items = append(items, item)
+		}
+	}
+	return items, "next", nil
+}
+
+func (f *fakeService) Item(ctx context.Context, userID, itemID int64) (models.Item, error) {
+	item, ok := f.items[itemID]
+	if !ok {
+		return models.Item{}, storage.ErrItemNotFound
+	}
+	if _, err := f.collection(userID, item.CollectionID); err != nil {
+		return models.Item{}, storage.ErrItemNotFound
+	}
+	return item, nil
+}
+
+func (f *fakeService) SetItem(ctx context.Context, userID int64, collectionID int64, title string, description string, category_id int64, country string, images []string, year string, attributes models.Attributes) (int64, error) {
+	if _, err := f.collection(userID, collectionID); err != nil {
+		return 0, err
+	}
+	id := f.nextID()
+	f.items[id] = models.Item{ItemID: id, CollectionID: collectionID, Title: title}
+	return id, nil
+}
+
+func (f *fakeService) DeleteItem(ctx context.Context, userID, itemID int64) error {
+	if _, err := f.Item(ctx, userID, itemID); err != nil {
+		return err
+	}
+	if f.listed[itemID] {
+		return storage.ErrItemInLot
+	}
+	delete(f.items, itemID)
+	return nil
+}
+
+func (f *fakeService) DiscardItem(ctx context.Context, userID, itemID int64) error {
+	delete(f.items, itemID)
+	f.discarded = append(f.discarded, itemID)
+	return nil
+}
+
+// UploadItemImage accepts images whose content starts with "img" and finds
+// every one a duplicate of item 100.
+func (f *fakeService) UploadItemImage(ctx context.Context, userID, itemID int64, r io.Reader, isPrimary bool) (models.Image, []models.Duplicate, error) {
+	data, _ := io.ReadAll(r)
+	if !bytes.HasPrefix(data, []byte("img")) {
+		return models.Image{}, nil, svc.ErrUnsupportedImage
+	}
+	id := f.nextID()
+	f.images[id] = itemID
+	return models.Image{ImageID: id, ItemID: itemID}, []models.Duplicate{{
+		Image:      models.DuplicateImage{ItemID: itemID, ImageID: id},
+		Match:      models.DuplicateImage{ItemID: 100, Title: "1 rouble 1913"},
+		Similarity: 0.95,
+	}}, nil
+}
+
+func (f *fakeService) DeleteItemImage(ctx context.Context, userID, itemID, imageID int64) error {
+	delete(f.images, imageID)
+	return nil
+}
+
+func (f *fakeService) MoveItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	if _, err := f.collection(userID, collectionID); err != nil {
+		return nil, err
+	}
+	f.sel = sel
+
+	results := make([]models.ItemResult, len(sel.ItemIDs))
+	for i, itemID := range sel.ItemIDs {
+		results[i].ItemID = itemID
+		item, err := f.Item(ctx, userID, itemID)
+		switch {
+		case err != nil:
+			results[i].Error = err.Error()
+		case f.listed[itemID]:
+			results[i].Error = storage.ErrItemListed.Error()
+		default:
+			item.CollectionID = collectionID
+			f.items[itemID] = item
+			results[i].OK = true
+		}
+	}
+	return results, nil
+}
+
+// UpdateItems takes a filter to match more items than a bulk change may
+// touch.
+func (f *fakeService) UpdateItems(ctx context.Context, userID int64, sel models.ItemSelection, change models.ItemChange) ([]models.ItemResult, error) {
+	if change.Action == "rename" {
+		return nil, svc.ValidationErrors{"action": "must be one of delete, tag, category, country"}
+	}
+	f.sel, f.change = sel, change
+	if len(sel.ItemIDs) == 0 {
+		return nil, storage.ErrTooManyItems
+	}
+	return nil, nil
+}
+
+func (f *fakeService) ItemHistory(ctx context.Context, userID, itemID int64) ([]models.Version, error) {
+	if _, err := f.Item(ctx, userID, itemID); err != nil {
+		return nil, err
+	}
+	return []models.Version{
+		{Version: 2, Action: models.VersionUpdate, Changes: map[string]models.FieldChange{"title": {From: "1 rouble", To: "1 rouble 1913"}}},
+		{Version: 1, Action: models.VersionCreate},
+	}, nil
+}
+
+// RevertItem knows two versions of every item; the first was titled
+// "1 rouble".
+func (f *fakeService) RevertItem(ctx context.Context, userID, itemID int64, version int) (models.Item, error) {
+	item, err := f.Item(ctx, userID, itemID)
+	switch {
+	case err != nil:
+		return models.Item{}, err
+	case version > 2:
+		return models.Item{}, storage.ErrVersionNotFound
+	case f.locked[itemID]:
+		return models.Item{}, storage.ErrItemLocked
+	}
+	if version == 1 {
+		item.Title = "1 rouble"
+	}
+	f.items[itemID] = item
+	return item, nil
+}
+
+func (f *fakeService) Trash(ctx context.Context, userID int64) (models.Trash, error) {
+	var trash models.Trash
+	for _, c := range f.trashedCollections {
+		if c.Collection.UserID == userID {
+			trash.Collections = append(trash.Collections, c)
+		}
+	}
+	return trash, nil
+}
+
+func (f *fakeService) RestoreCollection(ctx context.Context, userID, collectionID int64) error {
+	c, ok := f.trashedCollections[collectionID]
+	if !ok || c.Collection.UserID != userID {
+		return storage.ErrCollectionNotFound
+	}
+	delete(f.trashedCollections, collectionID)
+	f.collections[collectionID] = c.Collection
+	return nil
+}
+
+func (f *fakeService) RestoreItem(ctx context.Context, userID, itemID int64) (models.Item, error) {
+	item, ok := f.trashedItems[itemID]
+	if !ok {
+		return models.Item{}, storage.ErrItemNotFound
+	}
+	if _, err := f.collection(userID, item.CollectionID); err != nil {
+		return models.Item{}, storage.ErrItemNotFound
+	}
+	for _, other := range f.items {
+		if other.CollectionID == item.CollectionID && other.Title == item.Title {
+			return models.Item{}, storage.ErrItemExists
+		}
+	}
+	delete(f.trashedItems, itemID)
+	f.items[itemID] = item
+	return item, nil
+}
+
+func (f *fakeService) Categories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	for _, c := range f.categories {
+		categories = append(categories, c)
+	}
+	return categories, nil
+}
+
+func (f *fakeService) CreateCategory(ctx context.Context, name string, description string, parentID int64, schema []models.AttributeField) (int64, error) {
+	if _, ok := f.categories[parentID]; parentID != 0 && !ok {
+		return 0, storage.ErrCategoryNotFound
+	}
+	for i, field := range schema {
+		if field.Type == "" {
+			return 0, svc.ValidationErrors{fmt.Sprintf("attribute_schema[%d].type", i): "is required"}
+		}
+	}
+	id := f.nextID()
+	f.categories[id] = models.Category{CategoryID: id, ParentID: parentID, CategoryName: name, Description: description}
+	return id, nil
+}
+
+func (f *fakeService) Lot(ctx context.Context, userID, lotID int64) (models.Lot, error) {
+	lot, ok := f.lots[lotID]
+	if !ok || lot.UserID != userID {
+		return models.Lot{}, storage.ErrLotNotFound
+	}
+	return lot, nil
+}
+
+func (f *fakeService) SetLotStatus(ctx context.Context, userID, lotID int64, status string) error {
+	lot, err := f.Lot(ctx, userID, lotID)
+	if err != nil {
+		return err
+	}
+	if lot.Status == models.LotSold {
+		return storage.ErrLotLocked
+	}
+	lot.Status = status
+	f.lots[lotID] = lot
+	return nil
+}
+
+func (f *fakeService) MakeOffer(ctx context.Context, userID, lotID int64, offer models.Offer) (int64, error) {
+	if offer.Amount <= 0 {
+		return 0, svc.ValidationErrors{"amount": "must be positive, in minor units of the currency"}
+	}
+	f.offer = offer
+	return f.nextID(), nil
+}
+
+// AcceptOffer lets the first accept of an offer win, as the row lock on
+// the lot does in postgres.
+func (f *fakeService) AcceptOffer(ctx context.Context, userID, offerID int64) error {
+	offer, ok := f.offers[offerID]
+	if !ok || offer.SellerID != userID {
+		return storage.ErrOfferNotFound
+	}
+	if offer.Status != models.OfferPending {
+		return storage.ErrOfferClosed
+	}
+	offer.Status = models.OfferAccepted
+	f.offers[offerID] = offer
+	return nil
+}
+
+// PlaceBid bids on the auction of every lot.
+func (f *fakeService) PlaceBid(ctx context.Context, userID, lotID, amount int64) (models.Auction, error) {
+	if amount < f.auction.MinBid {
+		return models.Auction{}, storage.ErrBidTooLow
+	}
+	f.auction.CurrentBid, f.auction.BidderID = amount, userID
+	f.auction.MinBid = amount + f.auction.MinIncrement
+	return f.auction, nil
+}
+
+func (f *fakeService) AcceptTrade(ctx context.Context, userID, tradeID, collectionID int64) error {
+	trade, ok := f.trades[tradeID]
+	if !ok || trade.RecipientID != userID {
+		return storage.ErrTradeNotFound
+	}
+	if trade.Status != models.TradePending {
+		return storage.ErrTradeStale
+	}
+	trade.Status, trade.RecipientCollectionID = models.TradeAccepted, collectionID
+	f.trades[tradeID] = trade
+	return nil
+}
+
+func (f *fakeService) CreateWant(ctx context.Context, userID int64, w models.Want) (int64, error) {
+	if w.Title == "" {
+		return 0, svc.ValidationErrors{"title": "is required"}
+	}
+	f.want = w
+	return f.nextID(), nil
+}
+
+func (f *fakeService) WantMatches(ctx context.Context, userID, wantID int64) ([]models.WantMatch, error) {
+	if want, ok := f.wants[wantID]; wantID != 0 && (!ok || want.UserID != userID) {
+		return nil, storage.ErrWantNotFound
+	}
+	f.wantID = wantID
+	return []models.WantMatch{{MatchID: 1, WantID: 5, LotID: 5}}, nil
+}
+
+// PostComment lets each user post one comment.
+func (f *fakeService) PostComment(ctx context.Context, userID int64, c models.Comment) (int64, error) {
+	for _, posted := range f.comments {
+		if posted.AuthorID == userID {
+			return 0, storage.ErrRateLimited
+		}
+	}
+	c.AuthorID = userID
+	f.comments = append(f.comments, c)
+	return int64(len(f.comments)), nil
+}
+
+// FavoriteItem favorites items the user may see: those of public
+// collections.
+func (f *fakeService) FavoriteItem(ctx context.Context, userID, itemID int64) error {
+	item, ok := f.items[itemID]
+	if !ok || !f.collections[item.CollectionID].IsPublic {
+		return storage.ErrItemNotFound
+	}
+	f.favorited = append(f.favorited, itemID)
+	return nil
+}
+
+func (f *fakeService) Follow(ctx context.Context, userID int64, username string) error {
+	switch {
+	case username == usernames[userID]:
+		return storage.ErrFollowSelf
+	case username != "owner" && username != "stranger":
+		return storage.ErrUserNotFound
+	}
+	f.followed = append(f.followed, username)
+	return nil
+}
+
+// PublicFollowers knows that the stranger keeps their followers private.
+func (f *fakeService) PublicFollowers(ctx context.Context, username string) ([]models.Follow, error) {
+	switch username {
+	case "owner":
+		return []models.Follow{{Username: "stranger"}}, nil
+	case "stranger":
+		return nil, storage.ErrFollowersPrivate
+	}
+	return nil, storage.ErrUserNotFound
+}
+
+// publicCollection returns a public collection of the user with username.
+func (f *fakeService) publicCollection(username string, c models.Collection) (models.PublicCollection, bool) {
+	if !c.IsPublic || usernames[c.UserID] != username {
+		return models.PublicCollection{}, false
+	}
+	pc := models.PublicCollection{CollectionID: c.CollectionID, Owner: username, CollectionName: c.CollectionName}
+	for _, item := range f.items {
+		if item.CollectionID == c.CollectionID {
+			pc.ItemCount++
+		}
+	}
+	return pc, true
+}
+
+func (f *fakeService) PublicProfile(ctx context.Context, username string) (models.PublicUser, []models.PublicCollection, error) {
+	if username != "owner" && username != "stranger" {
+		return models.PublicUser{}, nil, storage.ErrUserNotFound
+	}
+	var collections []models.PublicCollection
+	for _, c := range f.collections {
+		if pc, ok := f.publicCollection(username, c); ok {
+			collections = append(collections, pc)
+		}
+	}
+	return models.PublicUser{Username: username}, collections, nil
+}
+
+func (f *fakeService) PublicCollection(ctx context.Context, username string, collectionID int64) (models.PublicCollection, []models.Item, error) {
+	pc, ok := f.publicCollection(username, f.collections[collectionID])
+	if !ok {
+		return models.PublicCollection{}, nil, storage.ErrCollectionNotFound
+	}
+	var items []models.Item
+	for _, item := range f.items {
+		if item.CollectionID == collectionID {
+			items = append(items, item)
+		}
+	}
+	return pc, items, nil
+}
+
+func (f *fakeService) RecentPublicCollections(ctx context.Context, limit int) ([]models.PublicCollection, error) {
+	var collections []models.PublicCollection
+	for _, c := range f.collections {
+		if pc, ok := f.publicCollection(usernames[c.UserID], c); ok {
+			collections = append(collections, pc)
+		}
+	}
+	return collections, nil
+}
+
+func (f *fakeService) ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error) {
+	if _, err := f.collection(ownerID, collectionID); err != nil {
+		return models.CollectionShare{}, err
+	}
+	if invitee == "nobody" {
+		return models.CollectionShare{}, storage.ErrUserNotFound
+	}
+	share := models.CollectionShare{ShareID: f.nextID(), CollectionID: collectionID, Email: invitee, Role: role, Pending: true}
+	f.shares[share.ShareID] = share
+	return share, nil
+}
+
+func (f *fakeService) UnshareCollection(ctx context.Context, ownerID, collectionID, shareID int64) error {
+	share, ok := f.shares[shareID]
+	if !ok || share.CollectionID != collectionID {
+		return storage.ErrShareNotFound
+	}
+	delete(f.shares, shareID)
+	return nil
+}
+
+func (f *fakeService) CreateShareLink(ctx context.Context, ownerID, collectionID int64, ttl time.Duration, password string, maxViews int64) (models.ShareLink, error) {
+	f.ttl = ttl
+	return models.ShareLink{LinkID: 1, CollectionID: collectionID, Token: "secret", PasswordHash: "hash", HasPassword: password != "", MaxViews: maxViews}, nil
+}
+
+// OpenShareLink opens collection 10 by the link "secret" with the password
+// "appraise".
+func (f *fakeService) OpenShareLink(ctx context.Context, token, password string) (models.PublicCollection, []models.Item, error) {
+	switch {
+	case token != "secret":
+		return models.PublicCollection{}, nil, storage.ErrShareLinkNotFound
+	case password != "appraise":
+		return models.PublicCollection{}, nil, storage.ErrShareLinkPassword
+	}
+	return f.PublicCollection(ctx, "owner", 10)
+}
+
+func (f *fakeService) SubscribeNotifications(userID int64) (<-chan models.Notification, func()) {
+	events := make(chan models.Notification, len(f.notifications))
+	for _, n := range f.notifications {
+		events <- n
+	}
+	close(events)
+	return events, func() {}
+}
+
+// Notifications knows that the newest notification is 4.
+func (f *fakeService) Notifications(ctx context.Context, userID, before int64, limit int, unreadOnly bool) ([]models.Notification, error) {
+	return []models.Notification{{NotificationID: 4}}, nil
+}
+
+func (f *fakeService) NotificationsAfter(ctx context.Context, userID, after int64) ([]models.Notification, error) {
+	f.after = append(f.after, after)
+	if after >= 5 {
+		return nil, nil
+	}
+	return f.missed, nil
+}
+
+func (f *fakeService) UnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	return 3, nil
+}
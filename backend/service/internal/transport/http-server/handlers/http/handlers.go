@@ -3,11 +3,13 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
@@ -16,6 +18,7 @@ import (
 	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	"github.com/mmmakskl/HeritageKeeper/service/lib/jwt"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -24,18 +27,119 @@ type service interface {
 	Login(ctx context.Context, email string) error
 	Register(ctx context.Context, userID int64, email string, username string) error
 	User(ctx context.Context, userID int64) (models.User, error)
-	Users() ([]models.User, error)
+	Users(ctx context.Context, opts models.ListOptions) ([]models.User, string, error)
 	UpdateUserInfo(ctx context.Context, userID int64, username string, email string, phone string, birth_date time.Time) error
 	SetCollection(ctx context.Context, userID int64, collectionName string, description string, image_url string, categoryID int64, isPublic bool) (int64, error)
 	UpdateCollection(ctx context.Context, userID, collectionID int64, collectionName string, description string, categoryID int64, isPublic bool) error
 	DeleteCollection(ctx context.Context, userID, collectionID int64) error
 	Collection(ctx context.Context, userID, collectionID int64) (models.Collection, error)
-	Collections(ctx context.Context, userID int64) ([]models.Collection, error)
-	SetItem(ctx context.Context, userID int64, collectionID int64, title string, description string, category_id int64, country string, images []string, year string, attributes []string) (int64, error)
-	UpdateItem(ctx context.Context, userID int64, collectionID int64, itemID int64, title string, description string, category_id int64, country string, images []string, year string, attributes []string) error
-	DeleteItem(ctx context.Context, collectionID, itemID int64) error
-	Item(ctx context.Context, collectionID, itemID int64) (models.Item, error)
-	Items(ctx context.Context, collectionID int64) ([]models.Item, error)
+	Collections(ctx context.Context, userID int64, opts models.ListOptions) ([]models.Collection, string, error)
+	SetItem(ctx context.Context, userID int64, collectionID int64, title string, description string, category_id int64, country string, images []string, year string, attributes models.Attributes) (int64, error)
+	UpdateItem(ctx context.Context, userID int64, collectionID int64, itemID int64, title string, description string, category_id int64, country string, images []string, year string, attributes models.Attributes) error
+	DeleteItem(ctx context.Context, userID, itemID int64) error
+	DiscardItem(ctx context.Context, userID, itemID int64) error
+	MoveItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error)
+	CopyItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error)
+	UpdateItems(ctx context.Context, userID int64, sel models.ItemSelection, change models.ItemChange) ([]models.ItemResult, error)
+	ItemHistory(ctx context.Context, userID, itemID int64) ([]models.Version, error)
+	CollectionHistory(ctx context.Context, userID, collectionID int64) ([]models.Version, error)
+	RevertItem(ctx context.Context, userID, itemID int64, version int) (models.Item, error)
+	Trash(ctx context.Context, userID int64) (models.Trash, error)
+	RestoreCollection(ctx context.Context, userID, collectionID int64) error
+	RestoreItem(ctx context.Context, userID, itemID int64) (models.Item, error)
+	Item(ctx context.Context, userID, itemID int64) (models.Item, error)
+	Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, string, error)
+	Categories(ctx context.Context) ([]models.Category, error)
+	CategorySchema(ctx context.Context, categoryID int64) ([]models.AttributeField, error)
+	CreateCategory(ctx context.Context, categoryName string, description string, parentID int64, schema []models.AttributeField) (int64, error)
+	UpdateCategory(ctx context.Context, categoryID int64, categoryName string, description string, parentID int64, schema []models.AttributeField) error
+	DeleteCategory(ctx context.Context, categoryID int64) error
+	AddItemTags(ctx context.Context, userID, itemID int64, tags []string) ([]string, error)
+	RemoveItemTag(ctx context.Context, userID, itemID int64, tag string) error
+	Tags(ctx context.Context, userID int64) ([]models.Tag, error)
+	AutocompleteTags(ctx context.Context, userID int64, prefix string, limit int) ([]models.Tag, error)
+	ItemsByTags(ctx context.Context, userID int64, tags []string) ([]models.Item, error)
+	MergeTags(ctx context.Context, userID int64, from []string, into string) (string, error)
+	UploadItemImage(ctx context.Context, userID, itemID int64, r io.Reader, isPrimary bool) (models.Image, []models.Duplicate, error)
+	Duplicates(ctx context.Context, userID int64) ([]models.Duplicate, error)
+	PublicProfile(ctx context.Context, username string) (models.PublicUser, []models.PublicCollection, error)
+	PublicCollection(ctx context.Context, username string, collectionID int64) (models.PublicCollection, []models.Item, error)
+	PublicItem(ctx context.Context, username string, itemID int64) (models.Item, []models.Image, error)
+	RecentPublicCollections(ctx context.Context, limit int) ([]models.PublicCollection, error)
+	Search(ctx context.Context, userID int64, filter models.SearchFilter) (models.SearchResult, error)
+	Facets(ctx context.Context, userID int64, filter models.SearchFilter) (models.Facets, error)
+	ItemImages(ctx context.Context, userID, itemID int64) ([]models.Image, error)
+	SetPrimaryItemImage(ctx context.Context, userID, itemID, imageID int64) error
+	DeleteItemImage(ctx context.Context, userID, itemID, imageID int64) error
+	UploadCollectionImage(ctx context.Context, userID, collectionID int64, r io.Reader, isPrimary bool) (models.Image, error)
+	CollectionImages(ctx context.Context, userID, collectionID int64) ([]models.Image, error)
+	DeleteCollectionImage(ctx context.Context, userID, collectionID, imageID int64) error
+	UploadAvatar(ctx context.Context, userID int64, r io.Reader) (string, error)
+	OpenImage(ctx context.Context, key, expires, sig string) (io.ReadCloser, blob.Info, error)
+	CreateLot(ctx context.Context, userID int64, lot models.Lot) (int64, error)
+	UpdateLot(ctx context.Context, userID int64, lot models.Lot) error
+	SetLotStatus(ctx context.Context, userID, lotID int64, status string) error
+	DeleteLot(ctx context.Context, userID, lotID int64) error
+	Lot(ctx context.Context, userID, lotID int64) (models.Lot, error)
+	Lots(ctx context.Context, userID, collectionID int64) ([]models.Lot, error)
+	PublicLots(ctx context.Context, opts models.ListOptions) ([]models.Lot, string, error)
+	PublicLot(ctx context.Context, lotID int64) (models.Lot, error)
+	MakeOffer(ctx context.Context, userID, lotID int64, offer models.Offer) (int64, error)
+	CounterOffer(ctx context.Context, userID, offerID int64, counter models.Offer) (int64, error)
+	AcceptOffer(ctx context.Context, userID, offerID int64) error
+	RejectOffer(ctx context.Context, userID, offerID int64) error
+	Offer(ctx context.Context, userID, offerID int64) (models.Offer, error)
+	Offers(ctx context.Context, userID, lotID int64) ([]models.Offer, error)
+	PlaceBid(ctx context.Context, userID, lotID, amount int64) (models.Auction, error)
+	Bids(ctx context.Context, lotID int64) ([]models.Bid, error)
+	ProposeTrade(ctx context.Context, userID int64, t models.Trade) (int64, error)
+	CounterTrade(ctx context.Context, userID, tradeID int64, counter models.Trade) (int64, error)
+	AcceptTrade(ctx context.Context, userID, tradeID, collectionID int64) error
+	DeclineTrade(ctx context.Context, userID, tradeID int64) error
+	Trade(ctx context.Context, userID, tradeID int64) (models.Trade, error)
+	Trades(ctx context.Context, userID int64) ([]models.Trade, error)
+	ItemTransfers(ctx context.Context, userID, itemID int64) ([]models.ItemTransfer, error)
+	CreateWant(ctx context.Context, userID int64, w models.Want) (int64, error)
+	UpdateWant(ctx context.Context, userID int64, w models.Want) error
+	DeleteWant(ctx context.Context, userID, wantID int64) error
+	Want(ctx context.Context, userID, wantID int64) (models.Want, error)
+	Wants(ctx context.Context, userID int64) ([]models.Want, error)
+	WantMatches(ctx context.Context, userID, wantID int64) ([]models.WantMatch, error)
+	FavoriteItem(ctx context.Context, userID, itemID int64) error
+	UnfavoriteItem(ctx context.Context, userID, itemID int64) error
+	FavoriteCollection(ctx context.Context, userID, collectionID int64) error
+	UnfavoriteCollection(ctx context.Context, userID, collectionID int64) error
+	Favorites(ctx context.Context, userID int64) (models.Favorites, error)
+	Follow(ctx context.Context, userID int64, username string) error
+	Unfollow(ctx context.Context, userID int64, username string) error
+	Followers(ctx context.Context, userID int64) ([]models.Follow, error)
+	Following(ctx context.Context, userID int64) ([]models.Follow, error)
+	PublicFollowers(ctx context.Context, username string) ([]models.Follow, error)
+	PublicFollowing(ctx context.Context, username string) ([]models.Follow, error)
+	SetFollowersPrivate(ctx context.Context, userID int64, private bool) error
+	PostComment(ctx context.Context, userID int64, c models.Comment) (int64, error)
+	EditComment(ctx context.Context, userID, commentID int64, body string) error
+	DeleteComment(ctx context.Context, userID, commentID int64) error
+	ItemComments(ctx context.Context, username string, itemID int64) ([]models.Comment, error)
+	CollectionComments(ctx context.Context, username string, collectionID int64) ([]models.Comment, error)
+	Notifications(ctx context.Context, userID, before int64, limit int, unreadOnly bool) ([]models.Notification, error)
+	NotificationsAfter(ctx context.Context, userID, after int64) ([]models.Notification, error)
+	UnreadNotifications(ctx context.Context, userID int64) (int64, error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID int64) error
+	MarkAllNotificationsRead(ctx context.Context, userID int64) (int64, error)
+	NotificationPreferences(ctx context.Context, userID int64) ([]models.NotificationPreference, error)
+	SetNotificationPreferences(ctx context.Context, userID int64, prefs []models.NotificationPreference) error
+	SubscribeNotifications(userID int64) (<-chan models.Notification, func())
+	Feed(ctx context.Context, userID, before int64, limit int) ([]models.Activity, int64, error)
+	ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error)
+	UnshareCollection(ctx context.Context, ownerID, collectionID, shareID int64) error
+	LeaveCollection(ctx context.Context, userID, collectionID int64) error
+	CollectionShares(ctx context.Context, ownerID, collectionID int64) ([]models.CollectionShare, error)
+	SharedCollections(ctx context.Context, userID int64) ([]models.SharedCollection, error)
+	CreateShareLink(ctx context.Context, ownerID, collectionID int64, ttl time.Duration, password string, maxViews int64) (models.ShareLink, error)
+	ShareLinks(ctx context.Context, ownerID, collectionID int64) ([]models.ShareLink, error)
+	RevokeShareLink(ctx context.Context, ownerID, linkID int64) error
+	OpenShareLink(ctx context.Context, token, password string) (models.PublicCollection, []models.Item, error)
 }
 
 type Request struct {
@@ -50,14 +154,16 @@ type Request struct {
 
 type Response struct {
 	resp.Response
-	Users     []models.User `json:"users,omitempty"`
-	UserID    int64         `json:"user_id,omitempty"`
-	Username  string        `json:"username,omitempty"`
-	Phone     string        `json:"phone,omitempty"`
-	BirthDate *time.Time    `json:"birth_date,omitempty"`
-	Email     string        `json:"email,omitempty"`
-	Message   string        `json:"message,omitempty"`
-	Token     string        `json:"token,omitempty"`
+	Users      []models.User `json:"users,omitempty"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+	UserID     int64         `json:"user_id,omitempty"`
+	Username   string        `json:"username,omitempty"`
+	Phone      string        `json:"phone,omitempty"`
+	BirthDate  *time.Time    `json:"birth_date,omitempty"`
+	Email      string        `json:"email,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	Token      string        `json:"token,omitempty"`
+	ImageURL   string        `json:"profile_image_url,omitempty"`
 }
 
 type handler struct {
@@ -253,6 +359,7 @@ func (h *handler) Profile(log *slog.Logger) http.HandlerFunc {
 			Phone:     user.Phone,
 			BirthDate: &user.Birth_date,
 			Email:     user.Email,
+			ImageURL:  user.ImageUrl,
 			Message:   "user profile",
 		})
 	}
@@ -267,11 +374,20 @@ func (h *handler) Users(log *slog.Logger) http.HandlerFunc {
 			slog.String("request_id", middleware.GetReqID(r.Context())),
 		)
 
-		users, err := h.service.Users()
+		opts, err := parseListOptions(r)
+		if err != nil {
+			log.Error("failed to parse list options", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
+			return
+		}
+
+		users, next, err := h.service.Users(r.Context(), opts)
 		if err != nil {
 			log.Error("failed to get users", slog.String("err", err.Error()))
 
-			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			if !listError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
 			return
 		}
 
@@ -280,8 +396,9 @@ func (h *handler) Users(log *slog.Logger) http.HandlerFunc {
 				Status: response.OK().Status,
 				Error:  response.OK().Error,
 			},
-			Message: "users",
-			Users:   users,
+			Message:    "users",
+			Users:      users,
+			NextCursor: next,
 		})
 	}
 }
@@ -373,3 +490,29 @@ func (h *handler) UpdateUserInfo(log *slog.Logger) http.HandlerFunc {
 }
 
 // Get, uprate collection
+
+// userIDFromRequest returns the id of the authenticated user taken from the
+// JWT claims that the auth middleware put into the request context.
+func userIDFromRequest(r *http.Request) (int64, error) {
+	claims, err := jwt.GetClaimsFromContext(r.Context())
+	if err != nil {
+		return 0, err
+	}
+
+	userID, err := jwt.GetUserIDFromClaims(claims)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(userID, 10, 64)
+}
+
+// int64URLParam parses the named chi URL parameter as int64.
+func int64URLParam(r *http.Request, name string) (int64, error) {
+	param := chi.URLParam(r, name)
+	if param == "" {
+		return 0, fmt.Errorf("%s is empty", name)
+	}
+
+	return strconv.ParseInt(param, 10, 64)
+}
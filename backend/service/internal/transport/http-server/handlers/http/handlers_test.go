@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	mw "github.com/mmmakskl/HeritageKeeper/service/internal/transport/http-server/middleware"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret = "test-secret"
+	// testUploadSize is the per-file upload limit of the suite.
+	testUploadSize = 1 << 10
+)
+
+// testSuite serves the handlers over fake behind a real chi router, with
+// the routes and middleware of the app.
+type testSuite struct {
+	*testing.T
+	server *httptest.Server
+	fake   *fakeService
+}
+
+func newTestSuite(t *testing.T) *testSuite {
+	t.Helper()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	fake := newFakeService()
+	h := NewHandlers(nil, fake)
+	isAdmin := func(ctx context.Context, userID int64) (bool, error) {
+		return userID == adminID, nil
+	}
+	upload := mw.UploadMiddleware(testUploadSize, 1, time.Minute)
+	itemUpload := mw.UploadMiddleware(testUploadSize, MaxItemImages, time.Minute)
+
+	router := chi.NewRouter()
+	router.Route("/api", func(r chi.Router) {
+		r.Get("/keeper/categories", h.Categories(log))
+		r.Get("/keeper/public/collections/recent", h.RecentPublicCollections(log))
+		r.Get("/keeper/public/users/{username}", h.PublicProfile(log))
+		r.Get("/keeper/public/users/{username}/collections/{id}", h.PublicCollection(log))
+		r.Get("/keeper/public/users/{username}/followers", h.PublicFollowers(log))
+		r.Get("/keeper/public/links/{token}", h.PublicShareLink(log))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(mw.StreamAuthMiddleware(testSecret))
+		r.Get("/api/keeper/notifications/stream", h.NotificationStream(log))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(mw.JWTAuthMiddleware(testSecret))
+		r.Use(mw.AdminMiddleware(isAdmin))
+		r.Post("/api/keeper/category", h.CreateCategory(log))
+	})
+
+	router.Group(func(r chi.Router) {
+		r.Use(mw.JWTAuthMiddleware(testSecret))
+		r.With(itemUpload).Post("/api/keeper/collection/item", h.CreateItem(log))
+		r.Get("/api/keeper/collection/{id}/items", h.Items(log))
+		r.Get("/api/keeper/collection/item/{item_id}", h.Item(log))
+		r.Delete("/api/keeper/collection/item/{item_id}", h.DeleteItem(log))
+		r.Get("/api/keeper/collection/item/{item_id}/history", h.ItemHistory(log))
+		r.Post("/api/keeper/collection/item/{item_id}/revert", h.RevertItem(log))
+		r.With(upload).Post("/api/keeper/collection/item/{item_id}/images", h.UploadItemImage(log))
+		r.Get("/api/keeper/trash", h.Trash(log))
+		r.Post("/api/keeper/trash/collections/{id}/restore", h.RestoreCollection(log))
+		r.Post("/api/keeper/trash/items/{item_id}/restore", h.RestoreItem(log))
+		r.Post("/api/keeper/items/move", h.MoveItems(log))
+		r.Post("/api/keeper/items/bulk", h.UpdateItems(log))
+		r.Get("/api/keeper/collection/{id}/lot/{lot_id}", h.Lot(log))
+		r.Put("/api/keeper/collection/{id}/lot/{lot_id}/status", h.SetLotStatus(log))
+		r.Post("/api/keeper/lots/{lot_id}/offers", h.MakeOffer(log))
+		r.Post("/api/keeper/lots/{lot_id}/bids", h.PlaceBid(log))
+		r.Post("/api/keeper/offers/{offer_id}/accept", h.AcceptOffer(log))
+		r.Post("/api/keeper/trades/{trade_id}/accept", h.AcceptTrade(log))
+		r.Post("/api/keeper/wants", h.CreateWant(log))
+		r.Get("/api/keeper/wants/matches", h.WantMatches(log))
+		r.Put("/api/keeper/favorites/items/{item_id}", h.FavoriteItem(log))
+		r.Put("/api/keeper/following/{username}", h.Follow(log))
+		r.Post("/api/keeper/items/{item_id}/comments", h.PostItemComment(log))
+		r.Post("/api/keeper/notifications/stream/token", h.NotificationStreamToken(log, mw.StreamTokenSigner(testSecret)))
+		r.Put("/api/keeper/collection/{id}/shares", h.ShareCollection(log))
+		r.Delete("/api/keeper/collection/{id}/shares/{share_id}", h.UnshareCollection(log))
+		r.Post("/api/keeper/collection/{id}/links", h.CreateShareLink(log))
+	})
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	return &testSuite{T: t, server: server, fake: fake}
+}
+
+func testToken(t *testing.T, userID int64) string {
+	t.Helper()
+
+	token := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, jwtlib.MapClaims{
+		"uid": userID,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+
+	return signed
+}
+
+// send sends a request as userID (0 means anonymous) and returns the status
+// code and the body of the response.
+func (s *testSuite) send(req *http.Request, userID int64) (int, []byte) {
+	s.Helper()
+
+	if userID != 0 {
+		req.Header.Set("Authorization", "Bearer "+testToken(s.T, userID))
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(s, err)
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(s, err)
+
+	return res.StatusCode, body
+}
+
+// do sends a request as userID and decodes the JSON response into out.
+func (s *testSuite) do(method, path string, userID int64, body string, out any) int {
+	s.Helper()
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequest(method, s.server.URL+path, reader)
+	require.NoError(s, err)
+
+	code, data := s.send(req, userID)
+	if out != nil && code == http.StatusOK {
+		require.NoError(s, json.NewDecoder(bytes.NewReader(data)).Decode(out))
+	}
+
+	return code
+}
+
+// apiTest is one request to a fresh suite and what should come of it.
+// Responses of type R are checked.
+type apiTest[R any] struct {
+	name   string
+	method string
+	path   string
+	userID int64
+	body   string
+	// header is set on the request.
+	header http.Header
+	// setup changes the fixture before the request is sent.
+	setup func(f *fakeService)
+	// code is the expected status code, http.StatusOK when 0.
+	code int
+	// err is the expected error of the response, none when empty.
+	err string
+	// check checks the response and the fake after it.
+	check func(t *testing.T, f *fakeService, res R)
+}
+
+// runAPITests runs every test in a suite of its own.
+func runAPITests[R any](t *testing.T, tests []apiTest[R]) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestSuite(t)
+			if tt.setup != nil {
+				tt.setup(st.fake)
+			}
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequest(tt.method, st.server.URL+tt.path, body)
+			require.NoError(t, err)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+
+			code, data := st.send(req, tt.userID)
+			want := tt.code
+			if want == 0 {
+				want = http.StatusOK
+			}
+			require.Equal(t, want, code)
+
+			// Responses other than 200 come from the middleware and
+			// are not JSON, so check gets a zero R for them.
+			var res R
+			if code == http.StatusOK {
+				var status resp.Response
+				require.NoError(t, json.Unmarshal(data, &status))
+				if tt.err == "" {
+					assert.Equal(t, resp.StatusOK, status.Status, status.Error)
+				} else {
+					assert.Equal(t, resp.StatusError, status.Status)
+					assert.Contains(t, status.Error, tt.err)
+				}
+				require.NoError(t, json.Unmarshal(data, &res))
+			}
+
+			if tt.check != nil {
+				tt.check(t, st.fake, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/signedurl"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
+)
+
+// maxMultipartMemory is how much of a multipart upload is kept in memory
+// before the rest is spooled to a temporary file.
+const maxMultipartMemory = 8 << 20
+
+// MaxItemImages is how many images can be uploaded together with an item.
+const MaxItemImages = 10
+
+type ImageResponse struct {
+	resp.Response
+	Image      *models.Image      `json:"image,omitempty"`
+	Images     []models.Image     `json:"images,omitempty"`
+	ImageURL   string             `json:"url,omitempty"`
+	Duplicates []models.Duplicate `json:"duplicates,omitempty"`
+	Warning    string             `json:"warning,omitempty"`
+	Message    string             `json:"message,omitempty"`
+}
+
+// duplicateWarning is the warning shown when an upload looks like an image
+// the user already has.
+func duplicateWarning(duplicates []models.Duplicate) string {
+	if len(duplicates) == 0 {
+		return ""
+	}
+	return "a similar image already exists in your collections"
+}
+
+// imageError renders the client-facing message for image errors and reports
+// whether err was one of them.
+func imageError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, svc.ErrImageTooLarge):
+		render.JSON(w, r, response.Error(fmt.Sprintf("image is too large %d", http.StatusRequestEntityTooLarge)))
+	case errors.Is(err, svc.ErrUnsupportedImage):
+		render.JSON(w, r, response.Error(fmt.Sprintf("unsupported image type %d", http.StatusUnsupportedMediaType)))
+	case errors.Is(err, storage.ErrImageNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("image not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	default:
+		return false
+	}
+	return true
+}
+
+// formImage returns the "image" file of a multipart request.
+func formImage(r *http.Request) (io.ReadCloser, error) {
+	if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+		return nil, err
+	}
+
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// formImageError renders the error of reading a multipart upload.
+func formImageError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		render.JSON(w, r, response.Error(fmt.Sprintf("image is too large %d", http.StatusRequestEntityTooLarge)))
+		return
+	}
+	render.JSON(w, r, response.Error(fmt.Sprintf("image is required %d", http.StatusBadRequest)))
+}
+
+func (h *handler) UploadItemImage(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UploadItemImage"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		file, err := formImage(r)
+		if err != nil {
+			log.Error("failed to read image", slog.String("err", err.Error()))
+			formImageError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		isPrimary, _ := strconv.ParseBool(r.FormValue("is_primary"))
+
+		image, duplicates, err := h.service.UploadItemImage(r.Context(), userID, itemID, file, isPrimary)
+		if err != nil {
+			log.Error("failed to upload item image", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("item image uploaded", slog.Int64("image_id", image.ImageID))
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Image:      &image,
+			Duplicates: duplicates,
+			Warning:    duplicateWarning(duplicates),
+			Message:    "item image uploaded",
+		})
+	}
+}
+
+func (h *handler) ItemImages(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ItemImages"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		images, err := h.service.ItemImages(r.Context(), userID, itemID)
+		if err != nil {
+			log.Error("failed to get item images", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Images:  images,
+			Message: "item images",
+		})
+	}
+}
+
+func (h *handler) SetPrimaryItemImage(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.SetPrimaryItemImage"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		imageID, err := int64URLParam(r, "image_id")
+		if err != nil {
+			log.Error("failed to parse image id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse image id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.SetPrimaryItemImage(r.Context(), userID, itemID, imageID); err != nil {
+			log.Error("failed to set primary item image", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "primary image set",
+		})
+	}
+}
+
+func (h *handler) DeleteItemImage(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteItemImage"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		imageID, err := int64URLParam(r, "image_id")
+		if err != nil {
+			log.Error("failed to parse image id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse image id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.DeleteItemImage(r.Context(), userID, itemID, imageID); err != nil {
+			log.Error("failed to delete item image", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("item image deleted", slog.Int64("image_id", imageID))
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "item image deleted",
+		})
+	}
+}
+
+func (h *handler) UploadCollectionImage(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UploadCollectionImage"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		file, err := formImage(r)
+		if err != nil {
+			log.Error("failed to read image", slog.String("err", err.Error()))
+			formImageError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		isPrimary, _ := strconv.ParseBool(r.FormValue("is_primary"))
+
+		image, err := h.service.UploadCollectionImage(r.Context(), userID, collectionID, file, isPrimary)
+		if err != nil {
+			log.Error("failed to upload collection image", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("collection image uploaded", slog.Int64("image_id", image.ImageID))
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Image:   &image,
+			Message: "collection image uploaded",
+		})
+	}
+}
+
+func (h *handler) CollectionImages(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CollectionImages"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		images, err := h.service.CollectionImages(r.Context(), userID, collectionID)
+		if err != nil {
+			log.Error("failed to get collection images", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Images:  images,
+			Message: "collection images",
+		})
+	}
+}
+
+func (h *handler) DeleteCollectionImage(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteCollectionImage"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		imageID, err := int64URLParam(r, "image_id")
+		if err != nil {
+			log.Error("failed to parse image id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse image id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.DeleteCollectionImage(r.Context(), userID, collectionID, imageID); err != nil {
+			log.Error("failed to delete collection image", slog.String("err", err.Error()))
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("collection image deleted", slog.Int64("image_id", imageID))
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "collection image deleted",
+		})
+	}
+}
+
+func (h *handler) UploadAvatar(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UploadAvatar"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		file, err := formImage(r)
+		if err != nil {
+			log.Error("failed to read image", slog.String("err", err.Error()))
+			formImageError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		url, err := h.service.UploadAvatar(r.Context(), userID, file)
+		if err != nil {
+			log.Error("failed to upload avatar", slog.String("err", err.Error()))
+			if errors.Is(err, storage.ErrUserNotFound) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("user not found %d", http.StatusNotFound)))
+				return
+			}
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("avatar uploaded", slog.Int64("user_id", userID))
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			ImageURL: url,
+			Message:  "avatar uploaded",
+		})
+	}
+}
+
+// Image serves a stored image behind a signed URL produced by the service.
+func (h *handler) Image(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Image"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		key := chi.URLParam(r, "*")
+		query := r.URL.Query()
+
+		rc, info, err := h.service.OpenImage(r.Context(), key, query.Get("expires"), query.Get("sig"))
+		if err != nil {
+			log.Error("failed to open image", slog.String("key", key), slog.String("err", err.Error()))
+			switch {
+			case errors.Is(err, signedurl.ErrExpired), errors.Is(err, signedurl.ErrInvalidSignature):
+				http.Error(w, "Forbidden", http.StatusForbidden)
+			case errors.Is(err, blob.ErrNotFound), errors.Is(err, storage.ErrImageNotFound):
+				http.Error(w, "Not Found", http.StatusNotFound)
+			default:
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+			return
+		}
+		defer rc.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		if info.Size > 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		}
+		w.Header().Set("Cache-Control", "private, max-age=3600")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		if _, err := io.Copy(w, rc); err != nil {
+			log.Error("failed to write image", slog.String("err", err.Error()))
+		}
+	}
+}
+
+func (h *handler) Duplicates(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Duplicates"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		duplicates, err := h.service.Duplicates(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get duplicates", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, ImageResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Duplicates: duplicates,
+			Message:    "duplicates",
+		})
+	}
+}
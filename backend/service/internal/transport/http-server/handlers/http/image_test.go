@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemForm returns the multipart form that creates an item in collection
+// 10 with the given image files, and the header that goes with it.
+func itemForm(files ...string) (string, http.Header) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("item", `{"collection_id":10,"description":"silver","category_id":1,
+		"is_public":true,"title":"1 rouble 1914","country":"Russia","year":"1914"}`)
+	for _, content := range files {
+		part, _ := form.CreateFormFile("image", "coin.jpg")
+		part.Write([]byte(content))
+	}
+	form.Close()
+
+	return body.String(), http.Header{"Content-Type": {form.FormDataContentType()}}
+}
+
+func TestCreateItem_Images(t *testing.T) {
+	// createItem is a test that posts an item with files.
+	createItem := func(test apiTest[ItemResponse], files ...string) apiTest[ItemResponse] {
+		test.method, test.path, test.userID = http.MethodPost, "/api/keeper/collection/item", ownerID
+		test.body, test.header = itemForm(files...)
+		return test
+	}
+	// rolledBack checks that nothing of the item was kept.
+	rolledBack := func(t *testing.T, f *fakeService, res ItemResponse) {
+		assert.Len(t, f.items, 3)
+		assert.Empty(t, f.images)
+	}
+
+	runAPITests(t, []apiTest[ItemResponse]{
+		createItem(apiTest[ItemResponse]{
+			name: "duplicates",
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Len(t, res.Images, 2)
+				require.Len(t, res.Duplicates, 2)
+				assert.Equal(t, int64(100), res.Duplicates[0].Match.ItemID)
+				assert.Equal(t, 0.95, res.Duplicates[0].Similarity)
+				assert.NotEmpty(t, res.Warning)
+				assert.Contains(t, f.items, res.ItemID)
+			},
+		}, "img-front", "img-back"),
+		createItem(apiTest[ItemResponse]{
+			name: "bad image",
+			err:  "unsupported image type",
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				rolledBack(t, f, res)
+				// The item is deleted for good rather than sent to the trash.
+				assert.Len(t, f.discarded, 1)
+			},
+		}, "img-front", "<svg/>"),
+		createItem(apiTest[ItemResponse]{
+			name:  "too many images",
+			err:   "at most 10 images",
+			check: rolledBack,
+		}, strings.Split(strings.Repeat("img,", MaxItemImages+1), ",")[:MaxItemImages+1]...),
+		// Past the per-file limit times MaxItemImages plus the multipart
+		// overhead.
+		createItem(apiTest[ItemResponse]{
+			name:  "body too large",
+			err:   "image is too large 413",
+			check: rolledBack,
+		}, "img"+strings.Repeat("x", 2<<20)),
+	})
+}
@@ -1,43 +1,58 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
 	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
 	"github.com/mmmakskl/HeritageKeeper/service/lib/jwt"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
 )
 
 type ItemRequest struct {
 	CollectionID int64 `json:"collection_id" validate:"required"`
 	ItemID       int64 `json:"item_id"`
 	// CollectionName string   `json:"collection_name"`
-	Description string   `json:"description" validate:"required"`
-	CategoryID  int64    `json:"category_id" validate:"required"`
-	IsPublic    bool     `json:"is_public" validate:"required"`
-	Title       string   `json:"title" validate:"required"`
-	Country     string   `json:"country" validate:"required"`
-	Year        string   `json:"year" validate:"required"`
-	Images      []string `json:"item_images_url" validate:"required"`
-	Attributes  []string `json:"attributes"`
+	Description string            `json:"description" validate:"required"`
+	CategoryID  int64             `json:"category_id" validate:"required"`
+	IsPublic    bool              `json:"is_public" validate:"required"`
+	Title       string            `json:"title" validate:"required"`
+	Country     string            `json:"country" validate:"required"`
+	Year        string            `json:"year" validate:"required"`
+	Images      []string          `json:"item_images_url" validate:"required"`
+	Attributes  models.Attributes `json:"attributes"`
 }
 
 type ItemResponse struct {
 	resp.Response
-	CollectionID int64  `json:"collection_id"`
-	Title        string `json:"title"`
-	Description  string `json:"description"`
-	CategoryID   int64  `json:"category_id"`
-	IsPublic     bool   `json:"is_public"`
-	Message      string `json:"message"`
-	ItemID       int64  `json:"item_id"`
+	CollectionID int64                `json:"collection_id"`
+	Title        string               `json:"title"`
+	Description  string               `json:"description"`
+	CategoryID   int64                `json:"category_id"`
+	IsPublic     bool                 `json:"is_public"`
+	Message      string               `json:"message"`
+	ItemID       int64                `json:"item_id"`
+	Item         *models.Item         `json:"item,omitempty"`
+	Items        []models.Item        `json:"items,omitempty"`
+	NextCursor   string               `json:"next_cursor,omitempty"`
+	Images       []models.Image       `json:"images,omitempty"`
+	Duplicates   []models.Duplicate   `json:"duplicates,omitempty"`
+	Warning      string               `json:"warning,omitempty"`
+	Errors       svc.ValidationErrors `json:"errors,omitempty"`
 }
 
 func (h *handler) CreateItem(log *slog.Logger) http.HandlerFunc {
@@ -72,16 +87,39 @@ func (h *handler) CreateItem(log *slog.Logger) http.HandlerFunc {
 			return
 		}
 
+		// Images can be uploaded together with the item as multipart/form-data,
+		// with the item JSON in the "item" field and the files in "image".
 		var req ItemRequest
-
-		err = render.DecodeJSON(r.Body, &req)
+		var files []*multipart.FileHeader
+
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			if err = r.ParseMultipartForm(maxMultipartMemory); err == nil {
+				err = json.Unmarshal([]byte(r.FormValue("item")), &req)
+				files = r.MultipartForm.File["image"]
+				if req.Images == nil {
+					req.Images = []string{}
+				}
+			}
+		} else {
+			err = render.DecodeJSON(r.Body, &req)
+		}
 		if err != nil {
 			log.Error("failed to decode request", slog.String("err", err.Error()))
 
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				formImageError(w, r, err)
+				return
+			}
 			render.JSON(w, r, resp.Error("failed to decode request"))
 
 			return
 		}
+		if len(files) > MaxItemImages {
+			log.Error("too many images", slog.Int("count", len(files)))
+			render.JSON(w, r, response.Error(fmt.Sprintf("at most %d images can be uploaded with an item %d", MaxItemImages, http.StatusBadRequest)))
+			return
+		}
 
 		if err := validator.New().Struct(req); err != nil {
 			log.Error("invalid request", slog.String("err", err.Error()))
@@ -93,10 +131,38 @@ func (h *handler) CreateItem(log *slog.Logger) http.HandlerFunc {
 
 		itemID, err := h.service.SetItem(r.Context(), userIDInt, req.CollectionID, req.Title, req.Description, req.CategoryID, req.Country, req.Images, req.Year, req.Attributes)
 		if err != nil {
+			if errors.Is(err, storage.ErrCollectionNotFound) || errors.Is(err, storage.ErrItemNotFound) {
+				log.Error("item or collection not found", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+				return
+			}
 			log.Error("failed to create item in storage", slog.String("err", err.Error()))
+			if errs, ok := validationErrors(err); ok {
+				render.JSON(w, r, ItemResponse{
+					Response: response.Error(fmt.Sprintf("invalid attributes %d", http.StatusBadRequest)),
+					Errors:   errs,
+				})
+				return
+			}
+			if categoryError(w, r, err) {
+				return
+			}
 			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
 			return
 		}
+
+		images, duplicates, err := h.uploadItemImages(r.Context(), userIDInt, itemID, files)
+		if err != nil {
+			log.Error("failed to upload item images", slog.String("err", err.Error()))
+			if err := h.service.DiscardItem(r.Context(), userIDInt, itemID); err != nil {
+				log.Error("failed to discard item", slog.String("err", err.Error()))
+			}
+			if !imageError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
 		log.Info("item created", slog.Int64("item_id", itemID))
 		render.JSON(w, r, ItemResponse{
 			Response: resp.Response{
@@ -110,6 +176,9 @@ func (h *handler) CreateItem(log *slog.Logger) http.HandlerFunc {
 			IsPublic:     req.IsPublic,
 			Message:      "item created",
 			ItemID:       itemID,
+			Images:       images,
+			Duplicates:   duplicates,
+			Warning:      duplicateWarning(duplicates),
 		})
 	}
 }
@@ -182,7 +251,25 @@ func (h *handler) UpdateItem(log *slog.Logger) http.HandlerFunc {
 
 		err = h.service.UpdateItem(r.Context(), userIDInt, req.CollectionID, req.ItemID, req.Title, req.Description, req.CategoryID, req.Country, req.Images, req.Year, req.Attributes)
 		if err != nil {
+			if errors.Is(err, storage.ErrCollectionNotFound) || errors.Is(err, storage.ErrItemNotFound) {
+				log.Error("item or collection not found", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+				return
+			}
 			log.Error("failed to update item in storage", slog.String("err", err.Error()))
+			if itemLockedError(w, r, err) {
+				return
+			}
+			if errs, ok := validationErrors(err); ok {
+				render.JSON(w, r, ItemResponse{
+					Response: response.Error(fmt.Sprintf("invalid attributes %d", http.StatusBadRequest)),
+					Errors:   errs,
+				})
+				return
+			}
+			if categoryError(w, r, err) {
+				return
+			}
 			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
 			return
 		}
@@ -202,3 +289,194 @@ func (h *handler) UpdateItem(log *slog.Logger) http.HandlerFunc {
 		})
 	}
 }
+
+func (h *handler) Items(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Items"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		opts, err := parseListOptions(r)
+		if err != nil {
+			log.Error("failed to parse list options", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
+			return
+		}
+
+		items, next, err := h.service.Items(r.Context(), userID, collectionID, opts)
+		if err != nil {
+			if errors.Is(err, storage.ErrCollectionNotFound) {
+				log.Error("collection not found", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+				return
+			}
+			log.Error("failed to get items", slog.String("err", err.Error()))
+			if !listError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("items", slog.Int64("collection_id", collectionID))
+		render.JSON(w, r, ItemResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CollectionID: collectionID,
+			Message:      "items",
+			Items:        items,
+			NextCursor:   next,
+		})
+	}
+}
+
+func (h *handler) Item(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Item"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		item, err := h.service.Item(r.Context(), userID, itemID)
+		if err != nil {
+			if errors.Is(err, storage.ErrItemNotFound) {
+				log.Error("item not found", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+				return
+			}
+			log.Error("failed to get item", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		log.Info("item", slog.Int64("item_id", itemID))
+		render.JSON(w, r, ItemResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			CollectionID: item.CollectionID,
+			Title:        item.Title,
+			Description:  item.Description,
+			CategoryID:   item.CategoryID,
+			Message:      "item",
+			ItemID:       item.ItemID,
+			Item:         &item,
+		})
+	}
+}
+
+func (h *handler) DeleteItem(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteItem"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		err = h.service.DeleteItem(r.Context(), userID, itemID)
+		if err != nil {
+			if errors.Is(err, storage.ErrItemNotFound) {
+				log.Error("item not found", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+				return
+			}
+			log.Error("failed to delete item in storage", slog.String("err", err.Error()))
+			if itemLockedError(w, r, err) || itemInLotError(w, r, err) {
+				return
+			}
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		log.Info("item deleted", slog.Int64("item_id", itemID))
+		render.JSON(w, r, ItemResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "item deleted",
+			ItemID:  itemID,
+		})
+	}
+}
+
+// uploadItemImages stores the images sent along with a new item. If one of
+// them fails, the ones already stored are removed again.
+func (h *handler) uploadItemImages(ctx context.Context, userID, itemID int64, files []*multipart.FileHeader) ([]models.Image, []models.Duplicate, error) {
+	var images []models.Image
+	var duplicates []models.Duplicate
+
+	for _, fh := range files {
+		image, found, err := h.uploadItemImage(ctx, userID, itemID, fh)
+		if err != nil {
+			for _, image := range images {
+				_ = h.service.DeleteItemImage(ctx, userID, itemID, image.ImageID)
+			}
+			return nil, nil, err
+		}
+		images = append(images, image)
+		duplicates = append(duplicates, found...)
+	}
+
+	return images, duplicates, nil
+}
+
+func (h *handler) uploadItemImage(ctx context.Context, userID, itemID int64, fh *multipart.FileHeader) (models.Image, []models.Duplicate, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return models.Image{}, nil, err
+	}
+	defer file.Close()
+
+	return h.service.UploadItemImage(ctx, userID, itemID, file, false)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItems(t *testing.T) {
+	runAPITests(t, []apiTest[ItemResponse]{
+		{
+			name:   "owner",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/10/items",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Equal(t, int64(10), res.CollectionID)
+				assert.Len(t, res.Items, 2)
+			},
+		},
+		{
+			name:   "list options",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/10/items?sort=year&order=desc&limit=20&cursor=abc&country=Russia&year_from=1900&tags=silver,coin",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Equal(t, "next", res.NextCursor)
+				assert.Equal(t, models.ListOptions{
+					Sort: "year", Desc: true, Limit: 20, Cursor: "abc",
+					Country: "Russia", YearFrom: 1900, Tags: []string{"silver", "coin"},
+				}, f.opts)
+			},
+		},
+		{
+			name:   "invalid order",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/10/items?order=sideways",
+			userID: ownerID,
+			err:    "invalid order",
+		},
+		{
+			name:   "foreign collection",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/10/items",
+			userID: strangerID,
+			err:    "collection not found",
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Empty(t, res.Items)
+			},
+		},
+	})
+}
+
+func TestItem(t *testing.T) {
+	runAPITests(t, []apiTest[ItemResponse]{
+		{
+			name:   "owner",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/item/100",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				require.NotNil(t, res.Item)
+				assert.Equal(t, "1 rouble 1913", res.Item.Title)
+				assert.Equal(t, int64(10), res.CollectionID)
+			},
+		},
+		{name: "foreign item", method: http.MethodGet, path: "/api/keeper/collection/item/100", userID: strangerID, err: "item not found"},
+		{name: "missing item", method: http.MethodGet, path: "/api/keeper/collection/item/999", userID: ownerID, err: "item not found"},
+		{name: "bad id", method: http.MethodGet, path: "/api/keeper/collection/item/abc", userID: ownerID, err: "failed to parse item id"},
+		{name: "anonymous", method: http.MethodGet, path: "/api/keeper/collection/item/100", code: http.StatusUnauthorized},
+	})
+}
+
+func TestDeleteItem(t *testing.T) {
+	runAPITests(t, []apiTest[ItemResponse]{
+		{
+			name:   "owner",
+			method: http.MethodDelete,
+			path:   "/api/keeper/collection/item/100",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Equal(t, int64(100), res.ItemID)
+				assert.NotContains(t, f.items, int64(100))
+			},
+		},
+		{
+			name:   "foreign item",
+			method: http.MethodDelete,
+			path:   "/api/keeper/collection/item/100",
+			userID: strangerID,
+			err:    "item not found",
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Contains(t, f.items, int64(100))
+			},
+		},
+		{
+			name:   "in lot",
+			method: http.MethodDelete,
+			path:   "/api/keeper/collection/item/101",
+			userID: ownerID,
+			setup:  func(f *fakeService) { f.listed[101] = true },
+			err:    "item is in a draft or active lot 409",
+			check: func(t *testing.T, f *fakeService, res ItemResponse) {
+				assert.Contains(t, f.items, int64(101))
+			},
+		},
+	})
+}
@@ -0,0 +1,513 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// LotRequest carries the terms of a lot. Price is in minor units of Currency
+// and is left out for exchange-only lots. A lot with Auction is sold by
+// auction and Price is its opening bid.
+type LotRequest struct {
+	Title        string          `json:"title"`
+	Description  string          `json:"description"`
+	Price        int64           `json:"price"`
+	Currency     string          `json:"currency"`
+	ExchangeOnly bool            `json:"exchange_only"`
+	Status       string          `json:"status"`
+	ItemIDs      []int64         `json:"item_ids"`
+	Auction      *AuctionRequest `json:"auction"`
+}
+
+// AuctionRequest carries the terms of an auction. Amounts are in minor units
+// of the lot currency, Extension is in seconds.
+type AuctionRequest struct {
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	ReservePrice int64     `json:"reserve_price"`
+	MinIncrement int64     `json:"min_increment"`
+	Extension    int64     `json:"extension"`
+}
+
+func (r *AuctionRequest) auction() *models.Auction {
+	if r == nil {
+		return nil
+	}
+	return &models.Auction{
+		StartsAt:     r.StartsAt,
+		EndsAt:       r.EndsAt,
+		ReservePrice: r.ReservePrice,
+		MinIncrement: r.MinIncrement,
+		Extension:    r.Extension,
+	}
+}
+
+type LotStatusRequest struct {
+	Status string `json:"status" validate:"required"`
+}
+
+type LotResponse struct {
+	resp.Response
+	LotID      int64                `json:"id,omitempty"`
+	Lot        *models.Lot          `json:"lot,omitempty"`
+	Lots       []models.Lot         `json:"lots,omitempty"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+	Errors     svc.ValidationErrors `json:"errors,omitempty"`
+	Message    string               `json:"message,omitempty"`
+}
+
+// itemLockedError renders the conflict of changing an item that is reserved
+// or sold and reports whether err was one.
+func itemLockedError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, storage.ErrItemLocked) {
+		return false
+	}
+	render.JSON(w, r, response.Error(fmt.Sprintf("item is reserved or sold %d", http.StatusConflict)))
+	return true
+}
+
+// itemInLotError renders the conflict of deleting an item that a draft or
+// active lot still holds and reports whether err was one.
+func itemInLotError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if !errors.Is(err, storage.ErrItemInLot) {
+		return false
+	}
+	render.JSON(w, r, response.Error(fmt.Sprintf("item is in a draft or active lot %d", http.StatusConflict)))
+	return true
+}
+
+// lotError renders the client-facing message for lot errors and reports
+// whether err was one of them.
+func lotError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, LotResponse{
+			Response: response.Error(fmt.Sprintf("invalid lot %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrLotNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found in collection %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrLotLocked):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot cannot be changed in its current status %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrItemListed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item is already listed in another lot %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrAuctionClosed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("auction has ended %d", http.StatusConflict)))
+	case errors.Is(err, svc.ErrInvalidLotStatus):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid lot status %d", http.StatusBadRequest)))
+	default:
+		return listError(w, r, err)
+	}
+	return true
+}
+
+// collectionLot returns the lot {lot_id} of the user if it belongs to the
+// collection {id} of the route.
+func (h *handler) collectionLot(r *http.Request, userID int64) (models.Lot, error) {
+	collectionID, err := int64URLParam(r, "id")
+	if err != nil {
+		return models.Lot{}, storage.ErrCollectionNotFound
+	}
+	lotID, err := int64URLParam(r, "lot_id")
+	if err != nil {
+		return models.Lot{}, storage.ErrLotNotFound
+	}
+
+	lot, err := h.service.Lot(r.Context(), userID, lotID)
+	if err != nil {
+		return models.Lot{}, err
+	}
+	if lot.CollectionID != collectionID {
+		return models.Lot{}, storage.ErrLotNotFound
+	}
+
+	return lot, nil
+}
+
+func (h *handler) CreateLot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CreateLot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req LotRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		lotID, err := h.service.CreateLot(r.Context(), userID, models.Lot{
+			CollectionID: collectionID,
+			Title:        req.Title,
+			Description:  req.Description,
+			Price:        req.Price,
+			Currency:     req.Currency,
+			ExchangeOnly: req.ExchangeOnly,
+			Status:       req.Status,
+			ItemIDs:      req.ItemIDs,
+			Auction:      req.Auction.auction(),
+		})
+		if err != nil {
+			log.Error("failed to create lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("lot created", slog.Int64("lot_id", lotID))
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lotID,
+			Message: "lot created",
+		})
+	}
+}
+
+func (h *handler) UpdateLot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UpdateLot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lot, err := h.collectionLot(r, userID)
+		if err != nil {
+			log.Error("failed to get lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		var req LotRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		lot.Title = req.Title
+		lot.Description = req.Description
+		lot.Price = req.Price
+		lot.Currency = req.Currency
+		lot.ExchangeOnly = req.ExchangeOnly
+		lot.ItemIDs = req.ItemIDs
+		lot.Auction = req.Auction.auction()
+
+		if err := h.service.UpdateLot(r.Context(), userID, lot); err != nil {
+			log.Error("failed to update lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("lot updated", slog.Int64("lot_id", lot.LotID))
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lot.LotID,
+			Message: "lot updated",
+		})
+	}
+}
+
+func (h *handler) SetLotStatus(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.SetLotStatus"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lot, err := h.collectionLot(r, userID)
+		if err != nil {
+			log.Error("failed to get lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		var req LotStatusRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := h.service.SetLotStatus(r.Context(), userID, lot.LotID, req.Status); err != nil {
+			log.Error("failed to set lot status", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("lot status changed", slog.Int64("lot_id", lot.LotID), slog.String("status", req.Status))
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lot.LotID,
+			Message: "lot " + req.Status,
+		})
+	}
+}
+
+func (h *handler) DeleteLot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteLot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lot, err := h.collectionLot(r, userID)
+		if err == nil {
+			err = h.service.DeleteLot(r.Context(), userID, lot.LotID)
+		}
+		if err != nil {
+			log.Error("failed to delete lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("lot deleted", slog.Int64("lot_id", lot.LotID))
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lot.LotID,
+			Message: "lot deleted",
+		})
+	}
+}
+
+func (h *handler) Lot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Lot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lot, err := h.collectionLot(r, userID)
+		if err != nil {
+			log.Error("failed to get lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lot.LotID,
+			Lot:     &lot,
+			Message: "lot",
+		})
+	}
+}
+
+func (h *handler) Lots(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Lots"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		lots, err := h.service.Lots(r.Context(), userID, collectionID)
+		if err != nil {
+			log.Error("failed to get lots", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Lots:    lots,
+			Message: "lots",
+		})
+	}
+}
+
+// PublicLots is the lot catalogue. It takes the parameters of
+// parseListOptions; q matches the lot title, the item filters match lots
+// with at least one matching item.
+func (h *handler) PublicLots(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicLots"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		opts, err := parseListOptions(r)
+		if err != nil {
+			log.Error("failed to parse list options", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
+			return
+		}
+
+		lots, next, err := h.service.PublicLots(r.Context(), opts)
+		if err != nil {
+			log.Error("failed to get lots", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Lots:       lots,
+			NextCursor: next,
+			Message:    "lots",
+		})
+	}
+}
+
+func (h *handler) PublicLot(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicLot"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		lotID, err := int64URLParam(r, "lot_id")
+		if err != nil {
+			log.Error("failed to parse lot id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse lot id %d", http.StatusBadRequest)))
+			return
+		}
+
+		lot, err := h.service.PublicLot(r.Context(), lotID)
+		if err != nil {
+			log.Error("failed to get lot", slog.String("err", err.Error()))
+			if !lotError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, LotResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			LotID:   lot.LotID,
+			Lot:     &lot,
+			Message: "lot",
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLot(t *testing.T) {
+	runAPITests(t, []apiTest[LotResponse]{
+		{
+			name:   "owner",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/10/lot/5",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res LotResponse) {
+				require.NotNil(t, res.Lot)
+				assert.Equal(t, int64(5), res.Lot.LotID)
+			},
+		},
+		{name: "other collection", method: http.MethodGet, path: "/api/keeper/collection/11/lot/5", userID: ownerID, err: "lot not found"},
+		{name: "stranger", method: http.MethodGet, path: "/api/keeper/collection/10/lot/5", userID: strangerID, err: "lot not found"},
+	})
+}
+
+func TestSetLotStatus(t *testing.T) {
+	runAPITests(t, []apiTest[LotResponse]{
+		{
+			name:   "reserve",
+			method: http.MethodPut,
+			path:   "/api/keeper/collection/10/lot/5/status",
+			userID: ownerID,
+			body:   `{"status":"reserved"}`,
+			check: func(t *testing.T, f *fakeService, res LotResponse) {
+				assert.Equal(t, models.LotReserved, f.lots[5].Status)
+			},
+		},
+		{
+			name:   "sold",
+			method: http.MethodPut,
+			path:   "/api/keeper/collection/10/lot/6/status",
+			userID: ownerID,
+			body:   `{"status":"active"}`,
+			err:    "409",
+			check: func(t *testing.T, f *fakeService, res LotResponse) {
+				assert.Equal(t, models.LotSold, f.lots[6].Status)
+			},
+		},
+	})
+}
@@ -0,0 +1,487 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// streamHeartbeat is how often an idle notification stream sends a comment,
+// so proxies keep it open and dead clients are noticed.
+const streamHeartbeat = 25 * time.Second
+
+type NotificationPreferencesRequest struct {
+	Preferences []models.NotificationPreference `json:"preferences"`
+}
+
+type NotificationResponse struct {
+	resp.Response
+	Notifications []models.Notification           `json:"notifications,omitempty"`
+	Preferences   []models.NotificationPreference `json:"preferences,omitempty"`
+	// NextBefore is the before of the next page, 0 on the last one.
+	NextBefore int64                `json:"next_before,omitempty"`
+	Unread     *int64               `json:"unread,omitempty"`
+	Marked     int64                `json:"marked,omitempty"`
+	Errors     svc.ValidationErrors `json:"errors,omitempty"`
+	Message    string               `json:"message,omitempty"`
+}
+
+type StreamTokenResponse struct {
+	resp.Response
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// notificationError renders the client-facing message for notification
+// errors and reports whether err was one of them.
+func notificationError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, NotificationResponse{
+			Response: response.Error(fmt.Sprintf("invalid notification preferences %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrNotificationNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("notification not found %d", http.StatusNotFound)))
+	default:
+		return false
+	}
+	return true
+}
+
+// Notifications lists the user's notifications, newest first. It reads
+// ?before=&limit=&unread=true; before is the next_before of the previous
+// page.
+func (h *handler) Notifications(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Notifications"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		query := r.URL.Query()
+		before, err := queryID(query, "before")
+		if err != nil {
+			log.Error("failed to parse before", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse before %d", http.StatusBadRequest)))
+			return
+		}
+		limit, err := queryInt(query, "limit")
+		if err != nil {
+			log.Error("failed to parse limit", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse limit %d", http.StatusBadRequest)))
+			return
+		}
+		unreadOnly, _ := strconv.ParseBool(query.Get("unread"))
+
+		notifications, err := h.service.Notifications(r.Context(), userID, before, limit, unreadOnly)
+		if err != nil {
+			log.Error("failed to get notifications", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var next int64
+		if len(notifications) > 0 && len(notifications) == limit {
+			next = notifications[len(notifications)-1].NotificationID
+		}
+
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Notifications: notifications,
+			NextBefore:    next,
+			Message:       "notifications",
+		})
+	}
+}
+
+func (h *handler) UnreadNotifications(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UnreadNotifications"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		unread, err := h.service.UnreadNotifications(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to count unread notifications", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Unread:  &unread,
+			Message: "unread notifications",
+		})
+	}
+}
+
+func (h *handler) MarkNotificationRead(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.MarkNotificationRead"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		notificationID, err := int64URLParam(r, "notification_id")
+		if err != nil {
+			log.Error("failed to parse notification id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse notification id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.MarkNotificationRead(r.Context(), userID, notificationID); err != nil {
+			log.Error("failed to mark notification read", slog.String("err", err.Error()))
+			if !notificationError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "notification read",
+		})
+	}
+}
+
+func (h *handler) MarkAllNotificationsRead(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.MarkAllNotificationsRead"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		marked, err := h.service.MarkAllNotificationsRead(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to mark notifications read", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Marked:  marked,
+			Message: "notifications read",
+		})
+	}
+}
+
+func (h *handler) NotificationPreferences(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.NotificationPreferences"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		prefs, err := h.service.NotificationPreferences(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get notification preferences", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Preferences: prefs,
+			Message:     "notification preferences",
+		})
+	}
+}
+
+func (h *handler) SetNotificationPreferences(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.SetNotificationPreferences"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req NotificationPreferencesRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := h.service.SetNotificationPreferences(r.Context(), userID, req.Preferences); err != nil {
+			log.Error("failed to set notification preferences", slog.String("err", err.Error()))
+			if !notificationError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("notification preferences updated")
+		render.JSON(w, r, NotificationResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "notification preferences updated",
+		})
+	}
+}
+
+// NotificationStreamToken issues a short-lived token that opens the
+// notification stream as ?token=, for clients that cannot set headers.
+func (h *handler) NotificationStreamToken(log *slog.Logger, sign func(userID int64) (string, time.Time, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.NotificationStreamToken"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		token, expires, err := sign(userID)
+		if err != nil {
+			log.Error("failed to sign stream token", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, StreamTokenResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Token:     token,
+			ExpiresAt: expires,
+		})
+	}
+}
+
+// writeEvent sends one Server-Sent Event. id is left out when it is 0.
+func writeEvent(w http.ResponseWriter, rc *http.ResponseController, event string, id int64, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
+
+// NotificationStream pushes the user's notifications as Server-Sent Events
+// for as long as the connection is open. It opens with an "unread" event
+// carrying the unread count; each "notification" event has the id of the
+// notification, so a client reconnecting with Last-Event-ID first gets
+// what it missed. The stream ends when the client falls too far behind,
+// which makes it reconnect and catch up the same way. A browser opening a
+// new EventSource, which cannot set headers, passes ?last_event_id= and a
+// stream token instead.
+func (h *handler) NotificationStream(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.NotificationStream"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		// Subscribe before catching up so that nothing falls in between;
+		// what comes both ways is skipped by id.
+		events, unsubscribe := h.service.SubscribeNotifications(userID)
+		defer unsubscribe()
+
+		var missed []models.Notification
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+		lastID, _ := strconv.ParseInt(lastEventID, 10, 64)
+		if lastID > 0 {
+			missed, err = h.service.NotificationsAfter(r.Context(), userID, lastID)
+		} else {
+			// A new stream catches up from the newest notification there is
+			// if the service asks it to.
+			var newest []models.Notification
+			if newest, err = h.service.Notifications(r.Context(), userID, 0, 1, false); err == nil && len(newest) > 0 {
+				lastID = newest[0].NotificationID
+			}
+		}
+		if err != nil {
+			log.Error("failed to get missed notifications", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		unread, err := h.service.UnreadNotifications(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to count unread notifications", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		rc := http.NewResponseController(w)
+		// The stream outlives the write timeout of the server.
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to lift write deadline", slog.String("err", err.Error()))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if err := writeEvent(w, rc, "unread", 0, map[string]int64{"unread": unread}); err != nil {
+			return
+		}
+		for _, n := range missed {
+			if err := writeEvent(w, rc, "notification", n.NotificationID, n); err != nil {
+				return
+			}
+			lastID = n.NotificationID
+		}
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+					return
+				}
+				if err := rc.Flush(); err != nil {
+					return
+				}
+			case n, ok := <-events:
+				if !ok {
+					log.Info("notification stream fell behind")
+					return
+				}
+				if n.NotificationID == 0 {
+					// Notifications may have been missed. If catching up
+					// fails, the client reconnects and catches up itself.
+					missed, err := h.service.NotificationsAfter(r.Context(), userID, lastID)
+					if err != nil {
+						log.Error("failed to get missed notifications", slog.String("err", err.Error()))
+						return
+					}
+					for _, n := range missed {
+						if err := writeEvent(w, rc, "notification", n.NotificationID, n); err != nil {
+							return
+						}
+						lastID = n.NotificationID
+					}
+					continue
+				}
+				if n.NotificationID <= lastID {
+					continue
+				}
+				if err := writeEvent(w, rc, "notification", n.NotificationID, n); err != nil {
+					return
+				}
+				lastID = n.NotificationID
+			}
+		}
+	}
+}
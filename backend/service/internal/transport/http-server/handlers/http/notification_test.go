@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	mw "github.com/mmmakskl/HeritageKeeper/service/internal/transport/http-server/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamedFixture is what a stream of the fixture sends after catching up
+// from 4: the unread count, then notifications 5 and 6.
+const streamedFixture = "event: unread\ndata: {\"unread\":3}\n\n" +
+	"id: 5\nevent: notification\ndata: {\"id\":5,\"type\":\"reply\",\"payload\":null,\"read\":false,\"created_at\":\"\"}\n\n" +
+	"id: 6\nevent: notification\ndata: {\"id\":6,\"type\":\"offer\",\"payload\":null,\"read\":false,\"created_at\":\"\"}\n\n"
+
+func TestNotificationStream(t *testing.T) {
+	streamToken, _, err := mw.StreamTokenSigner(testSecret)(ownerID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		query  string
+		header http.Header
+		setup  func(f *fakeService)
+		code   int
+		// after are the ids the stream caught up from.
+		after []int64
+	}{
+		{
+			name:   "last event id",
+			header: http.Header{"Authorization": {"Bearer " + testToken(t, ownerID)}, "Last-Event-Id": {"4"}},
+			after:  []int64{4},
+		},
+		{
+			// The service missed notification 5, asks the stream to catch
+			// up and then goes on. A new stream catches up from the newest
+			// notification there was.
+			name:   "catches up when asked",
+			header: http.Header{"Authorization": {"Bearer " + testToken(t, ownerID)}},
+			setup: func(f *fakeService) {
+				f.notifications = append([]models.Notification{{}}, f.notifications...)
+			},
+			after: []int64{4},
+		},
+		{
+			// The token and the last event id come in the query, as an
+			// EventSource sends them.
+			name:  "stream token",
+			query: "?last_event_id=4&token=" + url.QueryEscape(streamToken),
+			after: []int64{4},
+		},
+		{
+			name:  "session token in query",
+			query: "?token=" + url.QueryEscape(testToken(t, ownerID)),
+			code:  http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := newTestSuite(t)
+			if tt.setup != nil {
+				tt.setup(st.fake)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, st.server.URL+"/api/keeper/notifications/stream"+tt.query, nil)
+			require.NoError(t, err)
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+
+			code, body := st.send(req, 0)
+			if tt.code != 0 {
+				assert.Equal(t, tt.code, code)
+				return
+			}
+			require.Equal(t, http.StatusOK, code)
+			assert.Equal(t, tt.after, st.fake.after)
+			assert.Equal(t, streamedFixture, string(body))
+		})
+	}
+}
+
+func TestNotificationStreamToken(t *testing.T) {
+	st := newTestSuite(t)
+
+	var token StreamTokenResponse
+	st.do(http.MethodPost, "/api/keeper/notifications/stream/token", ownerID, "", &token)
+	require.NotEmpty(t, token.Token)
+	assert.WithinDuration(t, time.Now().Add(mw.StreamTokenTTL), token.ExpiresAt, 5*time.Second)
+
+	// A stream token opens nothing else.
+	req, err := http.NewRequest(http.MethodPost, st.server.URL+"/api/keeper/notifications/stream/token", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	code, _ := st.send(req, 0)
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// OfferRequest carries an offer or a counter offer. Amount is in minor units
+// of the lot currency; an offer without ExpiresAt expires in three days.
+type OfferRequest struct {
+	Amount    int64     `json:"amount"`
+	Message   string    `json:"message"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type OfferResponse struct {
+	resp.Response
+	OfferID int64                `json:"id,omitempty"`
+	Offer   *models.Offer        `json:"offer,omitempty"`
+	Offers  []models.Offer       `json:"offers,omitempty"`
+	Errors  svc.ValidationErrors `json:"errors,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// offerError renders the client-facing message for offer errors and reports
+// whether err was one of them.
+func offerError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, OfferResponse{
+			Response: response.Error(fmt.Sprintf("invalid offer %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrOfferNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("offer not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrLotNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrOfferClosed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("offer is no longer pending %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrLotLocked):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot is not taking offers %d", http.StatusConflict)))
+	case errors.Is(err, svc.ErrNotYourTurn):
+		render.JSON(w, r, response.Error(fmt.Sprintf("offer is waiting for the other party %d", http.StatusConflict)))
+	case errors.Is(err, svc.ErrOwnLot):
+		render.JSON(w, r, response.Error(fmt.Sprintf("cannot make an offer on your own lot %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrAuctionLot):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot is sold by auction %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrLotNotForSale):
+		render.JSON(w, r, response.Error(fmt.Sprintf("lot is offered for exchange only %d", http.StatusBadRequest)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) MakeOffer(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.MakeOffer"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		lotID, err := int64URLParam(r, "lot_id")
+		if err != nil {
+			log.Error("failed to parse lot id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse lot id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req OfferRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		offerID, err := h.service.MakeOffer(r.Context(), userID, lotID, models.Offer{
+			Amount:    req.Amount,
+			Message:   req.Message,
+			ExpiresAt: req.ExpiresAt,
+		})
+		if err != nil {
+			log.Error("failed to make offer", slog.String("err", err.Error()))
+			if !offerError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("offer made", slog.Int64("offer_id", offerID))
+		render.JSON(w, r, OfferResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			OfferID: offerID,
+			Message: "offer made",
+		})
+	}
+}
+
+func (h *handler) CounterOffer(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CounterOffer"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		offerID, err := int64URLParam(r, "offer_id")
+		if err != nil {
+			log.Error("failed to parse offer id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse offer id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req OfferRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		counterID, err := h.service.CounterOffer(r.Context(), userID, offerID, models.Offer{
+			Amount:    req.Amount,
+			Message:   req.Message,
+			ExpiresAt: req.ExpiresAt,
+		})
+		if err != nil {
+			log.Error("failed to counter offer", slog.String("err", err.Error()))
+			if !offerError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("offer countered", slog.Int64("offer_id", offerID), slog.Int64("counter_id", counterID))
+		render.JSON(w, r, OfferResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			OfferID: counterID,
+			Message: "offer countered",
+		})
+	}
+}
+
+// answerOffer builds the handlers that accept or reject the offer
+// {offer_id}.
+func (h *handler) answerOffer(log *slog.Logger, op, done string, answer func(r *http.Request, userID, offerID int64) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		offerID, err := int64URLParam(r, "offer_id")
+		if err != nil {
+			log.Error("failed to parse offer id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse offer id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := answer(r, userID, offerID); err != nil {
+			log.Error("failed to answer offer", slog.String("err", err.Error()))
+			if !offerError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info(done, slog.Int64("offer_id", offerID))
+		render.JSON(w, r, OfferResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			OfferID: offerID,
+			Message: done,
+		})
+	}
+}
+
+func (h *handler) AcceptOffer(log *slog.Logger) http.HandlerFunc {
+	return h.answerOffer(log, "handlers.auth.AcceptOffer", "offer accepted", func(r *http.Request, userID, offerID int64) error {
+		return h.service.AcceptOffer(r.Context(), userID, offerID)
+	})
+}
+
+func (h *handler) RejectOffer(log *slog.Logger) http.HandlerFunc {
+	return h.answerOffer(log, "handlers.auth.RejectOffer", "offer rejected", func(r *http.Request, userID, offerID int64) error {
+		return h.service.RejectOffer(r.Context(), userID, offerID)
+	})
+}
+
+func (h *handler) Offer(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Offer"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		offerID, err := int64URLParam(r, "offer_id")
+		if err != nil {
+			log.Error("failed to parse offer id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse offer id %d", http.StatusBadRequest)))
+			return
+		}
+
+		offer, err := h.service.Offer(r.Context(), userID, offerID)
+		if err != nil {
+			log.Error("failed to get offer", slog.String("err", err.Error()))
+			if !offerError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, OfferResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			OfferID: offer.OfferID,
+			Offer:   &offer,
+			Message: "offer",
+		})
+	}
+}
+
+// Offers lists the offers the user made or received, on the lot {lot_id}
+// when the route has one.
+func (h *handler) Offers(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Offers"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var lotID int64
+		if chi.URLParam(r, "lot_id") != "" {
+			lotID, err = int64URLParam(r, "lot_id")
+			if err != nil {
+				log.Error("failed to parse lot id", slog.String("err", err.Error()))
+				render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse lot id %d", http.StatusBadRequest)))
+				return
+			}
+		}
+
+		offers, err := h.service.Offers(r.Context(), userID, lotID)
+		if err != nil {
+			log.Error("failed to get offers", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, OfferResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Offers:  offers,
+			Message: "offers",
+		})
+	}
+}
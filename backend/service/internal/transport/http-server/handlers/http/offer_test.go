@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeOffer(t *testing.T) {
+	runAPITests(t, []apiTest[OfferResponse]{
+		{
+			name:   "offer",
+			method: http.MethodPost,
+			path:   "/api/keeper/lots/5/offers",
+			userID: strangerID,
+			body:   `{"amount":9000,"message":"hi","expires_at":"2030-01-02T15:04:05Z"}`,
+			check: func(t *testing.T, f *fakeService, res OfferResponse) {
+				assert.NotZero(t, res.OfferID)
+				assert.Equal(t, int64(9000), f.offer.Amount)
+				assert.Equal(t, 2030, f.offer.ExpiresAt.Year())
+			},
+		},
+		{
+			name:   "no amount",
+			method: http.MethodPost,
+			path:   "/api/keeper/lots/5/offers",
+			userID: strangerID,
+			body:   `{"amount":0}`,
+			err:    "invalid offer 400",
+			check: func(t *testing.T, f *fakeService, res OfferResponse) {
+				assert.Contains(t, res.Errors, "amount")
+			},
+		},
+	})
+}
+
+func TestAcceptOffer(t *testing.T) {
+	runAPITests(t, []apiTest[OfferResponse]{
+		{
+			name:   "pending",
+			method: http.MethodPost,
+			path:   "/api/keeper/offers/7/accept",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res OfferResponse) {
+				assert.Equal(t, models.OfferAccepted, f.offers[7].Status)
+			},
+		},
+		{
+			name:   "accepted already",
+			method: http.MethodPost,
+			path:   "/api/keeper/offers/7/accept",
+			userID: ownerID,
+			setup: func(f *fakeService) {
+				offer := f.offers[7]
+				offer.Status = models.OfferAccepted
+				f.offers[7] = offer
+			},
+			err: "no longer pending",
+		},
+		{name: "buyer", method: http.MethodPost, path: "/api/keeper/offers/7/accept", userID: strangerID, err: "offer not found"},
+	})
+}
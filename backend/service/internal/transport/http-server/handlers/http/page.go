@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+)
+
+// parseListOptions reads the paging, sorting and filter parameters of the
+// list endpoints: ?cursor=&limit=&sort=title&order=desc&q=&category_id=
+// &country=&year_from=&year_to=&tags=a,b&is_public=, and for lots
+// &currency=&price_min=&price_max=&exchange_only=.
+func parseListOptions(r *http.Request) (models.ListOptions, error) {
+	query := r.URL.Query()
+	opts := models.ListOptions{
+		Sort:     query.Get("sort"),
+		Cursor:   query.Get("cursor"),
+		Query:    query.Get("q"),
+		Country:  query.Get("country"),
+		Currency: query.Get("currency"),
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return models.ListOptions{}, fmt.Errorf("invalid order")
+	}
+
+	var err error
+	ints := []struct {
+		name string
+		dest *int
+	}{
+		{"limit", &opts.Limit},
+		{"year_from", &opts.YearFrom},
+		{"year_to", &opts.YearTo},
+	}
+	for _, p := range ints {
+		if *p.dest, err = queryInt(query, p.name); err != nil {
+			return models.ListOptions{}, fmt.Errorf("invalid %s", p.name)
+		}
+	}
+
+	bools := []struct {
+		name string
+		dest **bool
+	}{
+		{"is_public", &opts.IsPublic},
+		{"exchange_only", &opts.ExchangeOnly},
+	}
+	for _, p := range bools {
+		if value := query.Get(p.name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return models.ListOptions{}, fmt.Errorf("invalid %s", p.name)
+			}
+			*p.dest = &b
+		}
+	}
+
+	int64s := []struct {
+		name string
+		dest *int64
+	}{
+		{"category_id", &opts.CategoryID},
+		{"price_min", &opts.PriceMin},
+		{"price_max", &opts.PriceMax},
+	}
+	for _, p := range int64s {
+		if value := query.Get(p.name); value != "" {
+			if *p.dest, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return models.ListOptions{}, fmt.Errorf("invalid %s", p.name)
+			}
+		}
+	}
+
+	for _, param := range query["tags"] {
+		for _, tag := range strings.Split(param, ",") {
+			if strings.TrimSpace(tag) != "" {
+				opts.Tags = append(opts.Tags, tag)
+			}
+		}
+	}
+
+	return opts, nil
+}
+
+// listError renders the client errors of the list endpoints and reports
+// whether err was one of them.
+func listError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, svc.ErrInvalidCursor):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid cursor %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrInvalidSort):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid sort %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrInvalidPriceRange):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid price range %d", http.StatusBadRequest)))
+	default:
+		return searchError(w, r, err)
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// PublicResponse is returned by the unauthenticated browsing routes. It only
+// carries public models, never models.User or models.Collection.
+type PublicResponse struct {
+	resp.Response
+	User        *models.PublicUser        `json:"user,omitempty"`
+	Collection  *models.PublicCollection  `json:"collection,omitempty"`
+	Collections []models.PublicCollection `json:"collections,omitempty"`
+	Item        *models.Item              `json:"item,omitempty"`
+	Items       []models.Item             `json:"items,omitempty"`
+	Images      []models.Image            `json:"images,omitempty"`
+	Message     string                    `json:"message"`
+}
+
+// publicError renders not found errors of the public routes. A private
+// collection is reported exactly like a missing one.
+func publicError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrUserNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("user not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) PublicProfile(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicProfile"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		username := chi.URLParam(r, "username")
+
+		user, collections, err := h.service.PublicProfile(r.Context(), username)
+		if err != nil {
+			log.Error("failed to get public profile", slog.String("err", err.Error()))
+			if !publicError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, PublicResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			User:        &user,
+			Collections: collections,
+			Message:     "public profile",
+		})
+	}
+}
+
+func (h *handler) PublicCollection(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicCollection"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		collection, items, err := h.service.PublicCollection(r.Context(), chi.URLParam(r, "username"), collectionID)
+		if err != nil {
+			log.Error("failed to get public collection", slog.String("err", err.Error()))
+			if !publicError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, PublicResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Collection: &collection,
+			Items:      items,
+			Message:    "public collection",
+		})
+	}
+}
+
+func (h *handler) PublicItem(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicItem"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		item, images, err := h.service.PublicItem(r.Context(), chi.URLParam(r, "username"), itemID)
+		if err != nil {
+			log.Error("failed to get public item", slog.String("err", err.Error()))
+			if !publicError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, PublicResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Item:    &item,
+			Images:  images,
+			Message: "public item",
+		})
+	}
+}
+
+func (h *handler) RecentPublicCollections(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.RecentPublicCollections"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		collections, err := h.service.RecentPublicCollections(r.Context(), limit)
+		if err != nil {
+			log.Error("failed to get recent public collections", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, PublicResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Collections: collections,
+			Message:     "recent public collections",
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicProfile(t *testing.T) {
+	runAPITests(t, []apiTest[PublicResponse]{
+		{
+			name:   "anonymous",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/users/owner",
+			check: func(t *testing.T, f *fakeService, res PublicResponse) {
+				require.NotNil(t, res.User)
+				assert.Equal(t, "owner", res.User.Username)
+				require.Len(t, res.Collections, 1)
+				assert.Equal(t, int64(10), res.Collections[0].CollectionID)
+			},
+		},
+		{name: "unknown user", method: http.MethodGet, path: "/api/keeper/public/users/nobody", err: "user not found"},
+	})
+}
+
+func TestPublicProfile_NoPrivateFields(t *testing.T) {
+	runAPITests(t, []apiTest[json.RawMessage]{{
+		name:   "anonymous",
+		method: http.MethodGet,
+		path:   "/api/keeper/public/users/owner",
+		check: func(t *testing.T, f *fakeService, res json.RawMessage) {
+			for _, field := range []string{"email", "phone", "birth_date", "is_public", "user_id"} {
+				assert.NotContains(t, string(res), `"`+field+`"`)
+			}
+		},
+	}})
+}
+
+func TestPublicCollection(t *testing.T) {
+	// Private and foreign collections look exactly like missing ones.
+	notFound := func(t *testing.T, f *fakeService, res PublicResponse) {
+		assert.Nil(t, res.Collection)
+	}
+
+	runAPITests(t, []apiTest[PublicResponse]{
+		{
+			name:   "public",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/users/owner/collections/10",
+			check: func(t *testing.T, f *fakeService, res PublicResponse) {
+				require.NotNil(t, res.Collection)
+				assert.Len(t, res.Items, 2)
+			},
+		},
+		{name: "private", method: http.MethodGet, path: "/api/keeper/public/users/owner/collections/11", err: "collection not found", check: notFound},
+		{name: "foreign", method: http.MethodGet, path: "/api/keeper/public/users/stranger/collections/10", err: "collection not found", check: notFound},
+		{name: "missing", method: http.MethodGet, path: "/api/keeper/public/users/owner/collections/99", err: "collection not found", check: notFound},
+	})
+}
+
+func TestRecentPublicCollections(t *testing.T) {
+	runAPITests(t, []apiTest[PublicResponse]{{
+		name:   "anonymous",
+		method: http.MethodGet,
+		path:   "/api/keeper/public/collections/recent?limit=5",
+		check: func(t *testing.T, f *fakeService, res PublicResponse) {
+			assert.Len(t, res.Collections, 1)
+		},
+	}})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+)
+
+type SearchResponse struct {
+	resp.Response
+	models.SearchResult
+	Message string `json:"message"`
+}
+
+type FacetsResponse struct {
+	resp.Response
+	Facets  models.Facets `json:"facets"`
+	Message string        `json:"message"`
+}
+
+// queryInt parses an optional integer query parameter.
+func queryInt(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// queryID parses an optional id query parameter, 0 when it is missing.
+func queryID(query url.Values, name string) (int64, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err == nil && id < 0 {
+		err = fmt.Errorf("%s is negative", name)
+	}
+	return id, err
+}
+
+// parseSearchFilter reads ?q=&collection_id=&category_id=&country=
+// &year_from=&year_to=&tags=a,b&limit=&offset=.
+func parseSearchFilter(r *http.Request) (models.SearchFilter, error) {
+	query := r.URL.Query()
+	filter := models.SearchFilter{
+		Query:   query.Get("q"),
+		Country: query.Get("country"),
+	}
+
+	var err error
+	ints := []struct {
+		name string
+		dest *int
+	}{
+		{"year_from", &filter.YearFrom},
+		{"year_to", &filter.YearTo},
+		{"limit", &filter.Limit},
+		{"offset", &filter.Offset},
+	}
+	for _, p := range ints {
+		if *p.dest, err = queryInt(query, p.name); err != nil {
+			return models.SearchFilter{}, fmt.Errorf("invalid %s", p.name)
+		}
+	}
+
+	ids := []struct {
+		name string
+		dest *int64
+	}{
+		{"category_id", &filter.CategoryID},
+		{"collection_id", &filter.CollectionID},
+	}
+	for _, p := range ids {
+		if value := query.Get(p.name); value != "" {
+			if *p.dest, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return models.SearchFilter{}, fmt.Errorf("invalid %s", p.name)
+			}
+		}
+	}
+
+	for _, param := range query["tags"] {
+		for _, tag := range strings.Split(param, ",") {
+			if strings.TrimSpace(tag) != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+
+	return filter, nil
+}
+
+// searchError renders the client errors of search and facets and reports
+// whether err was one of them.
+func searchError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, svc.ErrInvalidQuery):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid search query %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrInvalidYearRange):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid year range %d", http.StatusBadRequest)))
+	case errors.Is(err, svc.ErrInvalidTag):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid tag %d", http.StatusBadRequest)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) Search(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Search"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		filter, err := parseSearchFilter(r)
+		if err != nil {
+			log.Error("failed to parse search filter", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
+			return
+		}
+
+		result, err := h.service.Search(r.Context(), userID, filter)
+		if err != nil {
+			log.Error("failed to search", slog.String("err", err.Error()))
+			if !searchError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, SearchResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			SearchResult: result,
+			Message:      "search results",
+		})
+	}
+}
+
+// Facets takes the same parameters as Search; either q or collection_id is
+// required.
+func (h *handler) Facets(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Facets"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		filter, err := parseSearchFilter(r)
+		if err != nil {
+			log.Error("failed to parse search filter", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("%s %d", err.Error(), http.StatusBadRequest)))
+			return
+		}
+
+		facets, err := h.service.Facets(r.Context(), userID, filter)
+		if err != nil {
+			log.Error("failed to count facets", slog.String("err", err.Error()))
+			if !searchError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, FacetsResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Facets:  facets,
+			Message: "facets",
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// ShareRequest shares a collection with User, a username or an email, as
+// Role: viewer or editor.
+type ShareRequest struct {
+	User string `json:"user"`
+	Role string `json:"role"`
+}
+
+type ShareResponse struct {
+	resp.Response
+	Share       *models.CollectionShare   `json:"share,omitempty"`
+	Shares      []models.CollectionShare  `json:"shares,omitempty"`
+	Collections []models.SharedCollection `json:"collections,omitempty"`
+	Errors      svc.ValidationErrors      `json:"errors,omitempty"`
+	Message     string                    `json:"message,omitempty"`
+}
+
+// shareError renders the client-facing message for sharing errors and
+// reports whether err was one of them.
+func shareError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, ShareResponse{
+			Response: response.Error(fmt.Sprintf("invalid share %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrUserNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("user not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrShareNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("share not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrShareSelf):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collections cannot be shared with their owner %d", http.StatusBadRequest)))
+	case errors.Is(err, storage.ErrOwnerOnly):
+		render.JSON(w, r, response.Error(fmt.Sprintf("only the owner of the collection can do this %d", http.StatusForbidden)))
+	default:
+		return false
+	}
+	return true
+}
+
+// ShareCollection shares a collection of the user, or changes the role of
+// someone it is shared with.
+func (h *handler) ShareCollection(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ShareCollection"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req ShareRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		share, err := h.service.ShareCollection(r.Context(), userID, collectionID, req.User, req.Role)
+		if err != nil {
+			log.Error("failed to share collection", slog.String("err", err.Error()))
+			if !shareError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("collection shared", slog.Int64("share_id", share.ShareID))
+		render.JSON(w, r, ShareResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Share:   &share,
+			Message: "collection shared",
+		})
+	}
+}
+
+func (h *handler) UnshareCollection(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UnshareCollection"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		shareID, err := int64URLParam(r, "share_id")
+		if err != nil {
+			log.Error("failed to parse share id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse share id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.UnshareCollection(r.Context(), userID, collectionID, shareID); err != nil {
+			log.Error("failed to unshare collection", slog.String("err", err.Error()))
+			if !shareError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("collection unshared", slog.Int64("share_id", shareID))
+		render.JSON(w, r, ShareResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "share revoked",
+		})
+	}
+}
+
+func (h *handler) CollectionShares(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CollectionShares"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		shares, err := h.service.CollectionShares(r.Context(), userID, collectionID)
+		if err != nil {
+			log.Error("failed to get collection shares", slog.String("err", err.Error()))
+			if !shareError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, ShareResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Shares:  shares,
+			Message: "collection shares",
+		})
+	}
+}
+
+// SharedCollections lists the collections others shared with the user.
+func (h *handler) SharedCollections(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.SharedCollections"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		shared, err := h.service.SharedCollections(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get shared collections", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, ShareResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Collections: shared,
+			Message:     "shared collections",
+		})
+	}
+}
+
+// LeaveCollection drops a collection shared with the user.
+func (h *handler) LeaveCollection(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.LeaveCollection"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.LeaveCollection(r.Context(), userID, collectionID); err != nil {
+			log.Error("failed to leave collection", slog.String("err", err.Error()))
+			if !shareError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("collection left", slog.Int64("collection_id", collectionID))
+		render.JSON(w, r, ShareResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "collection left",
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareCollection(t *testing.T) {
+	runAPITests(t, []apiTest[ShareResponse]{
+		{
+			name:   "invite",
+			method: http.MethodPut,
+			path:   "/api/keeper/collection/10/shares",
+			userID: ownerID,
+			body:   `{"user":"friend@example.com","role":"viewer"}`,
+			check: func(t *testing.T, f *fakeService, res ShareResponse) {
+				require.NotNil(t, res.Share)
+				assert.True(t, res.Share.Pending)
+				assert.Equal(t, models.RoleViewer, res.Share.Role)
+				assert.Contains(t, f.shares, res.Share.ShareID)
+			},
+		},
+		{
+			name:   "unknown user",
+			method: http.MethodPut,
+			path:   "/api/keeper/collection/10/shares",
+			userID: ownerID,
+			body:   `{"user":"nobody","role":"viewer"}`,
+			err:    "user not found 404",
+		},
+		{
+			name:   "foreign collection",
+			method: http.MethodPut,
+			path:   "/api/keeper/collection/10/shares",
+			userID: strangerID,
+			body:   `{"user":"friend","role":"viewer"}`,
+			err:    "collection not found 404",
+		},
+	})
+}
+
+func TestUnshareCollection(t *testing.T) {
+	runAPITests(t, []apiTest[ShareResponse]{
+		{
+			name:   "share",
+			method: http.MethodDelete,
+			path:   "/api/keeper/collection/10/shares/1",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res ShareResponse) {
+				assert.NotContains(t, f.shares, int64(1))
+			},
+		},
+		{name: "missing share", method: http.MethodDelete, path: "/api/keeper/collection/10/shares/2", userID: ownerID, err: "share not found 404"},
+		{name: "other collection", method: http.MethodDelete, path: "/api/keeper/collection/11/shares/1", userID: ownerID, err: "share not found 404"},
+	})
+}
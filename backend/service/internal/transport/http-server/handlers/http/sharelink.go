@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// shareLinkPasswordHeader carries the password of a share link, which
+// stays out of URLs and with them out of logs.
+const shareLinkPasswordHeader = "X-Share-Password"
+
+// ShareLinkRequest creates a share link. ExpiresIn is in seconds, a week
+// when 0; MaxViews of 0 allows any number of views.
+type ShareLinkRequest struct {
+	ExpiresIn int64  `json:"expires_in"`
+	Password  string `json:"password"`
+	MaxViews  int64  `json:"max_views"`
+}
+
+type ShareLinkResponse struct {
+	resp.Response
+	Link    *models.ShareLink    `json:"link,omitempty"`
+	Links   []models.ShareLink   `json:"links,omitempty"`
+	Errors  svc.ValidationErrors `json:"errors,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// shareLinkError renders the client-facing message for share link errors
+// and reports whether err was one of them.
+func shareLinkError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, ShareLinkResponse{
+			Response: response.Error(fmt.Sprintf("invalid share link %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrShareLinkNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("share link not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrShareLinkPassword):
+		render.JSON(w, r, response.Error(fmt.Sprintf("wrong share link password %d", http.StatusUnauthorized)))
+	default:
+		return false
+	}
+	return true
+}
+
+// CreateShareLink makes a link to a collection of the user. The token in
+// the response is shown only this once.
+func (h *handler) CreateShareLink(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CreateShareLink"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := int64URLParam(r, "id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req ShareLinkRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		link, err := h.service.CreateShareLink(r.Context(), userID, collectionID, time.Duration(req.ExpiresIn)*time.Second, req.Password, req.MaxViews)
+		if err != nil {
+			log.Error("failed to create share link", slog.String("err", err.Error()))
+			if !shareLinkError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("share link created", slog.Int64("link_id", link.LinkID))
+		render.JSON(w, r, ShareLinkResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Link:    &link,
+			Message: "share link created",
+		})
+	}
+}
+
+// ShareLinks lists the outstanding links of the user, those to the
+// collection ?collection_id only when it is given.
+func (h *handler) ShareLinks(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ShareLinks"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		collectionID, err := queryID(r.URL.Query(), "collection_id")
+		if err != nil {
+			log.Error("failed to parse collection id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse collection id %d", http.StatusBadRequest)))
+			return
+		}
+
+		links, err := h.service.ShareLinks(r.Context(), userID, collectionID)
+		if err != nil {
+			log.Error("failed to get share links", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, ShareLinkResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Links:   links,
+			Message: "share links",
+		})
+	}
+}
+
+func (h *handler) RevokeShareLink(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.RevokeShareLink"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		linkID, err := int64URLParam(r, "link_id")
+		if err != nil {
+			log.Error("failed to parse link id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse link id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.RevokeShareLink(r.Context(), userID, linkID); err != nil {
+			log.Error("failed to revoke share link", slog.String("err", err.Error()))
+			if !shareLinkError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("share link revoked", slog.Int64("link_id", linkID))
+		render.JSON(w, r, ShareLinkResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "share link revoked",
+		})
+	}
+}
+
+// PublicShareLink opens a share link: it returns the shared collection with
+// its items to anybody holding the token, and the password in the
+// X-Share-Password header for a link that has one.
+func (h *handler) PublicShareLink(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.PublicShareLink"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		collection, items, err := h.service.OpenShareLink(r.Context(), chi.URLParam(r, "token"), r.Header.Get(shareLinkPasswordHeader))
+		if err != nil {
+			log.Error("failed to open share link", slog.String("err", err.Error()))
+			if !shareLinkError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, PublicResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Collection: &collection,
+			Items:      items,
+			Message:    "shared collection",
+		})
+	}
+}
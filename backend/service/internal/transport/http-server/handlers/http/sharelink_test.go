@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShareLink(t *testing.T) {
+	runAPITests(t, []apiTest[ShareLinkResponse]{{
+		name:   "password",
+		method: http.MethodPost,
+		path:   "/api/keeper/collection/10/links",
+		userID: ownerID,
+		body:   `{"expires_in":3600,"password":"appraise","max_views":5}`,
+		check: func(t *testing.T, f *fakeService, res ShareLinkResponse) {
+			require.NotNil(t, res.Link)
+			assert.Equal(t, "secret", res.Link.Token)
+			assert.Empty(t, res.Link.PasswordHash, "the password hash is never sent")
+			assert.True(t, res.Link.HasPassword)
+			assert.Equal(t, time.Hour, f.ttl)
+		},
+	}})
+}
+
+func TestPublicShareLink(t *testing.T) {
+	runAPITests(t, []apiTest[PublicResponse]{
+		{
+			name:   "password",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/links/secret",
+			header: http.Header{shareLinkPasswordHeader: {"appraise"}},
+			check: func(t *testing.T, f *fakeService, res PublicResponse) {
+				require.NotNil(t, res.Collection)
+				assert.Equal(t, "owner", res.Collection.Owner)
+				assert.Len(t, res.Items, 2)
+			},
+		},
+		{name: "no password", method: http.MethodGet, path: "/api/keeper/public/links/secret", err: "wrong share link password 401"},
+		{
+			name:   "expired",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/links/expired",
+			header: http.Header{shareLinkPasswordHeader: {"appraise"}},
+			err:    "share link not found 404",
+		},
+	})
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+type PrivacyRequest struct {
+	FollowersPrivate bool `json:"followers_private"`
+}
+
+type SocialResponse struct {
+	resp.Response
+	Favorites *models.Favorites `json:"favorites,omitempty"`
+	Follows   []models.Follow   `json:"follows,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// socialError renders the client-facing message for favorite and follow
+// errors and reports whether err was one of them.
+func socialError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrUserNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("user not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrFollowSelf):
+		render.JSON(w, r, response.Error(fmt.Sprintf("users cannot follow themselves %d", http.StatusBadRequest)))
+	case errors.Is(err, storage.ErrFollowersPrivate):
+		render.JSON(w, r, response.Error(fmt.Sprintf("followers are private %d", http.StatusForbidden)))
+	default:
+		return false
+	}
+	return true
+}
+
+// favorite builds the handlers that add or remove the item or collection
+// whose id is the URL parameter param.
+func (h *handler) favorite(log *slog.Logger, op, param, what, done string, change func(ctx context.Context, userID, id int64) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		id, err := int64URLParam(r, param)
+		if err != nil {
+			log.Error("failed to parse "+what+" id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse %s id %d", what, http.StatusBadRequest)))
+			return
+		}
+
+		if err := change(r.Context(), userID, id); err != nil {
+			log.Error("failed to change favorites", slog.String("err", err.Error()))
+			if !socialError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info(done, slog.Int64(what+"_id", id))
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: done,
+		})
+	}
+}
+
+func (h *handler) FavoriteItem(log *slog.Logger) http.HandlerFunc {
+	return h.favorite(log, "handlers.auth.FavoriteItem", "item_id", "item", "item favorited", h.service.FavoriteItem)
+}
+
+func (h *handler) UnfavoriteItem(log *slog.Logger) http.HandlerFunc {
+	return h.favorite(log, "handlers.auth.UnfavoriteItem", "item_id", "item", "item unfavorited", h.service.UnfavoriteItem)
+}
+
+func (h *handler) FavoriteCollection(log *slog.Logger) http.HandlerFunc {
+	return h.favorite(log, "handlers.auth.FavoriteCollection", "id", "collection", "collection favorited", h.service.FavoriteCollection)
+}
+
+func (h *handler) UnfavoriteCollection(log *slog.Logger) http.HandlerFunc {
+	return h.favorite(log, "handlers.auth.UnfavoriteCollection", "id", "collection", "collection unfavorited", h.service.UnfavoriteCollection)
+}
+
+func (h *handler) Favorites(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Favorites"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		favorites, err := h.service.Favorites(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get favorites", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Favorites: &favorites,
+			Message:   "favorites",
+		})
+	}
+}
+
+// follow builds the handlers that follow or unfollow the collector
+// {username}.
+func (h *handler) follow(log *slog.Logger, op, done string, change func(ctx context.Context, userID int64, username string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		username := chi.URLParam(r, "username")
+		if err := change(r.Context(), userID, username); err != nil {
+			log.Error("failed to change follows", slog.String("err", err.Error()))
+			if !socialError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info(done, slog.String("username", username))
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: done,
+		})
+	}
+}
+
+func (h *handler) Follow(log *slog.Logger) http.HandlerFunc {
+	return h.follow(log, "handlers.auth.Follow", "user followed", h.service.Follow)
+}
+
+func (h *handler) Unfollow(log *slog.Logger) http.HandlerFunc {
+	return h.follow(log, "handlers.auth.Unfollow", "user unfollowed", h.service.Unfollow)
+}
+
+// ownFollows builds the handlers that list the user's followers or the
+// collectors the user follows.
+func (h *handler) ownFollows(log *slog.Logger, op, done string, list func(ctx context.Context, userID int64) ([]models.Follow, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		follows, err := list(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get follows", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Follows: follows,
+			Message: done,
+		})
+	}
+}
+
+func (h *handler) Followers(log *slog.Logger) http.HandlerFunc {
+	return h.ownFollows(log, "handlers.auth.Followers", "followers", h.service.Followers)
+}
+
+func (h *handler) Following(log *slog.Logger) http.HandlerFunc {
+	return h.ownFollows(log, "handlers.auth.Following", "following", h.service.Following)
+}
+
+// publicFollows builds the handlers that list the follows of the collector
+// {username} to anybody.
+func (h *handler) publicFollows(log *slog.Logger, op, done string, list func(ctx context.Context, username string) ([]models.Follow, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		follows, err := list(r.Context(), chi.URLParam(r, "username"))
+		if err != nil {
+			log.Error("failed to get follows", slog.String("err", err.Error()))
+			if !socialError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Follows: follows,
+			Message: done,
+		})
+	}
+}
+
+func (h *handler) PublicFollowers(log *slog.Logger) http.HandlerFunc {
+	return h.publicFollows(log, "handlers.PublicFollowers", "followers", h.service.PublicFollowers)
+}
+
+func (h *handler) PublicFollowing(log *slog.Logger) http.HandlerFunc {
+	return h.publicFollows(log, "handlers.PublicFollowing", "following", h.service.PublicFollowing)
+}
+
+// SetPrivacy hides the user's followers from other users or shows them.
+func (h *handler) SetPrivacy(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.SetPrivacy"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req PrivacyRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := h.service.SetFollowersPrivate(r.Context(), userID, req.FollowersPrivate); err != nil {
+			log.Error("failed to set privacy", slog.String("err", err.Error()))
+			if !socialError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("privacy updated", slog.Bool("followers_private", req.FollowersPrivate))
+		render.JSON(w, r, SocialResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Message: "privacy updated",
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFavoriteItem(t *testing.T) {
+	runAPITests(t, []apiTest[SocialResponse]{
+		{
+			name:   "public item",
+			method: http.MethodPut,
+			path:   "/api/keeper/favorites/items/100",
+			userID: strangerID,
+			check: func(t *testing.T, f *fakeService, res SocialResponse) {
+				assert.Equal(t, []int64{100}, f.favorited)
+			},
+		},
+		{name: "private item", method: http.MethodPut, path: "/api/keeper/favorites/items/102", userID: strangerID, err: "item not found"},
+		{name: "bad id", method: http.MethodPut, path: "/api/keeper/favorites/items/abc", userID: strangerID, err: "failed to parse item id"},
+	})
+}
+
+func TestFollow(t *testing.T) {
+	runAPITests(t, []apiTest[SocialResponse]{
+		{
+			name:   "collector",
+			method: http.MethodPut,
+			path:   "/api/keeper/following/stranger",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res SocialResponse) {
+				assert.Equal(t, []string{"stranger"}, f.followed)
+			},
+		},
+		{name: "self", method: http.MethodPut, path: "/api/keeper/following/owner", userID: ownerID, err: "cannot follow themselves"},
+		{name: "unknown user", method: http.MethodPut, path: "/api/keeper/following/nobody", userID: ownerID, err: "user not found"},
+	})
+}
+
+func TestPublicFollowers(t *testing.T) {
+	runAPITests(t, []apiTest[SocialResponse]{
+		{
+			name:   "public",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/users/owner/followers",
+			check: func(t *testing.T, f *fakeService, res SocialResponse) {
+				assert.Len(t, res.Follows, 1)
+			},
+		},
+		{
+			name:   "private",
+			method: http.MethodGet,
+			path:   "/api/keeper/public/users/stranger/followers",
+			err:    "followers are private",
+			check: func(t *testing.T, f *fakeService, res SocialResponse) {
+				assert.Empty(t, res.Follows)
+			},
+		},
+	})
+}
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+type TagRequest struct {
+	Tags []string `json:"tags,omitempty"`
+	From []string `json:"from,omitempty"`
+	Name string   `json:"name,omitempty"`
+}
+
+type TagResponse struct {
+	resp.Response
+	ItemID  int64         `json:"item_id,omitempty"`
+	Tag     string        `json:"tag,omitempty"`
+	Tags    []models.Tag  `json:"tags,omitempty"`
+	Names   []string      `json:"names,omitempty"`
+	Items   []models.Item `json:"items,omitempty"`
+	Message string        `json:"message,omitempty"`
+}
+
+// tagError renders the client-facing message for tag errors and reports
+// whether err was one of them.
+func tagError(w http.ResponseWriter, r *http.Request, err error) bool {
+	switch {
+	case errors.Is(err, svc.ErrInvalidTag):
+		render.JSON(w, r, response.Error(fmt.Sprintf("invalid tag %d", http.StatusBadRequest)))
+	case errors.Is(err, storage.ErrTagNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("tag not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	default:
+		return false
+	}
+	return true
+}
+
+// tagURLParam returns the unescaped {tag} URL parameter.
+func tagURLParam(r *http.Request) (string, error) {
+	return url.PathUnescape(chi.URLParam(r, "tag"))
+}
+
+func (h *handler) AddItemTags(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.AddItemTags"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req TagRequest
+
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		tags, err := h.service.AddItemTags(r.Context(), userID, itemID, req.Tags)
+		if err != nil {
+			log.Error("failed to add item tags", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("item tags added", slog.Int64("item_id", itemID))
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			ItemID:  itemID,
+			Names:   tags,
+			Message: "item tags added",
+		})
+	}
+}
+
+func (h *handler) RemoveItemTag(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.RemoveItemTag"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		tag, err := tagURLParam(r)
+		if err != nil {
+			log.Error("failed to parse tag", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("invalid tag %d", http.StatusBadRequest)))
+			return
+		}
+
+		err = h.service.RemoveItemTag(r.Context(), userID, itemID, tag)
+		if err != nil {
+			log.Error("failed to remove item tag", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("item tag removed", slog.Int64("item_id", itemID))
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			ItemID:  itemID,
+			Tag:     tag,
+			Message: "item tag removed",
+		})
+	}
+}
+
+func (h *handler) Tags(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Tags"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tags, err := h.service.Tags(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get tags", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Tags:    tags,
+			Message: "tags",
+		})
+	}
+}
+
+func (h *handler) AutocompleteTags(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.AutocompleteTags"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		tags, err := h.service.AutocompleteTags(r.Context(), userID, r.URL.Query().Get("prefix"), limit)
+		if err != nil {
+			log.Error("failed to autocomplete tags", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Tags:    tags,
+			Message: "tags",
+		})
+	}
+}
+
+func (h *handler) ItemsByTags(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ItemsByTags"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var tags []string
+		for _, param := range r.URL.Query()["tags"] {
+			tags = append(tags, strings.Split(param, ",")...)
+		}
+
+		items, err := h.service.ItemsByTags(r.Context(), userID, tags)
+		if err != nil {
+			log.Error("failed to get items by tags", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Items:   items,
+			Message: "items",
+		})
+	}
+}
+
+// RenameTag renames one of the caller's tags. If a tag with the new name
+// already exists the two are merged.
+func (h *handler) RenameTag(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.RenameTag"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tag, err := tagURLParam(r)
+		if err != nil {
+			log.Error("failed to parse tag", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("invalid tag %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req TagRequest
+
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		name, err := h.service.MergeTags(r.Context(), userID, []string{tag}, req.Name)
+		if err != nil {
+			log.Error("failed to rename tag", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("tag renamed", slog.String("tag", name))
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Tag:     name,
+			Message: "tag renamed",
+		})
+	}
+}
+
+func (h *handler) MergeTags(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.MergeTags"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req TagRequest
+
+		err = render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		name, err := h.service.MergeTags(r.Context(), userID, req.From, req.Name)
+		if err != nil {
+			log.Error("failed to merge tags", slog.String("err", err.Error()))
+			if !tagError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("tags merged", slog.String("tag", name))
+		render.JSON(w, r, TagResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Tag:     name,
+			Message: "tags merged",
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// TradeRequest proposes or counters a trade. CollectionID is the collection
+// of the user the items they get go to.
+type TradeRequest struct {
+	OfferedItemIDs   []int64 `json:"offered_item_ids"`
+	RequestedItemIDs []int64 `json:"requested_item_ids"`
+	CollectionID     int64   `json:"collection_id"`
+	Message          string  `json:"message"`
+}
+
+type AcceptTradeRequest struct {
+	CollectionID int64 `json:"collection_id"`
+}
+
+type TradeResponse struct {
+	resp.Response
+	TradeID   int64                 `json:"id,omitempty"`
+	Trade     *models.Trade         `json:"trade,omitempty"`
+	Trades    []models.Trade        `json:"trades,omitempty"`
+	Transfers []models.ItemTransfer `json:"transfers,omitempty"`
+	Errors    svc.ValidationErrors  `json:"errors,omitempty"`
+	Message   string                `json:"message,omitempty"`
+}
+
+// tradeError renders the client-facing message for trade errors and reports
+// whether err was one of them.
+func tradeError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, TradeResponse{
+			Response: response.Error(fmt.Sprintf("invalid trade %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrTradeNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("trade not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrTradeItems):
+		render.JSON(w, r, response.Error(fmt.Sprintf("requested items must be public items of one other user %d", http.StatusBadRequest)))
+	case errors.Is(err, storage.ErrTradeClosed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("trade is no longer pending %d", http.StatusConflict)))
+	case errors.Is(err, svc.ErrNotYourTurn):
+		render.JSON(w, r, response.Error(fmt.Sprintf("trade is waiting for the other party %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrTradeStale):
+		render.JSON(w, r, response.Error(fmt.Sprintf("items of the trade changed hands %d", http.StatusConflict)))
+	case errors.Is(err, storage.ErrItemListed):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item is listed in a lot %d", http.StatusConflict)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) ProposeTrade(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ProposeTrade"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req TradeRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		tradeID, err := h.service.ProposeTrade(r.Context(), userID, models.Trade{
+			OfferedItemIDs:       req.OfferedItemIDs,
+			RequestedItemIDs:     req.RequestedItemIDs,
+			ProposerCollectionID: req.CollectionID,
+			Message:              req.Message,
+		})
+		if err != nil {
+			log.Error("failed to propose trade", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("trade proposed", slog.Int64("trade_id", tradeID))
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			TradeID: tradeID,
+			Message: "trade proposed",
+		})
+	}
+}
+
+func (h *handler) CounterTrade(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CounterTrade"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tradeID, err := int64URLParam(r, "trade_id")
+		if err != nil {
+			log.Error("failed to parse trade id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse trade id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req TradeRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		counterID, err := h.service.CounterTrade(r.Context(), userID, tradeID, models.Trade{
+			OfferedItemIDs:       req.OfferedItemIDs,
+			RequestedItemIDs:     req.RequestedItemIDs,
+			ProposerCollectionID: req.CollectionID,
+			Message:              req.Message,
+		})
+		if err != nil {
+			log.Error("failed to counter trade", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("trade countered", slog.Int64("trade_id", tradeID), slog.Int64("counter_id", counterID))
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			TradeID: counterID,
+			Message: "trade countered",
+		})
+	}
+}
+
+func (h *handler) AcceptTrade(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.AcceptTrade"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tradeID, err := int64URLParam(r, "trade_id")
+		if err != nil {
+			log.Error("failed to parse trade id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse trade id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req AcceptTradeRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		if err := h.service.AcceptTrade(r.Context(), userID, tradeID, req.CollectionID); err != nil {
+			log.Error("failed to accept trade", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("trade accepted", slog.Int64("trade_id", tradeID))
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			TradeID: tradeID,
+			Message: "trade accepted",
+		})
+	}
+}
+
+func (h *handler) DeclineTrade(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeclineTrade"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tradeID, err := int64URLParam(r, "trade_id")
+		if err != nil {
+			log.Error("failed to parse trade id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse trade id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.DeclineTrade(r.Context(), userID, tradeID); err != nil {
+			log.Error("failed to decline trade", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("trade declined", slog.Int64("trade_id", tradeID))
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			TradeID: tradeID,
+			Message: "trade declined",
+		})
+	}
+}
+
+func (h *handler) Trade(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Trade"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		tradeID, err := int64URLParam(r, "trade_id")
+		if err != nil {
+			log.Error("failed to parse trade id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse trade id %d", http.StatusBadRequest)))
+			return
+		}
+
+		t, err := h.service.Trade(r.Context(), userID, tradeID)
+		if err != nil {
+			log.Error("failed to get trade", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			TradeID: t.TradeID,
+			Trade:   &t,
+			Message: "trade",
+		})
+	}
+}
+
+func (h *handler) Trades(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Trades"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		trades, err := h.service.Trades(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get trades", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Trades:  trades,
+			Message: "trades",
+		})
+	}
+}
+
+// ItemTransfers is the history of the item {item_id} changing hands.
+func (h *handler) ItemTransfers(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.ItemTransfers"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		transfers, err := h.service.ItemTransfers(r.Context(), userID, itemID)
+		if err != nil {
+			log.Error("failed to get item transfers", slog.String("err", err.Error()))
+			if !tradeError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, TradeResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Transfers: transfers,
+			Message:   "item transfers",
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptTrade(t *testing.T) {
+	runAPITests(t, []apiTest[TradeResponse]{
+		{
+			name:   "pending",
+			method: http.MethodPost,
+			path:   "/api/keeper/trades/3/accept",
+			userID: ownerID,
+			body:   `{"collection_id":12}`,
+			check: func(t *testing.T, f *fakeService, res TradeResponse) {
+				assert.Equal(t, models.TradeAccepted, f.trades[3].Status)
+				assert.Equal(t, int64(12), f.trades[3].RecipientCollectionID)
+			},
+		},
+		{
+			name:   "accepted already",
+			method: http.MethodPost,
+			path:   "/api/keeper/trades/3/accept",
+			userID: ownerID,
+			body:   `{"collection_id":12}`,
+			setup: func(f *fakeService) {
+				trade := f.trades[3]
+				trade.Status = models.TradeAccepted
+				f.trades[3] = trade
+			},
+			err: "changed hands",
+		},
+		{name: "missing trade", method: http.MethodPost, path: "/api/keeper/trades/4/accept", userID: ownerID, body: `{"collection_id":12}`, err: "trade not found"},
+		{name: "proposer", method: http.MethodPost, path: "/api/keeper/trades/3/accept", userID: strangerID, body: `{"collection_id":12}`, err: "trade not found"},
+	})
+}
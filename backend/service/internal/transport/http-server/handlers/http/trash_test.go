@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrash(t *testing.T) {
+	runAPITests(t, []apiTest[TrashResponse]{
+		{
+			name:   "owner",
+			method: http.MethodGet,
+			path:   "/api/keeper/trash",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res TrashResponse) {
+				require.NotNil(t, res.Trash)
+				require.Len(t, res.Trash.Collections, 1)
+				assert.Equal(t, int64(400), res.Trash.Collections[0].ItemCount)
+				assert.Equal(t, "Old stamps", res.Trash.Collections[0].Collection.CollectionName)
+			},
+		},
+		{
+			name:   "stranger",
+			method: http.MethodGet,
+			path:   "/api/keeper/trash",
+			userID: strangerID,
+			check: func(t *testing.T, f *fakeService, res TrashResponse) {
+				require.NotNil(t, res.Trash)
+				assert.Empty(t, res.Trash.Collections)
+			},
+		},
+	})
+}
+
+func TestRestoreCollection(t *testing.T) {
+	runAPITests(t, []apiTest[TrashResponse]{
+		{
+			name:   "trashed",
+			method: http.MethodPost,
+			path:   "/api/keeper/trash/collections/2/restore",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res TrashResponse) {
+				assert.Contains(t, f.collections, int64(2))
+				assert.NotContains(t, f.trashedCollections, int64(2))
+			},
+		},
+		{name: "missing", method: http.MethodPost, path: "/api/keeper/trash/collections/5/restore", userID: ownerID, err: "collection not found 404"},
+		{name: "stranger", method: http.MethodPost, path: "/api/keeper/trash/collections/2/restore", userID: strangerID, err: "collection not found 404"},
+	})
+}
+
+func TestRestoreItem(t *testing.T) {
+	runAPITests(t, []apiTest[TrashResponse]{
+		{
+			name:   "trashed",
+			method: http.MethodPost,
+			path:   "/api/keeper/trash/items/103/restore",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res TrashResponse) {
+				require.NotNil(t, res.Item)
+				assert.Equal(t, "Penny Black", res.Item.Title)
+				assert.Contains(t, f.items, int64(103))
+			},
+		},
+		{
+			name:   "title taken",
+			method: http.MethodPost,
+			path:   "/api/keeper/trash/items/104/restore",
+			userID: ownerID,
+			err:    "item already exists 409",
+			check: func(t *testing.T, f *fakeService, res TrashResponse) {
+				assert.Contains(t, f.trashedItems, int64(104))
+			},
+		},
+		{name: "missing", method: http.MethodPost, path: "/api/keeper/trash/items/9/restore", userID: ownerID, err: "item not found 404"},
+	})
+}
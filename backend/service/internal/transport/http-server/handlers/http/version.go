@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// RevertRequest names the version to put an item back to.
+type RevertRequest struct {
+	Version int `json:"version"`
+}
+
+type VersionResponse struct {
+	resp.Response
+	Versions []models.Version     `json:"versions,omitempty"`
+	Item     *models.Item         `json:"item,omitempty"`
+	Errors   svc.ValidationErrors `json:"errors,omitempty"`
+	Message  string               `json:"message,omitempty"`
+}
+
+// versionError renders the client-facing message for history and revert
+// errors and reports whether err was one of them.
+func versionError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, VersionResponse{
+			Response: response.Error(fmt.Sprintf("invalid revert %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+	if itemLockedError(w, r, err) {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrItemNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCollectionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("collection not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrVersionNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("version not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrItemExists):
+		render.JSON(w, r, response.Error(fmt.Sprintf("item already exists %d", http.StatusConflict)))
+	default:
+		return categoryError(w, r, err)
+	}
+	return true
+}
+
+// ItemHistory lists the versions of an item.
+func (h *handler) ItemHistory(log *slog.Logger) http.HandlerFunc {
+	return h.history(log, "handlers.auth.ItemHistory", "item_id", "item", h.service.ItemHistory)
+}
+
+// CollectionHistory lists the versions of a collection.
+func (h *handler) CollectionHistory(log *slog.Logger) http.HandlerFunc {
+	return h.history(log, "handlers.auth.CollectionHistory", "id", "collection", h.service.CollectionHistory)
+}
+
+func (h *handler) history(log *slog.Logger, op, param, what string, list func(ctx context.Context, userID, id int64) ([]models.Version, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		id, err := int64URLParam(r, param)
+		if err != nil {
+			log.Error("failed to parse "+what+" id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse %s id %d", what, http.StatusBadRequest)))
+			return
+		}
+
+		versions, err := list(r.Context(), userID, id)
+		if err != nil {
+			log.Error("failed to get "+what+" history", slog.String("err", err.Error()))
+			if !versionError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, VersionResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Versions: versions,
+			Message:  what + " history",
+		})
+	}
+}
+
+// RevertItem puts an item back to one of its versions.
+func (h *handler) RevertItem(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.RevertItem"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		itemID, err := int64URLParam(r, "item_id")
+		if err != nil {
+			log.Error("failed to parse item id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse item id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req RevertRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		item, err := h.service.RevertItem(r.Context(), userID, itemID, req.Version)
+		if err != nil {
+			log.Error("failed to revert item", slog.String("err", err.Error()))
+			if !versionError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("item reverted", slog.Int64("item_id", itemID), slog.Int("version", req.Version))
+		render.JSON(w, r, VersionResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Item:    &item,
+			Message: "item reverted",
+		})
+	}
+}
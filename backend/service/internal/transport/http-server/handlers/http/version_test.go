@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemHistory(t *testing.T) {
+	runAPITests(t, []apiTest[VersionResponse]{
+		{
+			name:   "owner",
+			method: http.MethodGet,
+			path:   "/api/keeper/collection/item/100/history",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res VersionResponse) {
+				require.Len(t, res.Versions, 2)
+				assert.Equal(t, "1 rouble 1913", res.Versions[0].Changes["title"].To)
+			},
+		},
+		{name: "missing item", method: http.MethodGet, path: "/api/keeper/collection/item/999/history", userID: ownerID, err: "item not found 404"},
+		{name: "stranger", method: http.MethodGet, path: "/api/keeper/collection/item/100/history", userID: strangerID, err: "item not found 404"},
+	})
+}
+
+func TestRevertItem(t *testing.T) {
+	runAPITests(t, []apiTest[VersionResponse]{
+		{
+			name:   "first version",
+			method: http.MethodPost,
+			path:   "/api/keeper/collection/item/100/revert",
+			userID: ownerID,
+			body:   `{"version":1}`,
+			check: func(t *testing.T, f *fakeService, res VersionResponse) {
+				require.NotNil(t, res.Item)
+				assert.Equal(t, "1 rouble", res.Item.Title)
+				assert.Equal(t, "1 rouble", f.items[100].Title)
+			},
+		},
+		{
+			name:   "missing version",
+			method: http.MethodPost,
+			path:   "/api/keeper/collection/item/100/revert",
+			userID: ownerID,
+			body:   `{"version":9}`,
+			err:    "version not found 404",
+		},
+		{
+			name:   "sold item",
+			method: http.MethodPost,
+			path:   "/api/keeper/collection/item/101/revert",
+			userID: ownerID,
+			body:   `{"version":1}`,
+			setup:  func(f *fakeService) { f.locked[101] = true },
+			err:    "item is reserved or sold 409",
+			check: func(t *testing.T, f *fakeService, res VersionResponse) {
+				assert.Equal(t, "50 kopeks 1912", f.items[101].Title)
+			},
+		},
+	})
+}
@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	svc "github.com/mmmakskl/HeritageKeeper/service/internal/service"
+	"github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	resp "github.com/mmmakskl/HeritageKeeper/service/lib/api/response"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// WantRequest creates or replaces an entry of the want list. Empty fields
+// match anything.
+type WantRequest struct {
+	Title      string            `json:"title"`
+	Keywords   string            `json:"keywords"`
+	CategoryID int64             `json:"category_id"`
+	Country    string            `json:"country"`
+	YearFrom   int               `json:"year_from"`
+	YearTo     int               `json:"year_to"`
+	MaxPrice   int64             `json:"max_price"`
+	Currency   string            `json:"currency"`
+	Attributes models.Attributes `json:"attributes"`
+}
+
+func (req WantRequest) want() models.Want {
+	return models.Want{
+		Title:      req.Title,
+		Keywords:   req.Keywords,
+		CategoryID: req.CategoryID,
+		Country:    req.Country,
+		YearFrom:   req.YearFrom,
+		YearTo:     req.YearTo,
+		MaxPrice:   req.MaxPrice,
+		Currency:   req.Currency,
+		Attributes: req.Attributes,
+	}
+}
+
+type WantResponse struct {
+	resp.Response
+	WantID  int64                `json:"id,omitempty"`
+	Want    *models.Want         `json:"want,omitempty"`
+	Wants   []models.Want        `json:"wants,omitempty"`
+	Matches []models.WantMatch   `json:"matches,omitempty"`
+	Errors  svc.ValidationErrors `json:"errors,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// wantError renders the client-facing message for want errors and reports
+// whether err was one of them.
+func wantError(w http.ResponseWriter, r *http.Request, err error) bool {
+	if errs, ok := validationErrors(err); ok {
+		render.JSON(w, r, WantResponse{
+			Response: response.Error(fmt.Sprintf("invalid want %d", http.StatusBadRequest)),
+			Errors:   errs,
+		})
+		return true
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrWantNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("want not found %d", http.StatusNotFound)))
+	case errors.Is(err, storage.ErrCategoryNotFound):
+		render.JSON(w, r, response.Error(fmt.Sprintf("category not found %d", http.StatusNotFound)))
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *handler) CreateWant(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.CreateWant"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var req WantRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		wantID, err := h.service.CreateWant(r.Context(), userID, req.want())
+		if err != nil {
+			log.Error("failed to create want", slog.String("err", err.Error()))
+			if !wantError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("want created", slog.Int64("want_id", wantID))
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			WantID:  wantID,
+			Message: "want created",
+		})
+	}
+}
+
+func (h *handler) UpdateWant(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.UpdateWant"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		wantID, err := int64URLParam(r, "want_id")
+		if err != nil {
+			log.Error("failed to parse want id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse want id %d", http.StatusBadRequest)))
+			return
+		}
+
+		var req WantRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request", slog.String("err", err.Error()))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+			return
+		}
+
+		want := req.want()
+		want.WantID = wantID
+		if err := h.service.UpdateWant(r.Context(), userID, want); err != nil {
+			log.Error("failed to update want", slog.String("err", err.Error()))
+			if !wantError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("want updated", slog.Int64("want_id", wantID))
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			WantID:  wantID,
+			Message: "want updated",
+		})
+	}
+}
+
+func (h *handler) DeleteWant(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.DeleteWant"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		wantID, err := int64URLParam(r, "want_id")
+		if err != nil {
+			log.Error("failed to parse want id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse want id %d", http.StatusBadRequest)))
+			return
+		}
+
+		if err := h.service.DeleteWant(r.Context(), userID, wantID); err != nil {
+			log.Error("failed to delete want", slog.String("err", err.Error()))
+			if !wantError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		log.Info("want deleted", slog.Int64("want_id", wantID))
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			WantID:  wantID,
+			Message: "want deleted",
+		})
+	}
+}
+
+func (h *handler) Want(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Want"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		wantID, err := int64URLParam(r, "want_id")
+		if err != nil {
+			log.Error("failed to parse want id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse want id %d", http.StatusBadRequest)))
+			return
+		}
+
+		want, err := h.service.Want(r.Context(), userID, wantID)
+		if err != nil {
+			log.Error("failed to get want", slog.String("err", err.Error()))
+			if !wantError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			WantID:  want.WantID,
+			Want:    &want,
+			Message: "want",
+		})
+	}
+}
+
+func (h *handler) Wants(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.Wants"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		wants, err := h.service.Wants(r.Context(), userID)
+		if err != nil {
+			log.Error("failed to get wants", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Wants:   wants,
+			Message: "wants",
+		})
+	}
+}
+
+// WantMatches lists what was found for the want list, or for the want
+// ?want_id only.
+func (h *handler) WantMatches(log *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auth.WantMatches"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		userID, err := userIDFromRequest(r)
+		if err != nil {
+			log.Error("failed to get user id", slog.String("err", err.Error()))
+			render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			return
+		}
+
+		var wantID int64
+		if v := r.URL.Query().Get("want_id"); v != "" {
+			if wantID, err = strconv.ParseInt(v, 10, 64); err != nil || wantID <= 0 {
+				log.Error("failed to parse want id", slog.String("want_id", v))
+				render.JSON(w, r, response.Error(fmt.Sprintf("failed to parse want id %d", http.StatusBadRequest)))
+				return
+			}
+		}
+
+		matches, err := h.service.WantMatches(r.Context(), userID, wantID)
+		if err != nil {
+			log.Error("failed to get want matches", slog.String("err", err.Error()))
+			if !wantError(w, r, err) {
+				render.JSON(w, r, response.Error(fmt.Sprintf("internal server error %d", http.StatusInternalServerError)))
+			}
+			return
+		}
+
+		render.JSON(w, r, WantResponse{
+			Response: resp.Response{
+				Status: response.OK().Status,
+				Error:  response.OK().Error,
+			},
+			Matches: matches,
+			Message: "want matches",
+		})
+	}
+}
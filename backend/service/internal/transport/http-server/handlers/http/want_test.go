@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWant(t *testing.T) {
+	runAPITests(t, []apiTest[WantResponse]{
+		{
+			name:   "want",
+			method: http.MethodPost,
+			path:   "/api/keeper/wants",
+			userID: ownerID,
+			body:   `{"title":"1 rouble 1913","year_from":1913,"max_price":50000,"currency":"RUB","attributes":{"metal":"silver"}}`,
+			check: func(t *testing.T, f *fakeService, res WantResponse) {
+				assert.NotZero(t, res.WantID)
+				assert.Equal(t, 1913, f.want.YearFrom)
+				assert.Equal(t, models.Attributes{"metal": "silver"}, f.want.Attributes)
+			},
+		},
+		{
+			name:   "no title",
+			method: http.MethodPost,
+			path:   "/api/keeper/wants",
+			userID: ownerID,
+			body:   `{}`,
+			err:    "400",
+			check: func(t *testing.T, f *fakeService, res WantResponse) {
+				assert.Contains(t, res.Errors, "title")
+			},
+		},
+	})
+}
+
+func TestWantMatches(t *testing.T) {
+	runAPITests(t, []apiTest[WantResponse]{
+		{
+			name:   "want",
+			method: http.MethodGet,
+			path:   "/api/keeper/wants/matches?want_id=5",
+			userID: ownerID,
+			check: func(t *testing.T, f *fakeService, res WantResponse) {
+				assert.Equal(t, int64(5), f.wantID)
+				assert.Len(t, res.Matches, 1)
+			},
+		},
+		{name: "missing want", method: http.MethodGet, path: "/api/keeper/wants/matches?want_id=6", userID: ownerID, err: "want not found"},
+		{name: "foreign want", method: http.MethodGet, path: "/api/keeper/wants/matches?want_id=5", userID: strangerID, err: "want not found"},
+		{name: "bad id", method: http.MethodGet, path: "/api/keeper/wants/matches?want_id=abc", userID: ownerID, err: "failed to parse want id"},
+	})
+}
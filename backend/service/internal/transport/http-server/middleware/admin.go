@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AdminChecker reports whether the user is an administrator. It is satisfied
+// by the SSO client's IsAdmin method.
+type AdminChecker func(ctx context.Context, userID int64) (bool, error)
+
+// AdminMiddleware lets the request through only if the user authenticated by
+// JWTAuthMiddleware is an administrator according to isAdmin.
+func AdminMiddleware(isAdmin AdminChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(ClaimsKey).(jwt.MapClaims)
+			if !ok {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			uid, ok := claims["uid"].(float64)
+			if !ok {
+				http.Error(w, fmt.Sprintf("invalid uid type: %T", claims["uid"]), http.StatusUnauthorized)
+				return
+			}
+
+			admin, err := isAdmin(r.Context(), int64(uid))
+			if err != nil {
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !admin {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -10,7 +10,7 @@ func CORSconfigure(address string) *cors.Cors {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{fmt.Sprintf("http://%s", address)}, // Укажите фронтенд
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Share-Password"},
 		AllowCredentials: true,
 		Debug:            true,
 	})
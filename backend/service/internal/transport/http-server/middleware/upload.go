@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// multipartOverhead leaves room for the form fields, part headers and
+// boundaries that come with the files of a multipart upload.
+const multipartOverhead = 1 << 20
+
+// UploadMiddleware bounds the body of an upload route to files of maxSize
+// bytes each plus multipartOverhead, and gives the request timeout to be read
+// and answered instead of the server-wide timeouts, which are too short for
+// large files.
+func UploadMiddleware(maxSize int64, files int, timeout time.Duration) func(http.Handler) http.Handler {
+	limit := maxSize*int64(files) + multipartOverhead
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(timeout)
+			rc := http.NewResponseController(w)
+			// Not every ResponseWriter supports deadlines; the server-wide
+			// ones still apply then.
+			_ = rc.SetReadDeadline(deadline)
+			_ = rc.SetWriteDeadline(deadline)
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
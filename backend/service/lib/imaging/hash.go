@@ -0,0 +1,51 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	"math/bits"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Hash returns the 64-bit difference hash (dHash) of an encoded image. Photos
+// of the same object hash to values a few bits apart regardless of size,
+// compression and small colour shifts.
+func Hash(data []byte) (uint64, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, ErrUnsupportedFormat
+	}
+
+	return DHash(img), nil
+}
+
+// DHash shrinks img to 9x8 grey pixels and sets a bit for every pixel that is
+// brighter than its right neighbour.
+func DHash(img image.Image) uint64 {
+	small := image.NewGray(image.Rect(0, 0, 9, 8))
+	xdraw.BiLinear.Scale(small, small.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small.GrayAt(x, y).Y > small.GrayAt(x+1, y).Y {
+				hash |= 1
+			}
+		}
+	}
+
+	return hash
+}
+
+// Distance is the number of differing bits between two hashes.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similarity maps a hash distance to a score between 0 and 1.
+func Similarity(distance int) float64 {
+	return 1 - float64(distance)/64
+}
@@ -0,0 +1,59 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gradient draws a diagonal gradient with a dark disc, a stand-in for a photo
+// of a coin.
+func gradient(w, h int, shift uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*255/w+y*255/h)/2) + shift
+			dx, dy := x-w/2, y-h/2
+			if dx*dx+dy*dy < (w/4)*(w/4) {
+				v /= 3
+			}
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	return img
+}
+
+func TestDHash_SameImageDifferentEncoding(t *testing.T) {
+	original := gradient(800, 600, 0)
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, gradient(400, 300, 5), &jpeg.Options{Quality: 40}))
+	h, err := Hash(buf.Bytes())
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, Distance(DHash(original), h), 6)
+}
+
+func TestDHash_DifferentImages(t *testing.T) {
+	a := DHash(gradient(400, 300, 0))
+
+	flipped := gradient(400, 300, 0)
+	b := image.NewRGBA(flipped.Bounds())
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			b.Set(399-x, 299-y, flipped.At(x, y))
+		}
+	}
+
+	assert.Greater(t, Distance(a, DHash(b)), 20)
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, Similarity(0))
+	assert.Equal(t, 0.75, Similarity(16))
+}
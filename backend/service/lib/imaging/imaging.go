@@ -0,0 +1,157 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooManyPixels     = errors.New("image has too many pixels")
+)
+
+type Size string
+
+const (
+	Thumb  Size = "thumb"
+	Medium Size = "medium"
+	Full   Size = "full"
+)
+
+// sizes are the generated variants from the largest to the smallest, each
+// bounded by the length of its longest side.
+var sizes = []struct {
+	size    Size
+	maxSide int
+}{
+	{Full, 2560},
+	{Medium, 1024},
+	{Thumb, 256},
+}
+
+const jpegQuality = 85
+
+// Variant is a re-encoded copy of an uploaded image. Re-encoding drops all
+// metadata of the original, EXIF and GPS included.
+type Variant struct {
+	Size        Size
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Processor turns uploads into sanitized, resized variants.
+type Processor struct {
+	maxPixels int
+}
+
+func New(maxPixels int) *Processor {
+	return &Processor{maxPixels: maxPixels}
+}
+
+// Process decodes a JPEG, PNG or WebP image and returns its Full, Medium and
+// Thumb variants, in that order. Images are never upscaled.
+//
+// The dimensions are read from the header before decoding, so a small file
+// that claims a huge canvas is rejected without allocating the pixels.
+func (p *Processor) Process(data []byte) ([]Variant, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	switch format {
+	case "jpeg", "png", "webp":
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > p.maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = exifOrientation(data)
+	}
+
+	variants := make([]Variant, 0, len(sizes))
+	var prev *image.RGBA
+	for _, s := range sizes {
+		var img *image.RGBA
+		if prev == nil {
+			// Resizing first keeps the rotation cheap; the bounding box is
+			// square, so it does not matter which side ends up as the width.
+			img = orient(resize(src, s.maxSide), orientation)
+		} else {
+			img = resize(prev, s.maxSide)
+		}
+		prev = img
+
+		v, err := encode(img)
+		if err != nil {
+			return nil, err
+		}
+		v.Size = s.size
+		variants = append(variants, v)
+	}
+
+	return variants, nil
+}
+
+// resize scales src down so that its longest side is at most maxSide.
+func resize(src image.Image, maxSide int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > maxSide || h > maxSide {
+		if w >= h {
+			h = max(1, h*maxSide/w)
+			w = maxSide
+		} else {
+			w = max(1, w*maxSide/h)
+			h = maxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	return dst
+}
+
+// encode writes opaque images as JPEG and keeps PNG for transparent ones.
+func encode(img *image.RGBA) (Variant, error) {
+	var buf bytes.Buffer
+	v := Variant{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	if img.Opaque() {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+			return Variant{}, err
+		}
+		v.ContentType = "image/jpeg"
+	} else {
+		if err := png.Encode(&buf, img); err != nil {
+			return Variant{}, err
+		}
+		v.ContentType = "image/png"
+	}
+
+	v.Data = buf.Bytes()
+	return v, nil
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func solid(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 segment carrying GPS-looking data and the given
+// orientation right after the SOI marker.
+func withExif(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	ifd := make([]byte, 2+12+4)
+	binary.BigEndian.PutUint16(ifd[0:], 1)
+	binary.BigEndian.PutUint16(ifd[2:], orientationTag)
+	binary.BigEndian.PutUint16(ifd[4:], 3)
+	binary.BigEndian.PutUint32(ifd[6:], 1)
+	binary.BigEndian.PutUint16(ifd[10:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, ifd...)...)
+	payload = append(payload, []byte("GPS 55.7558N 37.6173E")...)
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func TestProcess_Variants(t *testing.T) {
+	p := New(50_000_000)
+
+	variants, err := p.Process(encodeJPEG(t, solid(3000, 1500, color.RGBA{200, 100, 50, 255})))
+	require.NoError(t, err)
+	require.Len(t, variants, 3)
+
+	assert.Equal(t, Full, variants[0].Size)
+	assert.Equal(t, [2]int{2560, 1280}, [2]int{variants[0].Width, variants[0].Height})
+	assert.Equal(t, Medium, variants[1].Size)
+	assert.Equal(t, [2]int{1024, 512}, [2]int{variants[1].Width, variants[1].Height})
+	assert.Equal(t, Thumb, variants[2].Size)
+	assert.Equal(t, [2]int{256, 128}, [2]int{variants[2].Width, variants[2].Height})
+
+	for _, v := range variants {
+		assert.Equal(t, "image/jpeg", v.ContentType)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(v.Data))
+		require.NoError(t, err)
+		assert.Equal(t, v.Width, cfg.Width)
+	}
+}
+
+func TestProcess_NoUpscale(t *testing.T) {
+	variants, err := New(50_000_000).Process(encodeJPEG(t, solid(100, 80, color.White)))
+	require.NoError(t, err)
+
+	for _, v := range variants {
+		assert.Equal(t, 100, v.Width)
+		assert.Equal(t, 80, v.Height)
+	}
+}
+
+func TestProcess_StripsExifAndAppliesOrientation(t *testing.T) {
+	data := withExif(encodeJPEG(t, solid(400, 200, color.Black)), 6)
+	require.Equal(t, 6, exifOrientation(data))
+
+	variants, err := New(50_000_000).Process(data)
+	require.NoError(t, err)
+
+	full := variants[0]
+	assert.Equal(t, 200, full.Width)
+	assert.Equal(t, 400, full.Height)
+	assert.False(t, bytes.Contains(full.Data, []byte("Exif")))
+	assert.False(t, bytes.Contains(full.Data, []byte("GPS")))
+}
+
+func TestProcess_KeepsTransparencyAsPNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solid(10, 10, color.RGBA{0, 0, 0, 0})))
+
+	variants, err := New(50_000_000).Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", variants[0].ContentType)
+}
+
+func TestProcess_RejectsUnsupportedFormats(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, solid(10, 10, color.White), nil))
+
+	_, err := New(50_000_000).Process(buf.Bytes())
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = New(50_000_000).Process([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>"))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestProcess_RejectsDecompressionBombs(t *testing.T) {
+	// A tiny PNG whose header claims a 100000x100000 canvas.
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, solid(1, 1, color.White)))
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := New(50_000_000).Process(data)
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+}
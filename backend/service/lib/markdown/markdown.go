@@ -0,0 +1,201 @@
+// Package markdown renders the small markdown subset allowed in comments to
+// HTML that is safe to embed in a page.
+//
+// Supported are paragraphs, line breaks, "- " and "1. " lists, "> " quotes,
+// fenced code blocks, `code`, **bold**, *italic* and [links](https://...).
+// Everything else is text. The renderer never passes input through: every
+// piece of text is escaped and the only tags in the output are the ones it
+// writes itself, so no sanitizing of the result is needed.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	orderedRe = regexp.MustCompile(`^\d{1,9}\. `)
+
+	// allowedSchemes are the link schemes that cannot run code.
+	allowedSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+type block int
+
+const (
+	blockNone block = iota
+	blockParagraph
+	blockQuote
+	blockList
+	blockOrdered
+)
+
+type renderer struct {
+	b     strings.Builder
+	block block
+	lines []string
+}
+
+// ToHTML renders src to safe HTML.
+func ToHTML(src string) string {
+	r := &renderer{}
+
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			r.flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			r.b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case trimmed == "":
+			r.flush()
+		case strings.HasPrefix(trimmed, ">"):
+			r.add(blockQuote, strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " "))
+		case strings.HasPrefix(trimmed, "- "), strings.HasPrefix(trimmed, "* "):
+			r.add(blockList, trimmed[2:])
+		case orderedRe.MatchString(trimmed):
+			r.add(blockOrdered, trimmed[len(orderedRe.FindString(trimmed)):])
+		case r.block == blockList || r.block == blockOrdered:
+			// A plain line continues the last list item.
+			r.lines[len(r.lines)-1] += "\n" + trimmed
+		default:
+			r.add(blockParagraph, trimmed)
+		}
+	}
+	r.flush()
+
+	return strings.TrimSuffix(r.b.String(), "\n")
+}
+
+// add appends a line to the current block, starting a new block when the
+// kind changes.
+func (r *renderer) add(kind block, line string) {
+	if r.block != kind {
+		r.flush()
+		r.block = kind
+	}
+	r.lines = append(r.lines, line)
+}
+
+// flush writes the current block.
+func (r *renderer) flush() {
+	switch r.block {
+	case blockParagraph:
+		r.b.WriteString("<p>" + lines(r.lines) + "</p>\n")
+	case blockQuote:
+		r.b.WriteString("<blockquote><p>" + lines(r.lines) + "</p></blockquote>\n")
+	case blockList, blockOrdered:
+		tag := "ul"
+		if r.block == blockOrdered {
+			tag = "ol"
+		}
+		r.b.WriteString("<" + tag + ">")
+		for _, item := range r.lines {
+			r.b.WriteString("<li>" + lines(strings.Split(item, "\n")) + "</li>")
+		}
+		r.b.WriteString("</" + tag + ">\n")
+	}
+	r.block = blockNone
+	r.lines = r.lines[:0]
+}
+
+// lines renders the lines of a block joined by line breaks.
+func lines(ls []string) string {
+	out := make([]string, len(ls))
+	for i, l := range ls {
+		var b strings.Builder
+		inline(&b, l, true)
+		out[i] = b.String()
+	}
+	return strings.Join(out, "<br>\n")
+}
+
+// inline renders the spans of s. Links are not rendered inside links.
+func inline(b *strings.Builder, s string, links bool) {
+	for len(s) > 0 {
+		switch {
+		case s[0] == '`':
+			if end := strings.IndexByte(s[1:], '`'); end > 0 {
+				b.WriteString("<code>" + html.EscapeString(s[1:1+end]) + "</code>")
+				s = s[end+2:]
+				continue
+			}
+		case strings.HasPrefix(s, "**"):
+			if end := strings.Index(s[2:], "**"); end > 0 && emphasis(s[2:2+end]) {
+				b.WriteString("<strong>")
+				inline(b, s[2:2+end], links)
+				b.WriteString("</strong>")
+				s = s[end+4:]
+				continue
+			}
+		case s[0] == '*':
+			if end := strings.IndexByte(s[1:], '*'); end > 0 && emphasis(s[1:1+end]) {
+				b.WriteString("<em>")
+				inline(b, s[1:1+end], links)
+				b.WriteString("</em>")
+				s = s[end+2:]
+				continue
+			}
+		case s[0] == '[' && links:
+			if text, href, rest, ok := link(s); ok {
+				b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+				inline(b, text, false)
+				b.WriteString("</a>")
+				s = rest
+				continue
+			}
+		}
+
+		// Plain text up to the next character that may start a span.
+		n := strings.IndexAny(s[1:], "`*[")
+		if n < 0 {
+			n = len(s) - 1
+		}
+		b.WriteString(html.EscapeString(s[:n+1]))
+		s = s[n+1:]
+	}
+}
+
+// emphasis reports whether text between delimiters is emphasized. As in
+// CommonMark it may not start or end with a space, so "2 * 3 * 4" stays
+// as it is.
+func emphasis(text string) bool {
+	return strings.TrimSpace(text) == text
+}
+
+// link parses [text](href) at the start of s. Links with a scheme that is
+// not allowed, or without one, are left as text.
+func link(s string) (text, href, rest string, ok bool) {
+	closeText := strings.Index(s, "](")
+	if closeText < 0 {
+		return "", "", "", false
+	}
+	closeHref := strings.IndexByte(s[closeText+2:], ')')
+	if closeHref < 0 {
+		return "", "", "", false
+	}
+
+	text = s[1:closeText]
+	href = strings.TrimSpace(s[closeText+2 : closeText+2+closeHref])
+	if text == "" || strings.ContainsAny(href, " \t\n\"'<>`") {
+		return "", "", "", false
+	}
+
+	u, err := url.Parse(href)
+	if err != nil || !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", "", "", false
+	}
+
+	return text, href, s[closeText+3+closeHref:], true
+}
@@ -0,0 +1,51 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "paragraphs", src: "one\ntwo\n\nthree", want: "<p>one<br>\ntwo</p>\n<p>three</p>"},
+		{name: "emphasis", src: "**bold** and *italic* and `a*b`", want: "<p><strong>bold</strong> and <em>italic</em> and <code>a*b</code></p>"},
+		{name: "unclosed", src: "2 * 3 = **6", want: "<p>2 * 3 = **6</p>"},
+		{name: "list", src: "- one\n- two\ncontinued", want: "<ul><li>one</li><li>two<br>\ncontinued</li></ul>"},
+		{name: "ordered", src: "1. one\n2. two", want: "<ol><li>one</li><li>two</li></ol>"},
+		{name: "quote", src: "> is it genuine?\n\nyes", want: "<blockquote><p>is it genuine?</p></blockquote>\n<p>yes</p>"},
+		{name: "code block", src: "```\n<b>x</b>\n```", want: "<pre><code>&lt;b&gt;x&lt;/b&gt;</code></pre>"},
+		{name: "link", src: "[coin](https://example.com/a?b=1&c=2)", want: `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener noreferrer">coin</a></p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ToHTML(tt.src))
+		})
+	}
+}
+
+func TestToHTML_Unsafe(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "tags", src: `<script>alert(1)</script>`, want: "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{name: "javascript link", src: "[x](javascript:alert(1))", want: "<p>[x](javascript:alert(1))</p>"},
+		{name: "relative link", src: "[x](/api/keeper/profile)", want: "<p>[x](/api/keeper/profile)</p>"},
+		{name: "attribute breakout", src: `[x](https://a.com/"onmouseover="alert(1))`, want: "<p>[x](https://a.com/&#34;onmouseover=&#34;alert(1))</p>"},
+		{name: "tag in link text", src: "[<img src=x>](https://a.com)", want: `<p><a href="https://a.com" rel="nofollow noopener noreferrer">&lt;img src=x&gt;</a></p>`},
+		{name: "nested link", src: "[[a](https://b.com)](https://c.com)", want: `<p><a href="https://b.com" rel="nofollow noopener noreferrer">[a</a>](https://c.com)</p>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ToHTML(tt.src))
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package signedurl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, s *Signer, raw string) (string, url.Values) {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return strings.TrimPrefix(u.Path, s.prefix+"/"), u.Query()
+}
+
+func TestSigner_RoundTrip(t *testing.T) {
+	s := New("secret", "/api/keeper/images", time.Hour)
+
+	key, q := parse(t, s, s.URL("items/1/a.jpg"))
+	assert.Equal(t, "items/1/a.jpg", key)
+	assert.NoError(t, s.Verify(key, q.Get("expires"), q.Get("sig")))
+}
+
+func TestSigner_FailCases(t *testing.T) {
+	s := New("secret", "/api/keeper/images", time.Hour)
+	key, q := parse(t, s, s.URL("items/1/a.jpg"))
+
+	assert.ErrorIs(t, s.Verify("items/2/a.jpg", q.Get("expires"), q.Get("sig")), ErrInvalidSignature)
+	assert.ErrorIs(t, s.Verify(key, "9999999999", q.Get("sig")), ErrInvalidSignature)
+	assert.ErrorIs(t, New("other", "", time.Hour).Verify(key, q.Get("expires"), q.Get("sig")), ErrInvalidSignature)
+
+	s.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	assert.ErrorIs(t, s.Verify(key, q.Get("expires"), q.Get("sig")), ErrExpired)
+}
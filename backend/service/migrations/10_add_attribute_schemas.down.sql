@@ -0,0 +1,6 @@
+ALTER TABLE keeper.items
+    DROP CONSTRAINT IF EXISTS chk_items_attributes_object,
+    ALTER COLUMN attributes DROP NOT NULL,
+    ALTER COLUMN attributes DROP DEFAULT;
+
+ALTER TABLE keeper.categories DROP COLUMN IF EXISTS attribute_schema;
@@ -0,0 +1,34 @@
+ALTER TABLE keeper.categories
+    ADD COLUMN IF NOT EXISTS attribute_schema JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Attributes used to be a list of free-form strings. They become an object of
+-- typed values, the old strings are kept under "notes".
+UPDATE keeper.items
+SET attributes = CASE
+    WHEN jsonb_typeof(attributes) = 'array' AND jsonb_array_length(attributes) > 0 THEN
+        jsonb_build_object('notes', array_to_string(ARRAY(SELECT jsonb_array_elements_text(attributes)), ', '))
+    ELSE '{}'::jsonb
+END
+WHERE attributes IS NULL OR jsonb_typeof(attributes) <> 'object';
+
+ALTER TABLE keeper.items
+    ALTER COLUMN attributes SET DEFAULT '{}'::jsonb,
+    ALTER COLUMN attributes SET NOT NULL,
+    ADD CONSTRAINT chk_items_attributes_object CHECK (jsonb_typeof(attributes) = 'object');
+
+UPDATE keeper.categories SET attribute_schema = '[
+    {"key": "denomination", "label": "Denomination", "type": "string", "required": true},
+    {"key": "metal", "label": "Metal", "type": "enum", "enum": ["gold", "silver", "copper", "bronze", "nickel", "aluminium", "bimetal", "other"]},
+    {"key": "weight_g", "label": "Weight", "type": "number", "unit": "g", "min": 0},
+    {"key": "diameter_mm", "label": "Diameter", "type": "number", "unit": "mm", "min": 0},
+    {"key": "mint_mark", "label": "Mint mark", "type": "string"}
+]'::jsonb
+WHERE lower(name) IN ('coins', 'монеты') AND attribute_schema = '[]'::jsonb;
+
+UPDATE keeper.categories SET attribute_schema = '[
+    {"key": "denomination", "label": "Denomination", "type": "string"},
+    {"key": "perforation", "label": "Perforation", "type": "number", "min": 0},
+    {"key": "watermark", "label": "Watermark", "type": "string"},
+    {"key": "used", "label": "Used", "type": "boolean"}
+]'::jsonb
+WHERE lower(name) IN ('stamps', 'марки') AND attribute_schema = '[]'::jsonb;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS keeper.lot_items;
+DROP TABLE IF EXISTS keeper.lots;
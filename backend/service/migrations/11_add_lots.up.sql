@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS keeper.lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    collection_id INTEGER NOT NULL
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    -- Minor units of currency: kopecks, cents.
+    price BIGINT,
+    currency CHAR(3),
+    exchange_only BOOLEAN NOT NULL DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'active', 'reserved', 'sold', 'withdrawn')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_lots_price CHECK (
+        (exchange_only AND price IS NULL AND currency IS NULL)
+        OR (NOT exchange_only AND price > 0 AND currency IS NOT NULL)
+    )
+);
+
+CREATE TRIGGER trg_lots_update
+BEFORE UPDATE ON keeper.lots
+FOR EACH ROW
+EXECUTE FUNCTION keeper.update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_lots_collection_id ON keeper.lots(collection_id);
+CREATE INDEX IF NOT EXISTS idx_lots_catalogue ON keeper.lots(created_at DESC, id DESC)
+    WHERE status IN ('active', 'reserved');
+
+-- An item is held by its lot until the lot is withdrawn, so it cannot be
+-- listed twice and stays bound to the lot it was sold in.
+CREATE TABLE IF NOT EXISTS keeper.lot_items (
+    lot_id INTEGER NOT NULL
+        REFERENCES keeper.lots(id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    held BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (lot_id, item_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lot_items_held ON keeper.lot_items(item_id) WHERE held;
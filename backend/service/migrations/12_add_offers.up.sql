@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS keeper.offers (
+    id SERIAL PRIMARY KEY,
+    lot_id INTEGER NOT NULL
+        REFERENCES keeper.lots(id) ON DELETE CASCADE,
+    buyer_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    -- The buyer or, for counter offers, the seller.
+    author_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    parent_id INTEGER
+        REFERENCES keeper.offers(id) ON DELETE CASCADE,
+    -- Minor units of currency, as lots.price.
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    message TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'countered', 'accepted', 'rejected', 'cancelled')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TRIGGER trg_offers_update
+BEFORE UPDATE ON keeper.offers
+FOR EACH ROW
+EXECUTE FUNCTION keeper.update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_offers_lot_id ON keeper.offers(lot_id);
+CREATE INDEX IF NOT EXISTS idx_offers_buyer_id ON keeper.offers(buyer_id);
+-- A counter offer closes its parent, so a parent has at most one answer.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_offers_parent_id ON keeper.offers(parent_id);
+-- Only one offer can win a lot.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_offers_lot_accepted ON keeper.offers(lot_id) WHERE status = 'accepted';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS keeper.bids;
+DROP TABLE IF EXISTS keeper.auctions;
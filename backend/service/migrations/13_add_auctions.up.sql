@@ -0,0 +1,34 @@
+-- An auction lot is a lot with a row here. lots.price is the opening bid.
+CREATE TABLE IF NOT EXISTS keeper.auctions (
+    lot_id INTEGER PRIMARY KEY
+        REFERENCES keeper.lots(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    reserve_price BIGINT CHECK (reserve_price > 0),
+    min_increment BIGINT NOT NULL CHECK (min_increment > 0),
+    -- A bid within the last extension_secs moves ends_at to
+    -- extension_secs after the bid.
+    extension_secs INTEGER NOT NULL DEFAULT 0 CHECK (extension_secs >= 0),
+    current_bid BIGINT,
+    bidder_id INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE SET NULL,
+    bid_count INTEGER NOT NULL DEFAULT 0,
+    winner_id INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE SET NULL,
+    closed_at TIMESTAMPTZ,
+    CONSTRAINT chk_auctions_period CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_auctions_open ON keeper.auctions(ends_at) WHERE closed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS keeper.bids (
+    id SERIAL PRIMARY KEY,
+    lot_id INTEGER NOT NULL
+        REFERENCES keeper.auctions(lot_id) ON DELETE CASCADE,
+    bidder_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_bids_lot_id ON keeper.bids(lot_id, id DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS keeper.item_transfers;
+DROP TABLE IF EXISTS keeper.trade_items;
+DROP TABLE IF EXISTS keeper.trades;
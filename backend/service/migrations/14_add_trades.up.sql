@@ -0,0 +1,61 @@
+CREATE TABLE IF NOT EXISTS keeper.trades (
+    id SERIAL PRIMARY KEY,
+    proposer_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    recipient_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    parent_id INTEGER
+        REFERENCES keeper.trades(id) ON DELETE CASCADE,
+    -- Where the items each side receives go. The recipient picks theirs
+    -- when accepting.
+    proposer_collection_id INTEGER NOT NULL
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    recipient_collection_id INTEGER
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    message TEXT,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'countered', 'accepted', 'declined')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_trades_parties CHECK (proposer_id <> recipient_id)
+);
+
+CREATE TRIGGER trg_trades_update
+BEFORE UPDATE ON keeper.trades
+FOR EACH ROW
+EXECUTE FUNCTION keeper.update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_trades_proposer_id ON keeper.trades(proposer_id);
+CREATE INDEX IF NOT EXISTS idx_trades_recipient_id ON keeper.trades(recipient_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_trades_parent_id ON keeper.trades(parent_id);
+
+-- Offered items belong to the proposer, requested ones to the recipient.
+CREATE TABLE IF NOT EXISTS keeper.trade_items (
+    trade_id INTEGER NOT NULL
+        REFERENCES keeper.trades(id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    offered BOOLEAN NOT NULL,
+    PRIMARY KEY (trade_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_trade_items_item_id ON keeper.trade_items(item_id);
+
+CREATE TABLE IF NOT EXISTS keeper.item_transfers (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER NOT NULL
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    trade_id INTEGER
+        REFERENCES keeper.trades(id) ON DELETE SET NULL,
+    from_user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    to_user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    from_collection_id INTEGER
+        REFERENCES keeper.collections(id) ON DELETE SET NULL,
+    to_collection_id INTEGER
+        REFERENCES keeper.collections(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_transfers_item_id ON keeper.item_transfers(item_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS keeper.want_matches;
+DROP TABLE IF EXISTS keeper.wants;
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS keeper.wants (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    title VARCHAR(200) NOT NULL,
+    keywords TEXT NOT NULL DEFAULT '',
+    category_id INTEGER
+        REFERENCES keeper.categories(id) ON DELETE SET NULL,
+    country VARCHAR(100) NOT NULL DEFAULT '',
+    year_from SMALLINT,
+    year_to SMALLINT,
+    -- Minor units of currency, as lots.price.
+    max_price BIGINT CHECK (max_price > 0),
+    currency CHAR(3),
+    attributes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_wants_price CHECK ((max_price IS NULL) = (currency IS NULL)),
+    CONSTRAINT chk_wants_attributes CHECK (jsonb_typeof(attributes) = 'object')
+);
+
+CREATE TRIGGER trg_wants_update
+BEFORE UPDATE ON keeper.wants
+FOR EACH ROW
+EXECUTE FUNCTION keeper.update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_wants_user_id ON keeper.wants(user_id);
+
+-- A match of a want with a public item, or with a lot holding it.
+CREATE TABLE IF NOT EXISTS keeper.want_matches (
+    id SERIAL PRIMARY KEY,
+    want_id INTEGER NOT NULL
+        REFERENCES keeper.wants(id) ON DELETE CASCADE,
+    item_id INTEGER NOT NULL
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    lot_id INTEGER
+        REFERENCES keeper.lots(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_want_matches_unique ON keeper.want_matches(want_id, item_id, (COALESCE(lot_id, 0)));
+CREATE INDEX IF NOT EXISTS idx_want_matches_item_id ON keeper.want_matches(item_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS keeper.follows;
+DROP TABLE IF EXISTS keeper.favorites;
+ALTER TABLE keeper.users_info DROP COLUMN IF EXISTS followers_private;
@@ -0,0 +1,31 @@
+ALTER TABLE keeper.users_info ADD COLUMN IF NOT EXISTS followers_private BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- A favorite is either an item or a collection.
+CREATE TABLE IF NOT EXISTS keeper.favorites (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    item_id INTEGER
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    collection_id INTEGER
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_favorites_target CHECK ((item_id IS NULL) <> (collection_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_user_item ON keeper.favorites(user_id, item_id) WHERE item_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_favorites_user_collection ON keeper.favorites(user_id, collection_id) WHERE collection_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_favorites_item_id ON keeper.favorites(item_id);
+CREATE INDEX IF NOT EXISTS idx_favorites_collection_id ON keeper.favorites(collection_id);
+
+CREATE TABLE IF NOT EXISTS keeper.follows (
+    follower_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    followee_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (follower_id, followee_id),
+    CONSTRAINT chk_follows_self CHECK (follower_id <> followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON keeper.follows(followee_id);
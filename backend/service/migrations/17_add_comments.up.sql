@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS keeper.comments (
+    id SERIAL PRIMARY KEY,
+    item_id INTEGER
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    collection_id INTEGER
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    parent_id INTEGER
+        REFERENCES keeper.comments(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    -- Markdown source and its sanitized HTML, both emptied on delete.
+    body TEXT NOT NULL,
+    body_html TEXT NOT NULL,
+    edited_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT chk_comments_target CHECK ((item_id IS NULL) <> (collection_id IS NULL))
+);
+
+CREATE TRIGGER trg_comments_update
+BEFORE UPDATE ON keeper.comments
+FOR EACH ROW
+EXECUTE FUNCTION keeper.update_updated_at_column();
+
+CREATE INDEX IF NOT EXISTS idx_comments_item_id ON keeper.comments(item_id, id) WHERE item_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_collection_id ON keeper.comments(collection_id, id) WHERE collection_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_comments_author_created ON keeper.comments(author_id, created_at);
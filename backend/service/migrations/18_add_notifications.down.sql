@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS trg_notifications_announce ON keeper.notifications;
+DROP FUNCTION IF EXISTS keeper.announce_notification();
+DROP TABLE IF EXISTS keeper.notification_preferences;
+DROP TABLE IF EXISTS keeper.notifications;
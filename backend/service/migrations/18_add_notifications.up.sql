@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS keeper.notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    read_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON keeper.notifications(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON keeper.notifications(user_id) WHERE read_at IS NULL;
+
+-- Types are on unless the user turned them off.
+CREATE TABLE IF NOT EXISTS keeper.notification_preferences (
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    PRIMARY KEY (user_id, type)
+);
+
+-- Every replica of the service listens on keeper_notifications and pushes
+-- the notifications of its connected users. NOTIFY is delivered on commit.
+CREATE OR REPLACE FUNCTION keeper.announce_notification()
+RETURNS TRIGGER AS $$
+BEGIN
+   PERFORM pg_notify('keeper_notifications', json_build_object(
+       'id', NEW.id,
+       'user_id', NEW.user_id,
+       'type', NEW.type,
+       'payload', NEW.payload,
+       'created_at', NEW.created_at
+   )::text);
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_notifications_announce
+AFTER INSERT ON keeper.notifications
+FOR EACH ROW
+EXECUTE FUNCTION keeper.announce_notification();
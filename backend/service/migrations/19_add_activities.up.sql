@@ -0,0 +1,26 @@
+-- Domain events the activity feed is built from. Feeds are assembled when
+-- read, from the follows and favorite collections of the reader.
+CREATE TABLE IF NOT EXISTS keeper.activities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    type VARCHAR(32) NOT NULL,
+    collection_id INTEGER NOT NULL
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    item_id INTEGER
+        REFERENCES keeper.items(id) ON DELETE CASCADE,
+    lot_id INTEGER
+        REFERENCES keeper.lots(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activities_user_id ON keeper.activities(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_activities_collection_id ON keeper.activities(collection_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_activities_item_id ON keeper.activities(item_id) WHERE item_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_activities_lot_id ON keeper.activities(lot_id) WHERE lot_id IS NOT NULL;
+
+-- A collection is published and a lot listed only once.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_published ON keeper.activities(collection_id)
+    WHERE type = 'collection_published';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_listed ON keeper.activities(lot_id)
+    WHERE type = 'lot_listed';
@@ -0,0 +1,26 @@
+-- Private collections shared with other users. Viewers read the collection,
+-- editors also change its items. An invitation by an email nobody has
+-- registered with waits in email and is claimed on registration.
+CREATE TABLE IF NOT EXISTS keeper.collection_shares (
+    id BIGSERIAL PRIMARY KEY,
+    collection_id INTEGER NOT NULL
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    user_id INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    email VARCHAR(255),
+    role VARCHAR(16) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    invited_by INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK ((user_id IS NULL) <> (email IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_shares_user ON keeper.collection_shares(collection_id, user_id)
+    WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_shares_email ON keeper.collection_shares(collection_id, lower(email))
+    WHERE email IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_collection_shares_user_id ON keeper.collection_shares(user_id)
+    WHERE user_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_collection_shares_pending ON keeper.collection_shares(lower(email))
+    WHERE email IS NOT NULL;
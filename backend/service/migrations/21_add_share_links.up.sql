@@ -0,0 +1,20 @@
+-- Links that let anybody holding the token read a collection without an
+-- account. Only a SHA-256 hash of the token is stored.
+CREATE TABLE IF NOT EXISTS keeper.share_links (
+    id BIGSERIAL PRIMARY KEY,
+    collection_id INTEGER NOT NULL
+        REFERENCES keeper.collections(id) ON DELETE CASCADE,
+    created_by INTEGER NOT NULL
+        REFERENCES keeper.users_info(user_id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    password_hash VARCHAR(60),
+    expires_at TIMESTAMPTZ NOT NULL,
+    max_views INTEGER CHECK (max_views > 0),
+    views INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMPTZ,
+    last_viewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_created_by ON keeper.share_links(created_by, id DESC)
+    WHERE revoked_at IS NULL;
@@ -0,0 +1,20 @@
+-- Snapshots of items and collections taken on every create, update, delete
+-- and revert. Versions outlive what they describe, so there are no foreign
+-- keys to it; collection_id is where an item was when the version was made.
+CREATE TABLE IF NOT EXISTS keeper.versions (
+    id BIGSERIAL PRIMARY KEY,
+    entity VARCHAR(16) NOT NULL CHECK (entity IN ('item', 'collection')),
+    entity_id INTEGER NOT NULL,
+    collection_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL CHECK (action IN ('create', 'update', 'delete', 'revert')),
+    user_id INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE SET NULL,
+    snapshot JSONB NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    reverted_from INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (entity, entity_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_versions_collection_id ON keeper.versions(collection_id);
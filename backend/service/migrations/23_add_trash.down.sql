@@ -0,0 +1,18 @@
+ALTER TABLE keeper.versions DROP CONSTRAINT IF EXISTS versions_action_check;
+UPDATE keeper.versions SET action = 'update' WHERE action = 'restore';
+ALTER TABLE keeper.versions ADD CONSTRAINT versions_action_check
+    CHECK (action IN ('create', 'update', 'delete', 'revert'));
+
+-- What is in the trash was deleted; without the trash it is gone.
+DELETE FROM keeper.items WHERE deleted_at IS NOT NULL;
+DELETE FROM keeper.collections WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS keeper.idx_items_deleted_at;
+DROP INDEX IF EXISTS keeper.idx_collections_deleted_at;
+
+ALTER TABLE keeper.items
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE keeper.collections
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,22 @@
+-- Deleted collections and items go to the trash first. They are hidden from
+-- every read until restored, and purged for good once the retention period
+-- has passed. The items of a trashed collection stay as they are and go,
+-- or come back, with it.
+ALTER TABLE keeper.collections
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE SET NULL;
+ALTER TABLE keeper.items
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS deleted_by INTEGER
+        REFERENCES keeper.users_info(user_id) ON DELETE SET NULL;
+
+-- The trash listing and the purge job walk these.
+CREATE INDEX IF NOT EXISTS idx_collections_deleted_at ON keeper.collections(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_items_deleted_at ON keeper.items(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE keeper.versions DROP CONSTRAINT IF EXISTS versions_action_check;
+ALTER TABLE keeper.versions ADD CONSTRAINT versions_action_check
+    CHECK (action IN ('create', 'update', 'delete', 'revert', 'restore'));
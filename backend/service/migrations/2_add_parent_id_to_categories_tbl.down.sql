@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS keeper.idx_categories_parent_id;
+DROP INDEX IF EXISTS keeper.idx_categories_parent_name;
+
+-- Names are only unique under a parent now. Categories sharing a name with
+-- an older one get their id appended so that the old constraint holds again.
+UPDATE keeper.categories c
+SET name = left(c.name, 49 - length(c.id::text)) || '-' || c.id
+WHERE EXISTS (SELECT 1 FROM keeper.categories o WHERE o.name = c.name AND o.id < c.id);
+
+ALTER TABLE keeper.categories
+    ADD CONSTRAINT categories_name_key UNIQUE (name);
+
+ALTER TABLE keeper.categories
+    DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,11 @@
+ALTER TABLE keeper.categories
+    ADD COLUMN IF NOT EXISTS parent_id INTEGER
+        REFERENCES keeper.categories(id) ON DELETE RESTRICT;
+
+ALTER TABLE keeper.categories
+    DROP CONSTRAINT IF EXISTS categories_name_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_parent_name
+    ON keeper.categories(COALESCE(parent_id, 0), name);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON keeper.categories(parent_id);
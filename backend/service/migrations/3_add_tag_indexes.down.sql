@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS keeper.idx_tags_name_prefix;
+DROP INDEX IF EXISTS keeper.idx_item_tags_tag_id;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_item_tags_tag_id ON keeper.item_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_tags_name_prefix ON keeper.tags(name text_pattern_ops);
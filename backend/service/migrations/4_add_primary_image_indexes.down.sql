@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS keeper.idx_collection_images_collection_primary;
+DROP INDEX IF EXISTS keeper.idx_collection_images_collection_id;
+DROP INDEX IF EXISTS keeper.idx_images_item_primary;
+DROP INDEX IF EXISTS keeper.idx_images_item_id;
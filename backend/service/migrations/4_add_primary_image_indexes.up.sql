@@ -0,0 +1,7 @@
+CREATE INDEX IF NOT EXISTS idx_images_item_id ON keeper.images(item_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_images_item_primary
+    ON keeper.images(item_id) WHERE is_primary;
+
+CREATE INDEX IF NOT EXISTS idx_collection_images_collection_id ON keeper.collection_images(collection_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_collection_images_collection_primary
+    ON keeper.collection_images(collection_id) WHERE is_primary;
@@ -0,0 +1,11 @@
+ALTER TABLE keeper.collection_images
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS medium_url,
+    DROP COLUMN IF EXISTS thumb_url;
+
+ALTER TABLE keeper.images
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS medium_url,
+    DROP COLUMN IF EXISTS thumb_url;
@@ -0,0 +1,2 @@
+ALTER TABLE keeper.images
+    DROP COLUMN IF EXISTS phash;
@@ -0,0 +1,2 @@
+ALTER TABLE keeper.images
+    ADD COLUMN IF NOT EXISTS phash BIGINT;
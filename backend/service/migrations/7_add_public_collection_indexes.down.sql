@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS keeper.idx_items_collection_id;
+DROP INDEX IF EXISTS keeper.idx_collections_public_updated_at;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_collections_public_updated_at
+    ON keeper.collections(updated_at DESC) WHERE is_public;
+CREATE INDEX IF NOT EXISTS idx_items_collection_id ON keeper.items(collection_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS keeper.idx_collections_search_vector;
+ALTER TABLE keeper.collections DROP COLUMN IF EXISTS search_vector;
+
+DROP INDEX IF EXISTS keeper.idx_items_search_vector;
+ALTER TABLE keeper.items DROP COLUMN IF EXISTS search_vector;
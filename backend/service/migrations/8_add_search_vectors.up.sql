@@ -0,0 +1,19 @@
+ALTER TABLE keeper.items
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('russian', COALESCE(description, '')), 'B') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'B') ||
+        setweight(jsonb_to_tsvector('russian', COALESCE(attributes, '[]'::jsonb), '["string"]'), 'C') ||
+        setweight(jsonb_to_tsvector('english', COALESCE(attributes, '[]'::jsonb), '["string"]'), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_items_search_vector ON keeper.items USING GIN (search_vector);
+
+ALTER TABLE keeper.collections
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('russian', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(name, '')), 'A')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_collections_search_vector ON keeper.collections USING GIN (search_vector);
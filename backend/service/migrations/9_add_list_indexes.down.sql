@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS keeper.idx_users_info_created_at;
+DROP INDEX IF EXISTS keeper.idx_collections_user_updated_at;
+DROP INDEX IF EXISTS keeper.idx_collections_user_created_at;
+DROP INDEX IF EXISTS keeper.idx_collections_user_name;
+DROP INDEX IF EXISTS keeper.idx_items_collection_updated_at;
+DROP INDEX IF EXISTS keeper.idx_items_collection_created_at;
+DROP INDEX IF EXISTS keeper.idx_items_collection_year;
+DROP INDEX IF EXISTS keeper.idx_items_collection_title;
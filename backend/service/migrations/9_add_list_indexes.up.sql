@@ -0,0 +1,9 @@
+-- Keyset pagination walks these indexes in (sort column, id) order.
+CREATE INDEX IF NOT EXISTS idx_items_collection_title ON keeper.items(collection_id, title, id);
+CREATE INDEX IF NOT EXISTS idx_items_collection_year ON keeper.items(collection_id, (COALESCE(year, -1)), id);
+CREATE INDEX IF NOT EXISTS idx_items_collection_created_at ON keeper.items(collection_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_items_collection_updated_at ON keeper.items(collection_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_collections_user_name ON keeper.collections(user_id, name, id);
+CREATE INDEX IF NOT EXISTS idx_collections_user_created_at ON keeper.collections(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_collections_user_updated_at ON keeper.collections(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_info_created_at ON keeper.users_info(created_at, user_id);
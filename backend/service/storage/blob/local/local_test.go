@@ -0,0 +1,42 @@
+package local
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	png := "\x89PNG\r\n\x1a\n"
+	require.NoError(t, store.Put(ctx, "covers/3/b", strings.NewReader(png), int64(len(png)), "image/png"))
+
+	rc, info, err := store.Get(ctx, "covers/3/b")
+	require.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, png, string(data))
+	assert.Equal(t, "image/png", info.ContentType)
+	assert.Equal(t, int64(len(png)), info.Size)
+
+	require.NoError(t, store.Delete(ctx, "covers/3/b"))
+	_, _, err = store.Get(ctx, "covers/3/b")
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestStore_RejectsTraversal(t *testing.T) {
+	store, err := New(t.TempDir())
+	require.NoError(t, err)
+
+	err = store.Put(context.Background(), "../../etc/passwd", strings.NewReader("x"), 1, "text/plain")
+	assert.Error(t, err)
+}
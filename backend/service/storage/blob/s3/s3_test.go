@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/storage/blob"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server. It
+// checks that requests are signed and that the payload hash matches.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != hex.EncodeToString(sum[:]) {
+		http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func newTestStore(t *testing.T) (*Store, *fakeS3) {
+	t.Helper()
+
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := New(Config{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "keeper",
+		AccessKey: "access",
+		SecretKey: "secret",
+	}, server.Client())
+	require.NoError(t, err)
+
+	return store, fake
+}
+
+func TestStore_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newTestStore(t)
+
+	data := []byte("\xff\xd8\xff jpeg bytes")
+	require.NoError(t, store.Put(ctx, "items/1/a.jpg", bytes.NewReader(data), int64(len(data)), "image/jpeg"))
+	assert.Contains(t, fake.objects, "/keeper/items/1/a.jpg")
+
+	rc, info, err := store.Get(ctx, "items/1/a.jpg")
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+	assert.Equal(t, "image/jpeg", info.ContentType)
+
+	require.NoError(t, store.Delete(ctx, "items/1/a.jpg"))
+	_, _, err = store.Get(ctx, "items/1/a.jpg")
+	assert.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestStore_BadCredentials(t *testing.T) {
+	store, _ := newTestStore(t)
+	store.accessKey = "intruder"
+
+	err := store.Put(context.Background(), "items/1/a.jpg", strings.NewReader("x"), 1, "image/jpeg")
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// RecordActivity records a domain event for the activity feed. The
+// collection of a lot event may be left out. Publishing a collection or
+// listing a lot again is not recorded twice.
+func (s *Storage) RecordActivity(ctx context.Context, a models.Activity) error {
+	const op = "postgresql.RecordActivity"
+
+	var itemID int64
+	if len(a.Items) > 0 {
+		itemID = a.Items[0].ItemID
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO keeper.activities (user_id, type, collection_id, item_id, lot_id)
+		VALUES ($1, $2, COALESCE($3, (SELECT collection_id FROM keeper.lots WHERE id = $5)), $4, $5)
+		ON CONFLICT DO NOTHING`,
+		a.UserID, a.Type, nullableID(a.CollectionID), nullableID(itemID), nullableID(a.LotID))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Feed returns up to limit activities of the collectors the user follows
+// and in the collections the user favorited, newest first and older than
+// before when it is not 0. Only what is public now is returned: items and
+// collections in public collections, lots in the catalogue. Items that left
+// their collection since, or are in the trash, are left out too.
+func (s *Storage) Feed(ctx context.Context, userID, before int64, limit int) ([]models.Activity, error) {
+	const op = "postgresql.Feed"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT a.id, a.user_id, a.type, u.username, c.id,
+			CASE WHEN c.is_public THEN c.name ELSE '' END,
+			COALESCE(i.id, 0), COALESCE(i.title, ''), COALESCE(l.id, 0), COALESCE(l.title, ''), a.created_at
+		FROM keeper.activities a
+		JOIN keeper.users_info u ON u.user_id = a.user_id
+		JOIN keeper.collections c ON c.id = a.collection_id
+		LEFT JOIN keeper.items i ON i.id = a.item_id
+		LEFT JOIN keeper.lots l ON l.id = a.lot_id
+		WHERE (a.user_id IN (SELECT followee_id FROM keeper.follows WHERE follower_id = $1)
+				OR a.collection_id IN (SELECT collection_id FROM keeper.favorites
+					WHERE user_id = $1 AND collection_id IS NOT NULL))
+			AND a.user_id <> $1 AND NOT u.is_blocked
+			AND CASE a.type
+				WHEN 'lot_listed' THEN l.status IN ('active', 'reserved')
+				ELSE c.is_public AND c.deleted_at IS NULL
+					AND (a.item_id IS NULL OR (i.collection_id = c.id AND i.deleted_at IS NULL))
+			END
+			AND ($2 = 0 OR a.id < $2)
+		ORDER BY a.id DESC
+		LIMIT $3`, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var activities []models.Activity
+	for rows.Next() {
+		var a models.Activity
+		var item models.ActivityItem
+		err := rows.Scan(&a.ActivityID, &a.UserID, &a.Type, &a.Username, &a.CollectionID, &a.Collection,
+			&item.ItemID, &item.Title, &a.LotID, &a.Lot, &a.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if item.ItemID != 0 {
+			a.Items = []models.ActivityItem{item}
+			a.Count = 1
+		}
+		activities = append(activities, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return activities, nil
+}
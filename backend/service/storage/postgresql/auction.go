@@ -0,0 +1,252 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// auctionColumns are selected with a LEFT JOIN of keeper.auctions a and are
+// NULL for lots that are not auctions.
+const auctionColumns = `a.starts_at, a.ends_at, COALESCE(a.reserve_price, 0), COALESCE(a.min_increment, 0),
+	COALESCE(a.extension_secs, 0), COALESCE(a.current_bid, 0), COALESCE(a.bidder_id, 0), COALESCE(a.bid_count, 0),
+	COALESCE(a.winner_id, 0), a.closed_at`
+
+type auctionRow struct {
+	startsAt, endsAt, closedAt sql.NullTime
+	auction                    models.Auction
+}
+
+func (r *auctionRow) dest() []any {
+	a := &r.auction
+	return []any{&r.startsAt, &r.endsAt, &a.ReservePrice, &a.MinIncrement,
+		&a.Extension, &a.CurrentBid, &a.BidderID, &a.BidCount,
+		&a.WinnerID, &r.closedAt}
+}
+
+// get returns the scanned auction of a lot with opening bid price, or nil if
+// the lot is not an auction.
+func (r *auctionRow) get(price int64) *models.Auction {
+	if !r.startsAt.Valid {
+		return nil
+	}
+
+	a := r.auction
+	a.StartsAt, a.EndsAt = r.startsAt.Time, r.endsAt.Time
+	if r.closedAt.Valid {
+		closedAt := r.closedAt.Time
+		a.ClosedAt = &closedAt
+	}
+	a.MinBid = minBid(a, price)
+	a.ReserveMet = a.BidCount > 0 && a.CurrentBid >= a.ReservePrice
+	return &a
+}
+
+// minBid is the lowest bid the auction takes: the opening bid price, then
+// the current bid raised by the minimum increment.
+func minBid(a models.Auction, price int64) int64 {
+	if a.BidCount == 0 {
+		return price
+	}
+	return a.CurrentBid + a.MinIncrement
+}
+
+// applyBid records a bid of amount by bidderID, made at now, on an auction
+// with opening bid price. The caller holds the lock on the lot, so bids are
+// applied one after another.
+func applyBid(a *models.Auction, price, bidderID, amount int64, now time.Time) error {
+	if a.ClosedAt != nil || now.Before(a.StartsAt) || !now.Before(a.EndsAt) {
+		return storage.ErrAuctionClosed
+	}
+	if amount < minBid(*a, price) {
+		return storage.ErrBidTooLow
+	}
+
+	a.CurrentBid, a.BidderID = amount, bidderID
+	a.BidCount++
+	if extension := time.Duration(a.Extension) * time.Second; a.EndsAt.Sub(now) < extension {
+		a.EndsAt = now.Add(extension)
+	}
+	a.MinBid = minBid(*a, price)
+	a.ReserveMet = a.CurrentBid >= a.ReservePrice
+
+	return nil
+}
+
+func saveAuction(ctx context.Context, tx *sql.Tx, lotID int64, a models.Auction) error {
+	reserve := sql.NullInt64{Int64: a.ReservePrice, Valid: a.ReservePrice > 0}
+	_, err := tx.ExecContext(ctx, `INSERT INTO keeper.auctions (lot_id, starts_at, ends_at, reserve_price, min_increment, extension_secs)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (lot_id) DO UPDATE SET starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at,
+			reserve_price = EXCLUDED.reserve_price, min_increment = EXCLUDED.min_increment,
+			extension_secs = EXCLUDED.extension_secs`,
+		lotID, a.StartsAt, a.EndsAt, reserve, a.MinIncrement, a.Extension)
+	return err
+}
+
+// PlaceBid bids amount on an active auction lot and returns the auction
+// after the bid. The lot row is locked for the bid, so concurrent bids are
+// checked against each other's results and no two of them can win with the
+// same current bid.
+func (s *Storage) PlaceBid(ctx context.Context, lotID, bidderID, amount int64) (models.Auction, error) {
+	const op = "postgresql.PlaceBid"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, "SELECT status FROM keeper.lots WHERE id = $1 FOR UPDATE", lotID).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Auction{}, fmt.Errorf("%s: %w", op, storage.ErrLotNotFound)
+		}
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A new statement after the lock sees the bids committed while waiting
+	// for it, and its timestamp is the time the bid is taken.
+	var row auctionRow
+	var price sql.NullInt64
+	var now time.Time
+	err = tx.QueryRowContext(ctx, "SELECT "+auctionColumns+", l.price, statement_timestamp() FROM keeper.lots l "+
+		"LEFT JOIN keeper.auctions a ON a.lot_id = l.id WHERE l.id = $1", lotID).Scan(append(row.dest(), &price, &now)...)
+	if err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	auction := row.get(price.Int64)
+	if auction == nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, storage.ErrNotAuction)
+	}
+	if status != models.LotActive {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, storage.ErrAuctionClosed)
+	}
+	if err := applyBid(auction, price.Int64, bidderID, amount, now); err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO keeper.bids (lot_id, bidder_id, amount, created_at) VALUES ($1, $2, $3, $4)",
+		lotID, bidderID, amount, now)
+	if err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE keeper.auctions SET current_bid = $1, bidder_id = $2, bid_count = $3, ends_at = $4
+		WHERE lot_id = $5`, auction.CurrentBid, auction.BidderID, auction.BidCount, auction.EndsAt, lotID)
+	if err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Auction{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return *auction, nil
+}
+
+// CloseAuctions finalizes up to limit active auctions that have ended and
+// returns their lot ids. If the highest bid meets the reserve price the lot
+// is sold to its bidder, otherwise the lot is withdrawn and its items are
+// released. Lots locked by a bid in progress are skipped and left for the
+// next run, so fewer than limit may come back while more are due.
+func (s *Storage) CloseAuctions(ctx context.Context, limit int) ([]int64, error) {
+	const op = "postgresql.CloseAuctions"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT l.id FROM keeper.lots l
+		JOIN keeper.auctions a ON a.lot_id = l.id
+		WHERE l.status = 'active' AND a.closed_at IS NULL AND a.ends_at <= now()
+		ORDER BY a.ends_at LIMIT $1
+		FOR UPDATE OF l SKIP LOCKED`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var lotIDs []int64
+	for rows.Next() {
+		var lotID int64
+		if err := rows.Scan(&lotID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		lotIDs = append(lotIDs, lotID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var closed []int64
+	for _, lotID := range lotIDs {
+		// ends_at is checked again: a bid committed before the lock was
+		// taken may have extended the auction.
+		var winner sql.NullInt64
+		err := tx.QueryRowContext(ctx, `UPDATE keeper.auctions SET closed_at = now(),
+			winner_id = CASE WHEN bid_count > 0 AND current_bid >= COALESCE(reserve_price, 0) THEN bidder_id END
+			WHERE lot_id = $1 AND closed_at IS NULL AND ends_at <= now()
+			RETURNING winner_id`, lotID).Scan(&winner)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		status := models.LotWithdrawn
+		if winner.Valid {
+			status = models.LotSold
+		}
+		if err := moveLot(ctx, tx, lotID, models.LotActive, status); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		closed = append(closed, lotID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return closed, nil
+}
+
+// Bids returns the bids on an auction lot, highest first.
+func (s *Storage) Bids(ctx context.Context, lotID int64) ([]models.Bid, error) {
+	const op = "postgresql.Bids"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT b.id, b.lot_id, b.bidder_id, u.username, b.amount, b.created_at
+		FROM keeper.bids b
+		JOIN keeper.users_info u ON u.user_id = b.bidder_id
+		WHERE b.lot_id = $1 ORDER BY b.id DESC`, lotID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var bids []models.Bid
+	for rows.Next() {
+		var bid models.Bid
+		if err := rows.Scan(&bid.BidID, &bid.LotID, &bid.BidderID, &bid.Bidder, &bid.Amount, &bid.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		bids = append(bids, bid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bids, nil
+}
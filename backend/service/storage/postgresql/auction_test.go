@@ -0,0 +1,179 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var auctionStart = time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestAuction() models.Auction {
+	return models.Auction{
+		StartsAt:     auctionStart,
+		EndsAt:       auctionStart.Add(time.Hour),
+		ReservePrice: 5000,
+		MinIncrement: 100,
+		Extension:    120,
+	}
+}
+
+func TestApplyBid(t *testing.T) {
+	a := newTestAuction()
+	now := auctionStart.Add(10 * time.Minute)
+
+	assert.ErrorIs(t, applyBid(&a, 1000, 7, 999, now), storage.ErrBidTooLow)
+	require.NoError(t, applyBid(&a, 1000, 7, 1000, now))
+	assert.Equal(t, int64(1100), a.MinBid)
+	assert.False(t, a.ReserveMet)
+
+	assert.ErrorIs(t, applyBid(&a, 1000, 8, 1099, now), storage.ErrBidTooLow)
+	require.NoError(t, applyBid(&a, 1000, 8, 6000, now))
+	assert.True(t, a.ReserveMet)
+	assert.Equal(t, int64(2), a.BidCount)
+	assert.Equal(t, int64(8), a.BidderID)
+	assert.Equal(t, auctionStart.Add(time.Hour), a.EndsAt)
+
+	assert.ErrorIs(t, applyBid(&a, 1000, 7, 9000, auctionStart.Add(-time.Minute)), storage.ErrAuctionClosed)
+	assert.ErrorIs(t, applyBid(&a, 1000, 7, 9000, auctionStart.Add(time.Hour)), storage.ErrAuctionClosed)
+}
+
+func TestApplyBid_AntiSniping(t *testing.T) {
+	a := newTestAuction()
+
+	// A bid 30 seconds before the end leaves two minutes to answer it.
+	late := a.EndsAt.Add(-30 * time.Second)
+	require.NoError(t, applyBid(&a, 1000, 7, 1000, late))
+	assert.Equal(t, late.Add(2*time.Minute), a.EndsAt)
+
+	// The auction can now be bid on past its original end.
+	require.NoError(t, applyBid(&a, 1000, 8, 1100, auctionStart.Add(time.Hour+time.Minute)))
+	assert.Equal(t, auctionStart.Add(time.Hour+3*time.Minute), a.EndsAt)
+
+	a.Extension = 0
+	assert.ErrorIs(t, applyBid(&a, 1000, 7, 1200, a.EndsAt), storage.ErrAuctionClosed)
+}
+
+// TestPlaceBid_ConcurrentBidders has bidders race for every price level of
+// one auction in the test database.
+func TestPlaceBid_ConcurrentBidders(t *testing.T) {
+	s := newTestStorage(t)
+	db := s.db
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	const (
+		bidders   = 300
+		levels    = 20
+		price     = 1000
+		increment = 100
+	)
+
+	sellerID := d.user("seller")
+	userIDs := make([]int64, bidders)
+	for i := range userIDs {
+		userIDs[i] = d.user(fmt.Sprintf("bidder%d", i))
+	}
+
+	lotID := d.lot(sellerID, d.collection(sellerID, "Coins", true), "Rouble", price)
+	_, err := db.ExecContext(ctx, `INSERT INTO keeper.auctions (lot_id, starts_at, ends_at, min_increment)
+		VALUES ($1, now() - interval '1 hour', now() + interval '1 hour', $2)`, lotID, increment)
+	require.NoError(t, err)
+
+	// Every bidder goes up the levels in order, so a level is only tried
+	// once the one below it has been won.
+	var (
+		mu  sync.Mutex
+		won = make(map[int64][]int64)
+		wg  sync.WaitGroup
+	)
+	for _, bidderID := range userIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for level := range int64(levels) {
+				amount := price + level*increment
+				_, err := s.PlaceBid(ctx, lotID, bidderID, amount)
+				switch {
+				case err == nil:
+					mu.Lock()
+					won[amount] = append(won[amount], bidderID)
+					mu.Unlock()
+				case errors.Is(err, storage.ErrBidTooLow):
+				default:
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, won, levels)
+	for amount, winners := range won {
+		assert.Len(t, winners, 1, "amount %d", amount)
+	}
+
+	var stored, distinct int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT count(*), count(DISTINCT amount) FROM keeper.bids WHERE lot_id = $1",
+		lotID).Scan(&stored, &distinct))
+	assert.Equal(t, levels, stored)
+	assert.Equal(t, levels, distinct)
+
+	var currentBid, bidderID, bidCount int64
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT current_bid, bidder_id, bid_count FROM keeper.auctions WHERE lot_id = $1",
+		lotID).Scan(&currentBid, &bidderID, &bidCount))
+	assert.Equal(t, int64(stored), bidCount)
+	top := int64(price + (levels-1)*increment)
+	assert.Equal(t, top, currentBid)
+	assert.Equal(t, won[top][0], bidderID)
+}
+
+// TestCloseAuctions_SkipsLocked closes ended auctions while a bid holds the
+// lock on one of the lots, and expects that lot to be left for the next run.
+func TestCloseAuctions_SkipsLocked(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	sellerID := d.user("seller")
+	bidderID := d.user("bidder")
+	collectionID := d.collection(sellerID, "Coins", true)
+	soldID := d.lot(sellerID, collectionID, "Rouble", 1000)
+	lockedID := d.lot(sellerID, collectionID, "Kopek", 1000)
+	_, err := s.db.ExecContext(ctx, `INSERT INTO keeper.auctions (lot_id, starts_at, ends_at, min_increment, current_bid, bidder_id, bid_count)
+		VALUES ($1, now() - interval '2 hours', now() - interval '1 hour', 100, 1000, $3, 1),
+			($2, now() - interval '2 hours', now() - interval '1 hour', 100, NULL, NULL, 0)`, soldID, lockedID, bidderID)
+	require.NoError(t, err)
+
+	status := func(lotID int64) string {
+		var status string
+		require.NoError(t, s.db.QueryRowContext(ctx, "SELECT status FROM keeper.lots WHERE id = $1", lotID).Scan(&status))
+		return status
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "SELECT id FROM keeper.lots WHERE id = $1 FOR UPDATE", lockedID)
+	require.NoError(t, err)
+
+	closed, err := s.CloseAuctions(ctx, 100)
+	require.NoError(t, err)
+	assert.Contains(t, closed, soldID)
+	assert.NotContains(t, closed, lockedID)
+	assert.Equal(t, models.LotSold, status(soldID))
+	assert.Equal(t, models.LotActive, status(lockedID))
+
+	require.NoError(t, tx.Rollback())
+	closed, err = s.CloseAuctions(ctx, 100)
+	require.NoError(t, err)
+	assert.Contains(t, closed, lockedID)
+	assert.Equal(t, models.LotWithdrawn, status(lockedID))
+}
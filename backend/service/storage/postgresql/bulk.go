@@ -0,0 +1,346 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// itemFailure is the error of one item of a bulk operation. The item is
+// rolled back and reported, and the operation goes on with the next one.
+type itemFailure struct{ error }
+
+func (f itemFailure) Unwrap() error { return f.error }
+
+// bulkItems applies apply to each item in a savepoint of tx. An item that
+// fails with an itemFailure is rolled back alone; any other error aborts the
+// whole operation. apply returns the id of the item it made, if any.
+func bulkItems(ctx context.Context, tx *sql.Tx, itemIDs []int64, apply func(itemID int64) (int64, error)) ([]models.ItemResult, error) {
+	results := make([]models.ItemResult, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_item"); err != nil {
+			return nil, err
+		}
+
+		newID, err := apply(itemID)
+		var failure itemFailure
+		switch {
+		case err == nil:
+			results = append(results, models.ItemResult{ItemID: itemID, OK: true, NewItemID: newID})
+		case errors.As(err, &failure):
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_item"); err != nil {
+				return nil, err
+			}
+			results = append(results, models.ItemResult{ItemID: itemID, Error: failure.Error()})
+		default:
+			return nil, err
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_item"); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// selectItems locks the selected items in collections where the user has
+// role and returns their ids, along with the results of requested ids that
+// are not among them.
+func selectItems(ctx context.Context, tx *sql.Tx, userID int64, sel models.ItemSelection, role string) ([]int64, []models.ItemResult, error) {
+	var rows *sql.Rows
+	var err error
+	if len(sel.ItemIDs) > 0 {
+		rows, err = tx.QueryContext(ctx, `SELECT i.id FROM keeper.items i
+			JOIN keeper.collections c ON c.id = i.collection_id
+			WHERE i.id = ANY($1) AND i.deleted_at IS NULL AND `+canAccess("c", "$2", role)+`
+			ORDER BY i.id FOR UPDATE OF i`, pq.Array(sel.ItemIDs), userID)
+	} else {
+		if err := collectionAccessible(ctx, tx, userID, sel.CollectionID, role); err != nil {
+			return nil, nil, err
+		}
+		q := &listQuery{}
+		q.and("i.collection_id = " + q.arg(sel.CollectionID))
+		q.and("i.deleted_at IS NULL")
+		q.itemFilters(sel.Filter)
+		rows, err = tx.QueryContext(ctx, "SELECT i.id FROM keeper.items i WHERE "+q.conditions()+`
+			ORDER BY i.id LIMIT `+q.arg(sel.Limit+1)+" FOR UPDATE", q.args...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var itemIDs []int64
+	found := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, err
+		}
+		itemIDs = append(itemIDs, id)
+		found[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(itemIDs) > sel.Limit {
+		return nil, nil, storage.ErrTooManyItems
+	}
+
+	var missing []models.ItemResult
+	for _, id := range sel.ItemIDs {
+		if !found[id] {
+			missing = append(missing, models.ItemResult{ItemID: id, Error: storage.ErrItemNotFound.Error()})
+		}
+	}
+	return itemIDs, missing, nil
+}
+
+// titleTaken fails the item when the collection already has an item with
+// its title. A moved item does not clash with itself.
+func titleTaken(ctx context.Context, tx *sql.Tx, itemID, collectionID int64, moving bool) error {
+	var taken bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.items t
+		JOIN keeper.items i ON i.id = $1
+		WHERE t.collection_id = $2 AND t.title = i.title AND t.deleted_at IS NULL AND (t.id <> i.id OR NOT $3))`,
+		itemID, collectionID, moving).Scan(&taken)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return itemFailure{storage.ErrItemExists}
+	}
+	return nil
+}
+
+// itemLocked fails the item when it is in a reserved or sold lot.
+func itemLocked(ctx context.Context, tx *sql.Tx, itemID int64) error {
+	if err := itemsLocked(ctx, tx, "li.item_id = $1", itemID); err != nil {
+		if errors.Is(err, storage.ErrItemLocked) {
+			return itemFailure{err}
+		}
+		return err
+	}
+	return nil
+}
+
+// MoveItems moves the selected items of the user into another collection
+// of theirs. Items held by a lot stay where they are, and so do items whose
+// title the target collection already has.
+func (s *Storage) MoveItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	const op = "postgresql.MoveItems"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := ownedCollectionExists(ctx, tx, userID, collectionID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	itemIDs, missing, err := selectItems(ctx, tx, userID, sel, models.RoleOwner)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	results, err := bulkItems(ctx, tx, itemIDs, func(itemID int64) (int64, error) {
+		var held bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.lot_items WHERE item_id = $1 AND held)", itemID).Scan(&held)
+		if err != nil {
+			return 0, err
+		}
+		if held {
+			return 0, itemFailure{storage.ErrItemListed}
+		}
+		if err := titleTaken(ctx, tx, itemID, collectionID, true); err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE keeper.items SET collection_id = $1 WHERE id = $2", collectionID, itemID)
+		if err != nil {
+			return 0, err
+		}
+		return 0, recordItemVersions(ctx, tx, models.Version{UserID: userID, Action: models.VersionUpdate}, "i.id = $1", itemID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return append(results, missing...), nil
+}
+
+// CopyItems copies the selected items of the user, with their tags, into a
+// collection of theirs. Images are left to the caller, which has to copy
+// the files too.
+func (s *Storage) CopyItems(ctx context.Context, userID int64, sel models.ItemSelection, collectionID int64) ([]models.ItemResult, error) {
+	const op = "postgresql.CopyItems"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := ownedCollectionExists(ctx, tx, userID, collectionID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	itemIDs, missing, err := selectItems(ctx, tx, userID, sel, models.RoleOwner)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	results, err := bulkItems(ctx, tx, itemIDs, func(itemID int64) (int64, error) {
+		if err := titleTaken(ctx, tx, itemID, collectionID, false); err != nil {
+			return 0, err
+		}
+
+		var copyID int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO keeper.items (collection_id, title, description, category_id, country, item_images_url, year, attributes)
+			SELECT $1, title, description, category_id, country, item_images_url, year, attributes
+			FROM keeper.items WHERE id = $2 RETURNING id`, collectionID, itemID).Scan(&copyID)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO keeper.item_tags (item_id, tag_id)
+			SELECT $1, tag_id FROM keeper.item_tags WHERE item_id = $2`, copyID, itemID)
+		if err != nil {
+			return 0, err
+		}
+		return copyID, recordItemVersions(ctx, tx, models.Version{UserID: userID, Action: models.VersionCreate}, "i.id = $1", copyID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return append(results, missing...), nil
+}
+
+// UpdateItems applies change to the selected items the user may edit.
+// Items in reserved or sold lots are not changed. A category change runs
+// the attributes of each item through check, which returns them as they
+// fit the new category or fails the item.
+func (s *Storage) UpdateItems(
+	ctx context.Context,
+	userID int64,
+	sel models.ItemSelection,
+	change models.ItemChange,
+	check func(models.Item) (models.Attributes, error),
+) ([]models.ItemResult, error) {
+	const op = "postgresql.UpdateItems"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	itemIDs, missing, err := selectItems(ctx, tx, userID, sel, models.RoleEditor)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var apply func(itemID int64) error
+	switch change.Action {
+	case models.BulkDelete:
+		apply = func(itemID int64) error {
+			if err := itemLocked(ctx, tx, itemID); err != nil {
+				return err
+			}
+			if err := itemsInLots(ctx, tx, "li.item_id = $1", itemID); err != nil {
+				if errors.Is(err, storage.ErrItemInLot) {
+					return itemFailure{err}
+				}
+				return err
+			}
+			return trashItem(ctx, tx, userID, itemID)
+		}
+	case models.BulkTag:
+		if err := upsertTags(ctx, tx, change.AddTags); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		apply = func(itemID int64) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO keeper.item_tags (item_id, tag_id)
+				SELECT $1, id FROM keeper.tags WHERE name = ANY($2)
+				ON CONFLICT DO NOTHING`, itemID, pq.Array(change.AddTags))
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, `DELETE FROM keeper.item_tags it USING keeper.tags t
+				WHERE it.tag_id = t.id AND it.item_id = $1 AND t.name = ANY($2)`, itemID, pq.Array(change.RemoveTags))
+			return err
+		}
+	case models.BulkCategory:
+		apply = func(itemID int64) error {
+			if err := itemLocked(ctx, tx, itemID); err != nil {
+				return err
+			}
+			item, err := scanItem(tx.QueryRowContext(ctx, "SELECT "+itemColumns+" FROM keeper.items i WHERE i.id = $1", itemID))
+			if err != nil {
+				return err
+			}
+			attributes, err := check(item)
+			if err != nil {
+				return itemFailure{err}
+			}
+			attributesJSON, err := attributesJSON(attributes)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, "UPDATE keeper.items SET category_id = $1, attributes = $2 WHERE id = $3",
+				nullableID(change.CategoryID), attributesJSON, itemID)
+			return err
+		}
+	case models.BulkCountry:
+		apply = func(itemID int64) error {
+			if err := itemLocked(ctx, tx, itemID); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, "UPDATE keeper.items SET country = $1 WHERE id = $2", change.Country, itemID)
+			return err
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown bulk action %q", op, change.Action)
+	}
+
+	// Deleted items are recorded before they go, the others once changed.
+	results, err := bulkItems(ctx, tx, itemIDs, func(itemID int64) (int64, error) {
+		if change.Action == models.BulkDelete {
+			if err := recordItemVersions(ctx, tx, models.Version{UserID: userID, Action: models.VersionDelete}, "i.id = $1", itemID); err != nil {
+				return 0, err
+			}
+			return 0, apply(itemID)
+		}
+		if err := apply(itemID); err != nil {
+			return 0, err
+		}
+		return 0, recordItemVersions(ctx, tx, models.Version{UserID: userID, Action: models.VersionUpdate}, "i.id = $1", itemID)
+	})
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return append(results, missing...), nil
+}
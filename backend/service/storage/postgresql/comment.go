@@ -0,0 +1,172 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// commentLockSpace is the first key of the advisory locks that serialize
+// the comments of one author, so concurrent posts cannot slip past the rate
+// limits together.
+const commentLockSpace = 43
+
+// commentTargetExists reports a not found error unless the comment target
+// is public.
+func commentTargetExists(ctx context.Context, q queryer, c models.Comment) error {
+	if c.ItemID != 0 {
+		return publicItemExists(ctx, q, c.ItemID)
+	}
+	return publicCollectionExists(ctx, q, c.CollectionID)
+}
+
+// rateLimited reports storage.ErrRateLimited when the author posted as many
+// comments as one of the limits allows.
+func rateLimited(ctx context.Context, q queryer, authorID int64, limits []models.RateLimit) error {
+	for _, limit := range limits {
+		var posted int
+		err := q.QueryRowContext(ctx, `SELECT count(*) FROM keeper.comments
+			WHERE author_id = $1 AND created_at > now() - make_interval(secs => $2)`,
+			authorID, limit.Window.Seconds()).Scan(&posted)
+		if err != nil {
+			return err
+		}
+		if posted >= limit.Count {
+			return storage.ErrRateLimited
+		}
+	}
+	return nil
+}
+
+// CreateComment posts a comment on a public item or collection. A reply
+// must be on the same target as its parent, and deleted comments take no
+// replies.
+func (s *Storage) CreateComment(ctx context.Context, c models.Comment, limits []models.RateLimit) (int64, error) {
+	const op = "postgresql.CreateComment"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, $2)", commentLockSpace, c.AuthorID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := rateLimited(ctx, tx, c.AuthorID, limits); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := commentTargetExists(ctx, tx, c); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if c.ParentID != 0 {
+		var exists bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.comments
+			WHERE id = $1 AND item_id IS NOT DISTINCT FROM $2 AND collection_id IS NOT DISTINCT FROM $3
+				AND deleted_at IS NULL)`, c.ParentID, nullableID(c.ItemID), nullableID(c.CollectionID)).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if !exists {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+		}
+	}
+
+	var commentID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.comments (item_id, collection_id, parent_id, author_id, body, body_html)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		nullableID(c.ItemID), nullableID(c.CollectionID), nullableID(c.ParentID), c.AuthorID, c.Body, c.HTML).Scan(&commentID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyComment(ctx, tx, commentID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return commentID, nil
+}
+
+// UpdateComment changes the text of a comment of the author.
+func (s *Storage) UpdateComment(ctx context.Context, authorID, commentID int64, body, html string) error {
+	const op = "postgresql.UpdateComment"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE keeper.comments SET body = $1, body_html = $2, edited_at = now()
+		WHERE id = $3 AND author_id = $4 AND deleted_at IS NULL`, body, html, commentID, authorID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return commentAffected(op, res)
+}
+
+// DeleteComment turns a comment into a placeholder. Its author and the
+// owner of the commented collection may delete it.
+func (s *Storage) DeleteComment(ctx context.Context, userID, commentID int64) error {
+	const op = "postgresql.DeleteComment"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE keeper.comments m SET body = '', body_html = '', deleted_at = now()
+		WHERE m.id = $1 AND m.deleted_at IS NULL AND (m.author_id = $2 OR EXISTS(
+			SELECT 1 FROM keeper.collections c
+			WHERE c.user_id = $2 AND c.id = COALESCE(m.collection_id,
+				(SELECT i.collection_id FROM keeper.items i WHERE i.id = m.item_id))))`, commentID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return commentAffected(op, res)
+}
+
+func commentAffected(op string, res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCommentNotFound)
+	}
+	return nil
+}
+
+// Comments returns the comments on an item, or on a collection when itemID
+// is 0, oldest first. Comments of blocked users read as deleted.
+func (s *Storage) Comments(ctx context.Context, itemID, collectionID int64) ([]models.Comment, error) {
+	const op = "postgresql.Comments"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT m.id, COALESCE(m.item_id, 0), COALESCE(m.collection_id, 0),
+			COALESCE(m.parent_id, 0), m.author_id, a.username, m.body, m.body_html,
+			m.deleted_at IS NOT NULL OR a.is_blocked, m.edited_at IS NOT NULL, m.created_at, m.updated_at
+		FROM keeper.comments m
+		JOIN keeper.users_info a ON a.user_id = m.author_id
+		WHERE m.item_id = $1 OR m.collection_id = $2
+		ORDER BY m.id`, itemID, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var c models.Comment
+		err := rows.Scan(&c.CommentID, &c.ItemID, &c.CollectionID, &c.ParentID, &c.AuthorID, &c.Author,
+			&c.Body, &c.HTML, &c.Deleted, &c.Edited, &c.CreatedAt, &c.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		comments = append(comments, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return comments, nil
+}
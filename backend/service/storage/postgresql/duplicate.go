@@ -0,0 +1,116 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// hashDistance is the SQL for the number of differing bits of two phash
+// values.
+func hashDistance(a, b string) string {
+	return fmt.Sprintf("length(replace(((%s # %s)::bit(64))::text, '0', ''))", a, b)
+}
+
+// ownedImageHashes selects the hashed images of the user's items that are
+// not in the trash.
+const ownedImageHashes = `SELECT im.id, im.item_id, i.collection_id, i.title,
+		COALESCE(im.thumb_url, im.image_url) AS thumb, im.phash
+	FROM keeper.images im
+	JOIN keeper.items i ON i.id = im.item_id
+	JOIN keeper.collections c ON c.id = i.collection_id
+	WHERE c.user_id = $1 AND im.phash IS NOT NULL AND i.deleted_at IS NULL AND c.deleted_at IS NULL`
+
+// Duplicates returns pairs of the user's items whose images are at most
+// maxDistance bits apart, closest first. Each pair of items is reported once,
+// by its most similar images.
+func (s *Storage) Duplicates(ctx context.Context, userID int64, maxDistance int) ([]models.Duplicate, error) {
+	const op = "postgresql.Duplicates"
+
+	rows, err := s.db.QueryContext(ctx, `WITH owned AS (`+ownedImageHashes+`),
+		pairs AS (
+			SELECT DISTINCT ON (a.item_id, b.item_id)
+				a.item_id, a.collection_id, a.title, a.id, a.thumb,
+				b.item_id AS match_item_id, b.collection_id AS match_collection_id, b.title AS match_title,
+				b.id AS match_id, b.thumb AS match_thumb,
+				`+hashDistance("a.phash", "b.phash")+` AS distance
+			FROM owned a
+			JOIN owned b ON a.item_id < b.item_id
+			WHERE `+hashDistance("a.phash", "b.phash")+` <= $2
+			ORDER BY a.item_id, b.item_id, distance
+		)
+		SELECT * FROM pairs ORDER BY distance, item_id, match_item_id`, userID, maxDistance)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	duplicates, err := scanDuplicates(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return duplicates, nil
+}
+
+// SimilarImages returns the images of the user's other items that are at most
+// maxDistance bits away from hash, one per item, closest first. Only the
+// Match side of the result is filled.
+func (s *Storage) SimilarImages(ctx context.Context, userID, itemID int64, hash uint64, maxDistance int) ([]models.Duplicate, error) {
+	const op = "postgresql.SimilarImages"
+
+	rows, err := s.db.QueryContext(ctx, `WITH owned AS (`+ownedImageHashes+`),
+		matches AS (
+			SELECT DISTINCT ON (item_id) item_id, collection_id, title, id, thumb,
+				`+hashDistance("phash", "$3")+` AS distance
+			FROM owned
+			WHERE item_id <> $2 AND `+hashDistance("phash", "$3")+` <= $4
+			ORDER BY item_id, distance
+		)
+		SELECT * FROM matches ORDER BY distance, item_id`, userID, itemID, int64(hash), maxDistance)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var duplicates []models.Duplicate
+	for rows.Next() {
+		var d models.Duplicate
+		err := rows.Scan(&d.Match.ItemID, &d.Match.CollectionID, &d.Match.Title, &d.Match.ImageID, &d.Match.ThumbKey, &d.Distance)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		duplicates = append(duplicates, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return duplicates, nil
+}
+
+func scanDuplicates(rows *sql.Rows) ([]models.Duplicate, error) {
+	defer rows.Close()
+
+	var duplicates []models.Duplicate
+	for rows.Next() {
+		var d models.Duplicate
+		err := rows.Scan(
+			&d.Image.ItemID, &d.Image.CollectionID, &d.Image.Title, &d.Image.ImageID, &d.Image.ThumbKey,
+			&d.Match.ItemID, &d.Match.CollectionID, &d.Match.Title, &d.Match.ImageID, &d.Match.ThumbKey,
+			&d.Distance,
+		)
+		if err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return duplicates, nil
+}
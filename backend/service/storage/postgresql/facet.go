@@ -0,0 +1,94 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// facetQueries select key, value, label and count for every facet from the
+// items of an itemScope.
+var facetQueries = []struct {
+	dest    func(*models.Facets) *[]models.FacetValue
+	columns string
+	tail    string
+}{
+	{
+		dest:    func(f *models.Facets) *[]models.FacetValue { return &f.Countries },
+		columns: "'', i.country, '', count(*)",
+		tail:    "AND COALESCE(i.country, '') <> '' GROUP BY i.country",
+	},
+	{
+		dest: func(f *models.Facets) *[]models.FacetValue { return &f.Categories },
+		columns: `'', i.category_id::text,
+			(SELECT name FROM keeper.categories WHERE id = i.category_id), count(*)`,
+		tail: "AND i.category_id IS NOT NULL GROUP BY i.category_id",
+	},
+	{
+		dest:    func(f *models.Facets) *[]models.FacetValue { return &f.Decades },
+		columns: "'', (i.year / 10 * 10)::text || 's', '', count(*)",
+		tail:    "AND i.year IS NOT NULL GROUP BY 2",
+	},
+	{
+		dest:    func(f *models.Facets) *[]models.FacetValue { return &f.Tags },
+		columns: "'', t.name, '', count(*)",
+		tail:    `AND t.id IS NOT NULL GROUP BY t.name`,
+	},
+	{
+		dest:    func(f *models.Facets) *[]models.FacetValue { return &f.Attributes },
+		columns: "a.key, a.value, '', count(DISTINCT i.id)",
+		tail:    "AND a.value <> '' GROUP BY a.key, a.value",
+	},
+}
+
+// facetJoins are the extra joins a facet query needs, by position.
+var facetJoins = map[int]string{
+	3: `JOIN keeper.item_tags it ON it.item_id = i.id
+		JOIN keeper.tags t ON t.id = it.tag_id`,
+	4: `CROSS JOIN LATERAL jsonb_each_text(i.attributes) a`,
+}
+
+// Facets counts the items matching filter by country, category, decade, tag
+// and attribute value. Each facet is a single grouped query over the same
+// scope as Search, limited to the limit most frequent values.
+func (s *Storage) Facets(ctx context.Context, userID int64, filter models.SearchFilter, limit int) (models.Facets, error) {
+	const op = "postgresql.Facets"
+
+	var facets models.Facets
+	for n, fq := range facetQueries {
+		sc := newItemScope(userID, filter)
+		if join, ok := facetJoins[n]; ok {
+			sc.joins = append(sc.joins, join)
+		}
+		// The conditions of the facet come right after the scope's WHERE.
+		query := sc.sql(fq.columns, fq.tail+" ORDER BY 4 DESC, 2 LIMIT "+sc.arg(limit))
+
+		values, err := s.facetValues(ctx, query, sc.args)
+		if err != nil {
+			return models.Facets{}, fmt.Errorf("%s: %w", op, err)
+		}
+		*fq.dest(&facets) = values
+	}
+
+	return facets, nil
+}
+
+func (s *Storage) facetValues(ctx context.Context, query string, args []any) ([]models.FacetValue, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []models.FacetValue{}
+	for rows.Next() {
+		var v models.FacetValue
+		if err := rows.Scan(&v.Key, &v.Value, &v.Label, &v.Count); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	return values, rows.Err()
+}
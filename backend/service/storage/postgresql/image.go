@@ -0,0 +1,319 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// imageColumns are the columns of keeper.images and keeper.collection_images
+// read by scanImage. Queries select the owning item_id or collection_id right
+// before them.
+const imageColumns = "id, image_url, COALESCE(thumb_url, ''), COALESCE(medium_url, ''), COALESCE(width, 0), COALESCE(height, 0), is_primary, upload_date"
+
+func scanImage(row rowScanner, ownerID *int64) (models.Image, error) {
+	var image models.Image
+	err := row.Scan(ownerID, &image.ImageID, &image.Key, &image.ThumbKey, &image.MediumKey,
+		&image.Width, &image.Height, &image.IsPrimary, &image.UploadedAt)
+	return image, err
+}
+
+// scanImages scans image rows, storing the owner column in the field returned
+// by owner.
+func scanImages(rows *sql.Rows, owner func(*models.Image) *int64) ([]models.Image, error) {
+	defer rows.Close()
+
+	var images []models.Image
+	for rows.Next() {
+		var ownerID int64
+		image, err := scanImage(rows, &ownerID)
+		if err != nil {
+			return nil, err
+		}
+		*owner(&image) = ownerID
+		images = append(images, image)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+// AddItemImage records an uploaded image of the user's item with its
+// variants. The first image of an item always becomes its primary one.
+func (s *Storage) AddItemImage(ctx context.Context, userID, itemID int64, image models.Image) (models.Image, error) {
+	const op = "postgresql.AddItemImage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var hasPrimary bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.images WHERE item_id = $1 AND is_primary)", itemID).Scan(&hasPrimary)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	image.IsPrimary = image.IsPrimary || !hasPrimary
+
+	if image.IsPrimary {
+		if _, err := tx.ExecContext(ctx, "UPDATE keeper.images SET is_primary = FALSE WHERE item_id = $1 AND is_primary", itemID); err != nil {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	image.ItemID = itemID
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.images (item_id, image_url, thumb_url, medium_url, width, height, is_primary, phash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, upload_date`,
+		itemID, image.Key, image.ThumbKey, image.MediumKey, image.Width, image.Height, image.IsPrimary, int64(image.Hash)).Scan(&image.ImageID, &image.UploadedAt)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return image, nil
+}
+
+func (s *Storage) ItemImages(ctx context.Context, userID, itemID int64) ([]models.Image, error) {
+	const op = "postgresql.ItemImages"
+
+	if err := itemAccessible(ctx, s.db, userID, itemID, models.RoleViewer); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT item_id, "+imageColumns+`
+		FROM keeper.images WHERE item_id = $1 ORDER BY is_primary DESC, id`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	images, err := scanImages(rows, func(i *models.Image) *int64 { return &i.ItemID })
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return images, nil
+}
+
+func (s *Storage) SetPrimaryItemImage(ctx context.Context, userID, itemID, imageID int64) error {
+	const op = "postgresql.SetPrimaryItemImage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE keeper.images SET is_primary = FALSE WHERE item_id = $1 AND is_primary", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE keeper.images SET is_primary = TRUE WHERE id = $1 AND item_id = $2", imageID, itemID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrImageNotFound)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// DeleteItemImage removes the image record and returns it so the caller can
+// delete the files of its variants. If the primary image is removed the
+// oldest remaining one is promoted.
+func (s *Storage) DeleteItemImage(ctx context.Context, userID, itemID, imageID int64) (models.Image, error) {
+	const op = "postgresql.DeleteItemImage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	image, err := scanImage(tx.QueryRowContext(ctx, "DELETE FROM keeper.images WHERE id = $1 AND item_id = $2 RETURNING item_id, "+imageColumns,
+		imageID, itemID), new(int64))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Image{}, fmt.Errorf("%s: %w", op, storage.ErrImageNotFound)
+		}
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	image.ItemID = itemID
+
+	if image.IsPrimary {
+		_, err = tx.ExecContext(ctx, `UPDATE keeper.images SET is_primary = TRUE
+			WHERE id = (SELECT id FROM keeper.images WHERE item_id = $1 ORDER BY id LIMIT 1)`, itemID)
+		if err != nil {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return image, nil
+}
+
+// AddCollectionImage records an uploaded image of the user's collection. The
+// medium variant of the primary image is also the collection's
+// cover_image_url.
+func (s *Storage) AddCollectionImage(ctx context.Context, userID, collectionID int64, image models.Image) (models.Image, error) {
+	const op = "postgresql.AddCollectionImage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := collectionAccessible(ctx, tx, userID, collectionID, models.RoleEditor); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var hasPrimary bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.collection_images WHERE collection_id = $1 AND is_primary)", collectionID).Scan(&hasPrimary)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	image.IsPrimary = image.IsPrimary || !hasPrimary
+
+	if image.IsPrimary {
+		if _, err := tx.ExecContext(ctx, "UPDATE keeper.collection_images SET is_primary = FALSE WHERE collection_id = $1 AND is_primary", collectionID); err != nil {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE keeper.collections SET cover_image_url = COALESCE(NULLIF($1, ''), $2) WHERE id = $3",
+			image.MediumKey, image.Key, collectionID); err != nil {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	image.CollectionID = collectionID
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.collection_images (collection_id, image_url, thumb_url, medium_url, width, height, is_primary)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, upload_date`,
+		collectionID, image.Key, image.ThumbKey, image.MediumKey, image.Width, image.Height, image.IsPrimary).Scan(&image.ImageID, &image.UploadedAt)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return image, nil
+}
+
+func (s *Storage) CollectionImages(ctx context.Context, userID, collectionID int64) ([]models.Image, error) {
+	const op = "postgresql.CollectionImages"
+
+	if err := collectionAccessible(ctx, s.db, userID, collectionID, models.RoleViewer); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT collection_id, "+imageColumns+`
+		FROM keeper.collection_images WHERE collection_id = $1 ORDER BY is_primary DESC, id`, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	images, err := scanImages(rows, func(i *models.Image) *int64 { return &i.CollectionID })
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return images, nil
+}
+
+// DeleteCollectionImage removes the image record and returns it. If it was
+// the cover, the oldest remaining image becomes the new cover.
+func (s *Storage) DeleteCollectionImage(ctx context.Context, userID, collectionID, imageID int64) (models.Image, error) {
+	const op = "postgresql.DeleteCollectionImage"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := collectionAccessible(ctx, tx, userID, collectionID, models.RoleEditor); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	image, err := scanImage(tx.QueryRowContext(ctx, "DELETE FROM keeper.collection_images WHERE id = $1 AND collection_id = $2 RETURNING collection_id, "+imageColumns,
+		imageID, collectionID), new(int64))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Image{}, fmt.Errorf("%s: %w", op, storage.ErrImageNotFound)
+		}
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+	image.CollectionID = collectionID
+
+	if image.IsPrimary {
+		var cover sql.NullString
+		err = tx.QueryRowContext(ctx, `UPDATE keeper.collection_images SET is_primary = TRUE
+			WHERE id = (SELECT id FROM keeper.collection_images WHERE collection_id = $1 ORDER BY id LIMIT 1)
+			RETURNING COALESCE(medium_url, image_url)`, collectionID).Scan(&cover)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE keeper.collections SET cover_image_url = $1 WHERE id = $2", cover, collectionID); err != nil {
+			return models.Image{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Image{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return image, nil
+}
+
+// SetUserAvatar stores the blob key of the user's avatar and returns the key
+// of the previous one, if any.
+func (s *Storage) SetUserAvatar(ctx context.Context, userID int64, key string) (string, error) {
+	const op = "postgresql.SetUserAvatar"
+
+	var old sql.NullString
+	err := s.db.QueryRowContext(ctx, `UPDATE keeper.users_info u SET profile_image_url = $1
+		FROM keeper.users_info prev
+		WHERE u.user_id = $2 AND prev.user_id = u.user_id
+		RETURNING prev.profile_image_url`, key, userID).Scan(&old)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return old.String, nil
+}
@@ -0,0 +1,489 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	lotColumns = `l.id, l.user_id, u.username, l.collection_id, l.title, COALESCE(l.description, ''),
+	COALESCE(l.price, 0), COALESCE(l.currency, ''), l.exchange_only, l.status, l.created_at, l.updated_at,
+	COALESCE((SELECT array_agg(li.item_id ORDER BY li.item_id) FROM keeper.lot_items li WHERE li.lot_id = l.id), '{}'), ` + auctionColumns
+
+	lotsFrom = ` FROM keeper.lots l JOIN keeper.users_info u ON u.user_id = l.user_id
+	LEFT JOIN keeper.auctions a ON a.lot_id = l.id`
+
+	// Lots listed in the public catalogue.
+	catalogueCondition = `l.status IN ('active', 'reserved') AND NOT u.is_blocked`
+)
+
+var lotSorts = map[string]sortColumn{
+	"":                   {"l.id", "int"},
+	models.SortTitle:     {"l.title", "text"},
+	models.SortPrice:     {"COALESCE(l.price, 0)", "bigint"},
+	models.SortCreatedAt: {"l.created_at", "timestamptz"},
+	models.SortUpdatedAt: {"l.updated_at", "timestamptz"},
+}
+
+func scanLot(row rowScanner, extra ...any) (models.Lot, error) {
+	var lot models.Lot
+	var auction auctionRow
+	dest := []any{&lot.LotID, &lot.UserID, &lot.Seller, &lot.CollectionID, &lot.Title, &lot.Description,
+		&lot.Price, &lot.Currency, &lot.ExchangeOnly, &lot.Status, &lot.CreatedAt, &lot.UpdatedAt, pq.Array(&lot.ItemIDs)}
+	dest = append(dest, auction.dest()...)
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return models.Lot{}, err
+	}
+	lot.Auction = auction.get(lot.Price)
+	return lot, nil
+}
+
+func scanLots(rows *sql.Rows) ([]models.Lot, error) {
+	defer rows.Close()
+
+	var lots []models.Lot
+	for rows.Next() {
+		lot, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lots, nil
+}
+
+// lotPrice maps the price of a lot to the nullable price and currency
+// columns. Exchange-only lots have neither.
+func lotPrice(lot models.Lot) (sql.NullInt64, sql.NullString) {
+	if lot.ExchangeOnly {
+		return sql.NullInt64{}, sql.NullString{}
+	}
+	return sql.NullInt64{Int64: lot.Price, Valid: true}, sql.NullString{String: lot.Currency, Valid: true}
+}
+
+// holdItems binds items of the collection to a lot. An item that is held by
+// another lot makes it fail with storage.ErrItemListed.
+func holdItems(ctx context.Context, tx *sql.Tx, lotID, collectionID int64, itemIDs []int64) error {
+	var found int
+	err := tx.QueryRowContext(ctx, "SELECT count(*) FROM keeper.items WHERE collection_id = $1 AND id = ANY($2) AND deleted_at IS NULL",
+		collectionID, pq.Array(itemIDs)).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != len(itemIDs) {
+		return storage.ErrItemNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO keeper.lot_items (lot_id, item_id) SELECT $1, unnest($2::int[])", lotID, pq.Array(itemIDs))
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
+			return storage.ErrItemListed
+		}
+		return err
+	}
+
+	return nil
+}
+
+// lockLot locks a lot of the user for the rest of the transaction and
+// returns its status and collection.
+func lockLot(ctx context.Context, tx *sql.Tx, userID, lotID int64) (string, int64, error) {
+	var status string
+	var collectionID int64
+	err := tx.QueryRowContext(ctx, "SELECT status, collection_id FROM keeper.lots WHERE id = $1 AND user_id = $2 FOR UPDATE",
+		lotID, userID).Scan(&status, &collectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, storage.ErrLotNotFound
+	}
+	return status, collectionID, err
+}
+
+// itemsLocked reports storage.ErrItemLocked when one of the lot items
+// matched by cond is in a reserved or sold lot. Such items may not be edited
+// or deleted.
+func itemsLocked(ctx context.Context, q queryer, cond string, args ...any) error {
+	var locked bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.lot_items li
+		JOIN keeper.lots l ON l.id = li.lot_id
+		WHERE li.held AND l.status IN ('reserved', 'sold') AND `+cond+`)`, args...).Scan(&locked)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return storage.ErrItemLocked
+	}
+	return nil
+}
+
+// itemsInLots reports storage.ErrItemInLot when one of the lot items
+// matched by cond is in a draft or active lot. Such items may not go to the
+// trash: what is in the trash cannot be sold, and the lot has to be
+// withdrawn first.
+func itemsInLots(ctx context.Context, q queryer, cond string, args ...any) error {
+	var listed bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.lot_items li
+		JOIN keeper.lots l ON l.id = li.lot_id
+		WHERE li.held AND l.status IN ('draft', 'active') AND `+cond+`)`, args...).Scan(&listed)
+	if err != nil {
+		return err
+	}
+	if listed {
+		return storage.ErrItemInLot
+	}
+	return nil
+}
+
+func (s *Storage) CreateLot(ctx context.Context, userID int64, lot models.Lot) (int64, error) {
+	const op = "postgresql.CreateLot"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := ownedCollectionExists(ctx, tx, userID, lot.CollectionID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	price, currency := lotPrice(lot)
+	var lotID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.lots (user_id, collection_id, title, description, price, currency, exchange_only, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		userID, lot.CollectionID, lot.Title, lot.Description, price, currency, lot.ExchangeOnly, lot.Status).Scan(&lotID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := holdItems(ctx, tx, lotID, lot.CollectionID, lot.ItemIDs); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if lot.Auction != nil {
+		if err := saveAuction(ctx, tx, lotID, *lot.Auction); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lotID, nil
+}
+
+// UpdateLot changes the terms and items of a lot. Only draft and active lots
+// can be changed, reserved ones have a buyer waiting. Auction terms can only
+// be changed, added or dropped while the lot is a draft.
+func (s *Storage) UpdateLot(ctx context.Context, userID int64, lot models.Lot) error {
+	const op = "postgresql.UpdateLot"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	status, collectionID, err := lockLot(ctx, tx, userID, lot.LotID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if status != models.LotDraft && status != models.LotActive {
+		return fmt.Errorf("%s: %w", op, storage.ErrLotLocked)
+	}
+
+	var isAuction bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.auctions WHERE lot_id = $1)", lot.LotID).Scan(&isAuction)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if (isAuction || lot.Auction != nil) && status != models.LotDraft {
+		return fmt.Errorf("%s: %w", op, storage.ErrLotLocked)
+	}
+
+	price, currency := lotPrice(lot)
+	_, err = tx.ExecContext(ctx, `UPDATE keeper.lots
+		SET title = $1, description = $2, price = $3, currency = $4, exchange_only = $5
+		WHERE id = $6`, lot.Title, lot.Description, price, currency, lot.ExchangeOnly, lot.LotID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.lot_items WHERE lot_id = $1", lot.LotID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := holdItems(ctx, tx, lot.LotID, collectionID, lot.ItemIDs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch {
+	case lot.Auction != nil:
+		err = saveAuction(ctx, tx, lot.LotID, *lot.Auction)
+	case isAuction:
+		_, err = tx.ExecContext(ctx, "DELETE FROM keeper.auctions WHERE lot_id = $1", lot.LotID)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// SetLotStatus moves a lot to status if its current status is one of from.
+// The items of the lot are locked as well, so they cannot be edited while
+// the lot becomes reserved or sold. A withdrawn lot releases its items.
+func (s *Storage) SetLotStatus(ctx context.Context, userID, lotID int64, status string, from []string) error {
+	const op = "postgresql.SetLotStatus"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := setLotStatus(ctx, tx, userID, lotID, status, from); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func setLotStatus(ctx context.Context, tx *sql.Tx, userID, lotID int64, status string, from []string) error {
+	current, _, err := lockLot(ctx, tx, userID, lotID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(from, current) {
+		return storage.ErrLotLocked
+	}
+
+	// Auctions are run by their bids: once there is one, only the closer
+	// moves the lot on, and an auction that has ended cannot be published.
+	var bids int64
+	var ended bool
+	err = tx.QueryRowContext(ctx, "SELECT bid_count, ends_at <= now() FROM keeper.auctions WHERE lot_id = $1", lotID).Scan(&bids, &ended)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case bids > 0, status == models.LotReserved, status == models.LotSold:
+		return storage.ErrLotLocked
+	case status == models.LotActive && ended:
+		return storage.ErrAuctionClosed
+	}
+
+	return moveLot(ctx, tx, lotID, current, status)
+}
+
+// moveLot moves a locked lot from status current to status.
+func moveLot(ctx context.Context, tx *sql.Tx, lotID int64, current, status string) error {
+	_, err := tx.ExecContext(ctx, `SELECT 1 FROM keeper.items
+		WHERE id IN (SELECT item_id FROM keeper.lot_items WHERE lot_id = $1) FOR UPDATE`, lotID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE keeper.lots SET status = $1 WHERE id = $2", status, lotID); err != nil {
+		return err
+	}
+
+	if status == models.LotWithdrawn {
+		if _, err := tx.ExecContext(ctx, "UPDATE keeper.lot_items SET held = FALSE WHERE lot_id = $1", lotID); err != nil {
+			return err
+		}
+	}
+
+	// A reservation that is released without a sale cancels the offer that
+	// won the lot, so another one can be accepted.
+	if current == models.LotReserved && status != models.LotSold {
+		_, err := tx.ExecContext(ctx, "UPDATE keeper.offers SET status = 'cancelled' WHERE lot_id = $1 AND status = 'accepted'", lotID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteLot deletes a draft or withdrawn lot. Lots that were offered to
+// buyers are kept for the record.
+func (s *Storage) DeleteLot(ctx context.Context, userID, lotID int64) error {
+	const op = "postgresql.DeleteLot"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	status, _, err := lockLot(ctx, tx, userID, lotID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if status != models.LotDraft && status != models.LotWithdrawn {
+		return fmt.Errorf("%s: %w", op, storage.ErrLotLocked)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.lots WHERE id = $1", lotID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Lot(ctx context.Context, userID, lotID int64) (models.Lot, error) {
+	const op = "postgresql.Lot"
+
+	lot, err := scanLot(s.db.QueryRowContext(ctx, "SELECT "+lotColumns+lotsFrom+" WHERE l.id = $1 AND l.user_id = $2", lotID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Lot{}, fmt.Errorf("%s: %w", op, storage.ErrLotNotFound)
+		}
+		return models.Lot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lot, nil
+}
+
+// Lots returns all lots of a collection, newest first.
+func (s *Storage) Lots(ctx context.Context, userID, collectionID int64) ([]models.Lot, error) {
+	const op = "postgresql.Lots"
+
+	if err := ownedCollectionExists(ctx, s.db, userID, collectionID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+lotColumns+lotsFrom+" WHERE l.collection_id = $1 ORDER BY l.id DESC", collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lots, err := scanLots(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lots, nil
+}
+
+// LotItems returns the items of a lot.
+func (s *Storage) LotItems(ctx context.Context, lotID int64) ([]models.Item, error) {
+	const op = "postgresql.LotItems"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+itemColumns+` FROM keeper.items i
+		JOIN keeper.lot_items li ON li.item_id = i.id
+		WHERE li.lot_id = $1 ORDER BY i.id`, lotID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, err := scanItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// PublicLots is the lot catalogue: active and reserved lots of users that are
+// not blocked. Item filters match lots with at least one such item.
+func (s *Storage) PublicLots(ctx context.Context, opts models.ListOptions) ([]models.Lot, *models.Cursor, error) {
+	const op = "postgresql.PublicLots"
+
+	sort := lotSorts[opts.Sort]
+	q := &listQuery{}
+	q.and(catalogueCondition)
+	if opts.Query != "" {
+		q.contains("l.title", opts.Query)
+	}
+	if opts.Currency != "" {
+		q.and("l.currency = " + q.arg(opts.Currency))
+	}
+	if opts.PriceMin != 0 {
+		q.and("l.price >= " + q.arg(opts.PriceMin))
+	}
+	if opts.PriceMax != 0 {
+		q.and("l.price <= " + q.arg(opts.PriceMax))
+	}
+	if opts.ExchangeOnly != nil {
+		q.and("l.exchange_only = " + q.arg(*opts.ExchangeOnly))
+	}
+
+	// Item filters go into a subquery over the lot's items.
+	n := len(q.where)
+	itemOpts := opts
+	itemOpts.Query = ""
+	q.itemFilters(itemOpts)
+	if conds := q.where[n:]; len(conds) > 0 {
+		q.where = append(q.where[:n:n], `EXISTS(SELECT 1 FROM keeper.lot_items li
+			JOIN keeper.items i ON i.id = li.item_id
+			WHERE li.lot_id = l.id AND `+(&listQuery{where: conds}).conditions()+")")
+	}
+	tail := q.page(sort, "l.id", opts)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+lotColumns+", ("+sort.expr+")::text"+lotsFrom+" WHERE "+q.conditions()+" "+tail, q.args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var lots []models.Lot
+	var values []string
+	for rows.Next() {
+		var value string
+		lot, err := scanLot(rows, &value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
+		}
+		lots = append(lots, lot)
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	lots, next := pageOf(lots, values, opts.Limit, func(l models.Lot) int64 { return l.LotID })
+	return lots, next, nil
+}
+
+// PublicLot returns a lot of the catalogue. Sold lots stay readable so links
+// to them keep working.
+func (s *Storage) PublicLot(ctx context.Context, lotID int64) (models.Lot, error) {
+	const op = "postgresql.PublicLot"
+
+	lot, err := scanLot(s.db.QueryRowContext(ctx, "SELECT "+lotColumns+lotsFrom+`
+		WHERE l.id = $1 AND l.status IN ('active', 'reserved', 'sold') AND NOT u.is_blocked`, lotID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Lot{}, fmt.Errorf("%s: %w", op, storage.ErrLotNotFound)
+		}
+		return models.Lot{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return lot, nil
+}
@@ -0,0 +1,331 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	// notificationChannel is where the insert trigger of keeper.notifications
+	// announces new rows.
+	notificationChannel = "keeper_notifications"
+
+	notificationColumns = "id, user_id, type, payload, read_at IS NOT NULL, created_at"
+)
+
+// notificationEnabled is the condition that the user in column user has not
+// turned notifications of typ off.
+func notificationEnabled(user, typ string) string {
+	return `NOT EXISTS(SELECT 1 FROM keeper.notification_preferences p
+		WHERE p.user_id = ` + user + ` AND p.type = '` + typ + `' AND NOT p.enabled)`
+}
+
+// notify records notifications of typ for the users selected by recipients,
+// a query of user_id and payload. Notifications are written in the
+// transaction of what they tell about, so they exist only if it commits.
+func notify(ctx context.Context, q queryer, typ, recipients string, args ...any) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO keeper.notifications (user_id, type, payload)
+		SELECT r.user_id, '`+typ+`', r.payload FROM (`+recipients+`) r
+		WHERE `+notificationEnabled("r.user_id", typ), args...)
+	return err
+}
+
+// notifyComment tells the owner of the commented collection about a new
+// comment, and the author of the parent about a reply. Nobody hears about
+// their own comments, and an owner replied to gets the reply only.
+func notifyComment(ctx context.Context, q queryer, commentID int64) error {
+	const from = ` FROM keeper.comments m
+		LEFT JOIN keeper.items i ON i.id = m.item_id
+		JOIN keeper.collections c ON c.id = COALESCE(m.collection_id, i.collection_id)
+		JOIN keeper.users_info o ON o.user_id = c.user_id
+		JOIN keeper.users_info a ON a.user_id = m.author_id
+		LEFT JOIN keeper.comments pc ON pc.id = m.parent_id
+		WHERE m.id = $1`
+	const payload = `jsonb_build_object('comment_id', m.id, 'item_id', m.item_id, 'collection_id', c.id,
+		'owner', o.username, 'author', a.username)`
+
+	err := notify(ctx, q, models.NotificationComment, `SELECT c.user_id, `+payload+` AS payload`+from+`
+		AND c.user_id <> m.author_id AND pc.author_id IS DISTINCT FROM c.user_id`, commentID)
+	if err != nil {
+		return err
+	}
+
+	return notify(ctx, q, models.NotificationReply, `SELECT pc.author_id AS user_id, `+payload+` || jsonb_build_object('parent_id', pc.id) AS payload`+from+`
+		AND pc.author_id <> m.author_id`, commentID)
+}
+
+// notifyOffers tells about an event on offers. The party an offer was made
+// to hears about it, its author about the answer, and the buyer about an
+// offer closed by a sale to someone else.
+func notifyOffers(ctx context.Context, q queryer, event string, offerIDs ...int64) error {
+	other := "CASE WHEN o.author_id = o.buyer_id THEN l.user_id ELSE o.buyer_id END"
+	to, by := other, "o.author_id"
+	switch event {
+	case models.OfferAccepted, models.OfferRejected:
+		to, by = by, to
+	case models.NotificationEventClosed:
+		to, by = "o.buyer_id", "l.user_id"
+	}
+
+	return notify(ctx, q, models.NotificationOffer, `SELECT `+to+` AS user_id, jsonb_build_object('event', $2::text,
+			'offer_id', o.id, 'lot_id', o.lot_id, 'amount', o.amount, 'currency', o.currency, 'by', b.username) AS payload
+		FROM keeper.offers o
+		JOIN keeper.lots l ON l.id = o.lot_id
+		JOIN keeper.users_info b ON b.user_id = `+by+`
+		WHERE o.id = ANY($1)`, pq.Array(offerIDs), event)
+}
+
+// notifyTrade tells the recipient of a trade about it and its proposer
+// about the answer.
+func notifyTrade(ctx context.Context, q queryer, event string, tradeID int64) error {
+	to, by := "t.recipient_id", "t.proposer_id"
+	if event == models.TradeAccepted || event == models.TradeDeclined {
+		to, by = by, to
+	}
+
+	return notify(ctx, q, models.NotificationTrade, `SELECT `+to+` AS user_id, jsonb_build_object('event', $2::text,
+			'trade_id', t.id, 'by', b.username) AS payload
+		FROM keeper.trades t
+		JOIN keeper.users_info b ON b.user_id = `+by+`
+		WHERE t.id = $1`, tradeID, event)
+}
+
+// notifyMatches runs insert, a statement recording want matches, and tells
+// the owners of the wants about the matches it added.
+func notifyMatches(ctx context.Context, q queryer, insert string, args ...any) error {
+	_, err := q.ExecContext(ctx, `WITH m AS (`+insert+` RETURNING id, want_id, item_id, lot_id)
+		INSERT INTO keeper.notifications (user_id, type, payload)
+		SELECT w.user_id, '`+models.NotificationWantMatch+`', jsonb_build_object('match_id', m.id, 'want_id', w.id,
+			'want_title', w.title, 'item_id', i.id, 'item_title', i.title, 'lot_id', m.lot_id)
+		FROM m
+		JOIN keeper.wants w ON w.id = m.want_id
+		JOIN keeper.items i ON i.id = m.item_id
+		WHERE `+notificationEnabled("w.user_id", models.NotificationWantMatch), args...)
+	return err
+}
+
+func scanNotification(row rowScanner) (models.Notification, error) {
+	var n models.Notification
+	var payload []byte
+	err := row.Scan(&n.NotificationID, &n.UserID, &n.Type, &payload, &n.Read, &n.CreatedAt)
+	n.Payload = payload
+	return n, err
+}
+
+// Notifications returns notifications of the user, newest first: up to
+// limit of those older than before, or the newest when before is 0.
+func (s *Storage) Notifications(ctx context.Context, userID, before int64, limit int, unreadOnly bool) ([]models.Notification, error) {
+	const op = "postgresql.Notifications"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+notificationColumns+` FROM keeper.notifications
+		WHERE user_id = $1 AND ($2 = 0 OR id < $2) AND (NOT $3 OR read_at IS NULL)
+		ORDER BY id DESC LIMIT $4`, userID, before, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+// NotificationsAfter returns up to limit notifications of the user newer
+// than after, oldest first. It lets a stream that reconnects catch up.
+func (s *Storage) NotificationsAfter(ctx context.Context, userID, after int64, limit int) ([]models.Notification, error) {
+	const op = "postgresql.NotificationsAfter"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+notificationColumns+` FROM keeper.notifications
+		WHERE user_id = $1 AND id > $2
+		ORDER BY id LIMIT $3`, userID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		notifications = append(notifications, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+func (s *Storage) UnreadNotifications(ctx context.Context, userID int64) (int64, error) {
+	const op = "postgresql.UnreadNotifications"
+
+	var count int64
+	err := s.db.QueryRowContext(ctx, "SELECT count(*) FROM keeper.notifications WHERE user_id = $1 AND read_at IS NULL",
+		userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read. Marking
+// it twice is not an error.
+func (s *Storage) MarkNotificationRead(ctx context.Context, userID, notificationID int64) error {
+	const op = "postgresql.MarkNotificationRead"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE keeper.notifications SET read_at = COALESCE(read_at, now())
+		WHERE id = $1 AND user_id = $2`, notificationID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrNotificationNotFound)
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead marks every notification of the user as read
+// and returns how many were unread.
+func (s *Storage) MarkAllNotificationsRead(ctx context.Context, userID int64) (int64, error) {
+	const op = "postgresql.MarkAllNotificationsRead"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE keeper.notifications SET read_at = now() WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// NotificationPreferences returns the preferences the user set. Types
+// without one are on.
+func (s *Storage) NotificationPreferences(ctx context.Context, userID int64) ([]models.NotificationPreference, error) {
+	const op = "postgresql.NotificationPreferences"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT type, enabled FROM keeper.notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var prefs []models.NotificationPreference
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.Scan(&p.Type, &p.Enabled); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		prefs = append(prefs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return prefs, nil
+}
+
+func (s *Storage) SetNotificationPreferences(ctx context.Context, userID int64, prefs []models.NotificationPreference) error {
+	const op = "postgresql.SetNotificationPreferences"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	for _, p := range prefs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO keeper.notification_preferences (user_id, type, enabled) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled`, userID, p.Type, p.Enabled)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ListenNotifications passes every notification written by any replica to
+// handle until ctx is done. The listener reconnects by itself; what was
+// written while it was away is not replayed. missed is called instead, once
+// listening starts and after every reconnect, so that streams catch up
+// through NotificationsAfter.
+func (s *Storage) ListenNotifications(ctx context.Context, handle func(models.Notification), missed func()) error {
+	const op = "postgresql.ListenNotifications"
+
+	listener := pq.NewListener(s.connStr, time.Second, time.Minute, nil)
+	defer listener.Close()
+
+	if err := listener.Listen(notificationChannel); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// A listener started again after a failure missed what was written in
+	// between.
+	missed()
+
+	// The connection is checked now and then, so a dead one is noticed
+	// even when nothing is written.
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			go listener.Ping()
+		case event := <-listener.Notify:
+			// nil follows a reconnect.
+			if event == nil {
+				missed()
+				continue
+			}
+			var n struct {
+				ID        int64           `json:"id"`
+				UserID    int64           `json:"user_id"`
+				Type      string          `json:"type"`
+				Payload   json.RawMessage `json:"payload"`
+				CreatedAt string          `json:"created_at"`
+			}
+			if err := json.Unmarshal([]byte(event.Extra), &n); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+			handle(models.Notification{
+				NotificationID: n.ID,
+				UserID:         n.UserID,
+				Type:           n.Type,
+				Payload:        n.Payload,
+				CreatedAt:      n.CreatedAt,
+			})
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	// Pending offers past their expiry read as expired; nothing has to sweep
+	// them.
+	offerColumns = `o.id, o.lot_id, l.user_id, o.buyer_id, u.username, o.author_id, COALESCE(o.parent_id, 0),
+	o.amount, o.currency, COALESCE(o.message, ''),
+	CASE WHEN o.status = 'pending' AND o.expires_at <= now() THEN 'expired' ELSE o.status END,
+	o.expires_at, o.created_at, o.updated_at`
+
+	offersFrom = ` FROM keeper.offers o
+	JOIN keeper.lots l ON l.id = o.lot_id
+	JOIN keeper.users_info u ON u.user_id = o.buyer_id`
+
+	// Condition of an offer that can still be answered.
+	offerOpen = `status = 'pending' AND expires_at > now()`
+)
+
+func scanOffer(row rowScanner) (models.Offer, error) {
+	var offer models.Offer
+	err := row.Scan(&offer.OfferID, &offer.LotID, &offer.SellerID, &offer.BuyerID, &offer.Buyer, &offer.AuthorID, &offer.ParentID,
+		&offer.Amount, &offer.Currency, &offer.Message, &offer.Status, &offer.ExpiresAt, &offer.CreatedAt, &offer.UpdatedAt)
+	return offer, err
+}
+
+// insertOffer adds an offer to an active lot. The lot is checked in the same
+// statement, so an offer cannot slip in after the lot stopped taking them.
+func insertOffer(ctx context.Context, q queryer, offer models.Offer) (int64, error) {
+	var offerID int64
+	err := q.QueryRowContext(ctx, `INSERT INTO keeper.offers (lot_id, buyer_id, author_id, parent_id, amount, currency, message, expires_at)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8 FROM keeper.lots WHERE id = $1 AND status = 'active'
+		RETURNING id`,
+		offer.LotID, offer.BuyerID, offer.AuthorID, nullableID(offer.ParentID), offer.Amount, offer.Currency, offer.Message, offer.ExpiresAt).Scan(&offerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrLotLocked
+	}
+	return offerID, err
+}
+
+// closeOffer moves a pending, unexpired offer to status. An offer that was
+// answered or expired in the meantime fails with storage.ErrOfferClosed.
+func closeOffer(ctx context.Context, q queryer, offerID int64, status string) error {
+	res, err := q.ExecContext(ctx, "UPDATE keeper.offers SET status = $1 WHERE id = $2 AND "+offerOpen, status, offerID)
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
+			return storage.ErrOfferClosed
+		}
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrOfferClosed
+	}
+	return nil
+}
+
+func (s *Storage) CreateOffer(ctx context.Context, offer models.Offer) (int64, error) {
+	const op = "postgresql.CreateOffer"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	offerID, err := insertOffer(ctx, tx, offer)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyOffers(ctx, tx, models.NotificationEventProposed, offerID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return offerID, nil
+}
+
+// CounterOffer closes the pending offer parentID as countered and answers it
+// with offer.
+func (s *Storage) CounterOffer(ctx context.Context, parentID int64, offer models.Offer) (int64, error) {
+	const op = "postgresql.CounterOffer"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := closeOffer(ctx, tx, parentID, models.OfferCountered); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	offer.ParentID = parentID
+	offerID, err := insertOffer(ctx, tx, offer)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyOffers(ctx, tx, models.NotificationEventCountered, offerID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return offerID, nil
+}
+
+func (s *Storage) RejectOffer(ctx context.Context, offerID int64) error {
+	const op = "postgresql.RejectOffer"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := closeOffer(ctx, tx, offerID, models.OfferRejected); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyOffers(ctx, tx, models.OfferRejected, offerID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AcceptOffer accepts an offer and reserves its lot for the buyer. The lot
+// row is locked first, so of two offers accepted at the same time one waits
+// for the other and then finds the lot reserved. The other pending offers on
+// the lot are rejected.
+func (s *Storage) AcceptOffer(ctx context.Context, offerID int64) error {
+	const op = "postgresql.AcceptOffer"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var lotID int64
+	var status string
+	var isAuction bool
+	err = tx.QueryRowContext(ctx, `SELECT l.id, l.status, EXISTS(SELECT 1 FROM keeper.auctions a WHERE a.lot_id = l.id)
+		FROM keeper.lots l
+		JOIN keeper.offers o ON o.lot_id = l.id
+		WHERE o.id = $1 FOR UPDATE OF l`, offerID).Scan(&lotID, &status, &isAuction)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrOfferNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	// An auction is won by bids, the lot may have become one after the
+	// offer was made.
+	if status != models.LotActive || isAuction {
+		return fmt.Errorf("%s: %w", op, storage.ErrLotLocked)
+	}
+
+	if err := closeOffer(ctx, tx, offerID, models.OfferAccepted); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "UPDATE keeper.offers SET status = 'rejected' WHERE lot_id = $1 AND status = 'pending' RETURNING id", lotID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var closed []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		closed = append(closed, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyOffers(ctx, tx, models.OfferAccepted, offerID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := notifyOffers(ctx, tx, models.NotificationEventClosed, closed...); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := moveLot(ctx, tx, lotID, status, models.LotReserved); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Offer(ctx context.Context, offerID int64) (models.Offer, error) {
+	const op = "postgresql.Offer"
+
+	offer, err := scanOffer(s.db.QueryRowContext(ctx, "SELECT "+offerColumns+offersFrom+" WHERE o.id = $1", offerID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Offer{}, fmt.Errorf("%s: %w", op, storage.ErrOfferNotFound)
+		}
+		return models.Offer{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return offer, nil
+}
+
+// Offers returns the offers the user made or received, newest first. A
+// non-zero lotID narrows them down to one lot.
+func (s *Storage) Offers(ctx context.Context, userID, lotID int64) ([]models.Offer, error) {
+	const op = "postgresql.Offers"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+offerColumns+offersFrom+`
+		WHERE (l.user_id = $1 OR o.buyer_id = $1) AND ($2 = 0 OR o.lot_id = $2)
+		ORDER BY o.id DESC`, userID, lotID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var offers []models.Offer
+	for rows.Next() {
+		offer, err := scanOffer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		offers = append(offers, offer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return offers, nil
+}
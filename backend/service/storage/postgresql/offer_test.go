@@ -0,0 +1,93 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAcceptOffer_Concurrent accepts two offers on one lot at once, or one
+// offer twice, and expects exactly one acceptance each time.
+func TestAcceptOffer_Concurrent(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	sellerID := d.user("seller")
+	buyerIDs := []int64{d.user("buyer1"), d.user("buyer2")}
+	collectionID := d.collection(sellerID, "Coins", true)
+
+	tests := []struct {
+		name string
+		// offers are the indexes into the offers made on the lot that are
+		// accepted at once.
+		offers []int
+	}{
+		{name: "two offers", offers: []int{0, 1}},
+		{name: "same offer", offers: []int{0, 0}},
+	}
+
+	const rounds = 20
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range rounds {
+				lotID := d.lot(sellerID, collectionID, "Rouble", 1000)
+				offerIDs := make([]int64, len(buyerIDs))
+				for i, buyerID := range buyerIDs {
+					var err error
+					offerIDs[i], err = s.CreateOffer(ctx, models.Offer{
+						LotID:     lotID,
+						BuyerID:   buyerID,
+						AuthorID:  buyerID,
+						Amount:    900,
+						Currency:  "RUB",
+						ExpiresAt: time.Now().Add(time.Hour),
+					})
+					require.NoError(t, err)
+				}
+
+				start := make(chan struct{})
+				errs := make([]error, len(tt.offers))
+				var wg sync.WaitGroup
+				for i, offer := range tt.offers {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						<-start
+						errs[i] = s.AcceptOffer(ctx, offerIDs[offer])
+					}()
+				}
+				close(start)
+				wg.Wait()
+
+				accepted := 0
+				for _, err := range errs {
+					if err == nil {
+						accepted++
+						continue
+					}
+					// The loser finds the lot reserved, or its offer closed
+					// with the rest.
+					assert.True(t, errors.Is(err, storage.ErrLotLocked) || errors.Is(err, storage.ErrOfferClosed),
+						"unexpected error: %v", err)
+				}
+				assert.Equal(t, 1, accepted)
+
+				var stored int
+				var status string
+				require.NoError(t, s.db.QueryRowContext(ctx, `SELECT
+					(SELECT count(*) FROM keeper.offers WHERE lot_id = $1 AND status = 'accepted'), status
+					FROM keeper.lots WHERE id = $1`, lotID).Scan(&stored, &status))
+				assert.Equal(t, 1, stored)
+				assert.Equal(t, models.LotReserved, status)
+			}
+		})
+	}
+}
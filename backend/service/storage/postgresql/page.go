@@ -0,0 +1,125 @@
+package postgresql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// sortColumn is an expression a list can be ordered by and the type its text
+// form in a cursor is cast back to.
+type sortColumn struct {
+	expr string
+	cast string
+}
+
+var (
+	itemSorts = map[string]sortColumn{
+		"":                   {"i.id", "int"},
+		models.SortTitle:     {"i.title", "text"},
+		models.SortYear:      {"COALESCE(i.year, -1)", "int"},
+		models.SortCreatedAt: {"i.created_at", "timestamptz"},
+		models.SortUpdatedAt: {"i.updated_at", "timestamptz"},
+	}
+	collectionSorts = map[string]sortColumn{
+		"":                   {"id", "int"},
+		models.SortTitle:     {"name", "text"},
+		models.SortCreatedAt: {"created_at", "timestamptz"},
+		models.SortUpdatedAt: {"updated_at", "timestamptz"},
+	}
+	userSorts = map[string]sortColumn{
+		"":                   {"user_id", "int"},
+		models.SortUsername:  {"username", "text"},
+		models.SortCreatedAt: {"created_at", "timestamptz"},
+	}
+)
+
+// listQuery collects the conditions and arguments of a paginated list.
+type listQuery struct {
+	args  []any
+	where []string
+}
+
+func (q *listQuery) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *listQuery) and(cond string) {
+	q.where = append(q.where, cond)
+}
+
+func (q *listQuery) conditions() string {
+	if len(q.where) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(q.where, " AND ")
+}
+
+// contains adds a case-insensitive substring match of column.
+func (q *listQuery) contains(column, value string) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+	q.and(column + " ILIKE " + q.arg("%"+escaped+"%"))
+}
+
+// page continues after opts.After and returns the ORDER BY and LIMIT clauses.
+// One row more than the limit is selected to tell whether there is a next
+// page.
+func (q *listQuery) page(sort sortColumn, id string, opts models.ListOptions) string {
+	order, cmp := "ASC", ">"
+	if opts.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if opts.After != nil {
+		q.and(fmt.Sprintf("(%s, %s) %s (%s::%s, %s)",
+			sort.expr, id, cmp, q.arg(opts.After.Value), sort.cast, q.arg(opts.After.ID)))
+	}
+
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT %s", sort.expr, order, id, order, q.arg(opts.Limit+1))
+}
+
+// itemFilters adds the field filters of opts to a query over keeper.items i.
+func (q *listQuery) itemFilters(opts models.ListOptions) {
+	if opts.Query != "" {
+		q.contains("i.title", opts.Query)
+	}
+	if opts.CategoryID != 0 {
+		q.and(`i.category_id IN (
+			WITH RECURSIVE subcategories AS (
+				SELECT id FROM keeper.categories WHERE id = ` + q.arg(opts.CategoryID) + `
+				UNION
+				SELECT ch.id FROM keeper.categories ch JOIN subcategories s ON ch.parent_id = s.id
+			) SELECT id FROM subcategories)`)
+	}
+	if opts.Country != "" {
+		q.and("lower(i.country) = lower(" + q.arg(opts.Country) + ")")
+	}
+	if opts.YearFrom != 0 {
+		q.and("i.year >= " + q.arg(opts.YearFrom))
+	}
+	if opts.YearTo != 0 {
+		q.and("i.year <= " + q.arg(opts.YearTo))
+	}
+	if len(opts.Tags) > 0 {
+		tags := q.arg(pq.Array(opts.Tags))
+		q.and(`i.id IN (SELECT it.item_id FROM keeper.item_tags it
+			JOIN keeper.tags t ON t.id = it.tag_id
+			WHERE t.name = ANY(` + tags + `)
+			GROUP BY it.item_id
+			HAVING count(DISTINCT t.id) = cardinality(` + tags + `::text[]))`)
+	}
+}
+
+// pageOf drops the row fetched past the limit and returns the cursor of the
+// last row that is kept, or nil when there is no next page. values holds the
+// sort column of every row as text.
+func pageOf[T any](rows []T, values []string, limit int, id func(T) int64) ([]T, *models.Cursor) {
+	if len(rows) <= limit {
+		return rows, nil
+	}
+	rows = rows[:limit]
+	return rows, &models.Cursor{Value: values[limit-1], ID: id(rows[limit-1])}
+}
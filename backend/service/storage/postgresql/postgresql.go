@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -23,6 +24,9 @@ type DBstruct struct {
 
 type Storage struct {
 	db *sql.DB
+	// connStr opens the connection the notification listener keeps to
+	// itself.
+	connStr string
 }
 
 func New(cfg DBstruct) (*Storage, error) {
@@ -40,14 +44,14 @@ func New(cfg DBstruct) (*Storage, error) {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	return &Storage{db: db, connStr: connStr}, nil
 }
 
-func (s *Storage) Exists(query string) error {
+func (s *Storage) Exists(query string, args ...any) error {
 	const op = "postgresql.Exists"
 
 	var exists bool
-	err := s.db.QueryRow(query).Scan(&exists)
+	err := s.db.QueryRow(query, args...).Scan(&exists)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -60,18 +64,20 @@ func (s *Storage) Exists(query string) error {
 
 }
 
-// TODO: Сделать через транзакции
+// Register records a new user. Collections shared with their email before
+// they registered become shared with them.
 func (s *Storage) Register(ctx context.Context, userid int64, email string, username string) error {
 	const op = "postgresql.Register"
 
-	stmt, err := s.db.Prepare("INSERT INTO keeper.users_info (email, username, user_id, phone, birth_date) VALUES ($1, $2, $3, $4, $5);")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
 	birth_date := time.Time{}
-	_, err = stmt.Exec(email, username, userid, "-", birth_date)
+	_, err = tx.ExecContext(ctx, "INSERT INTO keeper.users_info (email, username, user_id, phone, birth_date) VALUES ($1, $2, $3, $4, $5);",
+		email, username, userid, "-", birth_date)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
@@ -81,6 +87,14 @@ func (s *Storage) Register(ctx context.Context, userid int64, email string, user
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if err := claimShares(ctx, tx, userid, email); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
@@ -135,14 +149,14 @@ func (s *Storage) Login(ctx context.Context, email string) error {
 func (s *Storage) User(ctx context.Context, userID int64) (models.User, error) {
 	const op = "postgresql.User"
 
-	stmt, err := s.db.Prepare("SELECT user_id, username, email, phone, birth_date FROM keeper.users_info WHERE user_id = $1")
+	stmt, err := s.db.Prepare("SELECT user_id, username, email, COALESCE(phone, ''), birth_date, COALESCE(profile_image_url, ''), followers_private FROM keeper.users_info WHERE user_id = $1")
 	if err != nil {
 		return models.User{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
 	var user models.User
-	err = stmt.QueryRow(userID).Scan(&user.UserID, &user.Username, &user.Email, &user.Phone, &user.Birth_date)
+	err = stmt.QueryRow(userID).Scan(&user.UserID, &user.Username, &user.Email, &user.Phone, &user.Birth_date, &user.ImageUrl, &user.FollowersPrivate)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.User{}, fmt.Errorf("%s: %w", op, err)
@@ -155,30 +169,42 @@ func (s *Storage) User(ctx context.Context, userID int64) (models.User, error) {
 	return user, nil
 }
 
-func (s *Storage) Users() ([]models.User, error) {
+// Users returns a page of users ordered by opts.Sort. The list is public, so
+// it leaves out email.
+func (s *Storage) Users(ctx context.Context, opts models.ListOptions) ([]models.User, *models.Cursor, error) {
 	const op = "postgresql.Users"
 
-	rows, err := s.db.Query("SELECT user_id, username, email FROM keeper.users_info")
+	sort := userSorts[opts.Sort]
+	q := &listQuery{}
+	if opts.Query != "" {
+		q.contains("username", opts.Query)
+	}
+	tail := q.page(sort, "user_id", opts)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT user_id, username, ("+sort.expr+")::text FROM keeper.users_info WHERE "+q.conditions()+" "+tail, q.args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
 
 	var users []models.User
+	var values []string
 	for rows.Next() {
 		var user models.User
-		if err := rows.Scan(&user.UserID, &user.Username, &user.Email); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		var value string
+		if err := rows.Scan(&user.UserID, &user.Username, &value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
 		}
-
 		users = append(users, user)
+		values = append(values, value)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return users, nil
+	users, next := pageOf(users, values, opts.Limit, func(u models.User) int64 { return u.UserID })
+	return users, next, nil
 }
 
 func (s *Storage) SetCollection(
@@ -192,78 +218,125 @@ func (s *Storage) SetCollection(
 ) (int64, error) {
 	const op = "postgresql.SetCollection"
 	// Проверяем, существует ли коллекция с таким именем
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE name = '%s' AND user_id = %d AND description = '%s')", collectionName, userID, description)); err != nil {
-		if !errors.Is(err, storage.ErrNotExists) {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrCollectionExists)
-		}
+	if err := s.Exists("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE name = $1 AND user_id = $2 AND description = $3 AND deleted_at IS NULL)",
+		collectionName, userID, description); err == nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrCollectionExists)
+	} else if !errors.Is(err, storage.ErrNotExists) {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := s.db.Prepare("INSERT INTO keeper.collections (user_id, name, description, cover_image_url, category_id, is_public) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	// _, err = stmt.Exec(userID, collectionName, description, image_url, categoryID, isPublic)
 	var collectionID int64
-	err = stmt.QueryRow(userID, collectionName, description, image_url, categoryID, isPublic).Scan(&collectionID)
+	err = tx.QueryRowContext(ctx, "INSERT INTO keeper.collections (user_id, name, description, cover_image_url, category_id, is_public) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		userID, collectionName, description, image_url, nullableID(categoryID), isPublic).Scan(&collectionID)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrCollectionExists)
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+		}
 
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	by := models.Version{UserID: userID, Action: models.VersionCreate}
+	if err := recordCollectionVersions(ctx, tx, by, "id = $1", collectionID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return collectionID, nil
 }
 
+// collectionColumns lists the columns of keeper.collections in the order of
+// models.Collection. Columns are named explicitly so that new ones, like
+// search_vector, do not break the scans.
+const collectionColumns = `id, user_id, name, COALESCE(description, ''), COALESCE(cover_image_url, ''),
+	COALESCE(category_id, 0), is_public, created_at, updated_at`
+
+// scanCollection reads a row selected with collectionColumns. Columns
+// selected after them are scanned into extra.
+func scanCollection(row rowScanner, extra ...any) (models.Collection, error) {
+	var collection models.Collection
+	dest := []any{&collection.CollectionID, &collection.UserID, &collection.CollectionName, &collection.Description, &collection.CollectionImageUrl, &collection.CategoryID, &collection.IsPublic, &collection.CreatedAt, &collection.UpdatedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return collection, err
+}
+
+// Collection returns a collection the user owns or that is shared with
+// them, with the role they have on it.
 func (s *Storage) Collection(ctx context.Context, userID, collectionID int64) (models.Collection, error) {
 	const op = "postgresql.Collection"
-	stmt, err := s.db.Prepare("SELECT * FROM keeper.collections WHERE id = $1 AND user_id = $2")
+	stmt, err := s.db.Prepare("SELECT " + collectionColumns + ", " + collectionRole("c", "$2") + " FROM keeper.collections c WHERE c.id = $1 AND " + canAccess("c", "$2", models.RoleViewer))
 	if err != nil {
 		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
-	var collection models.Collection
-	err = stmt.QueryRow(collectionID, userID).Scan(&collection.CollectionID, &collection.UserID, &collection.CollectionName, &collection.Description, &collection.CollectionImageUrl, &collection.CategoryID, &collection.IsPublic, &collection.CreatedAt, &collection.UpdatedAt)
+	var role string
+	collection, err := scanCollection(stmt.QueryRowContext(ctx, collectionID, userID), &role)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return models.Collection{}, fmt.Errorf("%s: %w", op, err)
 		}
 		return models.Collection{}, fmt.Errorf("%s: %w", op, err)
 	}
+	collection.Role = role
 	return collection, nil
 }
 
-func (s *Storage) Collections(ctx context.Context, userID int64) ([]models.Collection, error) {
+// Collections returns a page of the user's collections ordered by opts.Sort.
+func (s *Storage) Collections(ctx context.Context, userID int64, opts models.ListOptions) ([]models.Collection, *models.Cursor, error) {
 	const op = "postgresql.Collections"
 
-	stmt, err := s.db.Prepare("SELECT * FROM keeper.collections WHERE user_id = $1")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	sort := collectionSorts[opts.Sort]
+	q := &listQuery{}
+	q.and("user_id = " + q.arg(userID))
+	q.and("deleted_at IS NULL")
+	if opts.Query != "" {
+		q.contains("name", opts.Query)
 	}
-	defer stmt.Close()
+	if opts.CategoryID != 0 {
+		q.and("category_id = " + q.arg(opts.CategoryID))
+	}
+	if opts.IsPublic != nil {
+		q.and("is_public = " + q.arg(*opts.IsPublic))
+	}
+	tail := q.page(sort, "id", opts)
 
-	rows, err := stmt.Query(userID)
+	rows, err := s.db.QueryContext(ctx, "SELECT "+collectionColumns+", ("+sort.expr+")::text FROM keeper.collections WHERE "+q.conditions()+" "+tail, q.args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
+
 	var collections []models.Collection
+	var values []string
 	for rows.Next() {
 		var collection models.Collection
-		if err := rows.Scan(&collection.CollectionID, &collection.UserID, &collection.CollectionName, &collection.Description, &collection.CollectionImageUrl, &collection.CategoryID, &collection.IsPublic, &collection.CreatedAt, &collection.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		var value string
+		if err := rows.Scan(&collection.CollectionID, &collection.UserID, &collection.CollectionName, &collection.Description, &collection.CollectionImageUrl, &collection.CategoryID, &collection.IsPublic, &collection.CreatedAt, &collection.UpdatedAt, &value); err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
 		}
 		collections = append(collections, collection)
+		values = append(values, value)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
-	return collections, nil
+
+	collections, next := pageOf(collections, values, opts.Limit, func(c models.Collection) int64 { return c.CollectionID })
+	return collections, next, nil
 }
 
 func (s *Storage) UpdateCollection(
@@ -277,51 +350,93 @@ func (s *Storage) UpdateCollection(
 ) error {
 	const op = "postgresql.UpdateCollection"
 
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE id = %d AND user_id = %d)", collectionID, userID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
-			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
-		}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	defer tx.Rollback()
 
-	stmt, err := s.db.Prepare("UPDATE keeper.collections SET name = $1, description = $2, category_id = $3, is_public = $4 WHERE id = $5 AND user_id = $6;")
+	var role string
+	var wasPublic bool
+	err = tx.QueryRowContext(ctx, "SELECT "+collectionRole("c", "$2")+", c.is_public FROM keeper.collections c WHERE c.id = $1 AND "+canAccess("c", "$2", models.RoleEditor)+" FOR UPDATE OF c",
+		collectionID, userID).Scan(&role, &wasPublic)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
+		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
+	// Editors change the collection but not who sees it.
+	if role != models.RoleOwner && isPublic != wasPublic {
+		return fmt.Errorf("%s: %w", op, storage.ErrOwnerOnly)
+	}
 
-	_, err = stmt.Exec(collectionName, description, categoryID, isPublic, collectionID, userID)
+	_, err = tx.ExecContext(ctx, "UPDATE keeper.collections SET name = $1, description = $2, category_id = $3, is_public = $4 WHERE id = $5;",
+		collectionName, description, nullableID(categoryID), isPublic, collectionID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	by := models.Version{UserID: userID, Action: models.VersionUpdate}
+	if err := recordCollectionVersions(ctx, tx, by, "id = $1", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
+// DeleteCollection moves a collection of the user, with its items, to the
+// trash.
 func (s *Storage) DeleteCollection(ctx context.Context, userID, collectionID int64) error {
 	const op = "postgresql.DeleteCollection"
 
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE id = %d AND user_id = %d)", collectionID, userID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var locked int64
+	err = tx.QueryRowContext(ctx, "SELECT c.id FROM keeper.collections c WHERE c.id = $1 AND "+canAccess("c", "$2", models.RoleOwner)+" FOR UPDATE",
+		collectionID, userID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := s.db.Prepare("DELETE FROM keeper.collections WHERE id = $1 AND user_id = $2")
-	if err != nil {
+	// Reserved and sold lots keep their items, and with them the collection.
+	if err := itemsLocked(ctx, tx, "l.collection_id = $1", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := itemsInLots(ctx, tx, "l.collection_id = $1", collectionID); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
-	_, err = stmt.Exec(collectionID, userID)
+	// The items go with the collection, and so are recorded as deleted too.
+	by := models.Version{UserID: userID, Action: models.VersionDelete}
+	if err := recordItemVersions(ctx, tx, by, "i.collection_id = $1 AND i.deleted_at IS NULL", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := recordCollectionVersions(ctx, tx, by, "id = $1", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE keeper.collections SET deleted_at = now(), deleted_by = $2 WHERE id = $1", collectionID, userID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
-		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -339,37 +454,52 @@ func (s *Storage) SetItem(
 	country string,
 	images []string,
 	year string,
-	attributes []string,
+	attributes models.Attributes,
 ) (int64, error) {
 	const op = "postgresql.CreateItem"
 
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE id = %d AND user_id = %d)", collectionID, userID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
-		}
+	if err := collectionAccessible(ctx, s.db, userID, collectionID, models.RoleEditor); err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.items WHERE title = '%s' AND collection_id = %d)", title, collectionID)); err != nil {
-		if !errors.Is(err, storage.ErrNotExists) {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrItemExists)
-		}
-		// return 0, fmt.Errorf("%s: %s %w", op, "Item", storage.ErrExists)
+	if err := s.Exists("SELECT EXISTS(SELECT 1 FROM keeper.items WHERE title = $1 AND collection_id = $2 AND deleted_at IS NULL)",
+		title, collectionID); err == nil {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrItemExists)
+	} else if !errors.Is(err, storage.ErrNotExists) {
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	stmt, err := s.db.Prepare("INSERT INTO keeper.items (collection_id, title, description, category_id, country, item_images_url, year) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	attributesJSON, err := attributesJSON(attributes)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
 	pqImages := pq.StringArray(images)
 	var itemID int64
-	err = stmt.QueryRow(collectionID, title, description, category_id, country, pqImages, year).Scan(&itemID)
+	err = tx.QueryRowContext(ctx, "INSERT INTO keeper.items (collection_id, title, description, category_id, country, item_images_url, year, attributes) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		collectionID, title, description, nullableID(category_id), country, pqImages, year, attributesJSON).Scan(&itemID)
 	if err != nil {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
 			return 0, fmt.Errorf("%s: %w", op, storage.ErrItemExists)
 		}
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionCreate}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
@@ -387,127 +517,331 @@ func (s *Storage) UpdateItem(
 	country string,
 	images []string,
 	year string,
-	attributes []string,
+	attributes models.Attributes,
 ) error {
 	const op = "postgresql.UpdateItem"
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.collections WHERE id = %d AND user_id = %d)", collectionID, userID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
-			return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
-		}
+	if err := collectionAccessible(ctx, s.db, userID, collectionID, models.RoleEditor); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.items WHERE id = %d AND collection_id = %d)", itemID, collectionID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// The row lock keeps a lot from reserving the item while it is changed.
+	var locked int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM keeper.items WHERE id = $1 AND collection_id = $2 AND deleted_at IS NULL FOR UPDATE", itemID, collectionID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	stmt, err := s.db.Prepare("UPDATE keeper.items SET title = $1, description = $2, category_id = $3, country = $4, item_images_url = $5, year = $6, attributes = $7 WHERE id = $8 AND collection_id = $9")
+	if err := itemsLocked(ctx, tx, "li.item_id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	attributesJSON, err := attributesJSON(attributes)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(title, description, category_id, country, images, year, attributes, itemID, collectionID)
+	_, err = tx.ExecContext(ctx, "UPDATE keeper.items SET title = $1, description = $2, category_id = $3, country = $4, item_images_url = $5, year = $6, attributes = $7 WHERE id = $8 AND collection_id = $9",
+		title, description, nullableID(category_id), country, pq.Array(images), year, attributesJSON, itemID, collectionID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
+
+	by := models.Version{UserID: userID, Action: models.VersionUpdate}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 	return nil
 }
 
-func (s *Storage) DeleteItem(ctx context.Context, collectionID, itemID int64) error {
+// DeleteItem moves an item the user may edit to the trash.
+func (s *Storage) DeleteItem(ctx context.Context, userID, itemID int64) error {
 	const op = "postgresql.DeleteItem"
-	if err := s.Exists(fmt.Sprintf("SELECT EXISTS(SELECT 1 FROM keeper.items WHERE id = %d AND collection_id = %d)", itemID, collectionID)); err != nil {
-		if errors.Is(err, storage.ErrNotExists) {
-			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
-		}
-		return fmt.Errorf("%s: %w", op, err)
-	}
-	stmt, err := s.db.Prepare("DELETE FROM keeper.items WHERE id = $1 AND collection_id = $2")
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
-	_, err = stmt.Exec(itemID, collectionID)
+	defer tx.Rollback()
+
+	var locked int64
+	err = tx.QueryRowContext(ctx, `SELECT i.id FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = $1 AND i.deleted_at IS NULL AND `+canAccess("c", "$2", models.RoleEditor)+` FOR UPDATE OF i`, itemID, userID).Scan(&locked)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
 		}
 		return fmt.Errorf("%s: %w", op, err)
 	}
+	if err := itemsLocked(ctx, tx, "li.item_id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := itemsInLots(ctx, tx, "li.item_id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionDelete}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := trashItem(ctx, tx, userID, itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
 	return nil
 }
 
-func (s *Storage) Item(ctx context.Context, collectionID, itemID int64) (models.Item, error) {
+// DiscardItem deletes an item for good, bypassing the trash, together with
+// its versions. It undoes the creation of an item whose images could not be
+// uploaded, and returns the images it had, whose blobs are left to the
+// caller.
+func (s *Storage) DiscardItem(ctx context.Context, userID, itemID int64) ([]models.Image, error) {
+	const op = "postgresql.DiscardItem"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "DELETE FROM keeper.images WHERE item_id = $1 RETURNING item_id, "+imageColumns, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	images, err := scanImages(rows, func(image *models.Image) *int64 { return &image.ItemID })
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.versions WHERE entity = 'item' AND entity_id = $1", itemID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.items WHERE id = $1", itemID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return images, nil
+}
+
+// itemColumns is the column list scanned by scanItem. Nullable columns are
+// coalesced so that they fit into the plain string fields of models.Item.
+const itemColumns = `i.id, i.collection_id, i.title, COALESCE(i.description, ''), COALESCE(i.category_id, 0),
+	COALESCE(i.country, ''), COALESCE(i.item_images_url, '{}'), COALESCE(i.year::text, ''),
+	COALESCE(i.attributes, '{}'::jsonb), i.created_at, i.updated_at,
+	COALESCE((SELECT array_agg(t.name ORDER BY t.name) FROM keeper.item_tags it
+		JOIN keeper.tags t ON t.id = it.tag_id WHERE it.item_id = i.id), '{}')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// ownedItemExists reports storage.ErrItemNotFound unless the item belongs to
+// one of the user's collections.
+func ownedItemExists(ctx context.Context, q queryer, userID, itemID int64) error {
+	return itemAccessible(ctx, q, userID, itemID, models.RoleOwner)
+}
+
+// ownedCollectionExists reports storage.ErrCollectionNotFound unless the
+// collection belongs to the user.
+func ownedCollectionExists(ctx context.Context, q queryer, userID, collectionID int64) error {
+	return collectionAccessible(ctx, q, userID, collectionID, models.RoleOwner)
+}
+
+// attributesJSON encodes item attributes for the attributes column, which
+// always holds an object.
+func attributesJSON(attributes models.Attributes) ([]byte, error) {
+	if attributes == nil {
+		attributes = models.Attributes{}
+	}
+	return json.Marshal(attributes)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// scanItem reads a row selected with itemColumns. Columns selected after them
+// are scanned into extra.
+func scanItem(row rowScanner, extra ...any) (models.Item, error) {
+	var item models.Item
+	var attributes []byte
+	dest := []any{&item.ItemID, &item.CollectionID, &item.Title, &item.Description, &item.CategoryID, &item.Country, pq.Array(&item.Images), &item.Year, &attributes, &item.CreatedAt, &item.UpdatedAt, pq.Array(&item.Tags)}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return models.Item{}, err
+	}
+	if err := json.Unmarshal(attributes, &item.Attributes); err != nil {
+		return models.Item{}, err
+	}
+	return item, nil
+}
+
+func (s *Storage) Item(ctx context.Context, userID, itemID int64) (models.Item, error) {
 	const op = "postgresql.Item"
-	stmt, err := s.db.Prepare("SELECT * FROM keeper.items WHERE collection_id = $1 AND id = $2")
+	stmt, err := s.db.Prepare("SELECT " + itemColumns + " FROM keeper.items i JOIN keeper.collections c ON c.id = i.collection_id WHERE i.id = $1 AND i.deleted_at IS NULL AND " + canAccess("c", "$2", models.RoleViewer))
 	if err != nil {
 		return models.Item{}, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
-	var item models.Item
-	err = stmt.QueryRow(collectionID, itemID).Scan(&item.ItemID, &item.CollectionID, &item.Title, &item.Description, &item.CategoryID, &item.Country, &item.Images, &item.Year, &item.Attributes, &item.CreatedAt, &item.UpdatedAt)
+
+	item, err := scanItem(stmt.QueryRowContext(ctx, itemID, userID))
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return models.Item{}, fmt.Errorf("%s: %w", op, err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Item{}, fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
 		}
 		return models.Item{}, fmt.Errorf("%s: %w", op, err)
 	}
 	return item, nil
 }
 
-func (s *Storage) Items(ctx context.Context, collectionID int64) ([]models.Item, error) {
+// Items returns a page of the items of a collection ordered by opts.Sort.
+func (s *Storage) Items(ctx context.Context, userID, collectionID int64, opts models.ListOptions) ([]models.Item, *models.Cursor, error) {
 	const op = "postgresql.Items"
 
-	stmt, err := s.db.Prepare("SELECT * FROM keeper.items WHERE collection_id = $1")
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+	if err := collectionAccessible(ctx, s.db, userID, collectionID, models.RoleViewer); err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
-	defer stmt.Close()
 
-	rows, err := stmt.Query(collectionID)
+	sort := itemSorts[opts.Sort]
+	q := &listQuery{}
+	q.and("i.collection_id = " + q.arg(collectionID))
+	q.and("i.deleted_at IS NULL")
+	q.itemFilters(opts)
+	tail := q.page(sort, "i.id", opts)
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+itemColumns+", ("+sort.expr+")::text FROM keeper.items i WHERE "+q.conditions()+" "+tail, q.args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer rows.Close()
+
 	var items []models.Item
+	var values []string
 	for rows.Next() {
-		var item models.Item
-		if err := rows.Scan(&item.ItemID, &item.CollectionID, &item.Title, &item.Description, &item.CategoryID, &item.Country, &item.Images, &item.Year, &item.Attributes, &item.CreatedAt, &item.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+		var value string
+		item, err := scanItem(rows, &value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", op, err)
 		}
 		items = append(items, item)
+		values = append(values, value)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return nil, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, next := pageOf(items, values, opts.Limit, func(i models.Item) int64 { return i.ItemID })
+	return items, next, nil
+}
+
+// scanItems reads all rows selected with itemColumns and closes them.
+func scanItems(rows *sql.Rows) ([]models.Item, error) {
+	defer rows.Close()
+
+	var items []models.Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 	return items, nil
 }
 
-// TODO:
+// nullableID maps the zero id used by handlers for "not set" to SQL NULL.
+func nullableID(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
+const categoryColumns = "id, COALESCE(parent_id, 0), name, COALESCE(description, ''), attribute_schema"
+
+func scanCategory(row rowScanner) (models.Category, error) {
+	var category models.Category
+	var schema []byte
+	if err := row.Scan(&category.CategoryID, &category.ParentID, &category.CategoryName, &category.Description, &schema); err != nil {
+		return models.Category{}, err
+	}
+	if err := json.Unmarshal(schema, &category.AttributeSchema); err != nil {
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+func schemaJSON(schema []models.AttributeField) ([]byte, error) {
+	if schema == nil {
+		schema = []models.AttributeField{}
+	}
+	return json.Marshal(schema)
+}
+
 func (s *Storage) CreateCategory(
 	ctx context.Context,
 	categoryName string,
 	description string,
+	parentID int64,
+	schema []models.AttributeField,
 ) (int64, error) {
 	const op = "postgresql.CreateCategory"
 
-	stmt, err := s.db.Prepare("INSERT INTO keeper.categories (name, description) VALUES ($1, $2, $3) RETURNING category_id")
+	schemaJSON, err := schemaJSON(schema)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := s.db.Prepare("INSERT INTO keeper.categories (name, description, parent_id, attribute_schema) VALUES ($1, $2, $3, $4) RETURNING id")
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
 	var categoryID int64
-	err = stmt.QueryRow(categoryName, description).Scan(&categoryID)
+	err = stmt.QueryRowContext(ctx, categoryName, description, nullableID(parentID), schemaJSON).Scan(&categoryID)
 	if err != nil {
 		var pgErr *pq.Error
-		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23505") {
-			return 0, fmt.Errorf("%s: %w", op, storage.ErrCategoryExists)
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case pq.ErrorCode("23505"):
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrCategoryExists)
+			case pq.ErrorCode("23503"):
+				return 0, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+			}
 		}
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
@@ -515,17 +849,138 @@ func (s *Storage) CreateCategory(
 	return categoryID, nil
 }
 
-// TODO:
-func (s *Storage) Category(ctx context.Context) ([]models.Category, error) {
-	const op = "postgresql.GetCategory"
+func (s *Storage) UpdateCategory(
+	ctx context.Context,
+	categoryID int64,
+	categoryName string,
+	description string,
+	parentID int64,
+	schema []models.AttributeField,
+) error {
+	const op = "postgresql.UpdateCategory"
+
+	schemaJSON, err := schemaJSON(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if parentID != 0 {
+		// The new parent must not be the category itself or one of its descendants.
+		var cycle bool
+		err := s.db.QueryRowContext(ctx, `
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM keeper.categories WHERE id = $1
+				UNION ALL
+				SELECT c.id FROM keeper.categories c JOIN subtree st ON c.parent_id = st.id
+			)
+			SELECT EXISTS(SELECT 1 FROM subtree WHERE id = $2)`, categoryID, parentID).Scan(&cycle)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if cycle {
+			return fmt.Errorf("%s: %w", op, storage.ErrCategoryCycle)
+		}
+	}
+
+	stmt, err := s.db.Prepare("UPDATE keeper.categories SET name = $1, description = $2, parent_id = $3, attribute_schema = $4 WHERE id = $5")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, categoryName, description, nullableID(parentID), schemaJSON, categoryID)
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case pq.ErrorCode("23505"):
+				return fmt.Errorf("%s: %w", op, storage.ErrCategoryExists)
+			case pq.ErrorCode("23503"):
+				return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+			}
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteCategory(ctx context.Context, categoryID int64) error {
+	const op = "postgresql.DeleteCategory"
+
+	if err := s.Exists(`SELECT EXISTS(
+		SELECT 1 FROM keeper.categories WHERE parent_id = $1
+		UNION ALL SELECT 1 FROM keeper.collections WHERE category_id = $1
+		UNION ALL SELECT 1 FROM keeper.items WHERE category_id = $1)`, categoryID); err == nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrCategoryInUse)
+	} else if !errors.Is(err, storage.ErrNotExists) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	stmt, err := s.db.Prepare("DELETE FROM keeper.categories WHERE id = $1")
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	res, err := stmt.ExecContext(ctx, categoryID)
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return fmt.Errorf("%s: %w", op, storage.ErrCategoryInUse)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) Category(ctx context.Context, categoryID int64) (models.Category, error) {
+	const op = "postgresql.Category"
+
+	stmt, err := s.db.Prepare("SELECT " + categoryColumns + " FROM keeper.categories WHERE id = $1")
+	if err != nil {
+		return models.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer stmt.Close()
+
+	category, err := scanCategory(stmt.QueryRowContext(ctx, categoryID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Category{}, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+		}
+		return models.Category{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return category, nil
+}
+
+func (s *Storage) Categories(ctx context.Context) ([]models.Category, error) {
+	const op = "postgresql.Categories"
 
-	stmt, err := s.db.Prepare("SELECT * FROM keeper.categories")
+	stmt, err := s.db.Prepare("SELECT " + categoryColumns + " FROM keeper.categories ORDER BY name")
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query()
+	rows, err := stmt.QueryContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -533,8 +988,8 @@ func (s *Storage) Category(ctx context.Context) ([]models.Category, error) {
 
 	var categories []models.Category
 	for rows.Next() {
-		var category models.Category
-		if err := rows.Scan(&category.CategoryID, &category.CategoryName, &category.Description); err != nil {
+		category, err := scanCategory(rows)
+		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
 		categories = append(categories, category)
@@ -546,3 +1001,66 @@ func (s *Storage) Category(ctx context.Context) ([]models.Category, error) {
 
 	return categories, nil
 }
+
+// CategorySchema returns the attribute fields of a category together with
+// the ones it inherits, ancestors first. A field redeclared by a descendant
+// replaces the inherited one in place.
+func (s *Storage) CategorySchema(ctx context.Context, categoryID int64) ([]models.AttributeField, error) {
+	const op = "postgresql.CategorySchema"
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id, attribute_schema, 0 AS depth FROM keeper.categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, c.parent_id, c.attribute_schema, a.depth + 1
+			FROM keeper.categories c JOIN ancestors a ON c.id = a.parent_id
+			WHERE a.depth < 32
+		)
+		SELECT attribute_schema FROM ancestors ORDER BY depth DESC`, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var fields []models.AttributeField
+	found := false
+	for rows.Next() {
+		found = true
+
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		var own []models.AttributeField
+		if err := json.Unmarshal(data, &own); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		fields = mergeFields(fields, own)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+	}
+
+	return fields, nil
+}
+
+func mergeFields(inherited, own []models.AttributeField) []models.AttributeField {
+	for _, field := range own {
+		replaced := false
+		for i := range inherited {
+			if inherited[i].Key == field.Key {
+				inherited[i] = field
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			inherited = append(inherited, field)
+		}
+	}
+	return inherited
+}
@@ -0,0 +1,191 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// Public reads only ever see public collections of users that are not
+// blocked, and nothing in the trash. Every query below starts from these
+// joins and conditions.
+const (
+	publicCollectionsFrom = ` FROM keeper.collections c
+	JOIN keeper.users_info u ON u.user_id = c.user_id
+	WHERE c.is_public AND c.deleted_at IS NULL AND NOT u.is_blocked`
+
+	publicCollectionColumns = `c.id, u.username, c.name, COALESCE(c.description, ''),
+	COALESCE(c.cover_image_url, ''), COALESCE(c.category_id, 0),
+	(SELECT count(*) FROM keeper.items i WHERE i.collection_id = c.id AND i.deleted_at IS NULL),
+	c.created_at,
+	GREATEST(c.updated_at, (SELECT max(i.updated_at) FROM keeper.items i WHERE i.collection_id = c.id AND i.deleted_at IS NULL)) AS last_update`
+)
+
+func scanPublicCollection(row rowScanner) (models.PublicCollection, error) {
+	var c models.PublicCollection
+	err := row.Scan(&c.CollectionID, &c.Owner, &c.CollectionName, &c.Description,
+		&c.CollectionImageUrl, &c.CategoryID, &c.ItemCount, &c.CreatedAt, &c.UpdatedAt)
+	return c, err
+}
+
+func scanPublicCollections(rows *sql.Rows) ([]models.PublicCollection, error) {
+	defer rows.Close()
+
+	var collections []models.PublicCollection
+	for rows.Next() {
+		c, err := scanPublicCollection(rows)
+		if err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+func (s *Storage) PublicUser(ctx context.Context, username string) (models.PublicUser, error) {
+	const op = "postgresql.PublicUser"
+
+	var user models.PublicUser
+	err := s.db.QueryRowContext(ctx, `SELECT u.username, COALESCE(u.profile_image_url, ''), u.created_at,
+			(SELECT count(*) FROM keeper.follows f JOIN keeper.users_info o ON o.user_id = f.follower_id
+				WHERE f.followee_id = u.user_id AND NOT o.is_blocked),
+			(SELECT count(*) FROM keeper.follows f JOIN keeper.users_info o ON o.user_id = f.followee_id
+				WHERE f.follower_id = u.user_id AND NOT o.is_blocked),
+			u.followers_private
+		FROM keeper.users_info u WHERE u.username = $1 AND NOT u.is_blocked`, username).Scan(
+		&user.Username, &user.ImageUrl, &user.MemberSince, &user.Followers, &user.Following, &user.FollowersPrivate)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PublicUser{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return models.PublicUser{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return user, nil
+}
+
+func (s *Storage) PublicCollections(ctx context.Context, username string) ([]models.PublicCollection, error) {
+	const op = "postgresql.PublicCollections"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+publicCollectionColumns+publicCollectionsFrom+`
+		AND u.username = $1 ORDER BY last_update DESC, c.id`, username)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	collections, err := scanPublicCollections(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collections, nil
+}
+
+func (s *Storage) PublicCollection(ctx context.Context, username string, collectionID int64) (models.PublicCollection, error) {
+	const op = "postgresql.PublicCollection"
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+publicCollectionColumns+publicCollectionsFrom+`
+		AND u.username = $1 AND c.id = $2`, username, collectionID)
+
+	collection, err := scanPublicCollection(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PublicCollection{}, fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
+		}
+		return models.PublicCollection{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collection, nil
+}
+
+// RecentPublicCollections is the discovery feed: public collections of all
+// users, most recently changed first. Adding or editing an item counts as a
+// change of its collection.
+func (s *Storage) RecentPublicCollections(ctx context.Context, limit int) ([]models.PublicCollection, error) {
+	const op = "postgresql.RecentPublicCollections"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+publicCollectionColumns+publicCollectionsFrom+`
+		ORDER BY last_update DESC, c.id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	collections, err := scanPublicCollections(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collections, nil
+}
+
+func (s *Storage) PublicItems(ctx context.Context, username string, collectionID int64) ([]models.Item, error) {
+	const op = "postgresql.PublicItems"
+
+	if _, err := s.PublicCollection(ctx, username, collectionID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+itemColumns+" FROM keeper.items i WHERE i.collection_id = $1 AND i.deleted_at IS NULL ORDER BY i.id", collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, err := scanItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+func (s *Storage) PublicItem(ctx context.Context, username string, itemID int64) (models.Item, error) {
+	const op = "postgresql.PublicItem"
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+itemColumns+` FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		WHERE c.is_public AND c.deleted_at IS NULL AND i.deleted_at IS NULL AND NOT u.is_blocked
+			AND u.username = $1 AND i.id = $2`, username, itemID)
+
+	item, err := scanItem(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Item{}, fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
+		}
+		return models.Item{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return item, nil
+}
+
+// PublicItemImages returns the images of an item in a public collection.
+func (s *Storage) PublicItemImages(ctx context.Context, username string, itemID int64) ([]models.Image, error) {
+	const op = "postgresql.PublicItemImages"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT item_id, "+imageColumns+` FROM keeper.images
+		WHERE item_id = (SELECT i.id FROM keeper.items i
+			JOIN keeper.collections c ON c.id = i.collection_id
+			JOIN keeper.users_info u ON u.user_id = c.user_id
+			WHERE c.is_public AND c.deleted_at IS NULL AND i.deleted_at IS NULL AND NOT u.is_blocked
+				AND u.username = $1 AND i.id = $2)
+		ORDER BY is_primary DESC, id`, username, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	images, err := scanImages(rows, func(i *models.Image) *int64 { return &i.ItemID })
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return images, nil
+}
@@ -0,0 +1,188 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+)
+
+// tsQuery matches words in both Russian and English, so "монеты" finds
+// "монета" and "coins" finds "coin".
+func tsQuery(param string) string {
+	return fmt.Sprintf("(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))", param)
+}
+
+// headline highlights the matched words of an HTML escaped column.
+func headline(column string) string {
+	escaped := fmt.Sprintf("replace(replace(replace(COALESCE(%s, ''), '&', '&amp;'), '<', '&lt;'), '>', '&gt;')", column)
+	return fmt.Sprintf("ts_headline('russian', %s, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10')", escaped)
+}
+
+// itemScope builds the query over the items a user may see that match a
+// filter. Search and facets select different columns from the same scope.
+// The tables are aliased i (items), c (collections) and u (owners), and q
+// holds the parsed text query when there is one.
+type itemScope struct {
+	args  []any
+	ctes  []string
+	joins []string
+	where []string
+}
+
+func (sc *itemScope) arg(v any) string {
+	sc.args = append(sc.args, v)
+	return fmt.Sprintf("$%d", len(sc.args))
+}
+
+func newItemScope(userID int64, filter models.SearchFilter) *itemScope {
+	sc := &itemScope{}
+	user := sc.arg(userID)
+	sc.where = append(sc.where, "("+canAccess("c", user, models.RoleViewer)+" OR (c.is_public AND NOT u.is_blocked))",
+		"i.deleted_at IS NULL AND c.deleted_at IS NULL")
+
+	if filter.Query != "" {
+		sc.ctes = append(sc.ctes, "q AS (SELECT "+tsQuery(sc.arg(filter.Query))+" AS query)")
+		sc.joins = append(sc.joins, "CROSS JOIN q")
+		sc.where = append(sc.where, "(i.search_vector @@ q.query OR c.search_vector @@ q.query)")
+	}
+	if filter.CollectionID != 0 {
+		sc.where = append(sc.where, "i.collection_id = "+sc.arg(filter.CollectionID))
+	}
+	if filter.CategoryID != 0 {
+		sc.ctes = append(sc.ctes, `subcategories AS (
+			SELECT id FROM keeper.categories WHERE id = `+sc.arg(filter.CategoryID)+`
+			UNION
+			SELECT ch.id FROM keeper.categories ch JOIN subcategories s ON ch.parent_id = s.id
+		)`)
+		sc.where = append(sc.where, "i.category_id IN (SELECT id FROM subcategories)")
+	}
+	if filter.Country != "" {
+		sc.where = append(sc.where, "lower(i.country) = lower("+sc.arg(filter.Country)+")")
+	}
+	if filter.YearFrom != 0 {
+		sc.where = append(sc.where, "i.year >= "+sc.arg(filter.YearFrom))
+	}
+	if filter.YearTo != 0 {
+		sc.where = append(sc.where, "i.year <= "+sc.arg(filter.YearTo))
+	}
+	if len(filter.Tags) > 0 {
+		tags := sc.arg(pq.Array(filter.Tags))
+		sc.where = append(sc.where, `i.id IN (SELECT it.item_id FROM keeper.item_tags it
+			JOIN keeper.tags t ON t.id = it.tag_id
+			WHERE t.name = ANY(`+tags+`)
+			GROUP BY it.item_id
+			HAVING count(DISTINCT t.id) = cardinality(`+tags+`::text[]))`)
+	}
+
+	return sc
+}
+
+// sql returns SELECT columns over the scope followed by tail.
+func (sc *itemScope) sql(columns, tail string) string {
+	var b strings.Builder
+	if len(sc.ctes) > 0 {
+		b.WriteString("WITH RECURSIVE " + strings.Join(sc.ctes, ",\n") + "\n")
+	}
+	b.WriteString("SELECT " + columns + `
+		FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		` + strings.Join(sc.joins, "\n") + `
+		WHERE ` + strings.Join(sc.where, "\n\t\tAND ") + "\n" + tail)
+	return b.String()
+}
+
+// Search runs a full-text search over the items and collection names the
+// user may see: the user's own and everybody's public ones. Items also match
+// through the name of their collection.
+func (s *Storage) Search(ctx context.Context, userID int64, filter models.SearchFilter) (models.SearchResult, error) {
+	const op = "postgresql.Search"
+
+	items, err := s.searchItems(ctx, userID, filter)
+	if err != nil {
+		return models.SearchResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	collections, err := s.searchCollections(ctx, userID, filter)
+	if err != nil {
+		return models.SearchResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return models.SearchResult{Items: items, Collections: collections}, nil
+}
+
+func (s *Storage) searchItems(ctx context.Context, userID int64, filter models.SearchFilter) ([]models.ItemHit, error) {
+	sc := newItemScope(userID, filter)
+	query := sc.sql(itemColumns+`, u.username, c.user_id = $1,
+			ts_rank(i.search_vector || c.search_vector, q.query) AS rank,
+			`+headline("i.title")+`, `+headline("i.description"),
+		"ORDER BY rank DESC, i.id LIMIT "+sc.arg(filter.Limit)+" OFFSET "+sc.arg(filter.Offset))
+
+	rows, err := s.db.QueryContext(ctx, query, sc.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []models.ItemHit
+	for rows.Next() {
+		var hit models.ItemHit
+		item, err := scanItem(rows, &hit.Owner, &hit.IsOwn, &hit.Rank, &hit.TitleSnippet, &hit.DescriptionSnippet)
+		if err != nil {
+			return nil, err
+		}
+		hit.Item = item
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
+
+func (s *Storage) searchCollections(ctx context.Context, userID int64, filter models.SearchFilter) ([]models.CollectionHit, error) {
+	// A search inside one collection has no other collections to find.
+	if filter.CollectionID != 0 {
+		return nil, nil
+	}
+
+	args := []any{userID, filter.Query, filter.Limit, filter.Offset}
+
+	category := ""
+	if filter.CategoryID != 0 {
+		args = append(args, filter.CategoryID)
+		category = `AND c.category_id IN (
+			WITH RECURSIVE subcategories AS (
+				SELECT id FROM keeper.categories WHERE id = $5
+				UNION
+				SELECT ch.id FROM keeper.categories ch JOIN subcategories s ON ch.parent_id = s.id
+			) SELECT id FROM subcategories)`
+	}
+
+	rows, err := s.db.QueryContext(ctx, `WITH q AS (SELECT `+tsQuery("$2")+` AS query)
+		SELECT c.id, u.username, c.user_id = $1, c.name, `+headline("c.name")+`,
+			ts_rank(c.search_vector, q.query) AS rank
+		FROM keeper.collections c
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		CROSS JOIN q
+		WHERE c.search_vector @@ q.query AND c.deleted_at IS NULL
+			AND (`+canAccess("c", "$1", models.RoleViewer)+` OR (c.is_public AND NOT u.is_blocked))
+			`+category+`
+		ORDER BY rank DESC, c.id LIMIT $3 OFFSET $4`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []models.CollectionHit
+	for rows.Next() {
+		var hit models.CollectionHit
+		if err := rows.Scan(&hit.CollectionID, &hit.Owner, &hit.IsOwn, &hit.CollectionName, &hit.NameSnippet, &hit.Rank); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+
+	return hits, rows.Err()
+}
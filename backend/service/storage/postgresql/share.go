@@ -0,0 +1,295 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// collectionRole selects the role of user on the collection aliased c, NULL
+// when the user has none.
+func collectionRole(c, user string) string {
+	return `CASE WHEN ` + c + `.user_id = ` + user + ` THEN '` + models.RoleOwner + `'
+		ELSE (SELECT sh.role FROM keeper.collection_shares sh WHERE sh.collection_id = ` + c + `.id AND sh.user_id = ` + user + `) END`
+}
+
+// canAccess is the condition that user has role, or a stronger one, on the
+// collection aliased c. Nobody has a role on a collection in the trash.
+func canAccess(c, user, role string) string {
+	owner := c + ".user_id = " + user
+	if role == models.RoleOwner {
+		return "(" + owner + " AND " + notTrashed(c) + ")"
+	}
+
+	roles := "'" + models.RoleEditor + "'"
+	if role == models.RoleViewer {
+		roles += ", '" + models.RoleViewer + "'"
+	}
+	return "(" + notTrashed(c) + " AND (" + owner + ` OR EXISTS(SELECT 1 FROM keeper.collection_shares sh
+		WHERE sh.collection_id = ` + c + `.id AND sh.user_id = ` + user + ` AND sh.role IN (` + roles + `))))`
+}
+
+// collectionAccessible reports storage.ErrCollectionNotFound unless the user
+// has role on the collection.
+func collectionAccessible(ctx context.Context, q queryer, userID, collectionID int64, role string) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.collections c
+		WHERE c.id = $1 AND `+canAccess("c", "$2", role)+`)`, collectionID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// itemAccessible reports storage.ErrItemNotFound unless the item is not in
+// the trash and the user has role on the collection of the item.
+func itemAccessible(ctx context.Context, q queryer, userID, itemID int64, role string) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = $1 AND `+notTrashed("i")+` AND `+canAccess("c", "$2", role)+`)`, itemID, userID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrItemNotFound
+	}
+	return nil
+}
+
+const shareColumns = `sh.id, sh.collection_id, COALESCE(u.username, ''), COALESCE(sh.email, ''), sh.role,
+	sh.user_id IS NULL, i.username, sh.created_at, sh.updated_at`
+
+const shareFrom = ` FROM keeper.collection_shares sh
+	LEFT JOIN keeper.users_info u ON u.user_id = sh.user_id
+	JOIN keeper.users_info i ON i.user_id = sh.invited_by`
+
+func scanShare(row rowScanner) (models.CollectionShare, error) {
+	var share models.CollectionShare
+	err := row.Scan(&share.ShareID, &share.CollectionID, &share.Username, &share.Email, &share.Role,
+		&share.Pending, &share.InvitedBy, &share.CreatedAt, &share.UpdatedAt)
+	return share, err
+}
+
+// ShareCollection gives invitee role on a collection of the owner, or
+// changes the role they have. An invitee with an "@" is an email: it names
+// the user registered with it or, while there is none, waits for them.
+func (s *Storage) ShareCollection(ctx context.Context, ownerID, collectionID int64, invitee, role string) (models.CollectionShare, error) {
+	const op = "postgresql.ShareCollection"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := ownedCollectionExists(ctx, tx, ownerID, collectionID); err != nil {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var userID sql.NullInt64
+	if strings.Contains(invitee, "@") {
+		err = tx.QueryRowContext(ctx, "SELECT user_id FROM keeper.users_info WHERE lower(email) = lower($1)", invitee).Scan(&userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+		}
+	} else {
+		err = tx.QueryRowContext(ctx, "SELECT user_id FROM keeper.users_info WHERE username = $1 AND NOT is_blocked", invitee).Scan(&userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.CollectionShare{}, fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+			}
+			return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+	if userID.Int64 == ownerID {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, storage.ErrShareSelf)
+	}
+
+	var shareID int64
+	var inserted bool
+	if userID.Valid {
+		err = tx.QueryRowContext(ctx, `INSERT INTO keeper.collection_shares (collection_id, user_id, role, invited_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (collection_id, user_id) WHERE user_id IS NOT NULL
+			DO UPDATE SET role = EXCLUDED.role, updated_at = now()
+			RETURNING id, xmax = 0`, collectionID, userID.Int64, role, ownerID).Scan(&shareID, &inserted)
+	} else {
+		err = tx.QueryRowContext(ctx, `INSERT INTO keeper.collection_shares (collection_id, email, role, invited_by)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (collection_id, lower(email)) WHERE email IS NOT NULL
+			DO UPDATE SET role = EXCLUDED.role, updated_at = now()
+			RETURNING id, xmax = 0`, collectionID, invitee, role, ownerID).Scan(&shareID, &inserted)
+	}
+	if err != nil {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// A pending invitation is told about when it is claimed.
+	if inserted && userID.Valid {
+		if err := notifyShare(ctx, tx, "sh.id = $1", shareID); err != nil {
+			return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	share, err := scanShare(tx.QueryRowContext(ctx, "SELECT "+shareColumns+shareFrom+" WHERE sh.id = $1", shareID))
+	if err != nil {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.CollectionShare{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return share, nil
+}
+
+// notifyShare tells the users of the shares selected by cond that a
+// collection was shared with them.
+func notifyShare(ctx context.Context, q queryer, cond string, args ...any) error {
+	return notify(ctx, q, models.NotificationShare, `SELECT sh.user_id, jsonb_build_object('collection_id', c.id,
+			'collection', c.name, 'role', sh.role, 'by', o.username) AS payload
+		FROM keeper.collection_shares sh
+		JOIN keeper.collections c ON c.id = sh.collection_id
+		JOIN keeper.users_info o ON o.user_id = sh.invited_by
+		WHERE sh.user_id IS NOT NULL AND `+cond, args...)
+}
+
+// claimShares turns the invitations waiting for email into shares of the
+// user who registered with it.
+func claimShares(ctx context.Context, q queryer, userID int64, email string) error {
+	rows, err := q.QueryContext(ctx, `UPDATE keeper.collection_shares SET user_id = $1, email = NULL, updated_at = now()
+		WHERE email IS NOT NULL AND lower(email) = lower($2)
+		RETURNING id`, userID, email)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var shareIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		shareIDs = append(shareIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(shareIDs) == 0 {
+		return nil
+	}
+
+	return notifyShare(ctx, q, "sh.id = ANY($1)", pq.Array(shareIDs))
+}
+
+// UnshareCollection revokes a share of a collection of the owner.
+func (s *Storage) UnshareCollection(ctx context.Context, ownerID, collectionID, shareID int64) error {
+	const op = "postgresql.UnshareCollection"
+
+	res, err := s.db.ExecContext(ctx, `DELETE FROM keeper.collection_shares sh USING keeper.collections c
+		WHERE sh.id = $1 AND sh.collection_id = $2 AND c.id = sh.collection_id AND c.user_id = $3`,
+		shareID, collectionID, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrShareNotFound)
+	}
+
+	return nil
+}
+
+// LeaveCollection gives up the share the user has of a collection.
+func (s *Storage) LeaveCollection(ctx context.Context, userID, collectionID int64) error {
+	const op = "postgresql.LeaveCollection"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM keeper.collection_shares WHERE collection_id = $1 AND user_id = $2",
+		collectionID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrShareNotFound)
+	}
+
+	return nil
+}
+
+// CollectionShares lists the shares and pending invitations of a collection
+// of the owner, oldest first.
+func (s *Storage) CollectionShares(ctx context.Context, ownerID, collectionID int64) ([]models.CollectionShare, error) {
+	const op = "postgresql.CollectionShares"
+
+	if err := ownedCollectionExists(ctx, s.db, ownerID, collectionID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+shareColumns+shareFrom+`
+		WHERE sh.collection_id = $1 ORDER BY sh.created_at, sh.id`, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var shares []models.CollectionShare
+	for rows.Next() {
+		share, err := scanShare(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return shares, nil
+}
+
+// SharedCollections lists the collections shared with the user, most
+// recently shared first.
+func (s *Storage) SharedCollections(ctx context.Context, userID int64) ([]models.SharedCollection, error) {
+	const op = "postgresql.SharedCollections"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT c.*, sh.role, o.username, sh.created_at
+		FROM keeper.collection_shares sh
+		CROSS JOIN LATERAL (SELECT `+collectionColumns+` FROM keeper.collections WHERE id = sh.collection_id AND deleted_at IS NULL) c
+		JOIN keeper.users_info o ON o.user_id = c.user_id
+		WHERE sh.user_id = $1 AND NOT o.is_blocked
+		ORDER BY sh.created_at DESC, sh.id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var shared []models.SharedCollection
+	for rows.Next() {
+		var sc models.SharedCollection
+		sc.Collection, err = scanCollection(rows, &sc.Role, &sc.Owner, &sc.SharedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		sc.Collection.Role = sc.Role
+		shared = append(shared, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return shared, nil
+}
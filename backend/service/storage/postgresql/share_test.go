@@ -0,0 +1,94 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCanAccess checks the role each user has on a shared collection and
+// its items, and that nobody has one on a collection in the trash.
+func TestCanAccess(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	ownerID := d.user("owner")
+	editorID := d.user("editor")
+	viewerID := d.user("viewer")
+	strangerID := d.user("stranger")
+
+	collectionID := d.collection(ownerID, "Coins", false)
+	itemID := d.item(collectionID, "Rouble")
+	trashedID := d.collection(ownerID, "Stamps", false)
+	trashedItemID := d.item(trashedID, "Penny Red")
+	for _, id := range []int64{collectionID, trashedID} {
+		for name, role := range map[string]string{"editor": models.RoleEditor, "viewer": models.RoleViewer} {
+			_, err := s.ShareCollection(ctx, ownerID, id, d.username(name), role)
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, s.DeleteCollection(ctx, ownerID, trashedID))
+
+	tests := []struct {
+		name         string
+		userID       int64
+		collectionID int64
+		itemID       int64
+		role         string
+		want         bool
+	}{
+		{name: "owner as owner", userID: ownerID, role: models.RoleOwner, want: true},
+		{name: "owner as editor", userID: ownerID, role: models.RoleEditor, want: true},
+		{name: "owner as viewer", userID: ownerID, role: models.RoleViewer, want: true},
+		{name: "editor as owner", userID: editorID, role: models.RoleOwner},
+		{name: "editor as editor", userID: editorID, role: models.RoleEditor, want: true},
+		{name: "editor as viewer", userID: editorID, role: models.RoleViewer, want: true},
+		{name: "viewer as editor", userID: viewerID, role: models.RoleEditor},
+		{name: "viewer as viewer", userID: viewerID, role: models.RoleViewer, want: true},
+		{name: "stranger as viewer", userID: strangerID, role: models.RoleViewer},
+		{name: "owner of trashed", userID: ownerID, collectionID: trashedID, itemID: trashedItemID, role: models.RoleViewer},
+		{name: "editor of trashed", userID: editorID, collectionID: trashedID, itemID: trashedItemID, role: models.RoleViewer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.collectionID == 0 {
+				tt.collectionID, tt.itemID = collectionID, itemID
+			}
+
+			err := collectionAccessible(ctx, s.db, tt.userID, tt.collectionID, tt.role)
+			if tt.want {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, storage.ErrCollectionNotFound)
+			}
+
+			err = itemAccessible(ctx, s.db, tt.userID, tt.itemID, tt.role)
+			if tt.want {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, storage.ErrItemNotFound)
+			}
+		})
+	}
+
+	// The roles hold for the writes that check them.
+	assert.ErrorIs(t, s.DeleteItem(ctx, viewerID, itemID), storage.ErrItemNotFound)
+	assert.NoError(t, s.DeleteItem(ctx, editorID, itemID))
+	assert.ErrorIs(t, s.DeleteCollection(ctx, editorID, collectionID), storage.ErrCollectionNotFound)
+
+	// Revoking the share takes the role away.
+	shares, err := s.CollectionShares(ctx, ownerID, collectionID)
+	require.NoError(t, err)
+	for _, share := range shares {
+		if share.Username == d.username("viewer") {
+			require.NoError(t, s.UnshareCollection(ctx, ownerID, collectionID, share.ShareID))
+		}
+	}
+	assert.ErrorIs(t, collectionAccessible(ctx, s.db, viewerID, collectionID, models.RoleViewer), storage.ErrCollectionNotFound)
+}
@@ -0,0 +1,162 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	shareLinkColumns = `l.id, l.collection_id, COALESCE(l.password_hash, ''), COALESCE(l.max_views, 0), l.views,
+	l.expires_at, l.created_at, l.last_viewed_at`
+
+	// shareLinkActive is the condition that link l still opens its
+	// collection.
+	shareLinkActive = `l.revoked_at IS NULL AND l.expires_at > now()
+	AND (l.max_views IS NULL OR l.views < l.max_views)`
+)
+
+func scanShareLink(row rowScanner) (models.ShareLink, error) {
+	var link models.ShareLink
+	var lastViewed sql.NullString
+	err := row.Scan(&link.LinkID, &link.CollectionID, &link.PasswordHash, &link.MaxViews, &link.Views,
+		&link.ExpiresAt, &link.CreatedAt, &lastViewed)
+	link.HasPassword = link.PasswordHash != ""
+	link.LastViewedAt = lastViewed.String
+	return link, err
+}
+
+// CreateShareLink records a link to a collection of the owner.
+func (s *Storage) CreateShareLink(ctx context.Context, ownerID int64, link models.ShareLink) (models.ShareLink, error) {
+	const op = "postgresql.CreateShareLink"
+
+	if err := ownedCollectionExists(ctx, s.db, ownerID, link.CollectionID); err != nil {
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO keeper.share_links (collection_id, created_by, token_hash, password_hash, expires_at, max_views)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+		link.CollectionID, ownerID, link.TokenHash, sql.NullString{String: link.PasswordHash, Valid: link.PasswordHash != ""},
+		link.ExpiresAt, nullableID(link.MaxViews)).Scan(&link.LinkID, &link.CreatedAt)
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, err)
+	}
+	link.HasPassword = link.PasswordHash != ""
+
+	return link, nil
+}
+
+// ShareLinks lists the outstanding links of the owner, newest first, only
+// those to collectionID unless it is 0.
+func (s *Storage) ShareLinks(ctx context.Context, ownerID, collectionID int64) ([]models.ShareLink, error) {
+	const op = "postgresql.ShareLinks"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+shareLinkColumns+` FROM keeper.share_links l
+		WHERE l.created_by = $1 AND ($2 = 0 OR l.collection_id = $2) AND `+shareLinkActive+`
+		ORDER BY l.id DESC`, ownerID, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var links []models.ShareLink
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink stops a link of the owner from working.
+func (s *Storage) RevokeShareLink(ctx context.Context, ownerID, linkID int64) error {
+	const op = "postgresql.RevokeShareLink"
+
+	res, err := s.db.ExecContext(ctx, `UPDATE keeper.share_links SET revoked_at = now()
+		WHERE id = $1 AND created_by = $2 AND revoked_at IS NULL`, linkID, ownerID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrShareLinkNotFound)
+	}
+
+	return nil
+}
+
+// ShareLink returns the active link with the token hash, password hash
+// included, without counting a view.
+func (s *Storage) ShareLink(ctx context.Context, tokenHash []byte) (models.ShareLink, error) {
+	const op = "postgresql.ShareLink"
+
+	link, err := scanShareLink(s.db.QueryRowContext(ctx, "SELECT "+shareLinkColumns+` FROM keeper.share_links l
+		WHERE l.token_hash = $1 AND `+shareLinkActive, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ShareLink{}, fmt.Errorf("%s: %w", op, storage.ErrShareLinkNotFound)
+		}
+		return models.ShareLink{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return link, nil
+}
+
+// ViewShareLink counts a view of a link and returns its collection with
+// the items. A link that ran out of views in the meantime, or whose owner
+// was blocked, opens nothing.
+func (s *Storage) ViewShareLink(ctx context.Context, linkID int64) (models.PublicCollection, []models.Item, error) {
+	const op = "postgresql.ViewShareLink"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var collectionID int64
+	err = tx.QueryRowContext(ctx, `UPDATE keeper.share_links l SET views = views + 1, last_viewed_at = now()
+		WHERE l.id = $1 AND `+shareLinkActive+` RETURNING l.collection_id`, linkID).Scan(&collectionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, storage.ErrShareLinkNotFound)
+		}
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	collection, err := scanPublicCollection(tx.QueryRowContext(ctx, "SELECT "+publicCollectionColumns+` FROM keeper.collections c
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		WHERE c.id = $1 AND c.deleted_at IS NULL AND NOT u.is_blocked`, collectionID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, storage.ErrShareLinkNotFound)
+		}
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+itemColumns+" FROM keeper.items i WHERE i.collection_id = $1 AND i.deleted_at IS NULL ORDER BY i.id", collectionID)
+	if err != nil {
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	items, err := scanItems(rows)
+	if err != nil {
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.PublicCollection{}, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return collection, items, nil
+}
@@ -0,0 +1,333 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// publicItemExists reports storage.ErrItemNotFound unless the item is in a
+// public collection of a user that is not blocked, and neither is in the
+// trash.
+func publicItemExists(ctx context.Context, q queryer, itemID int64) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		WHERE i.id = $1 AND c.is_public AND NOT u.is_blocked
+			AND i.deleted_at IS NULL AND c.deleted_at IS NULL)`, itemID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrItemNotFound
+	}
+	return nil
+}
+
+// publicCollectionExists reports storage.ErrCollectionNotFound unless the
+// collection is public and its owner is not blocked.
+func publicCollectionExists(ctx context.Context, q queryer, collectionID int64) error {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1"+publicCollectionsFrom+" AND c.id = $1)", collectionID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return storage.ErrCollectionNotFound
+	}
+	return nil
+}
+
+// FavoriteItem marks a public item as a favorite of the user. Marking it
+// twice is not an error.
+func (s *Storage) FavoriteItem(ctx context.Context, userID, itemID int64) error {
+	const op = "postgresql.FavoriteItem"
+
+	if err := publicItemExists(ctx, s.db, itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO keeper.favorites (user_id, item_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, userID, itemID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// FavoriteCollection marks a public collection as a favorite of the user.
+func (s *Storage) FavoriteCollection(ctx context.Context, userID, collectionID int64) error {
+	const op = "postgresql.FavoriteCollection"
+
+	if err := publicCollectionExists(ctx, s.db, collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO keeper.favorites (user_id, collection_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, userID, collectionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Unfavorite removes an item or a collection from the user's favorites.
+// Exactly one of itemID and collectionID is set.
+func (s *Storage) Unfavorite(ctx context.Context, userID, itemID, collectionID int64) error {
+	const op = "postgresql.Unfavorite"
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM keeper.favorites
+		WHERE user_id = $1 AND (item_id = $2 OR collection_id = $3)`, userID, itemID, collectionID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Favorites returns the favorites of the user that are still public.
+func (s *Storage) Favorites(ctx context.Context, userID int64) (models.Favorites, error) {
+	const op = "postgresql.Favorites"
+
+	var favorites models.Favorites
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+itemColumns+`, u.username, f.created_at
+		FROM keeper.favorites f
+		JOIN keeper.items i ON i.id = f.item_id
+		JOIN keeper.collections c ON c.id = i.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		WHERE f.user_id = $1 AND c.is_public AND NOT u.is_blocked
+			AND i.deleted_at IS NULL AND c.deleted_at IS NULL
+		ORDER BY f.id DESC`, userID)
+	if err != nil {
+		return models.Favorites{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var fav models.FavoriteItem
+		item, err := scanItem(rows, &fav.Owner, &fav.FavoritedAt)
+		if err != nil {
+			return models.Favorites{}, fmt.Errorf("%s: %w", op, err)
+		}
+		fav.Item = item
+		favorites.Items = append(favorites.Items, fav)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Favorites{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err = s.db.QueryContext(ctx, "SELECT "+publicCollectionColumns+`
+		FROM keeper.favorites f
+		JOIN keeper.collections c ON c.id = f.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		WHERE f.user_id = $1 AND c.is_public AND c.deleted_at IS NULL AND NOT u.is_blocked
+		ORDER BY f.id DESC`, userID)
+	if err != nil {
+		return models.Favorites{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	favorites.Collections, err = scanPublicCollections(rows)
+	if err != nil {
+		return models.Favorites{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return favorites, nil
+}
+
+// Follow makes the user a follower of the collector with username.
+// Following someone twice is not an error.
+func (s *Storage) Follow(ctx context.Context, userID int64, username string) error {
+	const op = "postgresql.Follow"
+
+	var followeeID int64
+	err := s.db.QueryRowContext(ctx, "SELECT user_id FROM keeper.users_info WHERE username = $1 AND NOT is_blocked", username).Scan(&followeeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if followeeID == userID {
+		return fmt.Errorf("%s: %w", op, storage.ErrFollowSelf)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO keeper.follows (follower_id, followee_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`, userID, followeeID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Only a new follower is news.
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 1 {
+		err := notify(ctx, tx, models.NotificationFollower, `SELECT followee_id AS user_id,
+			jsonb_build_object('follower', u.username) AS payload
+			FROM keeper.follows f JOIN keeper.users_info u ON u.user_id = f.follower_id
+			WHERE f.follower_id = $1 AND f.followee_id = $2`, userID, followeeID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Unfollow(ctx context.Context, userID int64, username string) error {
+	const op = "postgresql.Unfollow"
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM keeper.follows
+		WHERE follower_id = $1 AND followee_id = (SELECT user_id FROM keeper.users_info WHERE username = $2)`, userID, username)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// follows lists the other side of the follows of a user: its followers
+// when followers is set, the collectors it follows otherwise. Blocked users
+// are left out. So are, for public lists, followed collectors who keep
+// their followers private: listing them would tell who follows them.
+func (s *Storage) follows(ctx context.Context, userID int64, followers, public bool) ([]models.Follow, error) {
+	self, other := "f.followee_id", "f.follower_id"
+	if !followers {
+		self, other = other, self
+	}
+	cond := ""
+	if public && !followers {
+		cond = " AND NOT o.followers_private"
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT o.username, COALESCE(o.profile_image_url, ''), f.created_at
+		FROM keeper.follows f
+		JOIN keeper.users_info o ON o.user_id = `+other+`
+		WHERE `+self+` = $1 AND NOT o.is_blocked`+cond+`
+		ORDER BY f.created_at DESC, o.user_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var follows []models.Follow
+	for rows.Next() {
+		var f models.Follow
+		if err := rows.Scan(&f.Username, &f.ImageUrl, &f.FollowedAt); err != nil {
+			return nil, err
+		}
+		follows = append(follows, f)
+	}
+
+	return follows, rows.Err()
+}
+
+// Followers returns the followers of the user, newest first.
+func (s *Storage) Followers(ctx context.Context, userID int64) ([]models.Follow, error) {
+	const op = "postgresql.Followers"
+
+	follows, err := s.follows(ctx, userID, true, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+// Following returns the collectors the user follows, newest first.
+func (s *Storage) Following(ctx context.Context, userID int64) ([]models.Follow, error) {
+	const op = "postgresql.Following"
+
+	follows, err := s.follows(ctx, userID, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+// publicUserID returns the id of a user that is not blocked and whether
+// its followers are private.
+func (s *Storage) publicUserID(ctx context.Context, username string) (int64, bool, error) {
+	var userID int64
+	var private bool
+	err := s.db.QueryRowContext(ctx, "SELECT user_id, followers_private FROM keeper.users_info WHERE username = $1 AND NOT is_blocked",
+		username).Scan(&userID, &private)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, storage.ErrUserNotFound
+	}
+	return userID, private, err
+}
+
+// PublicFollowers returns the followers of a collector unless the collector
+// keeps them private.
+func (s *Storage) PublicFollowers(ctx context.Context, username string) ([]models.Follow, error) {
+	const op = "postgresql.PublicFollowers"
+
+	userID, private, err := s.publicUserID(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if private {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrFollowersPrivate)
+	}
+
+	follows, err := s.follows(ctx, userID, true, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+// PublicFollowing returns the collectors a collector follows, except those
+// who keep their followers private.
+func (s *Storage) PublicFollowing(ctx context.Context, username string) ([]models.Follow, error) {
+	const op = "postgresql.PublicFollowing"
+
+	userID, _, err := s.publicUserID(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	follows, err := s.follows(ctx, userID, false, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return follows, nil
+}
+
+// SetFollowersPrivate hides or shows the user's followers to others.
+func (s *Storage) SetFollowersPrivate(ctx context.Context, userID int64, private bool) error {
+	const op = "postgresql.SetFollowersPrivate"
+
+	res, err := s.db.ExecContext(ctx, "UPDATE keeper.users_info SET followers_private = $1 WHERE user_id = $2", private, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrUserNotFound)
+	}
+
+	return nil
+}
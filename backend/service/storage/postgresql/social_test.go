@@ -0,0 +1,45 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usernames(follows []models.Follow) []string {
+	names := make([]string, len(follows))
+	for i, f := range follows {
+		names[i] = f.Username
+	}
+	return names
+}
+
+func TestPublicFollowing_HidesPrivateFollowers(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	collectorID := d.user("collector")
+	d.user("open")
+	privateID := d.user("private")
+	for _, name := range []string{"open", "private"} {
+		require.NoError(t, s.Follow(ctx, collectorID, d.username(name)))
+	}
+	require.NoError(t, s.SetFollowersPrivate(ctx, privateID, true))
+
+	following, err := s.PublicFollowing(ctx, d.username("collector"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{d.username("open")}, usernames(following),
+		"following the private collector would give away one of their followers")
+
+	following, err = s.Following(ctx, collectorID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{d.username("open"), d.username("private")}, usernames(following))
+
+	_, err = s.PublicFollowers(ctx, d.username("private"))
+	assert.ErrorIs(t, err, storage.ErrFollowersPrivate)
+}
@@ -0,0 +1,265 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+func (s *Storage) AddItemTags(ctx context.Context, userID, itemID int64, tags []string) error {
+	const op = "postgresql.AddItemTags"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := upsertTags(ctx, tx, tags); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO keeper.item_tags (item_id, tag_id)
+		SELECT $1, id FROM keeper.tags WHERE name = ANY($2)
+		ON CONFLICT DO NOTHING`, itemID, pq.Array(tags))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionUpdate}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func upsertTags(ctx context.Context, q queryer, tags []string) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO keeper.tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING`, pq.Array(tags))
+	return err
+}
+
+func (s *Storage) RemoveItemTag(ctx context.Context, userID, itemID int64, tag string) error {
+	const op = "postgresql.RemoveItemTag"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := itemAccessible(ctx, tx, userID, itemID, models.RoleEditor); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM keeper.item_tags it USING keeper.tags t
+		WHERE it.tag_id = t.id AND it.item_id = $1 AND t.name = $2`, itemID, tag)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrTagNotFound)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionUpdate}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Tags returns the tags used on the user's items with the number of items
+// carrying each of them. Items in the trash are not counted.
+func (s *Storage) Tags(ctx context.Context, userID int64) ([]models.Tag, error) {
+	const op = "postgresql.Tags"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT t.id, t.name, count(*)
+		FROM keeper.tags t
+		JOIN keeper.item_tags it ON it.tag_id = t.id
+		JOIN keeper.items i ON i.id = it.item_id
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE c.user_id = $1 AND i.deleted_at IS NULL AND c.deleted_at IS NULL
+		GROUP BY t.id, t.name
+		ORDER BY count(*) DESC, t.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// AutocompleteTags suggests existing tags starting with prefix. The user's
+// own tags come first, then tags popular across the whole catalogue.
+func (s *Storage) AutocompleteTags(ctx context.Context, userID int64, prefix string, limit int) ([]models.Tag, error) {
+	const op = "postgresql.AutocompleteTags"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT t.id, t.name, count(it.item_id)
+		FROM keeper.tags t
+		LEFT JOIN keeper.item_tags it ON it.tag_id = t.id
+		LEFT JOIN keeper.items i ON i.id = it.item_id
+		LEFT JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE t.name LIKE $2
+		GROUP BY t.id, t.name
+		ORDER BY bool_or(c.user_id = $1) IS TRUE DESC, count(it.item_id) DESC, t.name
+		LIMIT $3`, userID, likeEscaper.Replace(prefix)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tags, err := scanTags(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tags, nil
+}
+
+// likeEscaper escapes LIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func scanTags(rows *sql.Rows) ([]models.Tag, error) {
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.TagID, &tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// ItemsByTags returns the user's items that carry every one of the tags.
+func (s *Storage) ItemsByTags(ctx context.Context, userID int64, tags []string) ([]models.Item, error) {
+	const op = "postgresql.ItemsByTags"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+itemColumns+` FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE c.user_id = $1 AND i.deleted_at IS NULL AND c.deleted_at IS NULL AND i.id IN (
+			SELECT it.item_id FROM keeper.item_tags it
+			JOIN keeper.tags t ON t.id = it.tag_id
+			WHERE t.name = ANY($2)
+			GROUP BY it.item_id
+			HAVING count(DISTINCT t.id) = cardinality($2::text[]))
+		ORDER BY i.id`, userID, pq.Array(tags))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	items, err := scanItems(rows)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return items, nil
+}
+
+// MergeTags retags the user's items carrying any of the from tags with into
+// and drops the from tags from them. Renaming a tag is merging a single tag
+// into a new name. Tags are shared between users, so the old tag rows are
+// only deleted once nobody uses them any more.
+func (s *Storage) MergeTags(ctx context.Context, userID int64, from []string, into string) error {
+	const op = "postgresql.MergeTags"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var used bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.item_tags it
+		JOIN keeper.tags t ON t.id = it.tag_id
+		JOIN keeper.items i ON i.id = it.item_id
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE c.user_id = $1 AND t.name = ANY($2))`, userID, pq.Array(from)).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if !used {
+		return fmt.Errorf("%s: %w", op, storage.ErrTagNotFound)
+	}
+
+	if err := upsertTags(ctx, tx, []string{into}); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO keeper.item_tags (item_id, tag_id)
+		SELECT DISTINCT it.item_id, (SELECT id FROM keeper.tags WHERE name = $3)
+		FROM keeper.item_tags it
+		JOIN keeper.tags t ON t.id = it.tag_id
+		JOIN keeper.items i ON i.id = it.item_id
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE c.user_id = $1 AND t.name = ANY($2)
+		ON CONFLICT DO NOTHING`, userID, pq.Array(from), into)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM keeper.item_tags it
+		USING keeper.tags t, keeper.items i, keeper.collections c
+		WHERE it.tag_id = t.id AND it.item_id = i.id AND i.collection_id = c.id
+			AND c.user_id = $1 AND t.name = ANY($2) AND t.name <> $3`, userID, pq.Array(from), into)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM keeper.tags t
+		WHERE t.name = ANY($1) AND t.name <> $2
+			AND NOT EXISTS(SELECT 1 FROM keeper.item_tags it WHERE it.tag_id = t.id)`, pq.Array(from), into)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	// Items that already carried into are not changed, and so not recorded.
+	by := models.Version{UserID: userID, Action: models.VersionUpdate}
+	err = recordItemVersions(ctx, tx, by, `i.collection_id IN (SELECT id FROM keeper.collections WHERE user_id = $1)
+		AND i.id IN (SELECT it.item_id FROM keeper.item_tags it JOIN keeper.tags t ON t.id = it.tag_id WHERE t.name = $2)`,
+		userID, into)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
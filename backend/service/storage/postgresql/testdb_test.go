@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// testDSNEnv names the database the storage tests run against. Everything
+// in its keeper and sso_schema schemas is dropped and migrated again once
+// per run, so it must be a database of its own; make test starts one.
+const testDSNEnv = "KEEPER_TEST_DSN"
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// newTestStorage returns a Storage on the test database, migrated to the
+// latest version. The test is skipped when testDSNEnv is not set.
+func newTestStorage(t *testing.T) *Storage {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skip(testDSNEnv + " is not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(20)
+
+	migrateOnce.Do(func() { migrateErr = migrateTestDB(db) })
+	require.NoError(t, migrateErr)
+
+	return &Storage{db: db, connStr: dsn}
+}
+
+// migrateTestDB creates the schemas from scratch and applies the up
+// migrations of the auth service, then of this one.
+func migrateTestDB(db *sql.DB) error {
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `DROP SCHEMA IF EXISTS keeper, sso_schema CASCADE;
+		CREATE SCHEMA sso_schema; CREATE SCHEMA keeper`); err != nil {
+		return err
+	}
+
+	for _, m := range []struct{ schema, dir string }{
+		{"sso_schema", "../../../auth/migrations"},
+		{"keeper", "../../migrations"},
+	} {
+		files, err := filepath.Glob(filepath.Join(m.dir, "*.up.sql"))
+		if err != nil {
+			return err
+		}
+		sort.Slice(files, func(i, j int) bool { return migrationVersion(files[i]) < migrationVersion(files[j]) })
+
+		if _, err := conn.ExecContext(ctx, "SET search_path TO "+m.schema); err != nil {
+			return err
+		}
+		for _, file := range files {
+			query, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			if _, err := conn.ExecContext(ctx, string(query)); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+
+	_, err = conn.ExecContext(ctx, "RESET search_path")
+	return err
+}
+
+// migrationVersion returns the number a migration file name starts with.
+func migrationVersion(file string) int {
+	version, _ := strconv.Atoi(strings.SplitN(filepath.Base(file), "_", 2)[0])
+	return version
+}
+
+// testData creates rows for a test under names of its own and deletes them
+// when the test ends.
+type testData struct {
+	t      *testing.T
+	s      *Storage
+	suffix string
+	users  []int64
+}
+
+func newTestData(t *testing.T, s *Storage) *testData {
+	d := &testData{t: t, s: s, suffix: strconv.FormatInt(time.Now().UnixNano(), 36)}
+	// Deleting the sso users cascades to everything they own.
+	t.Cleanup(func() {
+		s.db.Exec("DELETE FROM sso_schema.users WHERE id = ANY($1)", pq.Array(d.users))
+	})
+	return d
+}
+
+// user creates a user and returns its id.
+func (d *testData) user(name string) int64 {
+	d.t.Helper()
+
+	name = name + "-" + d.suffix
+	email := name + "@example.com"
+
+	var userID int64
+	require.NoError(d.t, d.s.db.QueryRow("INSERT INTO sso_schema.users (email, pass_hash) VALUES ($1, '') RETURNING id",
+		email).Scan(&userID))
+	d.users = append(d.users, userID)
+
+	_, err := d.s.db.Exec("INSERT INTO keeper.users_info (user_id, username, email) VALUES ($1, $2, $3)", userID, name, email)
+	require.NoError(d.t, err)
+
+	return userID
+}
+
+// username returns the username user created for name.
+func (d *testData) username(name string) string {
+	return name + "-" + d.suffix
+}
+
+// collection creates a collection of the user and returns its id.
+func (d *testData) collection(userID int64, name string, public bool) int64 {
+	d.t.Helper()
+
+	var collectionID int64
+	require.NoError(d.t, d.s.db.QueryRow("INSERT INTO keeper.collections (user_id, name, is_public) VALUES ($1, $2, $3) RETURNING id",
+		userID, name, public).Scan(&collectionID))
+	return collectionID
+}
+
+// item creates an item in a collection and returns its id.
+func (d *testData) item(collectionID int64, title string) int64 {
+	d.t.Helper()
+
+	var itemID int64
+	require.NoError(d.t, d.s.db.QueryRow("INSERT INTO keeper.items (collection_id, title) VALUES ($1, $2) RETURNING id",
+		collectionID, title).Scan(&itemID))
+	return itemID
+}
+
+// lot puts an active lot of the user up for price and returns its id.
+func (d *testData) lot(userID, collectionID int64, title string, price int64) int64 {
+	d.t.Helper()
+
+	var lotID int64
+	require.NoError(d.t, d.s.db.QueryRow(`INSERT INTO keeper.lots (user_id, collection_id, title, price, currency, status)
+		VALUES ($1, $2, $3, $4, 'RUB', 'active') RETURNING id`, userID, collectionID, title, price).Scan(&lotID))
+	return lotID
+}
@@ -0,0 +1,394 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const tradeColumns = `t.id, t.proposer_id, p.username, t.recipient_id, r.username, COALESCE(t.parent_id, 0),
+	t.proposer_collection_id, COALESCE(t.recipient_collection_id, 0),
+	COALESCE((SELECT array_agg(ti.item_id ORDER BY ti.item_id) FROM keeper.trade_items ti WHERE ti.trade_id = t.id AND ti.offered), '{}'),
+	COALESCE((SELECT array_agg(ti.item_id ORDER BY ti.item_id) FROM keeper.trade_items ti WHERE ti.trade_id = t.id AND NOT ti.offered), '{}'),
+	COALESCE(t.message, ''), t.status, t.created_at, t.updated_at
+	FROM keeper.trades t
+	JOIN keeper.users_info p ON p.user_id = t.proposer_id
+	JOIN keeper.users_info r ON r.user_id = t.recipient_id`
+
+func scanTrade(row rowScanner) (models.Trade, error) {
+	var t models.Trade
+	err := row.Scan(&t.TradeID, &t.ProposerID, &t.Proposer, &t.RecipientID, &t.Recipient, &t.ParentID,
+		&t.ProposerCollectionID, &t.RecipientCollectionID,
+		pq.Array(&t.OfferedItemIDs), pq.Array(&t.RequestedItemIDs),
+		&t.Message, &t.Status, &t.CreatedAt, &t.UpdatedAt)
+	return t, err
+}
+
+// tradeRecipient returns the owner of the requested items. They must all
+// belong to one user other than the proposer and be visible to the
+// proposer: in a public collection, or part of the trade being countered.
+func tradeRecipient(ctx context.Context, q queryer, t models.Trade) (int64, error) {
+	var recipientID sql.NullInt64
+	var owners, found int
+	err := q.QueryRowContext(ctx, `SELECT min(c.user_id), count(DISTINCT c.user_id), count(*)
+		FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = ANY($1) AND i.deleted_at IS NULL AND c.deleted_at IS NULL AND (c.is_public
+			OR EXISTS(SELECT 1 FROM keeper.trade_items ti WHERE ti.trade_id = $2 AND ti.item_id = i.id))`,
+		pq.Array(t.RequestedItemIDs), t.ParentID).Scan(&recipientID, &owners, &found)
+	if err != nil {
+		return 0, err
+	}
+	if owners != 1 || found != len(t.RequestedItemIDs) || recipientID.Int64 == t.ProposerID {
+		return 0, storage.ErrTradeItems
+	}
+	return recipientID.Int64, nil
+}
+
+// insertTrade stores a proposal of t.ProposerID and returns its id and
+// recipient. The offered items must be the proposer's own.
+func insertTrade(ctx context.Context, tx *sql.Tx, t models.Trade) (int64, int64, error) {
+	if err := ownedCollectionExists(ctx, tx, t.ProposerID, t.ProposerCollectionID); err != nil {
+		return 0, 0, err
+	}
+
+	var found int
+	err := tx.QueryRowContext(ctx, `SELECT count(*) FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = ANY($1) AND c.user_id = $2 AND i.deleted_at IS NULL AND c.deleted_at IS NULL`, pq.Array(t.OfferedItemIDs), t.ProposerID).Scan(&found)
+	if err != nil {
+		return 0, 0, err
+	}
+	if found != len(t.OfferedItemIDs) {
+		return 0, 0, storage.ErrItemNotFound
+	}
+
+	recipientID, err := tradeRecipient(ctx, tx, t)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var tradeID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.trades (proposer_id, recipient_id, parent_id, proposer_collection_id, message)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		t.ProposerID, recipientID, nullableID(t.ParentID), t.ProposerCollectionID, t.Message).Scan(&tradeID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO keeper.trade_items (trade_id, item_id, offered)
+		SELECT $1::int, unnest($2::int[]), TRUE UNION ALL SELECT $1::int, unnest($3::int[]), FALSE`,
+		tradeID, pq.Array(t.OfferedItemIDs), pq.Array(t.RequestedItemIDs))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return tradeID, recipientID, nil
+}
+
+// closeTrade moves a pending trade to status. A trade answered in the
+// meantime fails with storage.ErrTradeClosed.
+func closeTrade(ctx context.Context, q queryer, tradeID int64, status string) error {
+	res, err := q.ExecContext(ctx, "UPDATE keeper.trades SET status = $1 WHERE id = $2 AND status = 'pending'", status, tradeID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return storage.ErrTradeClosed
+	}
+	return nil
+}
+
+func (s *Storage) CreateTrade(ctx context.Context, t models.Trade) (int64, error) {
+	const op = "postgresql.CreateTrade"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	tradeID, _, err := insertTrade(ctx, tx, t)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyTrade(ctx, tx, models.NotificationEventProposed, tradeID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tradeID, nil
+}
+
+// CounterTrade closes the pending trade parentID as countered and answers it
+// with t, which goes back to the proposer of parentID.
+func (s *Storage) CounterTrade(ctx context.Context, parentID int64, t models.Trade) (int64, error) {
+	const op = "postgresql.CounterTrade"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var parentProposer int64
+	err = tx.QueryRowContext(ctx, "SELECT proposer_id FROM keeper.trades WHERE id = $1", parentID).Scan(&parentProposer)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrTradeNotFound)
+		}
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := closeTrade(ctx, tx, parentID, models.TradeCountered); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	t.ParentID = parentID
+	tradeID, recipientID, err := insertTrade(ctx, tx, t)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if recipientID != parentProposer {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrTradeItems)
+	}
+
+	if err := notifyTrade(ctx, tx, models.NotificationEventCountered, tradeID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return tradeID, nil
+}
+
+func (s *Storage) DeclineTrade(ctx context.Context, tradeID int64) error {
+	const op = "postgresql.DeclineTrade"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	if err := closeTrade(ctx, tx, tradeID, models.TradeDeclined); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyTrade(ctx, tx, models.TradeDeclined, tradeID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// AcceptTrade accepts a pending trade and moves the items of each side into
+// the target collection of the other in one transaction, recording every
+// move in keeper.item_transfers. The items are locked in id order and must
+// still belong to the side that put them in the trade and not be held by a
+// lot; otherwise nothing moves.
+func (s *Storage) AcceptTrade(ctx context.Context, tradeID, collectionID int64) error {
+	const op = "postgresql.AcceptTrade"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	t, err := scanTrade(tx.QueryRowContext(ctx, "SELECT "+tradeColumns+" WHERE t.id = $1 FOR UPDATE OF t", tradeID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrTradeNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if t.Status != models.TradePending {
+		return fmt.Errorf("%s: %w", op, storage.ErrTradeClosed)
+	}
+
+	if err := ownedCollectionExists(ctx, tx, t.RecipientID, collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := ownedCollectionExists(ctx, tx, t.ProposerID, t.ProposerCollectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, storage.ErrTradeStale)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT i.id, c.user_id FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id IN (SELECT item_id FROM keeper.trade_items WHERE trade_id = $1)
+			AND i.deleted_at IS NULL AND c.deleted_at IS NULL
+		ORDER BY i.id FOR UPDATE OF i`, tradeID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	owners := map[int64]int64{}
+	for rows.Next() {
+		var itemID, ownerID int64
+		if err := rows.Scan(&itemID, &ownerID); err != nil {
+			rows.Close()
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		owners[itemID] = ownerID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, id := range t.OfferedItemIDs {
+		if owners[id] != t.ProposerID {
+			return fmt.Errorf("%s: %w", op, storage.ErrTradeStale)
+		}
+	}
+	for _, id := range t.RequestedItemIDs {
+		if owners[id] != t.RecipientID {
+			return fmt.Errorf("%s: %w", op, storage.ErrTradeStale)
+		}
+	}
+
+	var listed bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.lot_items
+		WHERE held AND item_id IN (SELECT item_id FROM keeper.trade_items WHERE trade_id = $1))`, tradeID).Scan(&listed)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if listed {
+		return fmt.Errorf("%s: %w", op, storage.ErrItemListed)
+	}
+
+	moves := []struct {
+		items    []int64
+		from, to int64
+		target   int64
+	}{
+		{t.OfferedItemIDs, t.ProposerID, t.RecipientID, collectionID},
+		{t.RequestedItemIDs, t.RecipientID, t.ProposerID, t.ProposerCollectionID},
+	}
+	for _, m := range moves {
+		_, err := tx.ExecContext(ctx, `INSERT INTO keeper.item_transfers
+			(item_id, trade_id, from_user_id, to_user_id, from_collection_id, to_collection_id)
+			SELECT id, $1, $2, $3, collection_id, $4 FROM keeper.items WHERE id = ANY($5)`,
+			tradeID, m.from, m.to, m.target, pq.Array(m.items))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE keeper.items SET collection_id = $1 WHERE id = ANY($2)", m.target, pq.Array(m.items))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE keeper.trades SET status = 'accepted', recipient_collection_id = $1 WHERE id = $2",
+		collectionID, tradeID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := notifyTrade(ctx, tx, models.TradeAccepted, tradeID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) Trade(ctx context.Context, tradeID int64) (models.Trade, error) {
+	const op = "postgresql.Trade"
+
+	t, err := scanTrade(s.db.QueryRowContext(ctx, "SELECT "+tradeColumns+" WHERE t.id = $1", tradeID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Trade{}, fmt.Errorf("%s: %w", op, storage.ErrTradeNotFound)
+		}
+		return models.Trade{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return t, nil
+}
+
+// Trades returns the trades the user proposed or received, newest first.
+func (s *Storage) Trades(ctx context.Context, userID int64) ([]models.Trade, error) {
+	const op = "postgresql.Trades"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+tradeColumns+`
+		WHERE t.proposer_id = $1 OR t.recipient_id = $1
+		ORDER BY t.id DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var trades []models.Trade
+	for rows.Next() {
+		t, err := scanTrade(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		trades = append(trades, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return trades, nil
+}
+
+// ItemTransfers returns how an item of the user changed hands, oldest first.
+func (s *Storage) ItemTransfers(ctx context.Context, userID, itemID int64) ([]models.ItemTransfer, error) {
+	const op = "postgresql.ItemTransfers"
+
+	if err := ownedItemExists(ctx, s.db, userID, itemID); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT id, item_id, COALESCE(trade_id, 0), from_user_id, to_user_id,
+		COALESCE(from_collection_id, 0), COALESCE(to_collection_id, 0), created_at
+		FROM keeper.item_transfers WHERE item_id = $1 ORDER BY id`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var transfers []models.ItemTransfer
+	for rows.Next() {
+		var tr models.ItemTransfer
+		err := rows.Scan(&tr.TransferID, &tr.ItemID, &tr.TradeID, &tr.FromUserID, &tr.ToUserID,
+			&tr.FromCollectionID, &tr.ToCollectionID, &tr.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		transfers = append(transfers, tr)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return transfers, nil
+}
@@ -0,0 +1,111 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keepAttributes lets RevertItem take the attributes of a version as they
+// are.
+func keepAttributes(item models.Item) (models.Attributes, error) {
+	return item.Attributes, nil
+}
+
+// TestAcceptTrade_TransfersAndReverts trades a coin of alice for a stamp
+// of bob, then reverts the coin to its first version.
+func TestAcceptTrade_TransfersAndReverts(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	aliceID := d.user("alice")
+	bobID := d.user("bob")
+	aliceCollectionID := d.collection(aliceID, "Coins", true)
+	bobCollectionID := d.collection(bobID, "Stamps", true)
+
+	coinID, err := s.SetItem(ctx, aliceID, aliceCollectionID, "Rouble", "", 0, "Russia", nil, "1913", nil)
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateItem(ctx, aliceID, aliceCollectionID, coinID, "Rouble 1913", "", 0, "Russia", nil, "1913", nil))
+	stampID, err := s.SetItem(ctx, bobID, bobCollectionID, "Penny Red", "", 0, "UK", nil, "1841", nil)
+	require.NoError(t, err)
+
+	tradeID, err := s.CreateTrade(ctx, models.Trade{
+		ProposerID:           bobID,
+		ProposerCollectionID: bobCollectionID,
+		OfferedItemIDs:       []int64{stampID},
+		RequestedItemIDs:     []int64{coinID},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.AcceptTrade(ctx, tradeID, aliceCollectionID))
+	assert.ErrorIs(t, s.AcceptTrade(ctx, tradeID, aliceCollectionID), storage.ErrTradeClosed)
+
+	coin, err := s.Item(ctx, bobID, coinID)
+	require.NoError(t, err)
+	assert.Equal(t, bobCollectionID, coin.CollectionID)
+	stamp, err := s.Item(ctx, aliceID, stampID)
+	require.NoError(t, err)
+	assert.Equal(t, aliceCollectionID, stamp.CollectionID)
+	_, err = s.Item(ctx, aliceID, coinID)
+	assert.ErrorIs(t, err, storage.ErrItemNotFound)
+
+	transfers, err := s.ItemTransfers(ctx, bobID, coinID)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, models.ItemTransfer{
+		TransferID:       transfers[0].TransferID,
+		ItemID:           coinID,
+		TradeID:          tradeID,
+		FromUserID:       aliceID,
+		ToUserID:         bobID,
+		FromCollectionID: aliceCollectionID,
+		ToCollectionID:   bobCollectionID,
+		CreatedAt:        transfers[0].CreatedAt,
+	}, transfers[0])
+
+	// The history went with the coin: alice cannot revert it any more, and
+	// bob's revert keeps it in his collection.
+	assert.ErrorIs(t, s.RevertItem(ctx, aliceID, coinID, 1, keepAttributes), storage.ErrItemNotFound)
+	require.NoError(t, s.RevertItem(ctx, bobID, coinID, 1, keepAttributes))
+	coin, err = s.Item(ctx, bobID, coinID)
+	require.NoError(t, err)
+	assert.Equal(t, "Rouble", coin.Title)
+	assert.Equal(t, bobCollectionID, coin.CollectionID)
+}
+
+// TestAcceptTrade_Stale accepts a trade whose requested item went to the
+// trash after it was proposed, and expects nothing to move.
+func TestAcceptTrade_Stale(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	aliceID := d.user("alice")
+	bobID := d.user("bob")
+	aliceCollectionID := d.collection(aliceID, "Coins", true)
+	bobCollectionID := d.collection(bobID, "Stamps", true)
+	coinID := d.item(aliceCollectionID, "Rouble")
+	stampID := d.item(bobCollectionID, "Penny Red")
+
+	tradeID, err := s.CreateTrade(ctx, models.Trade{
+		ProposerID:           bobID,
+		ProposerCollectionID: bobCollectionID,
+		OfferedItemIDs:       []int64{stampID},
+		RequestedItemIDs:     []int64{coinID},
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteItem(ctx, aliceID, coinID))
+
+	assert.ErrorIs(t, s.AcceptTrade(ctx, tradeID, aliceCollectionID), storage.ErrTradeStale)
+	stamp, err := s.Item(ctx, bobID, stampID)
+	require.NoError(t, err)
+	assert.Equal(t, bobCollectionID, stamp.CollectionID)
+
+	trade, err := s.Trade(ctx, tradeID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TradePending, trade.Status)
+}
@@ -0,0 +1,258 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// notTrashed is the condition that the item or collection aliased a is not
+// in the trash.
+func notTrashed(a string) string {
+	return a + ".deleted_at IS NULL"
+}
+
+// trashItem moves an item to the trash. Callers make sure that no lot
+// holds it, so the lots it was in are kept as they are.
+func trashItem(ctx context.Context, q queryer, userID, itemID int64) error {
+	_, err := q.ExecContext(ctx, "UPDATE keeper.items SET deleted_at = now(), deleted_by = $2 WHERE id = $1",
+		itemID, userID)
+	return err
+}
+
+// Trash lists the collections of the user in the trash and the items in
+// the trash of collections the user may edit, most recently deleted first.
+// A collection in the trash hides its items, whether they are in the trash
+// themselves or not.
+func (s *Storage) Trash(ctx context.Context, userID int64) (models.Trash, error) {
+	const op = "postgresql.Trash"
+
+	var trash models.Trash
+
+	rows, err := s.db.QueryContext(ctx, `SELECT c.*,
+			(SELECT count(*) FROM keeper.items i WHERE i.collection_id = c.id AND i.deleted_at IS NULL)
+		FROM (SELECT `+collectionColumns+`, deleted_at,
+				COALESCE((SELECT u.username FROM keeper.users_info u WHERE u.user_id = collections.deleted_by), '')
+			FROM keeper.collections WHERE user_id = $1 AND deleted_at IS NOT NULL) c
+		ORDER BY c.deleted_at DESC, c.id DESC`, userID)
+	if err != nil {
+		return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tc models.TrashedCollection
+		tc.Collection, err = scanCollection(rows, &tc.DeletedAt, &tc.DeletedBy, &tc.ItemCount)
+		if err != nil {
+			return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+		}
+		trash.Collections = append(trash.Collections, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+	}
+	rows.Close()
+
+	rows, err = s.db.QueryContext(ctx, "SELECT "+itemColumns+`, c.name, COALESCE(u.username, ''), i.deleted_at
+		FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		LEFT JOIN keeper.users_info u ON u.user_id = i.deleted_by
+		WHERE i.deleted_at IS NOT NULL AND `+canAccess("c", "$1", models.RoleEditor)+`
+		ORDER BY i.deleted_at DESC, i.id DESC`, userID)
+	if err != nil {
+		return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ti models.TrashedItem
+		ti.Item, err = scanItem(rows, &ti.CollectionName, &ti.DeletedBy, &ti.DeletedAt)
+		if err != nil {
+			return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+		}
+		trash.Items = append(trash.Items, ti)
+	}
+	if err := rows.Err(); err != nil {
+		return models.Trash{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return trash, nil
+}
+
+// RestoreCollection takes a collection of the user out of the trash, with
+// the items that went there with it.
+func (s *Storage) RestoreCollection(ctx context.Context, userID, collectionID int64) error {
+	const op = "postgresql.RestoreCollection"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE keeper.collections SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`, collectionID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrCollectionNotFound)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionRestore}
+	if err := recordItemVersions(ctx, tx, by, "i.collection_id = $1 AND i.deleted_at IS NULL", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := recordCollectionVersions(ctx, tx, by, "id = $1", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// RestoreItem takes an item out of the trash of a collection the user may
+// edit. The collection must not be in the trash itself, and must not have
+// got another item with the same title in the meantime.
+func (s *Storage) RestoreItem(ctx context.Context, userID, itemID int64) error {
+	const op = "postgresql.RestoreItem"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var collectionID int64
+	var title string
+	err = tx.QueryRowContext(ctx, `SELECT i.collection_id, i.title FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = $1 AND i.deleted_at IS NOT NULL AND `+canAccess("c", "$2", models.RoleEditor)+`
+		FOR UPDATE OF i`, itemID, userID).Scan(&collectionID, &title)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var taken bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM keeper.items
+		WHERE collection_id = $1 AND title = $2 AND deleted_at IS NULL)`, collectionID, title).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if taken {
+		return fmt.Errorf("%s: %w", op, storage.ErrItemExists)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE keeper.items SET deleted_at = NULL, deleted_by = NULL WHERE id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionRestore}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeTrash deletes for good up to limit collections and up to limit items
+// that went to the trash before before. It returns how many it deleted and
+// the images they had, whose blobs are left to the caller.
+func (s *Storage) PurgeTrash(ctx context.Context, before time.Time, limit int) (int, []models.Image, error) {
+	const op = "postgresql.PurgeTrash"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	// Rows being restored right now are skipped, so fewer than limit may
+	// come back while more have expired. They are purged next time if they
+	// are still in the trash.
+	collectionIDs, err := expiredIDs(ctx, tx, "keeper.collections", before, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	itemIDs, err := expiredIDs(ctx, tx, "keeper.items", before, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if len(collectionIDs) == 0 && len(itemIDs) == 0 {
+		return 0, nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT item_id, "+imageColumns+` FROM keeper.images
+		WHERE item_id = ANY($1) OR item_id IN (SELECT id FROM keeper.items WHERE collection_id = ANY($2))`,
+		pq.Array(itemIDs), pq.Array(collectionIDs))
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	images, err := scanImages(rows, func(image *models.Image) *int64 { return &image.ItemID })
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err = tx.QueryContext(ctx, "SELECT collection_id, "+imageColumns+" FROM keeper.collection_images WHERE collection_id = ANY($1)",
+		pq.Array(collectionIDs))
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	collectionImages, err := scanImages(rows, func(image *models.Image) *int64 { return &image.CollectionID })
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	images = append(images, collectionImages...)
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.items WHERE id = ANY($1)", pq.Array(itemIDs)); err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.collections WHERE id = ANY($1)", pq.Array(collectionIDs)); err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return len(collectionIDs) + len(itemIDs), images, nil
+}
+
+// expiredIDs locks up to limit rows of table that went to the trash before
+// before, oldest first, and returns their ids.
+func expiredIDs(ctx context.Context, tx *sql.Tx, table string, before time.Time, limit int) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM `+table+`
+		WHERE deleted_at < $1 ORDER BY deleted_at, id LIMIT $2 FOR UPDATE SKIP LOCKED`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
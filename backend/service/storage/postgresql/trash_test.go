@@ -0,0 +1,111 @@
+package postgresql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemTitles returns the titles of the items of a collection the user sees.
+func itemTitles(t *testing.T, s *Storage, userID, collectionID int64) []string {
+	t.Helper()
+
+	items, _, err := s.Items(context.Background(), userID, collectionID, models.ListOptions{Limit: 50})
+	require.NoError(t, err)
+	titles := make([]string, len(items))
+	for i, item := range items {
+		titles[i] = item.Title
+	}
+	return titles
+}
+
+func TestTrash_HidesDeleted(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	ownerID := d.user("owner")
+	collectionID := d.collection(ownerID, "Coins", false)
+	roubleID := d.item(collectionID, "Rouble")
+	kopekID := d.item(collectionID, "Kopek")
+
+	require.NoError(t, s.DeleteItem(ctx, ownerID, roubleID))
+	_, err := s.Item(ctx, ownerID, roubleID)
+	assert.ErrorIs(t, err, storage.ErrItemNotFound)
+	assert.Equal(t, []string{"Kopek"}, itemTitles(t, s, ownerID, collectionID))
+	assert.ErrorIs(t, s.DeleteItem(ctx, ownerID, roubleID), storage.ErrItemNotFound)
+
+	trash, err := s.Trash(ctx, ownerID)
+	require.NoError(t, err)
+	require.Len(t, trash.Items, 1)
+	assert.Equal(t, roubleID, trash.Items[0].Item.ItemID)
+
+	// The title is free while the item is in the trash, and so the item
+	// cannot come back while another one has it.
+	otherID, err := s.SetItem(ctx, ownerID, collectionID, "Rouble", "", 0, "", nil, "1913", nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.RestoreItem(ctx, ownerID, roubleID), storage.ErrItemExists)
+	require.NoError(t, s.DeleteItem(ctx, ownerID, otherID))
+	require.NoError(t, s.RestoreItem(ctx, ownerID, roubleID))
+	assert.ElementsMatch(t, []string{"Rouble", "Kopek"}, itemTitles(t, s, ownerID, collectionID))
+
+	// A collection in the trash hides its items without trashing them.
+	require.NoError(t, s.DeleteCollection(ctx, ownerID, collectionID))
+	_, err = s.Item(ctx, ownerID, kopekID)
+	assert.ErrorIs(t, err, storage.ErrItemNotFound)
+	_, _, err = s.Items(ctx, ownerID, collectionID, models.ListOptions{Limit: 50})
+	assert.ErrorIs(t, err, storage.ErrCollectionNotFound)
+	assert.ErrorIs(t, s.RestoreItem(ctx, ownerID, otherID), storage.ErrItemNotFound)
+
+	trash, err = s.Trash(ctx, ownerID)
+	require.NoError(t, err)
+	require.Len(t, trash.Collections, 1)
+	assert.Equal(t, int64(2), trash.Collections[0].ItemCount)
+
+	require.NoError(t, s.RestoreCollection(ctx, ownerID, collectionID))
+	assert.ElementsMatch(t, []string{"Rouble", "Kopek"}, itemTitles(t, s, ownerID, collectionID))
+}
+
+// TestPurgeTrash_SkipsLocked purges expired items while one of them is
+// being restored, and expects that one to be left for the next run.
+func TestPurgeTrash_SkipsLocked(t *testing.T) {
+	s := newTestStorage(t)
+	d := newTestData(t, s)
+	ctx := context.Background()
+
+	ownerID := d.user("owner")
+	collectionID := d.collection(ownerID, "Coins", false)
+	lockedID := d.item(collectionID, "Rouble")
+	expiredID := d.item(collectionID, "Kopek")
+	_, err := s.db.ExecContext(ctx, "UPDATE keeper.items SET deleted_at = now() - interval '60 days' WHERE id = ANY($1)",
+		pq.Array([]int64{lockedID, expiredID}))
+	require.NoError(t, err)
+
+	exists := func(itemID int64) bool {
+		var exists bool
+		require.NoError(t, s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.items WHERE id = $1)", itemID).Scan(&exists))
+		return exists
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "SELECT id FROM keeper.items WHERE id = $1 FOR UPDATE", lockedID)
+	require.NoError(t, err)
+
+	before := time.Now().Add(-30 * 24 * time.Hour)
+	_, _, err = s.PurgeTrash(ctx, before, 100)
+	require.NoError(t, err)
+	assert.False(t, exists(expiredID))
+	assert.True(t, exists(lockedID))
+
+	require.NoError(t, tx.Rollback())
+	_, _, err = s.PurgeTrash(ctx, before, 100)
+	require.NoError(t, err)
+	assert.False(t, exists(lockedID))
+}
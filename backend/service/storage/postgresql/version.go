@@ -0,0 +1,332 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+// pendingVersion is a snapshot about to be recorded as the version after
+// the last one of its item or collection.
+type pendingVersion struct {
+	entityID     int64
+	collectionID int64
+	last         int
+	previous     []byte
+	snapshot     any
+}
+
+// lastVersion joins the newest version of the entity aliased by id.
+func lastVersion(entity, id string) string {
+	return ` LEFT JOIN LATERAL (SELECT v.version, v.snapshot FROM keeper.versions v
+		WHERE v.entity = '` + entity + `' AND v.entity_id = ` + id + `
+		ORDER BY v.version DESC LIMIT 1) lv ON TRUE`
+}
+
+// recordItemVersions records the items selected by cond, as they are now,
+// as a new version made by the user and action of by. An update that
+// changed nothing is not recorded.
+func recordItemVersions(ctx context.Context, q queryer, by models.Version, cond string, args ...any) error {
+	rows, err := q.QueryContext(ctx, "SELECT "+itemColumns+", COALESCE(lv.version, 0), lv.snapshot FROM keeper.items i"+
+		lastVersion(models.EntityItem, "i.id")+" WHERE "+cond, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var pending []pendingVersion
+	for rows.Next() {
+		var p pendingVersion
+		item, err := scanItem(rows, &p.last, &p.previous)
+		if err != nil {
+			return err
+		}
+		p.entityID, p.collectionID = item.ItemID, item.CollectionID
+		p.snapshot = itemSnapshot(item)
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return insertVersions(ctx, q, models.EntityItem, by, pending)
+}
+
+// recordCollectionVersions records the collections selected by cond like
+// recordItemVersions does items.
+func recordCollectionVersions(ctx context.Context, q queryer, by models.Version, cond string, args ...any) error {
+	rows, err := q.QueryContext(ctx, "SELECT c.*, COALESCE(lv.version, 0), lv.snapshot FROM (SELECT "+collectionColumns+
+		" FROM keeper.collections WHERE "+cond+") c"+lastVersion(models.EntityCollection, "c.id"), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var pending []pendingVersion
+	for rows.Next() {
+		var p pendingVersion
+		collection, err := scanCollection(rows, &p.last, &p.previous)
+		if err != nil {
+			return err
+		}
+		p.entityID, p.collectionID = collection.CollectionID, collection.CollectionID
+		p.snapshot = models.CollectionSnapshot{
+			Name:        collection.CollectionName,
+			Description: collection.Description,
+			CategoryID:  collection.CategoryID,
+			IsPublic:    collection.IsPublic,
+		}
+		pending = append(pending, p)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	return insertVersions(ctx, q, models.EntityCollection, by, pending)
+}
+
+func itemSnapshot(item models.Item) models.ItemSnapshot {
+	return models.ItemSnapshot{
+		CollectionID: item.CollectionID,
+		Title:        item.Title,
+		Description:  item.Description,
+		CategoryID:   item.CategoryID,
+		Country:      item.Country,
+		Year:         item.Year,
+		Attributes:   item.Attributes,
+		Images:       item.Images,
+		Tags:         item.Tags,
+	}
+}
+
+func insertVersions(ctx context.Context, q queryer, entity string, by models.Version, pending []pendingVersion) error {
+	for _, p := range pending {
+		snapshot, err := json.Marshal(p.snapshot)
+		if err != nil {
+			return err
+		}
+		changes, err := diffSnapshots(p.previous, snapshot)
+		if err != nil {
+			return err
+		}
+		if by.Action == models.VersionUpdate && len(changes) == 0 && p.last > 0 {
+			continue
+		}
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.ExecContext(ctx, `INSERT INTO keeper.versions
+			(entity, entity_id, collection_id, version, action, user_id, snapshot, changes, reverted_from)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			entity, p.entityID, p.collectionID, p.last+1, by.Action, nullableID(by.UserID), snapshot, changesJSON,
+			sql.NullInt64{Int64: int64(by.RevertedFrom), Valid: by.RevertedFrom != 0})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffSnapshots returns the fields whose values differ between two JSON
+// snapshots. Every field of the first version differs from nothing.
+func diffSnapshots(previous, current []byte) (map[string]models.FieldChange, error) {
+	var before, after map[string]any
+	if previous != nil {
+		if err := json.Unmarshal(previous, &before); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(current, &after); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]models.FieldChange)
+	for field, to := range after {
+		if from, ok := before[field]; !ok || !reflect.DeepEqual(from, to) {
+			changes[field] = models.FieldChange{From: from, To: to}
+		}
+	}
+	for field, from := range before {
+		if _, ok := after[field]; !ok {
+			changes[field] = models.FieldChange{From: from}
+		}
+	}
+	return changes, nil
+}
+
+// versionCollection returns the collection an item is in, or was in when it
+// was last changed if it is gone.
+func versionCollection(ctx context.Context, q queryer, entity string, entityID int64) (int64, error) {
+	if entity == models.EntityCollection {
+		return entityID, nil
+	}
+
+	var collectionID int64
+	err := q.QueryRowContext(ctx, `SELECT collection_id FROM (
+			SELECT collection_id, 0 AS rank FROM keeper.items WHERE id = $1
+			UNION ALL
+			(SELECT collection_id, 1 FROM keeper.versions WHERE entity = 'item' AND entity_id = $1
+				ORDER BY version DESC LIMIT 1)
+		) c ORDER BY rank LIMIT 1`, entityID).Scan(&collectionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrItemNotFound
+	}
+	return collectionID, err
+}
+
+// Versions returns the history of an item or a collection the user may
+// view, newest first.
+func (s *Storage) Versions(ctx context.Context, userID int64, entity string, entityID int64) ([]models.Version, error) {
+	const op = "postgresql.Versions"
+
+	collectionID, err := versionCollection(ctx, s.db, entity, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := collectionAccessible(ctx, s.db, userID, collectionID, models.RoleViewer); err != nil {
+		if entity == models.EntityItem && errors.Is(err, storage.ErrCollectionNotFound) {
+			err = storage.ErrItemNotFound
+		}
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT v.id, v.version, v.action, COALESCE(v.user_id, 0), COALESCE(u.username, ''),
+			v.snapshot, v.changes, COALESCE(v.reverted_from, 0), v.created_at
+		FROM keeper.versions v
+		LEFT JOIN keeper.users_info u ON u.user_id = v.user_id
+		WHERE v.entity = $1 AND v.entity_id = $2
+		ORDER BY v.version DESC`, entity, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var versions []models.Version
+	for rows.Next() {
+		var v models.Version
+		var snapshot, changes []byte
+		err := rows.Scan(&v.VersionID, &v.Version, &v.Action, &v.UserID, &v.Username,
+			&snapshot, &changes, &v.RevertedFrom, &v.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		v.Snapshot = snapshot
+		if err := json.Unmarshal(changes, &v.Changes); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return versions, nil
+}
+
+// RevertItem puts the fields and tags of an item the user may edit back to
+// what they were in version, and records that as a new version. The item
+// stays in the collection it is in now. check returns the attributes of
+// the version as they fit the category now, or fails the revert.
+func (s *Storage) RevertItem(ctx context.Context, userID, itemID int64, version int, check func(models.Item) (models.Attributes, error)) error {
+	const op = "postgresql.RevertItem"
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var collectionID int64
+	err = tx.QueryRowContext(ctx, `SELECT i.collection_id FROM keeper.items i
+		JOIN keeper.collections c ON c.id = i.collection_id
+		WHERE i.id = $1 AND i.deleted_at IS NULL AND `+canAccess("c", "$2", models.RoleEditor)+` FOR UPDATE OF i`, itemID, userID).Scan(&collectionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrItemNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := itemsLocked(ctx, tx, "li.item_id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var data []byte
+	err = tx.QueryRowContext(ctx, "SELECT snapshot FROM keeper.versions WHERE entity = 'item' AND entity_id = $1 AND version = $2",
+		itemID, version).Scan(&data)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%s: %w", op, storage.ErrVersionNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	var snapshot models.ItemSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var taken bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM keeper.items WHERE collection_id = $1 AND title = $2 AND id <> $3 AND deleted_at IS NULL)",
+		collectionID, snapshot.Title, itemID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if taken {
+		return fmt.Errorf("%s: %w", op, storage.ErrItemExists)
+	}
+
+	attributes, err := check(models.Item{CategoryID: snapshot.CategoryID, Attributes: snapshot.Attributes})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	attributesJSON, err := attributesJSON(attributes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE keeper.items SET title = $1, description = $2, category_id = $3, country = $4, item_images_url = $5, year = $6, attributes = $7 WHERE id = $8",
+		snapshot.Title, snapshot.Description, nullableID(snapshot.CategoryID), snapshot.Country, pq.Array(snapshot.Images),
+		sql.NullString{String: snapshot.Year, Valid: snapshot.Year != ""}, attributesJSON, itemID)
+	if err != nil {
+		var pgErr *pq.Error
+		if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+			return fmt.Errorf("%s: %w", op, storage.ErrCategoryNotFound)
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM keeper.item_tags WHERE item_id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if len(snapshot.Tags) > 0 {
+		if err := upsertTags(ctx, tx, snapshot.Tags); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO keeper.item_tags (item_id, tag_id)
+			SELECT $1, id FROM keeper.tags WHERE name = ANY($2)`, itemID, pq.Array(snapshot.Tags))
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	by := models.Version{UserID: userID, Action: models.VersionRevert, RevertedFrom: version}
+	if err := recordItemVersions(ctx, tx, by, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
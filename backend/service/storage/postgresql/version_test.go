@@ -0,0 +1,51 @@
+package postgresql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snapshotJSON(t *testing.T, snapshot models.ItemSnapshot) []byte {
+	t.Helper()
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	return data
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := models.ItemSnapshot{
+		Title:      "Rouble 1898",
+		Country:    "Russia",
+		Attributes: models.Attributes{"metal": "silver"},
+		Tags:       []string{"coins"},
+	}
+	after := before
+	after.Title = "Rouble 1899"
+	after.Attributes = models.Attributes{"metal": "silver", "weight_g": 20.0}
+	after.Tags = []string{"coins"}
+
+	changes, err := diffSnapshots(snapshotJSON(t, before), snapshotJSON(t, after))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.FieldChange{
+		"title": {From: "Rouble 1898", To: "Rouble 1899"},
+		"attributes": {
+			From: map[string]any{"metal": "silver"},
+			To:   map[string]any{"metal": "silver", "weight_g": 20.0},
+		},
+	}, changes)
+
+	changes, err = diffSnapshots(snapshotJSON(t, after), snapshotJSON(t, after))
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestDiffSnapshots_FirstVersion(t *testing.T) {
+	changes, err := diffSnapshots(nil, snapshotJSON(t, models.ItemSnapshot{Title: "Rouble 1898"}))
+	require.NoError(t, err)
+	assert.Equal(t, models.FieldChange{To: "Rouble 1898"}, changes["title"])
+	assert.Contains(t, changes, "tags")
+}
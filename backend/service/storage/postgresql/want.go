@@ -0,0 +1,336 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mmmakskl/HeritageKeeper/service/domain/models"
+	"github.com/mmmakskl/HeritageKeeper/service/storage"
+)
+
+const (
+	wantColumns = `w.id, w.user_id, w.title, w.keywords, COALESCE(w.category_id, 0), w.country,
+	COALESCE(w.year_from, 0), COALESCE(w.year_to, 0), COALESCE(w.max_price, 0), COALESCE(w.currency, ''),
+	w.attributes, w.created_at, w.updated_at`
+
+	// wantItemCondition matches a want w with an item i of a collection c
+	// owned by u. The text of the want is its keywords, or its title when
+	// there are none. Nothing in the trash matches.
+	wantItemCondition = `NOT u.is_blocked AND c.user_id <> w.user_id
+	AND i.deleted_at IS NULL AND c.deleted_at IS NULL
+	AND i.search_vector @@ (websearch_to_tsquery('russian', COALESCE(NULLIF(w.keywords, ''), w.title))
+		|| websearch_to_tsquery('english', COALESCE(NULLIF(w.keywords, ''), w.title)))
+	AND (w.category_id IS NULL OR i.category_id IN (
+		WITH RECURSIVE subcategories AS (
+			SELECT w.category_id AS id
+			UNION
+			SELECT ch.id FROM keeper.categories ch JOIN subcategories s ON ch.parent_id = s.id
+		) SELECT id FROM subcategories))
+	AND (w.country = '' OR lower(i.country) = lower(w.country))
+	AND (w.year_from IS NULL OR i.year >= w.year_from)
+	AND (w.year_to IS NULL OR i.year <= w.year_to)
+	AND COALESCE(i.attributes, '{}'::jsonb) @> w.attributes`
+
+	// wantLotCondition matches the price of a lot l. Exchange-only lots
+	// match any want.
+	wantLotCondition = `(w.max_price IS NULL OR l.exchange_only
+		OR (l.currency = w.currency AND l.price <= w.max_price))`
+
+	wantItemsFrom = ` FROM keeper.items i
+	JOIN keeper.collections c ON c.id = i.collection_id
+	JOIN keeper.users_info u ON u.user_id = c.user_id`
+
+	// maxWantMatches caps the matches returned at once.
+	maxWantMatches = 500
+)
+
+func scanWant(row rowScanner) (models.Want, error) {
+	var w models.Want
+	var attributes []byte
+	err := row.Scan(&w.WantID, &w.UserID, &w.Title, &w.Keywords, &w.CategoryID, &w.Country,
+		&w.YearFrom, &w.YearTo, &w.MaxPrice, &w.Currency, &attributes, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return models.Want{}, err
+	}
+	if err := json.Unmarshal(attributes, &w.Attributes); err != nil {
+		return models.Want{}, err
+	}
+	return w, nil
+}
+
+// wantArgs maps the optional fields of a want to nullable columns.
+func wantArgs(w models.Want) ([]any, error) {
+	attributes, err := attributesJSON(w.Attributes)
+	if err != nil {
+		return nil, err
+	}
+	nullable := func(v int64) sql.NullInt64 { return sql.NullInt64{Int64: v, Valid: v != 0} }
+	currency := sql.NullString{String: w.Currency, Valid: w.MaxPrice != 0}
+	return []any{w.Title, w.Keywords, nullableID(w.CategoryID), w.Country,
+		nullable(int64(w.YearFrom)), nullable(int64(w.YearTo)), nullable(w.MaxPrice), currency, attributes}, nil
+}
+
+func wantError(err error) error {
+	var pgErr *pq.Error
+	if errors.As(err, &pgErr) && pgErr.Code == pq.ErrorCode("23503") {
+		return storage.ErrCategoryNotFound
+	}
+	return err
+}
+
+// matchItems records matches of wants with public items. cond narrows the
+// wants and items to match, over the tables aliased w, i, c and u. With
+// notifyOwners the owners of the wants hear about the new matches.
+func matchItems(ctx context.Context, q queryer, notifyOwners bool, cond string, args ...any) error {
+	insert := `INSERT INTO keeper.want_matches (want_id, item_id)
+		SELECT w.id, i.id` + wantItemsFrom + `
+		CROSS JOIN keeper.wants w
+		WHERE c.is_public AND ` + cond + ` AND ` + wantItemCondition + `
+		ON CONFLICT DO NOTHING`
+	if notifyOwners {
+		return notifyMatches(ctx, q, insert, args...)
+	}
+	_, err := q.ExecContext(ctx, insert, args...)
+	return err
+}
+
+// matchLots records matches of wants with the items of active lots. Lots
+// are public whatever the collection, so private items match through them.
+// cond may refer to the lot l as well.
+func matchLots(ctx context.Context, q queryer, notifyOwners bool, cond string, args ...any) error {
+	insert := `INSERT INTO keeper.want_matches (want_id, item_id, lot_id)
+		SELECT w.id, i.id, l.id` + wantItemsFrom + `
+		JOIN keeper.lot_items li ON li.item_id = i.id AND li.held
+		JOIN keeper.lots l ON l.id = li.lot_id
+		CROSS JOIN keeper.wants w
+		WHERE l.status = 'active' AND ` + cond + ` AND ` + wantItemCondition + ` AND ` + wantLotCondition + `
+		ON CONFLICT DO NOTHING`
+	if notifyOwners {
+		return notifyMatches(ctx, q, insert, args...)
+	}
+	_, err := q.ExecContext(ctx, insert, args...)
+	return err
+}
+
+// MatchItem matches an item, and the lots holding it, against all wants.
+func (s *Storage) MatchItem(ctx context.Context, itemID int64) error {
+	const op = "postgresql.MatchItem"
+
+	if err := matchItems(ctx, s.db, true, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if err := matchLots(ctx, s.db, true, "i.id = $1", itemID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MatchCollection matches the items of a collection against all wants. It
+// is run when a collection becomes public.
+func (s *Storage) MatchCollection(ctx context.Context, collectionID int64) error {
+	const op = "postgresql.MatchCollection"
+
+	if err := matchItems(ctx, s.db, true, "c.id = $1", collectionID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// MatchLot matches the items of a lot against all wants.
+func (s *Storage) MatchLot(ctx context.Context, lotID int64) error {
+	const op = "postgresql.MatchLot"
+
+	if err := matchLots(ctx, s.db, true, "l.id = $1", lotID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// matchWant matches a want against everything on display. Its owner is
+// not notified: the matches are there when they look at the want.
+func matchWant(ctx context.Context, tx *sql.Tx, wantID int64) error {
+	if err := matchItems(ctx, tx, false, "w.id = $1", wantID); err != nil {
+		return err
+	}
+	return matchLots(ctx, tx, false, "w.id = $1", wantID)
+}
+
+// CreateWant adds a want of w.UserID and matches it against the public
+// items and lots already there.
+func (s *Storage) CreateWant(ctx context.Context, w models.Want) (int64, error) {
+	const op = "postgresql.CreateWant"
+
+	args, err := wantArgs(w)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	var wantID int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO keeper.wants
+		(title, keywords, category_id, country, year_from, year_to, max_price, currency, attributes, user_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`, append(args, w.UserID)...).Scan(&wantID)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, wantError(err))
+	}
+
+	if err := matchWant(ctx, tx, wantID); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return wantID, nil
+}
+
+// UpdateWant changes a want of w.UserID and matches it again. Matches found
+// before are kept, those that no longer fit are hidden by WantMatches.
+func (s *Storage) UpdateWant(ctx context.Context, w models.Want) error {
+	const op = "postgresql.UpdateWant"
+
+	args, err := wantArgs(w)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE keeper.wants
+		SET title = $1, keywords = $2, category_id = $3, country = $4, year_from = $5, year_to = $6,
+			max_price = $7, currency = $8, attributes = $9
+		WHERE id = $10 AND user_id = $11`, append(args, w.WantID, w.UserID)...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, wantError(err))
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWantNotFound)
+	}
+
+	if err := matchWant(ctx, tx, w.WantID); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *Storage) DeleteWant(ctx context.Context, userID, wantID int64) error {
+	const op = "postgresql.DeleteWant"
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM keeper.wants WHERE id = $1 AND user_id = $2", wantID, userID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	} else if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWantNotFound)
+	}
+
+	return nil
+}
+
+func (s *Storage) Want(ctx context.Context, userID, wantID int64) (models.Want, error) {
+	const op = "postgresql.Want"
+
+	w, err := scanWant(s.db.QueryRowContext(ctx, "SELECT "+wantColumns+" FROM keeper.wants w WHERE w.id = $1 AND w.user_id = $2", wantID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Want{}, fmt.Errorf("%s: %w", op, storage.ErrWantNotFound)
+		}
+		return models.Want{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return w, nil
+}
+
+// Wants returns the want list of the user, newest first.
+func (s *Storage) Wants(ctx context.Context, userID int64) ([]models.Want, error) {
+	const op = "postgresql.Wants"
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+wantColumns+" FROM keeper.wants w WHERE w.user_id = $1 ORDER BY w.id DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var wants []models.Want
+	for rows.Next() {
+		w, err := scanWant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		wants = append(wants, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return wants, nil
+}
+
+// WantMatches returns the matches of the user's wants, or of one want when
+// wantID is not 0, newest first. Matches are checked again on read, so
+// those of items that went private, changed or left their lot are hidden.
+func (s *Storage) WantMatches(ctx context.Context, userID, wantID int64) ([]models.WantMatch, error) {
+	const op = "postgresql.WantMatches"
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+itemColumns+`, m.id, w.id, w.title, u.username,
+			COALESCE(m.lot_id, 0), COALESCE(l.price, 0), COALESCE(l.currency, ''), m.created_at
+		FROM keeper.want_matches m
+		JOIN keeper.wants w ON w.id = m.want_id
+		JOIN keeper.items i ON i.id = m.item_id
+		JOIN keeper.collections c ON c.id = i.collection_id
+		JOIN keeper.users_info u ON u.user_id = c.user_id
+		LEFT JOIN keeper.lots l ON l.id = m.lot_id
+		WHERE w.user_id = $1 AND ($2 = 0 OR w.id = $2)
+			AND `+wantItemCondition+`
+			AND CASE WHEN m.lot_id IS NULL THEN c.is_public
+				ELSE l.status IN ('active', 'reserved') AND `+wantLotCondition+`
+					AND EXISTS(SELECT 1 FROM keeper.lot_items li WHERE li.lot_id = l.id AND li.item_id = i.id AND li.held)
+			END
+		ORDER BY m.id DESC LIMIT $3`, userID, wantID, maxWantMatches)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var matches []models.WantMatch
+	for rows.Next() {
+		var m models.WantMatch
+		item, err := scanItem(rows, &m.MatchID, &m.WantID, &m.WantTitle, &m.Owner, &m.LotID, &m.Price, &m.Currency, &m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		m.Item = item
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	return matches, nil
+}
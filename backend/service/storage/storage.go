@@ -3,14 +3,45 @@ package storage
 import "errors"
 
 var (
-	ErrUserExists         = errors.New("user already exists")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrCollectionExists   = errors.New("collection already exists")
-	ErrCollectionNotFound = errors.New("collection not found")
-	ErrCategoryExists     = errors.New("category already exists")
-	ErrCategoryNotFound   = errors.New("category not found")
-	ErrItemExists         = errors.New("item already exists")
-	ErrItemNotFound       = errors.New("item not found")
-	ErrNotExists          = errors.New("not exists")
-	ErrExists             = errors.New("exists")
+	ErrUserExists           = errors.New("user already exists")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrCollectionExists     = errors.New("collection already exists")
+	ErrCollectionNotFound   = errors.New("collection not found")
+	ErrCategoryExists       = errors.New("category already exists")
+	ErrCategoryNotFound     = errors.New("category not found")
+	ErrCategoryInUse        = errors.New("category is in use")
+	ErrCategoryCycle        = errors.New("category cannot be its own ancestor")
+	ErrItemExists           = errors.New("item already exists")
+	ErrItemNotFound         = errors.New("item not found")
+	ErrTagNotFound          = errors.New("tag not found")
+	ErrImageNotFound        = errors.New("image not found")
+	ErrLotNotFound          = errors.New("lot not found")
+	ErrLotLocked            = errors.New("lot cannot be changed in its current status")
+	ErrItemListed           = errors.New("item is already listed in another lot")
+	ErrItemLocked           = errors.New("item is reserved or sold")
+	ErrItemInLot            = errors.New("item is in a draft or active lot")
+	ErrOfferNotFound        = errors.New("offer not found")
+	ErrOfferClosed          = errors.New("offer is no longer pending")
+	ErrNotAuction           = errors.New("lot is not an auction")
+	ErrAuctionClosed        = errors.New("auction is not open for bids")
+	ErrBidTooLow            = errors.New("bid is below the minimum bid")
+	ErrTradeNotFound        = errors.New("trade not found")
+	ErrTradeClosed          = errors.New("trade is no longer pending")
+	ErrTradeItems           = errors.New("requested items must be public items of one other user")
+	ErrTradeStale           = errors.New("items of the trade changed hands")
+	ErrWantNotFound         = errors.New("want not found")
+	ErrFollowSelf           = errors.New("users cannot follow themselves")
+	ErrFollowersPrivate     = errors.New("followers are private")
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrRateLimited          = errors.New("too many requests")
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrShareNotFound        = errors.New("share not found")
+	ErrShareSelf            = errors.New("collections cannot be shared with their owner")
+	ErrOwnerOnly            = errors.New("only the owner of the collection can do this")
+	ErrShareLinkNotFound    = errors.New("share link not found")
+	ErrShareLinkPassword    = errors.New("wrong share link password")
+	ErrTooManyItems         = errors.New("selection matches too many items")
+	ErrVersionNotFound      = errors.New("version not found")
+	ErrNotExists            = errors.New("not exists")
+	ErrExists               = errors.New("exists")
 )